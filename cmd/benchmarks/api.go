@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/api"
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+// apiRequests are the requests timed against the API, a read-mostly mix as a dashboard
+// and the CLI send them
+var apiRequests = []struct {
+	method, path string
+	body         func(x, y float64, round int) string // for a UE at x,y; nil = no body
+}{
+	{"GET", "/api/gnodebs", nil},
+	{"GET", "/api/ues", nil},
+	{"GET", "/api/sessions", nil},
+	{"POST", "/api/ues/{imsi}/move", func(x, y float64, round int) string {
+		return fmt.Sprintf(`{"x": %g, "y": %g}`, x+float64(round%2)*10, y) // 10 m away and back
+	}},
+}
+
+// apiHandler serves every benchmark network in turn: routes go on the default mux once
+// and keep pointing at it
+var apiHandler *api.Handler
+
+// benchmarkAPI times API requests over HTTP against a simulator network of the given
+// size, with every UE registered and most holding a session
+func benchmarkAPI(sz size) ([]Result, error) {
+	net, err := newAPINetwork(sz)
+	if err != nil {
+		return nil, err
+	}
+	imsis := make([]string, sz.UEs)
+	start := make([][2]float64, sz.UEs)
+	for i := range imsis {
+		imsis[i] = fmt.Sprintf("99999%010d", i+1)
+		start[i] = [2]float64{net.UEs[imsis[i]].X, net.UEs[imsis[i]].Y}
+	}
+	if apiHandler == nil {
+		apiHandler = api.NewHandler(net)
+		apiHandler.RegisterRoutes()
+	} else {
+		*apiHandler = *api.NewHandler(net)
+	}
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+	client := server.Client()
+
+	var results []Result
+	for _, request := range apiRequests {
+		var failure error
+		var latencies []time.Duration
+		next := 0
+		result := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			latencies = latencies[:0]
+			for i := 0; i < b.N && failure == nil; i++ {
+				path := request.path
+				var body io.Reader
+				if request.body != nil {
+					k := next % sz.UEs
+					path = strings.Replace(path, "{imsi}", imsis[k], 1)
+					body = bytes.NewBufferString(request.body(start[k][0], start[k][1], next/sz.UEs+1))
+				}
+				next++
+				sent := time.Now()
+				failure = send(client, request.method, server.URL+path, body)
+				latencies = append(latencies, time.Since(sent))
+			}
+		})
+		if failure != nil {
+			return nil, fmt.Errorf("%s %s: %w", request.method, request.path, failure)
+		}
+		r := newResult("api "+request.method+" "+request.path, sz.GNodeBs, sz.UEs, len(net.SMF.Sessions), result)
+		r.P95Ns = float64(percentile(latencies, 95))
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// send makes one request and reads the whole response, as a client would
+func send(client *http.Client, method, url string, body io.Reader) error {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+// newAPINetwork builds a full simulator network, with its journal and metrics, laid out
+// like benchNetwork
+func newAPINetwork(sz size) (*network.Network, error) {
+	// Step 1: Topology: gNodeBs on a grid, one omnidirectional cell each
+	perRow := int(math.Ceil(math.Sqrt(float64(sz.GNodeBs))))
+	topology := &config.Topology{}
+	for i := 0; i < sz.GNodeBs; i++ {
+		topology.GNodeBs = append(topology.GNodeBs, config.GNodeB{
+			X: float64(i%perRow) * siteSpacing, Y: float64(i/perRow) * siteSpacing,
+			HeightM: ran.DefaultHeightM, Range: siteRange, TAC: ran.DefaultTAC,
+			Cells: []config.Cell{{
+				PCI: i % 1008, BeamwidthDeg: 360, TiltDeg: ran.DefaultTiltDeg,
+				FrequencyGHz: ran.DefaultCarrier.FrequencyGHz, BandwidthMHz: ran.DefaultCarrier.BandwidthMHz,
+				Numerology: ran.DefaultCarrier.Numerology, TxPowerDBm: ran.DefaultTxPowerDBm, MaxCap: sz.UEs,
+			}},
+		})
+	}
+
+	// Step 2: An empty subscriber file; subscribers are added directly
+	dir, err := os.MkdirTemp("", "netsim-benchmarks")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	subscribersPath := filepath.Join(dir, "subscribers.json")
+	if err := os.WriteFile(subscribersPath, []byte(`{"subscribers": []}`), 0644); err != nil {
+		return nil, err
+	}
+	net, err := network.New(topology, subscribersPath, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: UEs spread over the grid, registered, with a session each where the link
+	// can carry it
+	side := float64(perRow-1) * siteSpacing
+	rng := net.RNG.Stream(sim.StreamPlacement)
+	for i := 0; i < sz.UEs; i++ {
+		imsi := fmt.Sprintf("99999%010d", i+1)
+		net.UDM.Subscribers[imsi] = &udm.Subscriber{IMSI: imsi, SubscriptionStatus: "active", MaxDataRate: 100}
+		u, err := net.AddUE(imsi, rng.Float64()*side, rng.Float64()*side)
+		if err != nil {
+			return nil, err
+		}
+		if err := net.AMF.Attach(u); err != nil {
+			return nil, fmt.Errorf("UE %d: %w", i, err)
+		}
+		net.SMF.EstablishSession(u, smf.IoT)
+	}
+	return net, nil
+}
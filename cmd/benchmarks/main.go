@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: benchmarks [flags] [-json results.json] [-baseline baseline.json]
+
+Measures how the simulator scales, with the Go benchmark harness built into the
+program so it runs anywhere the binary does.
+
+selection     N gNodeBs on a square grid and N UEs driving through it; every tick
+              moves each UE once through the AMF, which reselects or hands over as
+              needed. One tick must stay well under a second for the network to be
+              usable interactively. -linear also times the same tick with a
+              single-bucket index, i.e. a linear scan over every gNodeB, for comparison.
+registration  a registered UE deregisters and attaches again (AMF.DeregisterUE, AMF.Attach)
+session       a PDU session is established and terminated while every UE holds one
+              (SMF.EstablishSession, SMF.TerminateSession)
+handover      a UE moves across to the neighbouring gNodeB (AMF.MoveUE, inter-gNodeB handover)
+api           GET /api/gnodebs, /api/ues, /api/sessions and POST /api/ues/{imsi}/move
+              over HTTP, against a full network with journal and metrics
+
+registration, session, handover and api run once per -scales UE count, with about
+one gNodeB per -per-gnodeb UEs (a full square grid). -json saves the results; keep a run from a release as
+the baseline and pass it to -baseline to see what got slower: the program then
+exits with status 1 when any benchmark is slower than -tolerance allows.
+baseline.json next to this program is such a run at the default flags.
+
+The same hot paths are Go benchmarks too, for go test -bench in internal/core/amf
+(Attach, MoveUE) and internal/core/smf (EstablishSession).
+`
+
+// interactive is the longest a tick may take
+const interactive = time.Second
+
+// benchmarks lists every benchmark -run can pick, in the order they run
+var benchmarks = []string{"selection", "registration", "session", "handover", "api"}
+
+func main() {
+	gNodeBs := flag.Int("gnodebs", 10_000, "gNodeBs in the cell selection benchmark")
+	ues := flag.Int("ues", 100_000, "UEs in the cell selection benchmark")
+	linear := flag.Bool("linear", false, "also time a linear scan over every gNodeB (slow at full size)")
+	run := flag.String("run", strings.Join(benchmarks, ","), "benchmarks to run, comma-separated")
+	scales := flag.String("scales", "100,1000,10000", "UE counts for registration, session, handover and api")
+	perGNodeB := flag.Int("per-gnodeb", 10, "UEs per gNodeB at each scale")
+	jsonPath := flag.String("json", "", "write the results to this file as JSON")
+	baselinePath := flag.String("baseline", "", "compare with the results in this file")
+	tolerance := flag.Float64("tolerance", 20, "percent slower than the baseline that still passes")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := runBenchmarks(*run, *scales, *perGNodeB, *gNodeBs, *ues, *linear, *jsonPath, *baselinePath, *tolerance/100); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+}
+
+func runBenchmarks(run, scales string, perGNodeB, gNodeBs, ues int, linear bool, jsonPath, baselinePath string, tolerance float64) error {
+	// Step 1: Check the arguments before spending minutes benchmarking
+	selected := make(map[string]bool)
+	for _, name := range strings.Split(run, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(benchmarks, name) {
+			return fmt.Errorf("unknown benchmark %q, use %s", name, strings.Join(benchmarks, ", "))
+		}
+		selected[name] = true
+	}
+	var ueCounts []int
+	for _, s := range strings.Split(scales, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 {
+			return fmt.Errorf("scales must be UE counts >= 1, got %q", s)
+		}
+		ueCounts = append(ueCounts, n)
+	}
+	if perGNodeB < 1 {
+		return fmt.Errorf("per-gnodeb must be >= 1, got %d", perGNodeB)
+	}
+	if tolerance < 0 {
+		return fmt.Errorf("tolerance must be >= 0, got %g%%", tolerance*100)
+	}
+	var baseline Report
+	if baselinePath != "" {
+		var err error
+		if baseline, err = readReport(baselinePath); err != nil {
+			return err
+		}
+	}
+
+	// Step 2: Run them
+	var results []Result
+	if selected["selection"] {
+		found, err := benchmarkSelection(gNodeBs, ues, linear)
+		if err != nil {
+			return err
+		}
+		results = append(results, found...)
+	}
+	for _, p := range procedures {
+		if !selected[p.name] {
+			continue
+		}
+		fmt.Println(p.name + ":")
+		for _, sz := range sizesFor(ueCounts, perGNodeB) {
+			r, err := p.run(sz)
+			if err != nil {
+				return err
+			}
+			printResult(r)
+			results = append(results, r)
+		}
+	}
+	if selected["api"] {
+		fmt.Println("api:")
+		for _, sz := range sizesFor(ueCounts, perGNodeB) {
+			found, err := benchmarkAPI(sz)
+			if err != nil {
+				return err
+			}
+			for _, r := range found {
+				printResult(r)
+			}
+			results = append(results, found...)
+		}
+	}
+
+	// Step 3: Save and compare
+	if jsonPath != "" {
+		if err := writeReport(jsonPath, newReport(results)); err != nil {
+			return err
+		}
+		fmt.Println("\nwrote", len(results), "results to", jsonPath)
+	}
+	if baselinePath != "" {
+		if regressions := compare(results, baseline, tolerance); regressions > 0 {
+			return fmt.Errorf("%d benchmarks are more than %.0f%% slower than %s", regressions, tolerance*100, baselinePath)
+		}
+	}
+	return nil
+}
+
+// printResult prints one line per benchmark and size
+func printResult(r Result) {
+	line := fmt.Sprintf("  %-26s %6d gNodeBs %7d UEs %7d sessions  %10s/op  %10.1f/s  %6d allocs/op",
+		strings.TrimPrefix(r.Name, "api "), r.GNodeBs, r.UEs, r.Sessions, formatNs(r.NsPerOp), r.OpsPerSec, r.AllocsPerOp)
+	if r.P95Ns > 0 {
+		line += "  p95 " + formatNs(r.P95Ns)
+	}
+	fmt.Println(line)
+}
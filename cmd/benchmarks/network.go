@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// The benchmark network: sites siteSpacing apart, each covering siteRange (so
+// neighbours overlap), and UEs driving at vehicle speed, one step per simulated second
+const (
+	siteSpacing = 500.0
+	siteRange   = 600.0
+	ueSpeed     = 15.0 // m/s
+)
+
+// benchNetwork is an AMF and SMF with gNodeBs on a square grid and registered UEs
+// spread over it. There is no journal or clock: the benchmarks time the procedures alone.
+type benchNetwork struct {
+	amf     *amf.AMF
+	smf     *smf.SMF
+	gNodeBs []*ran.GNodeB // in grid order, row by row
+	perRow  int
+	ues     []*ue.UE
+	side    float64 // width of the square area, m
+	rng     *rand.Rand
+}
+
+func newBenchNetwork(gNodeBs, ues int, bucketSize float64) (*benchNetwork, error) {
+	subscribers := &udm.UDM{Subscribers: make(map[string]*udm.Subscriber)}
+	a := amf.NewAMF(subscribers)
+	a.Index = ran.NewIndex(bucketSize)
+	s := smf.NewSMF(subscribers, a)
+	a.Sessions = s
+
+	n := &benchNetwork{amf: a, smf: s, perRow: int(math.Ceil(math.Sqrt(float64(gNodeBs)))), rng: rand.New(rand.NewSource(1))}
+	for i := 0; i < gNodeBs; i++ {
+		x, y := float64(i%n.perRow)*siteSpacing, float64(i/n.perRow)*siteSpacing
+		cell := ran.CellConfig{PCI: i % 1008, Carrier: ran.DefaultCarrier, TxPowerDBm: ran.DefaultTxPowerDBm, MaxCap: ues}
+		g, err := ran.NewSectorGNodeB(x, y, siteRange, []ran.CellConfig{cell})
+		if err != nil {
+			return nil, err
+		}
+		a.RegisterGNodeB(g)
+		n.gNodeBs = append(n.gNodeBs, g)
+	}
+
+	n.side = float64(n.perRow-1) * siteSpacing
+	for i := 0; i < ues; i++ {
+		imsi := fmt.Sprintf("99999%010d", i+1)
+		subscribers.Subscribers[imsi] = &udm.Subscriber{IMSI: imsi, SubscriptionStatus: "active", MaxDataRate: 100}
+		u := ue.NewUE(imsi, n.rng.Float64()*n.side, n.rng.Float64()*n.side)
+		u.Heading = n.rng.Float64() * 2 * math.Pi
+		if err := a.Attach(u); err != nil {
+			return nil, fmt.Errorf("UE %d: %w", i, err)
+		}
+		n.ues = append(n.ues, u)
+	}
+	return n, nil
+}
+
+// withSessions gives every UE whose link can carry it one IoT session, so session
+// procedures run against a populated SMF, and returns the UEs that got one
+func (n *benchNetwork) withSessions() []*ue.UE {
+	var served []*ue.UE
+	for _, u := range n.ues {
+		if _, err := n.smf.EstablishSession(u, smf.IoT); err == nil {
+			served = append(served, u)
+		}
+	}
+	return served
+}
+
+// neighbour returns the gNodeB next to the one at grid index i, along the row
+func (n *benchNetwork) neighbour(i int) *ran.GNodeB {
+	if i%n.perRow+1 < n.perRow && i+1 < len(n.gNodeBs) {
+		return n.gNodeBs[i+1]
+	}
+	return n.gNodeBs[i-1]
+}
+
+// tick moves every UE one second further, turning back at the edge of the area
+func (n *benchNetwork) tick() {
+	for _, u := range n.ues {
+		x, y := u.X+ueSpeed*math.Cos(u.Heading), u.Y+ueSpeed*math.Sin(u.Heading)
+		if x < 0 || y < 0 || x > n.side || y > n.side {
+			u.Heading += math.Pi
+			x, y = u.X, u.Y
+		}
+		n.amf.MoveUE(u, x, y)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// size is the network a benchmark runs against
+type size struct {
+	GNodeBs, UEs int
+}
+
+// sizesFor gives every UE count about one gNodeB per uesPerGNodeB UEs, rounded up to
+// fill a square grid (so every UE is in range) of at least 2x2 (so there is somewhere to
+// hand over to)
+func sizesFor(ueCounts []int, uesPerGNodeB int) []size {
+	var sizes []size
+	for _, ues := range ueCounts {
+		perRow := max(2, int(math.Ceil(math.Sqrt(float64(ues)/float64(uesPerGNodeB)))))
+		sizes = append(sizes, size{GNodeBs: perRow * perRow, UEs: ues})
+	}
+	return sizes
+}
+
+// procedure is a core procedure benchmark: it times one operation against a network
+type procedure struct {
+	name string
+	run  func(sz size) (Result, error)
+}
+
+var procedures = []procedure{
+	{"registration", benchmarkRegistration},
+	{"session", benchmarkSession},
+	{"handover", benchmarkHandover},
+}
+
+// benchmarkRegistration times a registered UE deregistering and attaching again: cell
+// selection, RRC setup and the UDM check in AMF.Attach, and AMF.DeregisterUE
+func benchmarkRegistration(sz size) (Result, error) {
+	n, err := newBenchNetwork(sz.GNodeBs, sz.UEs, ran.DefaultBucketSize)
+	if err != nil {
+		return Result{}, err
+	}
+	var failure error
+	next := 0
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N && failure == nil; i++ {
+			u := n.ues[next%len(n.ues)]
+			next++
+			if failure = n.amf.DeregisterUE(u); failure == nil {
+				failure = n.amf.Attach(u)
+			}
+		}
+	})
+	return newResult("registration", sz.GNodeBs, sz.UEs, 0, result), failure
+}
+
+// benchmarkSession times SMF.EstablishSession (with its link check) and
+// SMF.TerminateSession while the UEs already hold a session. Only UEs whose link can
+// carry a second session are used, so every establishment succeeds.
+func benchmarkSession(sz size) (Result, error) {
+	n, err := newBenchNetwork(sz.GNodeBs, sz.UEs, ran.DefaultBucketSize)
+	if err != nil {
+		return Result{}, err
+	}
+	var ready []*ue.UE
+	for _, u := range n.withSessions() {
+		if session, err := n.smf.EstablishSession(u, smf.VoIP); err == nil {
+			n.smf.TerminateSession(session.SessionID)
+			ready = append(ready, u)
+		}
+	}
+	if len(ready) == 0 {
+		return Result{}, fmt.Errorf("session: no UE has a link for two sessions")
+	}
+
+	var failure error
+	next := 0
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N && failure == nil; i++ {
+			u := ready[next%len(ready)]
+			next++
+			var session *smf.PDUSession
+			if session, failure = n.smf.EstablishSession(u, smf.VoIP); failure == nil {
+				failure = n.smf.TerminateSession(session.SessionID)
+			}
+		}
+	})
+	return newResult("session", sz.GNodeBs, sz.UEs, len(n.smf.Sessions), result), failure
+}
+
+// shuttle is a UE going back and forth between two neighbouring gNodeBs
+type shuttle struct {
+	u              *ue.UE
+	ax, ay, bx, by float64
+	atB            bool
+}
+
+// benchmarkHandover times AMF.MoveUE for moves that each end in an inter-gNodeB
+// handover: every UE shuttles between points 20% and 80% of the way from a gNodeB to
+// its neighbour
+func benchmarkHandover(sz size) (Result, error) {
+	n, err := newBenchNetwork(sz.GNodeBs, sz.UEs, ran.DefaultBucketSize)
+	if err != nil {
+		return Result{}, err
+	}
+	shuttles := make([]*shuttle, len(n.ues))
+	for i, u := range n.ues {
+		g := n.gNodeBs[i%len(n.gNodeBs)]
+		to := n.neighbour(i % len(n.gNodeBs))
+		s := &shuttle{u: u, ax: g.X + 0.2*(to.X-g.X), ay: g.Y + 0.2*(to.Y-g.Y), bx: g.X + 0.8*(to.X-g.X), by: g.Y + 0.8*(to.Y-g.Y)}
+		if err := n.amf.MoveUE(u, s.ax, s.ay); err != nil {
+			return Result{}, err
+		}
+		shuttles[i] = s
+	}
+
+	var failure error
+	moves, handovers, next := 0, 0, 0
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		before := len(n.amf.Handovers)
+		for i := 0; i < b.N && failure == nil; i++ {
+			s := shuttles[next%len(shuttles)]
+			next++
+			s.atB = !s.atB
+			if s.atB {
+				failure = n.amf.MoveUE(s.u, s.bx, s.by)
+			} else {
+				failure = n.amf.MoveUE(s.u, s.ax, s.ay)
+			}
+		}
+		moves += b.N
+		handovers += len(n.amf.Handovers) - before
+		n.amf.Handovers = n.amf.Handovers[:0] // only counted here; do not let them pile up
+	})
+	if failure == nil && handovers < moves {
+		failure = fmt.Errorf("handover: only %d of %d moves handed over", handovers, moves)
+	}
+	return newResult("handover", sz.GNodeBs, sz.UEs, 0, result), failure
+}
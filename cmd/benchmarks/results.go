@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Result is one benchmark at one network size
+type Result struct {
+	Name        string  `json:"name"`
+	GNodeBs     int     `json:"gnodebs"`
+	UEs         int     `json:"ues"`
+	Sessions    int     `json:"sessions"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"nsPerOp"`
+	OpsPerSec   float64 `json:"opsPerSec"`
+	P95Ns       float64 `json:"p95Ns,omitempty"` // 95th percentile latency, where measured per operation
+	AllocsPerOp int64   `json:"allocsPerOp"`
+	BytesPerOp  int64   `json:"bytesPerOp"`
+}
+
+// Key identifies a result across runs: the benchmark and the network size. Sessions
+// are left out, as how many links can carry one depends on the radio model.
+func (r Result) Key() string {
+	return fmt.Sprintf("%s gnodebs=%d ues=%d", r.Name, r.GNodeBs, r.UEs)
+}
+
+// newResult reads a testing.Benchmark outcome
+func newResult(name string, gNodeBs, ues, sessions int, b testing.BenchmarkResult) Result {
+	r := Result{
+		Name:        name,
+		GNodeBs:     gNodeBs,
+		UEs:         ues,
+		Sessions:    sessions,
+		Iterations:  b.N,
+		NsPerOp:     float64(b.T.Nanoseconds()) / float64(max(b.N, 1)),
+		AllocsPerOp: b.AllocsPerOp(),
+		BytesPerOp:  b.AllocedBytesPerOp(),
+	}
+	if r.NsPerOp > 0 {
+		r.OpsPerSec = 1e9 / r.NsPerOp
+	}
+	return r
+}
+
+// Report is what -json writes and -baseline reads
+type Report struct {
+	Time      time.Time `json:"time"`
+	GoVersion string    `json:"goVersion"`
+	GOOS      string    `json:"goos"`
+	GOARCH    string    `json:"goarch"`
+	CPUs      int       `json:"cpus"`
+	Results   []Result  `json:"results"`
+}
+
+func newReport(results []Result) Report {
+	return Report{
+		Time:      time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Results:   results,
+	}
+}
+
+// writeReport saves a report as indented JSON
+func writeReport(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// readReport loads a report saved by writeReport
+func readReport(path string) (Report, error) {
+	var report Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
+
+// compare prints every result against the baseline result with the same key and
+// returns how many are slower by more than tolerance (0.2 = 20%)
+func compare(results []Result, baseline Report, tolerance float64) int {
+	old := make(map[string]Result)
+	for _, r := range baseline.Results {
+		old[r.Key()] = r
+	}
+	fmt.Printf("\ncompared with the baseline of %s (%s, %s/%s, %d CPUs), tolerance %.0f%%:\n",
+		baseline.Time.Format("2006-01-02 15:04"), baseline.GoVersion, baseline.GOOS, baseline.GOARCH, baseline.CPUs, tolerance*100)
+
+	sorted := append([]Result(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key() < sorted[j].Key() })
+	width := 0
+	for _, r := range sorted {
+		width = max(width, len(r.Key()))
+	}
+	regressions := 0
+	for _, r := range sorted {
+		before, found := old[r.Key()]
+		if !found || before.NsPerOp <= 0 {
+			fmt.Printf("  %-*s %10s  new\n", width, r.Key(), formatNs(r.NsPerOp))
+			continue
+		}
+		change := r.NsPerOp/before.NsPerOp - 1
+		verdict := "✓"
+		if change > tolerance {
+			verdict = "✗ slower"
+			regressions++
+		}
+		fmt.Printf("  %-*s %10s  was %10s  %+6.1f%%  %s\n", width, r.Key(), formatNs(r.NsPerOp), formatNs(before.NsPerOp), change*100, verdict)
+	}
+	return regressions
+}
+
+// formatNs prints a duration in nanoseconds with a sensible unit
+func formatNs(ns float64) string {
+	switch {
+	case ns < 1e3:
+		return fmt.Sprintf("%.0fns", ns)
+	case ns < 1e6:
+		return fmt.Sprintf("%.1fµs", ns/1e3)
+	case ns < 1e9:
+		return fmt.Sprintf("%.2fms", ns/1e6)
+	}
+	return fmt.Sprintf("%.2fs", ns/1e9)
+}
+
+// percentile returns the p-th percentile (0-100) of durations, sorting them
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations[min(len(durations)-1, int(float64(len(durations))*p/100))]
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/ran"
+)
+
+// benchmarkSelection times ticks of the moving UEs with the grid index, and with a
+// linear scan if asked. Each result is one tick.
+func benchmarkSelection(gNodeBs, ues int, linear bool) ([]Result, error) {
+	fmt.Printf("cell selection: %d gNodeBs, %d UEs moving every tick\n", gNodeBs, ues)
+	modes := []struct {
+		name       string
+		bucketSize float64
+	}{{"grid index", ran.DefaultBucketSize}}
+	if linear {
+		modes = append(modes, struct {
+			name       string
+			bucketSize float64
+		}{"linear scan", math.MaxFloat64})
+	}
+
+	var results []Result
+	for _, mode := range modes {
+		start := time.Now()
+		n, err := newBenchNetwork(gNodeBs, ues, mode.bucketSize)
+		if err != nil {
+			return nil, err
+		}
+		setup := time.Since(start)
+
+		handovers := len(n.amf.Handovers)
+		result := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n.tick()
+			}
+		})
+		perTick := time.Duration(result.NsPerOp())
+		verdict := "✓ interactive"
+		if perTick > interactive {
+			verdict = "✗ slower than " + interactive.String()
+		}
+		fmt.Printf("  %-12s %10v/tick  %8.2f µs/move  %6d ticks  %7d handovers  (setup %v)  %s\n",
+			mode.name, perTick.Round(time.Microsecond), float64(result.NsPerOp())/float64(ues)/1e3,
+			result.N, len(n.amf.Handovers)-handovers, setup.Round(time.Millisecond), verdict)
+		results = append(results, newResult("selection "+mode.name, gNodeBs, ues, 0, result))
+	}
+	return results, nil
+}
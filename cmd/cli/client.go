@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// client talks to a running simulator's HTTP API
+type client struct {
+	base string // e.g. http://localhost:8080
+	http *http.Client
+}
+
+func newClient(addr string) *client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &client{base: strings.TrimRight(addr, "/"), http: &http.Client{}}
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into
+// out (nil = ignore it). Non-2xx responses become errors carrying the server's message.
+func (c *client) do(method, path string, body, out interface{}) error {
+	data, _, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// send sends a request with an optional JSON body and returns the raw response body
+// and headers
+func (c *client) send(method, path string, body interface{}) ([]byte, http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot reach the simulator at %s: %w", c.base, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, errorMessage(data))
+	}
+	return data, resp.Header, nil
+}
+
+// errorMessage reads the server's error body: {"error", "code"}, or plain text from
+// older servers
+func errorMessage(data []byte) string {
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(data, &body) != nil || body.Error == "" {
+		return strings.TrimSpace(string(data))
+	}
+	if body.Code == "" {
+		return body.Error
+	}
+	return body.Error + " [" + body.Code + "]"
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// coverageCommand handles "coverage [-step m] [-area ...] [-metric m] [-scale n] <file>":
+// it fetches a coverage map and writes it to file, in the format its extension names
+func coverageCommand(c *client, args []string) error {
+	flags := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	step := flags.Float64("step", 0, "grid step in metres (0 = about 100x100 points)")
+	area := flags.String("area", "", "minX,minY,maxX,maxY in metres (default: every gNodeB's range)")
+	metric := flags.String("metric", "rsrp", "png only: rsrp, sinr, throughput or server")
+	scale := flags.Int("scale", 0, "png only: pixels per grid point (0 = about 800 pixels wide)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: coverage [flags] <file.csv|file.geojson|file.png>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("coverage needs one output file")
+	}
+	path := flags.Arg(0)
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	switch format {
+	case "csv", "geojson", "png":
+	case "json":
+		format = "geojson"
+	default:
+		return fmt.Errorf("cannot tell the format of %s, use .csv, .geojson or .png", path)
+	}
+
+	query := url.Values{"format": {format}, "metric": {*metric}}
+	if *step > 0 {
+		query.Set("step", fmt.Sprint(*step))
+	}
+	if *area != "" {
+		query.Set("area", *area)
+	}
+	if *scale > 0 {
+		query.Set("scale", fmt.Sprint(*scale))
+	}
+	data, _, err := c.send("GET", "/api/coverage?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote coverage map to %s (%d bytes)\n", path, len(data))
+	return nil
+}
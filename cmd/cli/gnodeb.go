@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gnodebInfo mirrors an entry of /api/gnodebs
+type gnodebInfo struct {
+	ID           int     `json:"id"`
+	X            float64 `json:"x"`
+	Y            float64 `json:"y"`
+	Range        float64 `json:"range"`
+	Down         bool    `json:"down"`
+	ConnectedUEs int     `json:"connectedUEs"`
+	InactiveUEs  int     `json:"inactiveUEs"`
+	MaxCap       int     `json:"maxCap"`
+	Cells        []struct {
+		ID           int     `json:"id"`
+		PCI          int     `json:"pci"`
+		Azimuth      float64 `json:"azimuth"`
+		Beamwidth    float64 `json:"beamwidth"`
+		Tilt         float64 `json:"tilt"`
+		FrequencyGHz float64 `json:"frequencyGHz"`
+		BandwidthMHz float64 `json:"bandwidthMHz"`
+		ConnectedUEs int     `json:"connectedUEs"`
+		MaxCap       int     `json:"maxCap"`
+	} `json:"cells"`
+}
+
+func (g gnodebInfo) row() []string {
+	state := "up"
+	if g.Down {
+		state = "down"
+	}
+	return []string{strconv.Itoa(g.ID), fmt.Sprintf("%.0f,%.0f", g.X, g.Y), fmt.Sprintf("%.0f", g.Range), state,
+		strconv.Itoa(len(g.Cells)), fmt.Sprintf("%d/%d", g.ConnectedUEs, g.MaxCap), strconv.Itoa(g.InactiveUEs)}
+}
+
+var gnodebHeader = []string{"id", "position", "range m", "state", "cells", "ues", "inactive"}
+
+// gnodebCommand handles "gnodeb list|show|create|move|range|delete|fail|restore"
+func gnodebCommand(c *client, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	if args[0] == "create" {
+		if len(args) != 3 && len(args) != 4 {
+			return fmt.Errorf("usage: gnodeb create <x> <y> [range]   (or <lat> <lon> with -geo)")
+		}
+		body, err := positionBody(args[1], args[2])
+		if err != nil {
+			return err
+		}
+		if len(args) == 4 {
+			if body["range"], err = strconv.ParseFloat(args[3], 64); err != nil {
+				return fmt.Errorf("range must be a number of metres, got %q", args[3])
+			}
+		}
+		return c.gnodebAction("POST", "/api/gnodebs", body)
+	}
+	if args[0] == "list" {
+		var gnbs []gnodebInfo
+		return c.fetch("/api/gnodebs", &gnbs, func() {
+			var rows [][]string
+			for _, g := range gnbs {
+				rows = append(rows, g.row())
+			}
+			printTable(gnodebHeader, rows)
+		})
+	}
+
+	var id int
+	if len(args) >= 2 {
+		var err error
+		if id, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("gNodeB id must be a number, got %q", args[1])
+		}
+	}
+	switch {
+	case args[0] == "show" && len(args) == 2:
+		return showGNodeB(c, id)
+
+	case args[0] == "fail" && (len(args) == 2 || len(args) == 3):
+		// An optional duration restores the gNodeB by itself afterwards, in simulated time
+		body := map[string]interface{}{"gnodebId": id, "action": "fail"}
+		if len(args) == 3 {
+			d, err := time.ParseDuration(args[2])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("duration must be like 30s or 2m, got %q", args[2])
+			}
+			body["durationSeconds"] = d.Seconds()
+		}
+		return outage(c, body)
+
+	case args[0] == "restore" && len(args) == 2:
+		return outage(c, map[string]interface{}{"gnodebId": id, "action": "restore"})
+
+	case args[0] == "move" && len(args) == 4:
+		body, err := positionBody(args[2], args[3])
+		if err != nil {
+			return err
+		}
+		return c.gnodebAction("PUT", gnodebPath(id), body)
+
+	case args[0] == "range" && len(args) == 3:
+		metres, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return fmt.Errorf("range must be a number of metres, got %q", args[2])
+		}
+		return c.gnodebAction("PUT", gnodebPath(id), map[string]interface{}{"range": metres})
+
+	case args[0] == "delete" && len(args) == 2:
+		if err := c.do("DELETE", gnodebPath(id), nil, nil); err != nil {
+			return err
+		}
+		fmt.Println("gNodeB", id, "removed")
+		return nil
+
+	case args[0] == "show" || args[0] == "fail" || args[0] == "restore" || args[0] == "move" || args[0] == "range" || args[0] == "delete":
+		return fmt.Errorf("usage: gnodeb show <id> | fail <id> [duration] | restore <id> | move <id> <x> <y> | range <id> <metres> | delete <id>")
+	}
+	return fmt.Errorf("unknown gnodeb command %q (list, show, create, move, range, delete, fail, restore)", args[0])
+}
+
+func gnodebPath(id int) string {
+	return "/api/gnodebs/" + strconv.Itoa(id)
+}
+
+// gnodebAction sends a gNodeB request and prints the gNodeB as the server returns it
+func (c *client) gnodebAction(method, path string, body interface{}) error {
+	raw, _, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	var g gnodebInfo
+	return render(raw, &g, func() { printTable(gnodebHeader, [][]string{g.row()}) })
+}
+
+// outage fails or restores a gNodeB and prints the report
+func outage(c *client, body map[string]interface{}) error {
+	raw, _, err := c.send("POST", "/api/gnodebs/outage", body)
+	if err != nil {
+		return err
+	}
+	var report struct {
+		GNodeBID        int      `json:"gnodebId"`
+		AffectedUEs     int      `json:"affectedUEs"`
+		Reestablished   []string `json:"reestablished"`
+		Dropped         []string `json:"dropped"`
+		InactiveLost    int      `json:"inactiveLost"`
+		DroppedSessions []int    `json:"droppedSessions"`
+		Reattached      []string `json:"reattached"`
+		Failed          []struct {
+			IMSI  string `json:"imsi"`
+			Error string `json:"error"`
+		} `json:"failed"`
+		RACHCollisions int     `json:"rachCollisions"`
+		AttachP95Ms    float64 `json:"attachP95Ms"`
+		RecoveryTimeMs float64 `json:"recoveryTimeMs"`
+	}
+	return render(raw, &report, func() {
+		if body["action"] == "fail" {
+			fmt.Printf("gNodeB %d failed: %d UEs affected, %d re-established, %d dropped, %d inactive lost, %d sessions dropped, recovery %.1f ms\n",
+				report.GNodeBID, report.AffectedUEs, len(report.Reestablished), len(report.Dropped), report.InactiveLost,
+				len(report.DroppedSessions), report.RecoveryTimeMs)
+			return
+		}
+		fmt.Printf("gNodeB %d restored: %d UEs reattached, %d failed, %d RACH collisions, attach p95 %.1f ms, recovery %.1f ms\n",
+			report.GNodeBID, len(report.Reattached), len(report.Failed), report.RACHCollisions, report.AttachP95Ms, report.RecoveryTimeMs)
+		for _, f := range report.Failed {
+			fmt.Printf("  %s: %s\n", f.IMSI, f.Error)
+		}
+	})
+}
+
+// showGNodeB prints one gNodeB with its cells and the UEs it serves
+func showGNodeB(c *client, id int) error {
+	var gnbs []gnodebInfo
+	if err := c.do("GET", "/api/gnodebs", nil, &gnbs); err != nil {
+		return err
+	}
+	var found *gnodebInfo
+	for i := range gnbs {
+		if gnbs[i].ID == id {
+			found = &gnbs[i]
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("no gNodeB %d", id)
+	}
+	var ues []ueInfo
+	if err := c.do("GET", "/api/ues", nil, &ues); err != nil {
+		return err
+	}
+	var served []string
+	for _, u := range ues {
+		if u.serving() != "-" && u.GNodeB == id {
+			served = append(served, u.IMSI)
+		}
+	}
+
+	if outputFormat == formatJSON {
+		return printJSON(struct {
+			gnodebInfo
+			UEs []string `json:"ues"`
+		}{*found, served})
+	}
+	printTable(gnodebHeader, [][]string{found.row()})
+	fmt.Println()
+	var rows [][]string
+	for _, cell := range found.Cells {
+		rows = append(rows, []string{strconv.Itoa(cell.ID), strconv.Itoa(cell.PCI), fmt.Sprintf("%g", cell.Azimuth),
+			fmt.Sprintf("%g", cell.FrequencyGHz), fmt.Sprintf("%g", cell.BandwidthMHz), fmt.Sprintf("%d/%d", cell.ConnectedUEs, cell.MaxCap)})
+	}
+	printTable([]string{"cell", "pci", "azimuth", "GHz", "MHz", "ues"}, rows)
+	fmt.Println()
+	fmt.Println("UEs:", orDash(strings.Join(served, " ")))
+	return nil
+}
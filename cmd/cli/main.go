@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: cli [-addr host:port] [-o table|json] [-geo] [<command> [arguments]]
+
+Controls a running netsim5g through its HTTP API. Without a command it starts an
+interactive shell with history and tab completion.
+
+Commands:
+  sim status            show the simulation clock
+  sim pause             freeze the simulation clock
+  sim resume            let the clock run again
+  sim step [N|5s]       while paused: run the next N events (default 1) or 5s of simulated time
+  sim speed <factor>    0 = as fast as possible, 1 = real time, N = N× real time
+  snapshot save <file>  write the complete network state to a file
+  snapshot load <file>  replace the network state with a saved one
+  coverage [flags] <file>
+                        write a coverage map (best server, RSRP, SINR, throughput)
+                        as .csv, .geojson or a .png heatmap; coverage -h for flags
+  ue list               list the UEs with their state, serving cell and link
+  ue show <imsi>        one UE in detail, with its sessions
+  ue create <imsi> <x> <y>
+                        add a UE (with -geo: <lat> <lon>)
+  ue move <imsi> <x> <y>
+                        move a UE (with -geo: <lat> <lon>)
+  ue connect <imsi> <gnodeb>
+                        RRC connection to a given gNodeB
+  ue register <imsi>    NAS registration through the connected gNodeB
+  ue attach <imsi>      connect to the best cell and register
+  ue detach <imsi>      deregister and release the connection
+  ue disconnect <imsi>  release the radio connection; a registered UE goes idle
+  ue delete <imsi>      deregister and remove a UE
+  gnodeb list           list the gNodeBs
+  gnodeb show <id>      one gNodeB with its cells and UEs
+  gnodeb create <x> <y> [range]
+                        add a gNodeB with one omnidirectional cell (with -geo: <lat> <lon>)
+  gnodeb move <id> <x> <y>
+                        move a gNodeB (with -geo: <lat> <lon>)
+  gnodeb range <id> <metres>
+                        change how far a gNodeB reaches
+  gnodeb delete <id>    remove a gNodeB that serves no UE (fail it first)
+  gnodeb fail <id> [duration]
+                        take a gNodeB down, restoring it after duration (e.g. 30s) if given
+  gnodeb restore <id>   bring a failed gNodeB back
+  session list [imsi]   list the PDU sessions, of all UEs or one
+  session establish <imsi> <VoIP|VideoStreaming|WebBrowsing|IoT>
+                        establish a PDU session, waking the UE up if it is idle
+  session terminate <id>
+                        release a PDU session
+  subscriber list       list the subscribers in the UDM
+  subscriber show <imsi>
+                        one subscriber
+  watch [-n N] [kind|imsi ...]
+                        print events as they happen, optionally only these kinds or UEs
+  repl                  the interactive shell
+`
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "simulator API address")
+	output := flag.String("o", formatTable, "output format: table or json")
+	flag.BoolVar(&geoPositions, "geo", false, "ue and gnodeb create and move take lat/lon instead of x/y")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := setOutput(*output); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(2)
+	}
+	if err := run(newClient(*addr), flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+}
+
+// run executes one command line
+func run(c *client, args []string) error {
+	if len(args) == 0 {
+		return replCommand(c)
+	}
+	switch args[0] {
+	case "sim":
+		return simCommand(c, args[1:])
+	case "snapshot":
+		return snapshotCommand(c, args[1:])
+	case "coverage":
+		return coverageCommand(c, args[1:])
+	case "ue":
+		return ueCommand(c, args[1:])
+	case "gnodeb":
+		return gnodebCommand(c, args[1:])
+	case "session":
+		return sessionCommand(c, args[1:])
+	case "subscriber":
+		return subscriberCommand(c, args[1:])
+	case "watch":
+		return watchCommand(c, args[1:])
+	case "repl":
+		return replCommand(c)
+	case "help":
+		flag.Usage()
+		return nil
+	}
+	return fmt.Errorf("unknown command %q, see help", args[0])
+}
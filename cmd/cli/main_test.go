@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// request is what the fake API was sent
+type request struct {
+	Method, Path string
+	Body         map[string]interface{}
+}
+
+// fakeAPI answers "METHOD /escaped/path" with canned JSON bodies and records every request;
+// anything else is a 404 in the API's error format
+func fakeAPI(t *testing.T, responses map[string]string) (*client, *[]request) {
+	t.Helper()
+	var got []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{Method: r.Method, Path: r.URL.EscapedPath()}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &req.Body); err != nil {
+				t.Errorf("%s %s: body %q is not JSON", r.Method, r.URL.Path, data)
+			}
+		}
+		got = append(got, req)
+		w.Header().Set("Content-Type", "application/json")
+		body, ok := responses[r.Method+" "+req.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			body = `{"error": "no such thing", "code": "not_found"}`
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return newClient(strings.TrimPrefix(server.URL, "http://")), &got
+}
+
+// capture returns what fn prints to stdout
+func capture(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	printed := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		printed <- string(data)
+	}()
+	err = fn()
+	w.Close()
+	return <-printed, err
+}
+
+// withOutput runs the rest of the test with format as the output format, and the
+// positions flag as given
+func withOutput(t *testing.T, format string, geo bool) {
+	t.Helper()
+	if err := setOutput(format); err != nil {
+		t.Fatal(err)
+	}
+	geoPositions = geo
+	t.Cleanup(func() { outputFormat, geoPositions = formatTable, false })
+}
+
+const (
+	ueJSON = `{"imsi": "001010000000001", "x": 12.4, "y": -3, "gNodeBConnected": 2, "cellConnected": 1, "state": 1,
+  "cmState": "CM-CONNECTED", "link": {"rsrp": -81.26, "sinr": 14.04, "cqi": 11, "mcs": 20, "throughputMbps": 312.55}}`
+	idleUEJSON = `{"imsi": "001010000000002", "x": 400, "y": 250, "gNodeBConnected": -1, "cellConnected": -1, "state": 2, "cmState": "CM-IDLE"}`
+	gnodebJSON = `{"id": 2, "x": 200, "y": 200, "range": 50, "down": false, "connectedUEs": 1, "inactiveUEs": 0, "maxCap": 9,
+  "cells": [{"id": 0, "pci": 6}, {"id": 1, "pci": 7}, {"id": 2, "pci": 8}]}`
+	simJSON     = `{"time": "2025-01-01T00:01:30Z", "elapsedSeconds": 90, "paused": true, "speed": 0, "pendingEvents": 3, "processedEvents": 41, "seed": 42, "ran": 2}`
+	outageJSON  = `{"gnodebId": 2, "affectedUEs": 3, "reestablished": ["a", "b"], "dropped": ["c"], "droppedSessions": [4], "recoveryTimeMs": 12.5}`
+	sessionJSON = `{"sessionID": 4, "ueIMSI": "001010000000001", "sessionType": "VoIP", "state": "Active", "maxBitRate": 1}`
+)
+
+func TestRequests(t *testing.T) {
+	c, got := fakeAPI(t, map[string]string{
+		"POST /api/ues":                             ueJSON,
+		"POST /api/ues/001010000000001/move":        ueJSON,
+		"POST /api/ues/001010000000001/connect":     ueJSON,
+		"POST /api/ues/001010000000001/attach":      ueJSON,
+		"DELETE /api/ues/001010000000001":           "",
+		"POST /api/gnodebs":                         gnodebJSON,
+		"PUT /api/gnodebs/2":                        gnodebJSON,
+		"DELETE /api/gnodebs/2":                     "",
+		"POST /api/gnodebs/outage":                  outageJSON,
+		"POST /api/sim":                             simJSON,
+		"POST /api/sessions":                        sessionJSON,
+		"DELETE /api/sessions/4":                    "",
+		"POST /api/ues/001%2F010000000001/register": ueJSON,
+	})
+	tests := []struct {
+		args []string
+		geo  bool
+		want request
+	}{
+		{[]string{"ue", "create", "001010000000001", "12.4", "-3"}, false, request{"POST", "/api/ues", map[string]interface{}{"imsi": "001010000000001", "x": 12.4, "y": -3.0}}},
+		{[]string{"ue", "create", "001010000000001", "52.1", "5.2"}, true, request{"POST", "/api/ues", map[string]interface{}{"imsi": "001010000000001", "lat": 52.1, "lon": 5.2}}},
+		{[]string{"ue", "move", "001010000000001", "1e3", "0"}, false, request{"POST", "/api/ues/001010000000001/move", map[string]interface{}{"x": 1000.0, "y": 0.0}}},
+		{[]string{"ue", "connect", "001010000000001", "2"}, false, request{"POST", "/api/ues/001010000000001/connect", map[string]interface{}{"gnodebId": 2.0}}},
+		{[]string{"ue", "attach", "001010000000001"}, false, request{"POST", "/api/ues/001010000000001/attach", nil}},
+		{[]string{"ue", "delete", "001010000000001"}, false, request{"DELETE", "/api/ues/001010000000001", nil}},
+		{[]string{"gnodeb", "create", "200", "200"}, false, request{"POST", "/api/gnodebs", map[string]interface{}{"x": 200.0, "y": 200.0}}},
+		{[]string{"gnodeb", "create", "200", "200", "75"}, false, request{"POST", "/api/gnodebs", map[string]interface{}{"x": 200.0, "y": 200.0, "range": 75.0}}},
+		{[]string{"gnodeb", "move", "2", "52", "5"}, true, request{"PUT", "/api/gnodebs/2", map[string]interface{}{"lat": 52.0, "lon": 5.0}}},
+		{[]string{"gnodeb", "range", "2", "80"}, false, request{"PUT", "/api/gnodebs/2", map[string]interface{}{"range": 80.0}}},
+		{[]string{"gnodeb", "delete", "2"}, false, request{"DELETE", "/api/gnodebs/2", nil}},
+		{[]string{"gnodeb", "fail", "2"}, false, request{"POST", "/api/gnodebs/outage", map[string]interface{}{"gnodebId": 2.0, "action": "fail"}}},
+		{[]string{"gnodeb", "fail", "2", "1m30s"}, false, request{"POST", "/api/gnodebs/outage", map[string]interface{}{"gnodebId": 2.0, "action": "fail", "durationSeconds": 90.0}}},
+		{[]string{"gnodeb", "restore", "2"}, false, request{"POST", "/api/gnodebs/outage", map[string]interface{}{"gnodebId": 2.0, "action": "restore"}}},
+		{[]string{"sim", "pause"}, false, request{"POST", "/api/sim", map[string]interface{}{"action": "pause"}}},
+		{[]string{"sim", "step"}, false, request{"POST", "/api/sim", map[string]interface{}{"action": "step"}}},
+		{[]string{"sim", "step", "10"}, false, request{"POST", "/api/sim", map[string]interface{}{"action": "step", "events": 10.0}}},
+		{[]string{"sim", "step", "500ms"}, false, request{"POST", "/api/sim", map[string]interface{}{"action": "step", "seconds": 0.5}}},
+		{[]string{"sim", "speed", "2.5"}, false, request{"POST", "/api/sim", map[string]interface{}{"action": "speed", "speed": 2.5}}},
+		{[]string{"session", "establish", "001010000000001", "VoIP"}, false, request{"POST", "/api/sessions", map[string]interface{}{"imsi": "001010000000001", "sessionType": "VoIP"}}},
+		{[]string{"session", "terminate", "4"}, false, request{"DELETE", "/api/sessions/4", nil}},
+		// IMSIs are escaped into the path
+		{[]string{"ue", "register", "001/010000000001"}, false, request{"POST", "/api/ues/001%2F010000000001/register", nil}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			withOutput(t, formatTable, tt.geo)
+			*got = nil
+			if _, err := capture(t, func() error { return run(c, tt.args) }); err != nil {
+				t.Fatal(err)
+			}
+			if len(*got) != 1 || !reflect.DeepEqual((*got)[0], tt.want) {
+				t.Errorf("sent %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgumentErrors(t *testing.T) {
+	c, got := fakeAPI(t, nil)
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"launch"}, `unknown command "launch", see help`},
+		{[]string{"ue", "fly"}, `unknown ue command "fly" (list, show, create, move, connect, register, attach, detach, disconnect, delete)`},
+		{[]string{"ue", "create", "001010000000001", "1"}, "usage: ue create <imsi> <x> <y>   (or <lat> <lon> with -geo)"},
+		{[]string{"ue", "create", "001010000000001", "one", "2"}, `position must be two numbers, got "one" "2"`},
+		{[]string{"ue", "connect", "001010000000001", "two"}, `gNodeB id must be a number, got "two"`},
+		{[]string{"ue", "attach"}, "usage: ue attach <imsi>"},
+		{[]string{"ue", "show"}, "usage: ue show <imsi>"},
+		{[]string{"gnodeb", "create", "1"}, "usage: gnodeb create <x> <y> [range]   (or <lat> <lon> with -geo)"},
+		{[]string{"gnodeb", "create", "1", "2", "far"}, `range must be a number of metres, got "far"`},
+		{[]string{"gnodeb", "show", "first"}, `gNodeB id must be a number, got "first"`},
+		{[]string{"gnodeb", "range", "2", "wide"}, `range must be a number of metres, got "wide"`},
+		{[]string{"gnodeb", "fail", "2", "soon"}, `duration must be like 30s or 2m, got "soon"`},
+		{[]string{"gnodeb", "fail", "2", "-5s"}, `duration must be like 30s or 2m, got "-5s"`},
+		{[]string{"gnodeb", "move", "2"}, "usage: gnodeb show <id> | fail <id> [duration] | restore <id> | move <id> <x> <y> | range <id> <metres> | delete <id>"},
+		{[]string{"gnodeb", "split", "2"}, `unknown gnodeb command "split" (list, show, create, move, range, delete, fail, restore)`},
+		{[]string{"sim", "step", "many"}, `step takes an event count or a duration like 5s, got "many"`},
+		{[]string{"sim", "speed"}, "usage: sim speed <factor> (0 = as fast as possible, 1 = real time)"},
+		{[]string{"sim", "speed", "fast"}, `speed must be a number, got "fast"`},
+		{[]string{"sim", "rewind"}, `unknown sim command "rewind" (status, pause, resume, step, speed)`},
+		{[]string{"session", "establish", "001010000000001"}, "usage: session list [imsi] | establish <imsi> <VoIP|VideoStreaming|WebBrowsing|IoT> | terminate <id>"},
+		{[]string{"session", "terminate", "last"}, `session id must be a number, got "last"`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			*got = nil
+			_, err := capture(t, func() error { return run(c, tt.args) })
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+			if len(*got) != 0 {
+				t.Errorf("sent %+v for a bad command line", *got)
+			}
+		})
+	}
+
+	if err := setOutput("xml"); err == nil || err.Error() != `output must be table or json, got "xml"` {
+		t.Errorf("setOutput(xml) gave %v", err)
+	}
+	if outputFormat != formatTable {
+		t.Errorf("output format %q after a bad setOutput", outputFormat)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/ues" {
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, `{"error": "UE 001010000000001 already exists", "code": "conflict"}`)
+			return
+		}
+		http.Error(w, "gone fishing", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	c := newClient(server.URL + "/")
+
+	_, err := capture(t, func() error { return run(c, []string{"ue", "create", "001010000000001", "0", "0"}) })
+	if want := "POST /api/ues: 409 Conflict: UE 001010000000001 already exists [conflict]"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+	_, err = capture(t, func() error { return run(c, []string{"gnodeb", "list"}) })
+	if want := "GET /api/gnodebs: 503 Service Unavailable: gone fishing"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+
+	server.Close()
+	_, err = capture(t, func() error { return run(c, []string{"sim"}) })
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot reach the simulator at "+server.URL+": ") {
+		t.Errorf("got %v, want cannot reach the simulator", err)
+	}
+}
+
+func TestTableOutput(t *testing.T) {
+	c, _ := fakeAPI(t, map[string]string{
+		"GET /api/ues":             "[" + ueJSON + ", " + idleUEJSON + "]",
+		"GET /api/gnodebs":         "[" + gnodebJSON + "]",
+		"GET /api/sessions":        "[" + sessionJSON + "]",
+		"GET /api/sim":             simJSON,
+		"POST /api/sim":            simJSON,
+		"POST /api/gnodebs/outage": outageJSON,
+	})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ue", "list"}, `IMSI             POSITION  RRC        CM            SERVING       RSRP DBM  SINR DB  MBPS
+001010000000001  12,-3     connected  CM-CONNECTED  gNB-2/cell-1  -81.3     14.0     312.6
+001010000000002  400,250   idle       CM-IDLE       -             -         -        -
+`},
+		{[]string{"gnodeb"}, `ID  POSITION  RANGE M  STATE  CELLS  UES  INACTIVE
+2   200,200   50       up     3      1/9  0
+`},
+		{[]string{"gnodeb", "show", "2"}, `ID  POSITION  RANGE M  STATE  CELLS  UES  INACTIVE
+2   200,200   50       up     3      1/9  0
+
+CELL  PCI  AZIMUTH  GHZ  MHZ  UES
+0     6    0        0    0    0/0
+1     7    0        0    0    0/0
+2     8    0        0    0    0/0
+
+UEs: 001010000000001
+`},
+		{[]string{"ue", "show", "001010000000001"}, `IMSI:      001010000000001
+Position:  12.4, -3.0 m
+RRC state: connected
+CM state:  CM-CONNECTED
+Serving:   gNB-2/cell-1
+Link:      RSRP -81.3 dBm, SINR 14.0 dB, CQI 11, MCS 20, 312.6 Mbps
+Sessions:  4 VoIP
+`},
+		{[]string{"session", "list", "001010000000001"}, `ID  IMSI             TYPE  STATE   MAX MBPS
+4   001010000000001  VoIP  Active  1
+`},
+		{[]string{"session", "list", "001010000000002"}, "ID  IMSI  TYPE  STATE  MAX MBPS\n(none)\n"},
+		{[]string{"sim"}, "t=1m30s (2025-01-01T00:01:30Z) paused, speed as fast as possible, 3 pending / 41 processed events, seed 42\n"},
+		{[]string{"sim", "step", "2"}, "ran 2 event(s)\nt=1m30s (2025-01-01T00:01:30Z) paused, speed as fast as possible, 3 pending / 41 processed events, seed 42\n"},
+		{[]string{"gnodeb", "fail", "2"}, "gNodeB 2 failed: 3 UEs affected, 2 re-established, 1 dropped, 0 inactive lost, 1 sessions dropped, recovery 12.5 ms\n"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			withOutput(t, formatTable, false)
+			out, err := capture(t, func() error { return run(c, tt.args) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Errorf("printed\n%s\nwant\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	c, _ := fakeAPI(t, map[string]string{
+		"GET /api/ues":      "[" + ueJSON + "]",
+		"GET /api/gnodebs":  "[" + gnodebJSON + "]",
+		"GET /api/sessions": "[" + sessionJSON + "]",
+		"POST /api/gnodebs": gnodebJSON,
+	})
+	withOutput(t, formatJSON, false)
+
+	// Step 1: lists and actions print the server's JSON as it is, indented
+	for _, tt := range []struct {
+		args []string
+		raw  string
+	}{
+		{[]string{"ue", "list"}, "[" + ueJSON + "]"},
+		{[]string{"gnodeb", "create", "200", "200"}, gnodebJSON},
+	} {
+		out, err := capture(t, func() error { return run(c, tt.args) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, want interface{}
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("%v printed %q, not JSON: %v", tt.args, out, err)
+		}
+		json.Unmarshal([]byte(tt.raw), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v printed %s, want the server's %s", tt.args, out, tt.raw)
+		}
+		if !strings.HasPrefix(out, "[\n  {\n    \"imsi\"") && !strings.HasPrefix(out, "{\n  \"id\": 2,") {
+			t.Errorf("%v printed %q, want it indented", tt.args, out)
+		}
+	}
+
+	// Step 2: show joins the record with what belongs to it
+	out, err := capture(t, func() error { return run(c, []string{"ue", "show", "001010000000001"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u struct {
+		IMSI     string        `json:"imsi"`
+		Sessions []sessionInfo `json:"sessions"`
+	}
+	if err := json.Unmarshal([]byte(out), &u); err != nil || u.IMSI != "001010000000001" || len(u.Sessions) != 1 || u.Sessions[0].ID != 4 {
+		t.Errorf("ue show printed %s (%v)", out, err)
+	}
+	out, err = capture(t, func() error { return run(c, []string{"gnodeb", "show", "2"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g struct {
+		ID  int      `json:"id"`
+		UEs []string `json:"ues"`
+	}
+	if err := json.Unmarshal([]byte(out), &g); err != nil || g.ID != 2 || !reflect.DeepEqual(g.UEs, []string{"001010000000001"}) {
+		t.Errorf("gnodeb show printed %s (%v)", out, err)
+	}
+
+	// Step 3: what is not there is an error, not an empty document
+	if _, err := capture(t, func() error { return run(c, []string{"gnodeb", "show", "9"}) }); err == nil || err.Error() != "no gNodeB 9" {
+		t.Errorf("gnodeb show 9 gave %v", err)
+	}
+	if _, err := capture(t, func() error { return run(c, []string{"ue", "show", "1"}) }); err == nil || err.Error() != "no UE 1" {
+		t.Errorf("ue show 1 gave %v", err)
+	}
+}
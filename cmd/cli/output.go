@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// outputFormat is how commands print what they get back: a table, or the API's JSON
+var outputFormat = formatTable
+
+// setOutput changes outputFormat, checking the name
+func setOutput(format string) error {
+	if format != formatTable && format != formatJSON {
+		return fmt.Errorf("output must be %s or %s, got %q", formatTable, formatJSON, format)
+	}
+	outputFormat = format
+	return nil
+}
+
+// fetch GETs path and prints the response: as indented JSON, or decoded into v and
+// laid out by table
+func (c *client) fetch(path string, v interface{}, table func()) error {
+	raw, _, err := c.send("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	return render(raw, v, table)
+}
+
+// render prints a raw JSON response in the current output format
+func render(raw []byte, v interface{}, table func()) error {
+	if outputFormat == formatJSON {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, bytes.TrimSpace(raw), "", "  "); err != nil {
+			return err
+		}
+		fmt.Println(pretty.String())
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return err
+	}
+	table()
+	return nil
+}
+
+// printTable lays rows out in aligned columns under an upper-case header
+func printTable(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	if len(rows) == 0 {
+		fmt.Println("(none)")
+	}
+}
+
+// printFields lays out one record as "name: value" lines
+func printFields(fields [][2]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s:\t%s\n", f[0], f[1])
+	}
+	w.Flush()
+}
+
+// orDash shows empty values as "-"
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// printJSON prints v as indented JSON
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
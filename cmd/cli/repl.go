@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/journal"
+)
+
+const prompt = "netsim5g> "
+
+const replHelp = `Type a command as on the command line, e.g. "ue list" or "gnodeb fail 2 30s".
+  output table|json     how results are printed
+  help                  this text and the command list
+  exit, quit, Ctrl-D    leave
+Tab completes commands, IMSIs, gNodeB and session IDs; up and down recall earlier lines.
+`
+
+// replCommand reads commands until exit or end of input. On a terminal lines are read by
+// a small line editor with history and tab completion; otherwise (pipes, or no raw mode)
+// line by line.
+func replCommand(c *client) error {
+	read := plainLines(os.Stdin)
+	if restore, err := makeRaw(os.Stdin.Fd()); err == nil {
+		restore()
+		editor := &lineEditor{in: bufio.NewReader(os.Stdin), complete: (&completer{c: c}).complete}
+		read = editor.readLine
+		fmt.Println("NetSim5G control, connected to", c.base, "- help for commands, Tab to complete")
+	}
+
+	for {
+		line, err := read(prompt)
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Print(replHelp, "\n", usage)
+			continue
+		case "repl":
+			continue
+		case "output":
+			if len(args) != 2 {
+				fmt.Println("output is", outputFormat)
+			} else if err := setOutput(args[1]); err != nil {
+				fmt.Fprintln(os.Stderr, "❌", err)
+			}
+			continue
+		}
+		if err := run(c, args); err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+		}
+	}
+}
+
+// plainLines prompts for and reads lines without any editing
+func plainLines(in io.Reader) func(string) (string, error) {
+	scanner := bufio.NewScanner(in)
+	return func(prompt string) (string, error) {
+		fmt.Print(prompt)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return scanner.Text(), nil
+	}
+}
+
+// errInterrupted is returned for a line abandoned with Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads one line at a time from a terminal in raw mode. It only edits at
+// the end of the line: typing, backspace, Ctrl-U to clear, Tab to complete, up and
+// down for history, Ctrl-C to abandon the line and Ctrl-D on an empty line for EOF.
+type lineEditor struct {
+	in       *bufio.Reader
+	history  []string
+	complete func(line string) (word string, options []string)
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	for {
+		line, err := e.edit(prompt)
+		if err != errInterrupted {
+			return line, err
+		}
+	}
+}
+
+func (e *lineEditor) edit(prompt string) (string, error) {
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		return "", err
+	}
+	// Commands run in the normal mode, so Ctrl-C reaches them as a signal
+	defer restore()
+
+	line := ""
+	redraw := func(line string) { fmt.Print("\r\033[K", prompt, line) }
+	recalled := len(e.history) // history index shown; len = the line being typed
+	lastTab := false
+	fmt.Print(prompt)
+
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		tab := false
+		switch b {
+		case '\r', '\n':
+			fmt.Print("\r\n")
+			if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+				e.history = append(e.history, line)
+			}
+			return line, nil
+		case 3: // Ctrl-C
+			fmt.Print("^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if line == "" {
+				return "", io.EOF
+			}
+		case 21: // Ctrl-U
+			line = ""
+			redraw(line)
+		case 127, 8: // backspace
+			if line != "" {
+				_, size := utf8.DecodeLastRuneInString(line)
+				line = line[:len(line)-size]
+				fmt.Print("\b \b")
+			}
+		case '\t':
+			tab = true
+			line = e.completeLine(line, lastTab, redraw)
+		case 27: // escape sequence: ESC [ A is up, ESC [ B is down, the rest is ignored
+			if next, _ := e.in.ReadByte(); next != '[' {
+				break
+			}
+			switch key, _ := e.in.ReadByte(); key {
+			case 'A':
+				if recalled > 0 {
+					recalled--
+					line = e.history[recalled]
+					redraw(line)
+				}
+			case 'B':
+				if recalled < len(e.history) {
+					recalled++
+					line = ""
+					if recalled < len(e.history) {
+						line = e.history[recalled]
+					}
+					redraw(line)
+				}
+			}
+		default:
+			if b >= 32 {
+				line += string(b)
+				os.Stdout.Write([]byte{b})
+			}
+		}
+		lastTab = tab
+	}
+}
+
+// completeLine completes the word being typed: a single option is filled in, several
+// are completed as far as they agree and listed on the second Tab
+func (e *lineEditor) completeLine(line string, again bool, redraw func(string)) string {
+	word, options := e.complete(line)
+	switch {
+	case len(options) == 0:
+		fmt.Print("\a")
+	case len(options) == 1:
+		line = line[:len(line)-len(word)] + options[0] + " "
+		redraw(line)
+	default:
+		if prefix := commonPrefix(options); len(prefix) > len(word) {
+			line = line[:len(line)-len(word)] + prefix
+			redraw(line)
+		} else if again {
+			fmt.Print("\r\n", strings.Join(options, "  "), "\r\n")
+			redraw(line)
+		} else {
+			fmt.Print("\a")
+		}
+	}
+	return line
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// commands is what each command takes as its next word
+var commands = map[string][]string{
+	"":           {"sim", "snapshot", "coverage", "ue", "gnodeb", "session", "subscriber", "watch", "output", "help", "exit", "quit"},
+	"sim":        {"status", "pause", "resume", "step", "speed"},
+	"snapshot":   {"save", "load"},
+	"ue":         {"list", "show", "create", "move", "connect", "register", "attach", "detach", "disconnect", "delete"},
+	"gnodeb":     {"list", "show", "create", "move", "range", "delete", "fail", "restore"},
+	"session":    {"list", "establish", "terminate"},
+	"subscriber": {"list", "show"},
+	"output":     {formatTable, formatJSON},
+}
+
+// eventKinds are offered to watch
+var eventKinds = []journal.Kind{
+	journal.UECreated, journal.UEMoved, journal.UERemoved, journal.Connected, journal.ConnectionFailed,
+	journal.Released, journal.Registered, journal.RegistrationFailed, journal.RegistrationUpdate,
+	journal.RNAUpdate, journal.Deregistered, journal.Paging, journal.PagingFailed, journal.Handover,
+	journal.HandoverFailed, journal.SessionEstablished, journal.SessionRejected, journal.SessionReleased,
+	journal.GNodeBFailed, journal.GNodeBRestored, journal.OutageFailed, journal.GNodeBAdded, journal.GNodeBUpdated,
+	journal.GNodeBRemoved, journal.RadioLinkFailure, journal.Reestablished, journal.Dropped,
+	journal.SubscriberUpdated, journal.SnapshotRestored,
+}
+
+// completer offers the words that may come next, asking the simulator for the IMSIs and
+// IDs it has right now
+type completer struct {
+	c *client
+}
+
+// complete returns the word being typed and the options that start with it
+func (cp *completer) complete(line string) (string, []string) {
+	words := strings.Fields(line)
+	word := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		word, words = words[len(words)-1], words[:len(words)-1]
+	}
+	var matches []string
+	for _, option := range cp.options(words) {
+		if strings.HasPrefix(option, word) {
+			matches = append(matches, option)
+		}
+	}
+	sort.Strings(matches)
+	return word, matches
+}
+
+// options lists what may follow the words typed so far
+func (cp *completer) options(words []string) []string {
+	if len(words) == 0 {
+		return commands[""]
+	}
+	if len(words) == 1 {
+		switch words[0] {
+		case "watch":
+			var kinds []string
+			for _, k := range eventKinds {
+				kinds = append(kinds, string(k))
+			}
+			return append(kinds, cp.imsis()...)
+		}
+		return commands[words[0]]
+	}
+
+	switch command, sub := words[0], words[1]; {
+	case command == "watch":
+		return cp.options(words[:1])
+	case len(words) == 2 && command == "ue" && sub != "list" && sub != "create":
+		return cp.imsis()
+	case len(words) == 3 && command == "ue" && sub == "connect":
+		return cp.gnodebs()
+	case len(words) == 2 && command == "gnodeb" && sub != "list" && sub != "create":
+		return cp.gnodebs()
+	case len(words) == 2 && command == "session" && (sub == "list" || sub == "establish"):
+		return cp.imsis()
+	case len(words) == 3 && command == "session" && sub == "establish":
+		return sessionTypes
+	case len(words) == 2 && command == "session" && sub == "terminate":
+		return cp.sessions()
+	case len(words) == 2 && command == "subscriber" && sub == "show":
+		var subscribers []udm.Subscriber
+		cp.c.do("GET", "/api/subscribers", nil, &subscribers)
+		var imsis []string
+		for _, s := range subscribers {
+			imsis = append(imsis, s.IMSI)
+		}
+		return imsis
+	}
+	return nil
+}
+
+func (cp *completer) imsis() []string {
+	var ues []ueInfo
+	cp.c.do("GET", "/api/ues", nil, &ues)
+	var imsis []string
+	for _, u := range ues {
+		imsis = append(imsis, u.IMSI)
+	}
+	return imsis
+}
+
+func (cp *completer) gnodebs() []string {
+	var gnbs []gnodebInfo
+	cp.c.do("GET", "/api/gnodebs", nil, &gnbs)
+	var ids []string
+	for _, g := range gnbs {
+		ids = append(ids, strconv.Itoa(g.ID))
+	}
+	return ids
+}
+
+func (cp *completer) sessions() []string {
+	var sessions []sessionInfo
+	cp.c.do("GET", "/api/sessions", nil, &sessions)
+	var ids []string
+	for _, s := range sessions {
+		ids = append(ids, strconv.Itoa(s.ID))
+	}
+	return ids
+}
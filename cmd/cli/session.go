@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sessionInfo mirrors an entry of /api/sessions
+type sessionInfo struct {
+	ID         int    `json:"sessionID"`
+	IMSI       string `json:"ueIMSI"`
+	Type       string `json:"sessionType"`
+	State      string `json:"state"`
+	MaxBitRate int    `json:"maxBitRate"`
+}
+
+func (s sessionInfo) row() []string {
+	return []string{strconv.Itoa(s.ID), s.IMSI, s.Type, s.State, strconv.Itoa(s.MaxBitRate)}
+}
+
+var sessionHeader = []string{"id", "imsi", "type", "state", "max Mbps"}
+
+// sessionTypes are the PDU session types the SMF knows
+var sessionTypes = []string{"VoIP", "VideoStreaming", "WebBrowsing", "IoT"}
+
+// sessionCommand handles "session list|establish|terminate"
+func sessionCommand(c *client, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch {
+	case args[0] == "list" && len(args) <= 2:
+		// An optional IMSI lists only that UE's sessions
+		var sessions []sessionInfo
+		return c.fetch("/api/sessions", &sessions, func() {
+			var rows [][]string
+			for _, s := range sessions {
+				if len(args) == 1 || s.IMSI == args[1] {
+					rows = append(rows, s.row())
+				}
+			}
+			printTable(sessionHeader, rows)
+		})
+
+	case args[0] == "establish" && len(args) == 3:
+		raw, _, err := c.send("POST", "/api/sessions", map[string]string{"imsi": args[1], "sessionType": args[2]})
+		if err != nil {
+			return err
+		}
+		var s sessionInfo
+		return render(raw, &s, func() { printTable(sessionHeader, [][]string{s.row()}) })
+
+	case args[0] == "terminate" && len(args) == 2:
+		if _, err := strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("session id must be a number, got %q", args[1])
+		}
+		if err := c.do("DELETE", "/api/sessions/"+args[1], nil, nil); err != nil {
+			return err
+		}
+		fmt.Println("session", args[1], "terminated")
+		return nil
+
+	case args[0] == "list" || args[0] == "establish" || args[0] == "terminate":
+		return fmt.Errorf("usage: session list [imsi] | establish <imsi> <%s> | terminate <id>", strings.Join(sessionTypes, "|"))
+	}
+	return fmt.Errorf("unknown session command %q (list, establish, terminate)", args[0])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// simStatus mirrors the /api/sim response
+type simStatus struct {
+	Time             time.Time `json:"time"`
+	ElapsedSeconds   float64   `json:"elapsedSeconds"`
+	Paused           bool      `json:"paused"`
+	Speed            float64   `json:"speed"`
+	PendingEvents    int       `json:"pendingEvents"`
+	ProcessedEvents  int       `json:"processedEvents"`
+	NextEventSeconds *float64  `json:"nextEventSeconds"`
+	Seed             int64     `json:"seed"`
+	Ran              int       `json:"ran"`
+}
+
+func (s simStatus) String() string {
+	state := "running"
+	if s.Paused {
+		state = "paused"
+	}
+	speed := fmt.Sprintf("%g×", s.Speed)
+	if s.Speed == 0 {
+		speed = "as fast as possible"
+	}
+	line := fmt.Sprintf("t=%v (%s) %s, speed %s, %d pending / %d processed events",
+		seconds(s.ElapsedSeconds), s.Time.Format(time.RFC3339Nano), state, speed, s.PendingEvents, s.ProcessedEvents)
+	if s.NextEventSeconds != nil {
+		line += fmt.Sprintf(", next at t=%v", seconds(*s.NextEventSeconds))
+	}
+	return line + fmt.Sprintf(", seed %d", s.Seed)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
+
+// simCommand handles "sim status|pause|resume|step [N|duration]|speed X"
+func simCommand(c *client, args []string) error {
+	if len(args) == 0 {
+		args = []string{"status"}
+	}
+
+	var status simStatus
+	var err error
+	switch args[0] {
+	case "status":
+		err = c.do("GET", "/api/sim", nil, &status)
+
+	case "pause", "resume":
+		err = c.do("POST", "/api/sim", map[string]interface{}{"action": args[0]}, &status)
+
+	case "step":
+		// "step" = one event, "step 10" = ten events, "step 5s" = five simulated seconds
+		req := map[string]interface{}{"action": "step"}
+		if len(args) > 1 {
+			if n, convErr := strconv.Atoi(args[1]); convErr == nil {
+				req["events"] = n
+			} else if d, convErr := time.ParseDuration(args[1]); convErr == nil {
+				req["seconds"] = d.Seconds()
+			} else {
+				return fmt.Errorf("step takes an event count or a duration like 5s, got %q", args[1])
+			}
+		}
+		err = c.do("POST", "/api/sim", req, &status)
+		if err == nil {
+			fmt.Printf("ran %d event(s)\n", status.Ran)
+		}
+
+	case "speed":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: sim speed <factor> (0 = as fast as possible, 1 = real time)")
+		}
+		speed, convErr := strconv.ParseFloat(args[1], 64)
+		if convErr != nil {
+			return fmt.Errorf("speed must be a number, got %q", args[1])
+		}
+		err = c.do("POST", "/api/sim", map[string]interface{}{"action": "speed", "speed": speed}, &status)
+
+	default:
+		return fmt.Errorf("unknown sim command %q (status, pause, resume, step, speed)", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+	fmt.Println(status)
+	return nil
+}
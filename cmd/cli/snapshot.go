@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// snapshotCommand handles "snapshot save <file>" and "snapshot load <file>"
+func snapshotCommand(c *client, args []string) error {
+	if len(args) != 2 || (args[0] != "save" && args[0] != "load") {
+		return fmt.Errorf("usage: snapshot save|load <file>")
+	}
+	path := args[1]
+
+	if args[0] == "save" {
+		var snap json.RawMessage
+		if err := c.do("GET", "/api/snapshot", nil, &snap); err != nil {
+			return err
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, snap, "", "  "); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(pretty.Bytes(), '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("saved network state to %s\n", path)
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var status simStatus
+	if err := c.do("POST", "/api/snapshot", json.RawMessage(data), &status); err != nil {
+		return err
+	}
+	fmt.Printf("restored %s\n%s\n", path, status)
+	return nil
+}
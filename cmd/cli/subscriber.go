@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+)
+
+var subscriberHeader = []string{"imsi", "phone", "status", "max Mbps"}
+
+func subscriberRow(s udm.Subscriber) []string {
+	return []string{s.IMSI, orDash(s.PhoneNumber), s.SubscriptionStatus, strconv.Itoa(s.MaxDataRate)}
+}
+
+// subscriberCommand handles "subscriber list|show"
+func subscriberCommand(c *client, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		var subscribers []udm.Subscriber
+		return c.fetch("/api/subscribers", &subscribers, func() {
+			var rows [][]string
+			for _, s := range subscribers {
+				rows = append(rows, subscriberRow(s))
+			}
+			printTable(subscriberHeader, rows)
+		})
+
+	case args[0] == "show" && len(args) == 2:
+		var subscribers []udm.Subscriber
+		if err := c.do("GET", "/api/subscribers", nil, &subscribers); err != nil {
+			return err
+		}
+		for _, s := range subscribers {
+			if s.IMSI != args[1] {
+				continue
+			}
+			if outputFormat == formatJSON {
+				return printJSON(s)
+			}
+			printFields([][2]string{
+				{"IMSI", s.IMSI},
+				{"Phone number", orDash(s.PhoneNumber)},
+				{"Status", s.SubscriptionStatus},
+				{"Max data rate", fmt.Sprintf("%d Mbps", s.MaxDataRate)},
+			})
+			return nil
+		}
+		return fmt.Errorf("no subscriber %s", args[1])
+
+	case args[0] == "list" || args[0] == "show":
+		return fmt.Errorf("usage: subscriber list | show <imsi>")
+	}
+	return fmt.Errorf("unknown subscriber command %q (list, show)", args[0])
+}
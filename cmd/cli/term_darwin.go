@@ -0,0 +1,11 @@
+//go:build darwin
+
+package main
+
+import "syscall"
+
+// ioctl requests that read and set the terminal attributes
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
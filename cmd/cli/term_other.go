@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+// makeRaw is not supported here: the REPL reads plain lines, without completion or history
+func makeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// ueInfo mirrors an entry of /api/ues
+type ueInfo struct {
+	IMSI     string  `json:"imsi"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Position *struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"position"`
+	GNodeB   int        `json:"gNodeBConnected"`
+	Cell     int        `json:"cellConnected"`
+	State    ue.UEState `json:"state"`
+	Speed    float64    `json:"speed"`
+	Mobility string     `json:"mobility"`
+	CMState  string     `json:"cmState"`
+	Link     *linkInfo  `json:"link"`
+	Building string     `json:"building"`
+	LOS      *bool      `json:"los"`
+}
+
+type linkInfo struct {
+	RSRP           float64 `json:"rsrp"`
+	SINR           float64 `json:"sinr"`
+	CQI            int     `json:"cqi"`
+	MCS            int     `json:"mcs"`
+	ThroughputMbps float64 `json:"throughputMbps"`
+}
+
+// serving names the UE's serving cell, "-" when it has none
+func (u ueInfo) serving() string {
+	if u.State != ue.Connected {
+		return "-"
+	}
+	return fmt.Sprintf("gNB-%d/cell-%d", u.GNodeB, u.Cell)
+}
+
+func (u ueInfo) row() []string {
+	rsrp, sinr, throughput := "-", "-", "-"
+	if u.Link != nil {
+		rsrp = fmt.Sprintf("%.1f", u.Link.RSRP)
+		sinr = fmt.Sprintf("%.1f", u.Link.SINR)
+		throughput = fmt.Sprintf("%.1f", u.Link.ThroughputMbps)
+	}
+	return []string{u.IMSI, fmt.Sprintf("%.0f,%.0f", u.X, u.Y), u.State.String(), orDash(u.CMState), u.serving(), rsrp, sinr, throughput}
+}
+
+var ueHeader = []string{"imsi", "position", "rrc", "cm", "serving", "rsrp dBm", "sinr dB", "Mbps"}
+
+// ueCommand handles "ue list|show|create|move|connect|register|attach|detach|disconnect|delete"
+func ueCommand(c *client, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	usage := func(form string) error { return fmt.Errorf("usage: ue %s", form) }
+
+	switch args[0] {
+	case "list":
+		var ues []ueInfo
+		return c.fetch("/api/ues", &ues, func() {
+			var rows [][]string
+			for _, u := range ues {
+				rows = append(rows, u.row())
+			}
+			printTable(ueHeader, rows)
+		})
+
+	case "show":
+		if len(args) != 2 {
+			return usage("show <imsi>")
+		}
+		return showUE(c, args[1])
+
+	case "create", "move":
+		if len(args) != 4 {
+			return usage(args[0] + " <imsi> <x> <y>   (or <lat> <lon> with -geo)")
+		}
+		body, err := positionBody(args[2], args[3])
+		if err != nil {
+			return err
+		}
+		if args[0] == "create" {
+			body["imsi"] = args[1]
+			return c.ueAction("POST", "/api/ues", body)
+		}
+		return c.ueAction("POST", ueActionPath(args[1], "move"), body)
+
+	case "connect":
+		if len(args) != 3 {
+			return usage("connect <imsi> <gnodeb id>")
+		}
+		id, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("gNodeB id must be a number, got %q", args[2])
+		}
+		return c.ueAction("POST", ueActionPath(args[1], "connect"), map[string]interface{}{"gnodebId": id})
+
+	case "register", "attach", "detach", "disconnect":
+		if len(args) != 2 {
+			return usage(args[0] + " <imsi>")
+		}
+		return c.ueAction("POST", ueActionPath(args[1], args[0]), nil)
+
+	case "delete":
+		if len(args) != 2 {
+			return usage("delete <imsi>")
+		}
+		if err := c.do("DELETE", "/api/ues/"+url.PathEscape(args[1]), nil, nil); err != nil {
+			return err
+		}
+		fmt.Println("UE", args[1], "removed")
+		return nil
+	}
+	return fmt.Errorf("unknown ue command %q (list, show, create, move, connect, register, attach, detach, disconnect, delete)", args[0])
+}
+
+// geoPositions makes ue create/move take lat/lon instead of x/y
+var geoPositions bool
+
+// positionBody turns two numbers into an x/y (or lat/lon) request body
+func positionBody(a, b string) (map[string]interface{}, error) {
+	first, err1 := strconv.ParseFloat(a, 64)
+	second, err2 := strconv.ParseFloat(b, 64)
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("position must be two numbers, got %q %q", a, b)
+	}
+	if geoPositions {
+		return map[string]interface{}{"lat": first, "lon": second}, nil
+	}
+	return map[string]interface{}{"x": first, "y": second}, nil
+}
+
+func ueActionPath(imsi, action string) string {
+	return "/api/ues/" + url.PathEscape(imsi) + "/" + action
+}
+
+// ueAction sends a UE request and prints the UE as the server returns it
+func (c *client) ueAction(method, path string, body interface{}) error {
+	raw, _, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	var u ueInfo
+	return render(raw, &u, func() { printTable(ueHeader, [][]string{u.row()}) })
+}
+
+// showUE prints everything about one UE: its state, link and sessions
+func showUE(c *client, imsi string) error {
+	var ues []ueInfo
+	if err := c.do("GET", "/api/ues", nil, &ues); err != nil {
+		return err
+	}
+	var found *ueInfo
+	for i := range ues {
+		if ues[i].IMSI == imsi {
+			found = &ues[i]
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("no UE %s", imsi)
+	}
+	var all, sessions []sessionInfo
+	if err := c.do("GET", "/api/sessions", nil, &all); err != nil {
+		return err
+	}
+	for _, s := range all {
+		if s.IMSI == imsi {
+			sessions = append(sessions, s)
+		}
+	}
+
+	if outputFormat == formatJSON {
+		return printJSON(struct {
+			ueInfo
+			Sessions []sessionInfo `json:"sessions"`
+		}{*found, sessions})
+	}
+	u := found
+	fields := [][2]string{
+		{"IMSI", u.IMSI},
+		{"Position", fmt.Sprintf("%.1f, %.1f m", u.X, u.Y)},
+	}
+	if u.Position != nil {
+		fields = append(fields, [2]string{"Lat/lon", fmt.Sprintf("%.7f, %.7f", u.Position.Lat, u.Position.Lon)})
+	}
+	fields = append(fields,
+		[2]string{"RRC state", u.State.String()},
+		[2]string{"CM state", orDash(u.CMState)},
+		[2]string{"Serving", u.serving()},
+	)
+	if u.Mobility != "" {
+		fields = append(fields, [2]string{"Mobility", fmt.Sprintf("%s, %.1f m/s", u.Mobility, u.Speed)})
+	}
+	if u.Building != "" {
+		fields = append(fields, [2]string{"Indoors", u.Building})
+	}
+	if u.LOS != nil {
+		fields = append(fields, [2]string{"Line of sight", strconv.FormatBool(*u.LOS)})
+	}
+	if u.Link != nil {
+		fields = append(fields, [2]string{"Link", fmt.Sprintf("RSRP %.1f dBm, SINR %.1f dB, CQI %d, MCS %d, %.1f Mbps",
+			u.Link.RSRP, u.Link.SINR, u.Link.CQI, u.Link.MCS, u.Link.ThroughputMbps)})
+	}
+	var names []string
+	for _, s := range sessions {
+		names = append(names, fmt.Sprintf("%d %s", s.ID, s.Type))
+	}
+	fields = append(fields, [2]string{"Sessions", orDash(strings.Join(names, ", "))})
+	printFields(fields)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/journal"
+)
+
+// watchInterval is how often watch polls for new events
+const watchInterval = 500 * time.Millisecond
+
+// watchCommand handles "watch [-n N] [kind|imsi ...]": it prints journal events as the
+// simulator records them, until Ctrl-C
+func watchCommand(c *client, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	history := flags.Int("n", 10, "recent events to print first")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: watch [-n N] [kind|imsi ...]   e.g. watch handover 001010000000001")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Step 1: Arguments made of digits are IMSIs, the others event kinds
+	query := url.Values{"limit": {strconv.Itoa(max(*history, 0))}}
+	var kinds []string
+	imsis := make(map[string]bool)
+	for _, arg := range flags.Args() {
+		if strings.Trim(arg, "0123456789") == "" {
+			imsis[arg] = true
+		} else {
+			kinds = append(kinds, arg)
+		}
+	}
+	if len(kinds) > 0 {
+		query.Set("kind", strings.Join(kinds, ","))
+	}
+	if len(imsis) == 1 {
+		for imsi := range imsis {
+			query.Set("imsi", imsi)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintln(os.Stderr, "watching events, Ctrl-C to stop")
+
+	// Step 2: Poll, each time from the sequence number the last response ended at
+	for {
+		data, header, err := c.send("GET", "/api/events?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		var events []journal.Event
+		if err := json.Unmarshal(data, &events); err != nil {
+			return err
+		}
+		for _, e := range events {
+			if len(imsis) <= 1 || imsis[e.IMSI] {
+				printEvent(e)
+			}
+		}
+		query.Del("limit")
+		query.Set("since", header.Get("X-Journal-Seq"))
+
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		case <-time.After(watchInterval):
+		}
+	}
+}
+
+// printEvent prints one event on a line, or as JSON
+func printEvent(e journal.Event) {
+	if outputFormat == formatJSON {
+		data, _ := json.Marshal(e)
+		fmt.Println(string(data))
+		return
+	}
+	line := fmt.Sprintf("%s  #%-6d %-19s", e.Time.Format("15:04:05.000"), e.Seq, e.Kind)
+	if e.IMSI != "" {
+		line += " " + e.IMSI
+	}
+	switch {
+	case e.From != nil && e.To != nil:
+		line += fmt.Sprintf(" gNB-%d/cell-%d → gNB-%d/cell-%d", e.From.GNodeB, e.From.Cell, e.To.GNodeB, e.To.Cell)
+	case e.To != nil:
+		line += fmt.Sprintf(" gNB-%d/cell-%d", e.To.GNodeB, e.To.Cell)
+	case e.GNodeB != 0:
+		line += fmt.Sprintf(" gNB-%d", e.GNodeB)
+	}
+	if e.Session != 0 {
+		line += fmt.Sprintf(" session %d", e.Session)
+	}
+	if e.SessionType != "" {
+		line += " " + e.SessionType
+	}
+	if e.LatencyMs != 0 {
+		line += fmt.Sprintf(" (%.1f ms)", e.LatencyMs)
+	}
+	if e.Detail != "" {
+		line += " " + e.Detail
+	}
+	if e.Error != "" {
+		line += " error: " + e.Error
+	}
+	fmt.Println(line)
+}
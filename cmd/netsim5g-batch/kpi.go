@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/scenario"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// KPIs are the results of one run (or the mean over the seeds of one point)
+type KPIs struct {
+	Registrations       float64 `json:"registrations"`
+	RegistrationSuccess float64 `json:"registrationSuccessRatio"`
+	HandoverAttempts    float64 `json:"handoverAttempts"`
+	HandoverSuccesses   float64 `json:"handoverSuccesses"`
+	HandoverFailures    float64 `json:"handoverFailures"`
+	SessionsEstablished float64 `json:"sessionsEstablished"`
+	SessionsRejected    float64 `json:"sessionsRejected"`
+	SessionsDropped     float64 `json:"sessionsDropped"`
+	MeanSetupMs         float64 `json:"meanSetupMs"`
+	ActiveSessions      float64 `json:"activeSessions"` // at the end
+	ConnectedUEs        float64 `json:"connectedUEs"`   // at the end
+	RadioLinkFailures   float64 `json:"radioLinkFailures"`
+	PeakCellLoad        float64 `json:"peakCellLoad"` // highest connected/MaxCap of any cell, sampled every second
+	AssertionsFailed    float64 `json:"assertionsFailed"`
+}
+
+// Result is one run, or one point when aggregated (Run then describes the first run)
+type Result struct {
+	Run
+	Runs  int // runs averaged into KPIs
+	KPIs  KPIs
+	Error string
+}
+
+// watchLoad samples the peak cell load once a simulated second until duration
+func watchLoad(net *network.Network, duration time.Duration, peak *float64) {
+	for at := time.Duration(0); at <= duration; at += time.Second {
+		net.Scheduler.At(net.Clock.Start().Add(at), sim.PriorityLow, "sample load", func(time.Time) {
+			for _, g := range net.Sites {
+				for _, c := range g.Cells {
+					if c.MaxCap > 0 {
+						*peak = math.Max(*peak, float64(len(c.ConnectedUEs))/float64(c.MaxCap))
+					}
+				}
+			}
+		})
+	}
+}
+
+// collectKPIs reads the run's metrics once the scenario is over
+func collectKPIs(net *network.Network, report scenario.Report, peakLoad float64) KPIs {
+	net.WriteMetrics(io.Discard) // refresh the gauges
+	m := net.Metrics
+	k := KPIs{
+		Registrations:       m.Sum("netsim_registrations_total"),
+		RegistrationSuccess: m.Sum("netsim_registration_success_ratio"),
+		HandoverAttempts:    m.Sum("netsim_handover_attempts_total"),
+		HandoverSuccesses:   m.Sum("netsim_handover_successes_total"),
+		HandoverFailures:    m.Sum("netsim_handover_failures_total"),
+		SessionsEstablished: m.Sum("netsim_sessions_established_total"),
+		SessionsRejected:    m.Sum("netsim_sessions_rejected_total"),
+		SessionsDropped:     m.Sum("netsim_sessions_dropped_total"),
+		ActiveSessions:      m.Sum("netsim_active_sessions"),
+		RadioLinkFailures:   m.Sum("netsim_radio_link_failures_total"),
+		PeakCellLoad:        peakLoad,
+	}
+	if n := m.Count("netsim_session_establishment_seconds"); n > 0 {
+		k.MeanSetupMs = m.Sum("netsim_session_establishment_seconds") / float64(n) * 1000
+	}
+	for _, u := range net.UEs {
+		if u.State == ue.Connected {
+			k.ConnectedUEs++
+		}
+	}
+	for _, res := range report.Results {
+		if !res.Passed {
+			k.AssertionsFailed++
+		}
+	}
+	return k
+}
+
+// aggregate averages the KPIs of the runs of each point; failed runs are left out
+func aggregate(results []Result) []Result {
+	var points []Result
+	byPoint := make(map[int]int) // point -> index in points
+	for _, r := range results {
+		i, seen := byPoint[r.Point]
+		if !seen {
+			i = len(points)
+			byPoint[r.Point] = i
+			points = append(points, Result{Run: r.Run})
+		}
+		if r.Error != "" {
+			points[i].Error = r.Error
+			continue
+		}
+		p := &points[i]
+		p.Runs++
+		p.KPIs = p.KPIs.plus(r.KPIs)
+	}
+	for i := range points {
+		if points[i].Runs > 0 {
+			points[i].KPIs = points[i].KPIs.scaled(1 / float64(points[i].Runs))
+		}
+	}
+	return points
+}
+
+func (k *KPIs) values() []*float64 {
+	return []*float64{
+		&k.Registrations, &k.RegistrationSuccess, &k.HandoverAttempts, &k.HandoverSuccesses,
+		&k.HandoverFailures, &k.SessionsEstablished, &k.SessionsRejected, &k.SessionsDropped,
+		&k.MeanSetupMs, &k.ActiveSessions, &k.ConnectedUEs, &k.RadioLinkFailures,
+		&k.PeakCellLoad, &k.AssertionsFailed,
+	}
+}
+
+var kpiColumns = []string{
+	"registrations", "registration_success_ratio", "handover_attempts", "handover_successes",
+	"handover_failures", "sessions_established", "sessions_rejected", "sessions_dropped",
+	"mean_setup_ms", "active_sessions", "connected_ues", "radio_link_failures",
+	"peak_cell_load", "assertions_failed",
+}
+
+func (k KPIs) plus(o KPIs) KPIs {
+	sum := k
+	dst, src := sum.values(), o.values()
+	for i := range dst {
+		*dst[i] += *src[i]
+	}
+	return sum
+}
+
+func (k KPIs) scaled(f float64) KPIs {
+	out := k
+	for _, v := range out.values() {
+		*v *= f
+	}
+	return out
+}
+
+// writeCSV writes one row per result
+func writeCSV(w io.Writer, results []Result) error {
+	out := csv.NewWriter(w)
+	header := append([]string{"run", "point", "seed", "runs", "range_m", "ues", "hysteresis_db"}, kpiColumns...)
+	out.Write(append(header, "error"))
+	for _, r := range results {
+		row := []string{
+			strconv.Itoa(r.Index), strconv.Itoa(r.Point), strconv.FormatInt(r.Seed, 10), strconv.Itoa(r.Runs),
+			param(r.Params.RangeM), strconv.Itoa(r.Params.UEs), param(r.Params.HysteresisDB),
+		}
+		for _, v := range r.KPIs.values() {
+			row = append(row, strconv.FormatFloat(*v, 'f', -1, 64))
+		}
+		out.Write(append(row, r.Error))
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// param prints a swept value, empty when the topology's own value was kept
+func param(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeJSON writes the results as an indented array; unswept values become null
+func writeJSON(w io.Writer, results []Result) error {
+	type jsonParams struct {
+		RangeM       *float64 `json:"rangeM"`
+		UEs          int      `json:"ues"`
+		HysteresisDB *float64 `json:"hysteresisDB"`
+	}
+	type jsonResult struct {
+		Run    int        `json:"run"`
+		Point  int        `json:"point"`
+		Seed   int64      `json:"seed"`
+		Runs   int        `json:"runs"`
+		Params jsonParams `json:"params"`
+		KPIs   KPIs       `json:"kpis"`
+		Error  string     `json:"error,omitempty"`
+	}
+	optional := func(v float64) *float64 {
+		if math.IsNaN(v) {
+			return nil
+		}
+		return &v
+	}
+
+	out := make([]jsonResult, 0, len(results))
+	for _, r := range results {
+		out = append(out, jsonResult{
+			Run:    r.Index,
+			Point:  r.Point,
+			Seed:   r.Seed,
+			Runs:   r.Runs,
+			Params: jsonParams{RangeM: optional(r.Params.RangeM), UEs: r.Params.UEs, HysteresisDB: optional(r.Params.HysteresisDB)},
+			KPIs:   r.KPIs,
+			Error:  r.Error,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+// How generated UEs behave: they attach at a random time in the first attachWindow,
+// open one session, then every moveEvery walk up to maxStep metres in a random
+// direction, sending a little uplink data so they stay connected
+const (
+	attachWindow = 10 * time.Second
+	moveEvery    = 5 * time.Second
+	maxStep      = 40.0
+	minDuration  = 60 * time.Second
+)
+
+// generatedIMSI numbers UEs under the test PLMN 999-99
+func generatedIMSI(i int) string {
+	return fmt.Sprintf("99999%010d", i+1)
+}
+
+// addLoad creates count subscribers and UEs and queues their activity until duration.
+// They are provisioned like real users: in the subscriber database and on every allow-list.
+func addLoad(net *network.Network, count int, duration time.Duration) error {
+	if count == 0 {
+		return nil
+	}
+	place := net.RNG.Stream(sim.StreamPlacement)
+	traffic := net.RNG.Stream(sim.StreamTraffic)
+	box := net.Coverage() // generated UEs live in the box around every gNodeB's coverage
+	start := net.Clock.Start()
+
+	for i := 0; i < count; i++ {
+		imsi := generatedIMSI(i)
+		if err := net.UDM.AddSubscriber(udm.Subscriber{IMSI: imsi, SubscriptionStatus: "active", MaxDataRate: 100}); err != nil {
+			return err
+		}
+		allow(net, imsi)
+		x, y := box.Random(place)
+		u, err := net.AddUE(imsi, x, y)
+		if err != nil {
+			return err
+		}
+
+		attachAt := start.Add(time.Duration(place.Int63n(int64(attachWindow))))
+		sessionType := smf.SessionType(traffic.Intn(int(smf.WebBrowsing) + 1))
+		net.Scheduler.At(attachAt, sim.PriorityNormal, "attach "+imsi, func(now time.Time) {
+			net.AMF.TickRRC(now)
+			if net.AMF.Attach(u) == nil {
+				net.SMF.EstablishSession(u, sessionType) // rejections are counted, not fatal
+			}
+		})
+
+		for at := attachAt.Add(moveEvery); at.Sub(start) <= duration; at = at.Add(moveEvery) {
+			net.Scheduler.At(at, sim.PriorityNormal, "move "+imsi, func(now time.Time) {
+				net.AMF.TickRRC(now)
+				heading := place.Float64() * 2 * math.Pi
+				step := place.Float64() * maxStep
+				newX, newY := box.Clamp(u.X+step*math.Cos(heading), u.Y+step*math.Sin(heading))
+				net.AMF.MoveUE(u, newX, newY) // leaving coverage is part of the experiment
+				net.AMF.UplinkData(u, now)
+			})
+		}
+	}
+	return nil
+}
+
+// allow adds imsi to the allow-lists of every gNodeB and cell that has one
+func allow(net *network.Network, imsi string) {
+	for _, g := range net.Sites {
+		if g.AllowedIMSIs != nil {
+			g.AllowedIMSIs[imsi] = true
+		}
+		for _, c := range g.Cells {
+			if c.AllowedIMSIs != nil {
+				c.AllowedIMSIs[imsi] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/scenario"
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+const usage = `Usage: netsim5g-batch -scenario <file> [sweep flags] [-out results.csv|results.json]
+
+Runs a scenario once for every combination of the swept parameters, in parallel,
+and writes the KPIs of every run as CSV or JSON.
+
+Sweep values are a list (10,100,1000) or start:stop:step (40:80:10); a parameter
+that is not swept keeps the topology's value. Each run gets its own seed (-seed,
+-seed+1, ... in run order), so any row can be reproduced on its own.
+
+Example:
+  netsim5g-batch -scenario internal/configs/scenarios/handover.yaml \
+    -range 40:80:20 -ues 10,100 -hysteresis 0:6:3 -seeds 3 -out sweep.csv
+`
+
+func main() {
+	scenarioPath := flag.String("scenario", "", "scenario file to run (required)")
+	topologyPath := flag.String("config", "internal/configs/topology.json", "gNodeB topology, unless the scenario names one")
+	subscribersPath := flag.String("subscribers", "internal/configs/subscribers.json", "subscriber database, unless the scenario names one")
+	ranges := flag.String("range", "", "gNodeB range in metres, applied to every gNodeB")
+	ues := flag.String("ues", "", "generated UEs on top of the scenario's own")
+	hysteresis := flag.String("hysteresis", "", "handover hysteresis (A3 offset) in dB")
+	seeds := flag.Int("seeds", 1, "runs per parameter combination, each with its own seed")
+	seed := flag.Int64("seed", sim.DefaultSeed, "seed of the first run")
+	workers := flag.Int("workers", runtime.NumCPU(), "runs in parallel")
+	out := flag.String("out", "", "result file; .json writes JSON, anything else CSV (default: CSV on stdout)")
+	mean := flag.Bool("aggregate", false, "one row per parameter combination, KPIs averaged over its seeds")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *scenarioPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*scenarioPath, *topologyPath, *subscribersPath, *ranges, *ues, *hysteresis, *seeds, *seed, *workers, *out, *mean); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+}
+
+func run(scenarioPath, topologyPath, subscribersPath, ranges, ues, hysteresis string, seeds int, seed int64, workers int, out string, mean bool) error {
+	sc, err := config.LoadScenario(scenarioPath)
+	if err != nil {
+		return err
+	}
+
+	// Step 1: Every combination of the swept values, seeds times
+	rangeValues, err := parseValues("range", ranges)
+	if err != nil {
+		return err
+	}
+	ueValues, err := parseValues("ues", ues)
+	if err != nil {
+		return err
+	}
+	hysteresisValues, err := parseValues("hysteresis", hysteresis)
+	if err != nil {
+		return err
+	}
+	if seeds < 1 || workers < 1 {
+		return fmt.Errorf("-seeds and -workers must be at least 1")
+	}
+	runs, err := plan(rangeValues, ueValues, hysteresisValues, seeds, seed)
+	if err != nil {
+		return err
+	}
+
+	// Step 2: Run them on a pool of workers. Each run has its own network, clock and
+	// random streams, so runs do not affect each other.
+	fmt.Fprintf(os.Stderr, "Running %d runs of %s on %d workers\n", len(runs), scenarioPath, workers)
+	started := time.Now()
+	results := make([]Result, len(runs))
+	jobs := make(chan Run)
+	var wg sync.WaitGroup
+	var progress sync.Mutex
+	done := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				results[r.Index] = runOne(sc, topologyPath, subscribersPath, r)
+				progress.Lock()
+				done++
+				if results[r.Index].Error != "" {
+					fmt.Fprintf(os.Stderr, "  run %d failed: %s\n", r.Index, results[r.Index].Error)
+				}
+				if done%10 == 0 || done == len(runs) {
+					fmt.Fprintf(os.Stderr, "  %d/%d done\n", done, len(runs))
+				}
+				progress.Unlock()
+			}
+		}()
+	}
+	for _, r := range runs {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+	fmt.Fprintf(os.Stderr, "✓ Finished in %v\n", time.Since(started).Round(time.Millisecond))
+
+	// Step 3: Write the results
+	if mean {
+		results = aggregate(results)
+	}
+	var w io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if filepath.Ext(out) == ".json" {
+		err = writeJSON(w, results)
+	} else {
+		err = writeCSV(w, results)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	if out != "" {
+		fmt.Fprintf(os.Stderr, "✓ Results written to %s\n", out)
+	}
+	return nil
+}
+
+// runOne plays the scenario with one parameter point and seed
+func runOne(sc *config.Scenario, topologyPath, subscribersPath string, r Run) Result {
+	duration := minDuration
+	for _, e := range sc.Events {
+		if end := e.At + e.Duration; end > duration {
+			duration = end
+		}
+	}
+
+	var net *network.Network
+	peakLoad := 0.0
+	opts := scenario.Options{
+		Seed: &r.Seed,
+		Setup: func(n *network.Network) error {
+			net = n
+			for _, g := range n.Sites {
+				if !math.IsNaN(r.Params.RangeM) {
+					g.Range = r.Params.RangeM
+					n.AMF.Index.Update(g) // the index searches with the range it was given
+				}
+			}
+			if !math.IsNaN(r.Params.HysteresisDB) {
+				n.AMF.HandoverHysteresisDB = r.Params.HysteresisDB
+			}
+			watchLoad(n, duration, &peakLoad)
+			return addLoad(n, r.Params.UEs, duration)
+		},
+	}
+
+	result := Result{Run: r, Runs: 1}
+	report, err := scenario.Run(sc, topologyPath, subscribersPath, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.KPIs = collectKPIs(net, report, peakLoad)
+	return result
+}
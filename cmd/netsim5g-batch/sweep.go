@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Params is one point of the sweep. NaN range or hysteresis = keep the topology's value.
+type Params struct {
+	RangeM       float64
+	UEs          int // generated UEs, on top of the scenario's own
+	HysteresisDB float64
+}
+
+// Run is one simulation: a parameter point and the seed it runs with
+type Run struct {
+	Index  int
+	Point  int // runs with the same point differ only by seed
+	Seed   int64
+	Params Params
+}
+
+// parseValues reads a sweep flag: "" = not swept, "a,b,c" = those values,
+// "start:stop:step" = start, start+step, ... up to and including stop
+func parseValues(name, spec string) ([]float64, error) {
+	if spec == "" {
+		return []float64{math.NaN()}, nil
+	}
+	if strings.Contains(spec, ":") {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("-%s %q: want start:stop:step", name, spec)
+		}
+		var bounds [3]float64
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, fmt.Errorf("-%s %q: %w", name, spec, err)
+			}
+			bounds[i] = v
+		}
+		start, stop, step := bounds[0], bounds[1], bounds[2]
+		if step <= 0 || stop < start {
+			return nil, fmt.Errorf("-%s %q: need step > 0 and stop >= start", name, spec)
+		}
+		var values []float64
+		for i := 0; ; i++ {
+			v := start + float64(i)*step // no accumulated rounding
+			if v > stop+step*1e-9 {
+				break
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	var values []float64
+	for _, p := range strings.Split(spec, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("-%s %q: %w", name, spec, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// plan lists every combination of the swept values, seeds times each. Seeds are
+// baseSeed, baseSeed+1, ... in run order, so any single run can be reproduced.
+func plan(ranges, ues, hysteresis []float64, seeds int, baseSeed int64) ([]Run, error) {
+	var runs []Run
+	point := 0
+	for _, r := range ranges {
+		if !math.IsNaN(r) && r <= 0 {
+			return nil, fmt.Errorf("gNodeB range must be positive, got %g", r)
+		}
+		for _, n := range ues {
+			count := 0
+			if !math.IsNaN(n) {
+				if n < 0 || n != math.Trunc(n) {
+					return nil, fmt.Errorf("UE count must be a whole number >= 0, got %g", n)
+				}
+				count = int(n)
+			}
+			for _, h := range hysteresis {
+				for s := 0; s < seeds; s++ {
+					runs = append(runs, Run{
+						Index:  len(runs),
+						Point:  point,
+						Seed:   baseSeed + int64(len(runs)),
+						Params: Params{RangeM: r, UEs: count, HysteresisDB: h},
+					})
+				}
+				point++
+			}
+		}
+	}
+	return runs, nil
+}
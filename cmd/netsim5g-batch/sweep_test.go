@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		spec   string
+		values []float64 // nil = NaN, not swept
+		err    string
+	}{
+		{"", nil, ""},
+		{"40", []float64{40}, ""},
+		{"10, 100,1000", []float64{10, 100, 1000}, ""},
+		{"-3,0.5", []float64{-3, 0.5}, ""}, // signs are plan's business
+		{"40:80:20", []float64{40, 60, 80}, ""},
+		{"40:90:20", []float64{40, 60, 80}, ""}, // stop not on a step
+		{"0:0.3:0.1", []float64{0, 0.1, 0.2, 0.30000000000000004}, ""},
+		{"5:5:1", []float64{5}, ""},
+		{"0:6:3", []float64{0, 3, 6}, ""},
+		{"40:80", nil, `-x "40:80": want start:stop:step`},
+		{"1:2:3:4", nil, "want start:stop:step"},
+		{"40:80:0", nil, "need step > 0 and stop >= start"},
+		{"40:80:-20", nil, "need step > 0 and stop >= start"},
+		{"80:40:20", nil, "need step > 0 and stop >= start"},
+		{"a:80:20", nil, `-x "a:80:20": strconv.ParseFloat: parsing "a": invalid syntax`},
+		{"10,,20", nil, `parsing "": invalid syntax`},
+		{"ten", nil, "invalid syntax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			values, err := parseValues("x", tt.spec)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got %v, %v; want error %q", values, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.values == nil {
+				if len(values) != 1 || !math.IsNaN(values[0]) {
+					t.Errorf("got %v, want [NaN]", values)
+				}
+				return
+			}
+			if len(values) != len(tt.values) {
+				t.Fatalf("got %v, want %v", values, tt.values)
+			}
+			for i := range values {
+				if math.Abs(values[i]-tt.values[i]) > 1e-12 {
+					t.Errorf("got %v, want %v", values, tt.values)
+				}
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	nan := math.NaN()
+
+	// Step 1: Every combination, seeds times, with consecutive seeds
+	runs, err := plan([]float64{40, 80}, []float64{0, 10}, []float64{3}, 2, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2*2*1*2 {
+		t.Fatalf("%d runs, want 8", len(runs))
+	}
+	want := []Params{{40, 0, 3}, {40, 0, 3}, {40, 10, 3}, {40, 10, 3}, {80, 0, 3}, {80, 0, 3}, {80, 10, 3}, {80, 10, 3}}
+	for i, r := range runs {
+		if r.Index != i || r.Seed != 100+int64(i) || r.Point != i/2 || r.Params != want[i] {
+			t.Errorf("run %d: %+v, want index %d, seed %d, point %d, %+v", i, r, i, 100+i, i/2, want[i])
+		}
+	}
+
+	// Step 2: Nothing swept: one point, the topology's values
+	runs, err = plan([]float64{nan}, []float64{nan}, []float64{nan}, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[2].Point != 0 || runs[2].Seed != 3 || runs[0].Params.UEs != 0 ||
+		!math.IsNaN(runs[0].Params.RangeM) || !math.IsNaN(runs[0].Params.HysteresisDB) {
+		t.Errorf("got %+v, want 3 seeds of one point keeping the topology's range and hysteresis", runs)
+	}
+
+	// Step 3: Values a run cannot take
+	for _, tt := range []struct {
+		ranges, ues []float64
+		err         string
+	}{
+		{[]float64{40, 0}, []float64{nan}, "gNodeB range must be positive, got 0"},
+		{[]float64{-10}, []float64{nan}, "gNodeB range must be positive, got -10"},
+		{[]float64{40}, []float64{-1}, "UE count must be a whole number >= 0, got -1"},
+		{[]float64{40}, []float64{2.5}, "UE count must be a whole number >= 0, got 2.5"},
+	} {
+		if runs, err := plan(tt.ranges, tt.ues, []float64{nan}, 1, 1); err == nil || err.Error() != tt.err {
+			t.Errorf("plan(%v, %v) = %d runs, %v; want error %q", tt.ranges, tt.ues, len(runs), err, tt.err)
+		}
+	}
+	if runs, err := plan([]float64{40}, []float64{1}, []float64{0}, 0, 1); err != nil || !reflect.DeepEqual(runs, []Run(nil)) {
+		t.Errorf("no seeds gave %v, %v; want no runs", runs, err)
+	}
+}
+
+func TestRunSweep(t *testing.T) {
+	scenario := "../../internal/configs/scenarios/handover.yaml"
+	dir := t.TempDir()
+
+	// Step 1: 2 ranges x 2 UE counts x 2 seeds, on two workers, as JSON and as CSV
+	// with the seeds averaged
+	sweep := func(out string, mean bool) {
+		t.Helper()
+		if err := run(scenario, "", "", "40,60", "0,5", "", 2, 7, 2, out, mean); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sweep(filepath.Join(dir, "runs.json"), false)
+	sweep(filepath.Join(dir, "again.json"), false)
+	sweep(filepath.Join(dir, "points.csv"), true)
+
+	var results []struct {
+		Run    int   `json:"run"`
+		Point  int   `json:"point"`
+		Seed   int64 `json:"seed"`
+		Params struct {
+			RangeM       *float64 `json:"rangeM"`
+			UEs          int      `json:"ues"`
+			HysteresisDB *float64 `json:"hysteresisDB"`
+		} `json:"params"`
+		Error string `json:"error"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "runs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("%d results, want 8", len(results))
+	}
+	for i, r := range results {
+		wantRange, wantUEs := []float64{40, 60}[i/4], []int{0, 5}[i/2%2]
+		if r.Run != i || r.Point != i/2 || r.Seed != 7+int64(i) || r.Error != "" ||
+			r.Params.RangeM == nil || *r.Params.RangeM != wantRange || r.Params.UEs != wantUEs || r.Params.HysteresisDB != nil {
+			t.Errorf("result %d: %+v, want point %d, seed %d, range %g, %d UEs", i, r, i/2, 7+i, wantRange, wantUEs)
+		}
+	}
+
+	// Step 2: Runs in one process do not affect each other: the sweep repeats exactly
+	if again, _ := os.ReadFile(filepath.Join(dir, "again.json")); string(again) != string(data) {
+		t.Error("the same sweep gave different results")
+	}
+
+	// Step 3: Averaged, one row per point after the header
+	f, err := os.Open(filepath.Join(dir, "points.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || rows[0][0] != "run" || rows[0][len(rows[0])-1] != "error" {
+		t.Fatalf("%d CSV rows, header %v; want the header and 4 points", len(rows), rows[0])
+	}
+	for i, row := range rows[1:] {
+		if row[1] != []string{"0", "1", "2", "3"}[i] || row[3] != "2" {
+			t.Errorf("row %d: point %s averaging %s runs, want point %d of 2", i, row[1], row[3], i)
+		}
+	}
+
+	// Step 4: Bad flags stop the sweep before anything runs
+	for _, tt := range []struct {
+		ranges, ues string
+		seeds       int
+		err         string
+	}{
+		{"40:80", "", 1, "want start:stop:step"},
+		{"0,40", "", 1, "gNodeB range must be positive"},
+		{"", "1.5", 1, "UE count must be a whole number"},
+		{"", "", 0, "-seeds and -workers must be at least 1"},
+	} {
+		out := filepath.Join(dir, "bad.csv")
+		if err := run(scenario, "", "", tt.ranges, tt.ues, "", tt.seeds, 1, 1, out, false); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("-range %q -ues %q -seeds %d: got %v, want %q", tt.ranges, tt.ues, tt.seeds, err, tt.err)
+		}
+		if _, err := os.Stat(out); err == nil {
+			t.Errorf("-range %q -ues %q -seeds %d wrote results", tt.ranges, tt.ues, tt.seeds)
+		}
+	}
+}
@@ -1,55 +1,200 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
+	"time"
 
 	"github.com/rizpur/NetSim5G/internal/api"
-	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/config"
 	"github.com/rizpur/NetSim5G/internal/core/smf"
-	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/radio"
 	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/scenario"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/traffic"
 	"github.com/rizpur/NetSim5G/internal/ue"
 )
 
 func main() {
+	topologyPath := flag.String("config", "internal/configs/topology.json", "gNodeB topology file (.json, .yaml or .yml), or a site file from a planning tool (.geojson or .csv)")
+	subscribersPath := flag.String("subscribers", "internal/configs/subscribers.json", "subscriber database (JSON)")
+	addr := flag.String("addr", ":8080", "API listen address")
+	scenarioPath := flag.String("scenario", "", "run a scenario file and exit (status 1 if an assertion fails)")
+	speed := flag.Float64("speed", 1, "simulation speed: 0 = as fast as possible, 1 = real time, N = N× real time (scenarios default to 0)")
+	restorePath := flag.String("restore", "", "resume from a snapshot file instead of running the demo")
+	paused := flag.Bool("paused", false, "start the API server with the simulation clock paused")
+	seed := flag.Int64("seed", sim.DefaultSeed, "random seed; the same seed and inputs reproduce a run exactly")
+	journalPath := flag.String("journal", "", "write every procedure to this file as JSON lines (see cmd/replay)")
+	uesPath := flag.String("ues", "", "add the UEs of this .geojson or .csv file (imsi and lat/lon or x/y per record) before serving")
+	withTraffic := flag.Bool("traffic", false, "give every PDU session traffic of its type, reported at /api/traffic (scenarios set traffic: true instead)")
+	flag.Parse()
+
+	var journalFile *os.File
+	if *journalPath != "" {
+		f, err := os.Create(*journalPath)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		journalFile = f
+	}
+
+	// Scenario mode: play a scenario file, report, and exit
+	if *scenarioPath != "" {
+		if !flagSet("speed") {
+			*speed = 0
+		}
+		opts := scenario.Options{Speed: *speed}
+		if flagSet("seed") {
+			opts.Seed = seed
+		}
+		if journalFile != nil {
+			opts.Journal = journalFile
+		}
+		status := runScenario(*scenarioPath, *topologyPath, *subscribersPath, opts)
+		journalFile.Close()
+		os.Exit(status)
+	}
+
 	fmt.Println("=== Initializing 5G Network ===")
 
-	// Step 1: Create UDM (subscriber database)
-	udmInstance, err := udm.NewUDM()
+	topology, err := config.Load(*topologyPath)
 	if err != nil {
 		panic(err)
 	}
+	net, err := network.New(topology, *subscribersPath, *seed)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("✓ Random seed %d\n", net.RNG.Seed())
+	if journalFile != nil {
+		net.Journal.SetOutput(journalFile)
+		defer net.Journal.Flush()
+		fmt.Printf("✓ Journal written to %s\n", *journalPath)
+	}
+	net.AMF.LinkAdaptation = radio.LinkAdaptation{ModelBLER: true, HARQMaxTx: 4}
 	fmt.Println("✓ UDM initialized")
-
-	// Step 2: Create AMF (needs UDM)
-	amfInstance := amf.NewAMF(udmInstance)
 	fmt.Println("✓ AMF initialized")
-
-	// Step 3: Create SMF (needs UDM)
-	smfInstance := smf.NewSMF(udmInstance)
 	fmt.Println("✓ SMF initialized")
+	for _, g := range net.Sites {
+		fmt.Printf("✓ gNodeB-%d initialized at (%.0f, %.0f) with %.0fm range and %d cell(s)\n", g.ID, g.X, g.Y, g.Range, len(g.Cells))
+	}
 
-	// Track all UEs and gNodeBs for API
-	allUEs := make(map[string]*ue.UE)
-	gNodeBs := make(map[int]*ran.GNodeB)
+	if *restorePath != "" {
+		// Resume a saved network instead of running the demo
+		snap, err := network.LoadSnapshot(*restorePath)
+		if err != nil {
+			panic(err)
+		}
+		if err := net.Restore(snap); err != nil {
+			panic(err)
+		}
+		fmt.Printf("✓ Restored %s: %d UEs, %d sessions at t=%v\n", *restorePath, len(net.UEs), len(net.SMF.Sessions), net.Clock.Elapsed())
+	} else if !demo(net) {
+		return
+	}
+	if *uesPath != "" {
+		ues, err := config.LoadUEs(*uesPath)
+		if err != nil {
+			panic(err)
+		}
+		for _, u := range ues {
+			x, y := u.X, u.Y
+			if u.Position != nil {
+				if x, y, err = net.ToXY(*u.Position); err != nil {
+					panic(err)
+				}
+			}
+			if _, err := net.AddUE(u.IMSI, x, y); err != nil {
+				panic(err)
+			}
+		}
+		fmt.Printf("✓ Added %d UEs from %s\n", len(ues), *uesPath)
+	}
+	amfInstance := net.AMF
 
-	// Step 4: Create 2 gNodeBs at different locations
-	gnb1, err := ran.NewGNodeB(100, 100, 50, 3) // gNodeB-1 at (100,100) with 50m range
-	if err != nil {
-		panic(err)
+	// Start API server
+	handler := api.NewHandler(net)
+	handler.RegisterRoutes()
+
+	// From here on the simulation clock follows the wall clock at the chosen speed,
+	// with the RRC inactivity timers checked and UEs with a mobility model moved
+	// every simulated second
+	net.Scheduler.Every(time.Second, sim.PriorityHigh, "rrc timers", func(now time.Time) {
+		amfInstance.TickRRC(now)
+	})
+	net.StartMobility(network.DefaultMobilityTick)
+	if *withTraffic {
+		net.StartTraffic(traffic.DefaultTick)
+	}
+	net.Scheduler.SetSpeed(*speed)
+	if *paused {
+		net.Scheduler.Pause()
 	}
-	amfInstance.RegisterGNodeB(gnb1)
-	gNodeBs[gnb1.ID] = gnb1
-	fmt.Printf("✓ gNodeB-%d initialized at (%.0f, %.0f) with %.0fm range\n", gnb1.ID, gnb1.X, gnb1.Y, gnb1.Range)
+	go net.Scheduler.Serve(nil)
+	if journalFile != nil {
+		go func() {
+			for range time.Tick(time.Second) {
+				net.Journal.Flush()
+			}
+		}()
+	}
+
+	fmt.Println("\n=== Starting API Server ===")
+	fmt.Printf("API listening on %s\n", *addr)
+	fmt.Println("Try: curl http://localhost:8080/api/ues")
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		fmt.Println("❌ API server stopped:", err)
+	}
+}
 
-	gnb2, err := ran.NewGNodeB(200, 200, 50, 3) // gNodeB-2 at (200,200) with 50m range
+// runScenario plays a scenario file and returns the process exit status
+func runScenario(path, topologyPath, subscribersPath string, opts scenario.Options) int {
+	sc, err := config.LoadScenario(path)
 	if err != nil {
-		panic(err)
+		fmt.Println("❌", err)
+		return 2
+	}
+	report, err := scenario.Run(sc, topologyPath, subscribersPath, opts)
+	if err != nil {
+		fmt.Println("❌", err)
+		return 2
+	}
+	report.Print(os.Stdout)
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+// flagSet reports whether a flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// demo walks through handover, link adaptation, RRC states, a site outage and mass
+// attach on the network, printing what happens. It returns false if a step failed.
+func demo(net *network.Network) bool {
+	amfInstance, smfInstance := net.AMF, net.SMF
+	allUEs, gNodeBs := net.UEs, net.GNodeBs
+
+	// The walkthrough below expects gNodeB-1 at (100,100) and gNodeB-2 at (200,200), as in the default topology
+	if len(net.Sites) < 2 {
+		fmt.Println("❌ The demo walkthrough needs at least 2 gNodeBs")
+		return false
 	}
-	amfInstance.RegisterGNodeB(gnb2)
-	gNodeBs[gnb2.ID] = gnb2
-	fmt.Printf("✓ gNodeB-%d initialized at (%.0f, %.0f) with %.0fm range\n", gnb2.ID, gnb2.X, gnb2.Y, gnb2.Range)
+	gnb1, gnb2 := net.Sites[0], net.Sites[1]
 
 	fmt.Println("\n=== Testing Handover: UE Moves Between gNodeBs ===")
 
@@ -62,12 +207,12 @@ func main() {
 	fmt.Println("\n--- Step 1: Initial Connection ---")
 	if err := gnb1.ConnectUE(ue1); err != nil {
 		fmt.Println("❌ Connection failed:", err)
-		return
+		return false
 	}
 	// Register with AMF after radio connection
 	if err := amfInstance.RegisterUE(ue1.IMSI, gnb1.ID); err != nil {
 		fmt.Println("❌ AMF registration failed:", err)
-		return
+		return false
 	}
 	fmt.Printf("✓ UE1 connected to gNodeB-%d\n", gnb1.ID)
 	fmt.Printf("✓ AMF registration: gNodeB-%d\n", amfInstance.RegisteredUEs[ue1.IMSI].GNodeBID)
@@ -77,7 +222,7 @@ func main() {
 	voipSession, err := smfInstance.EstablishSession(ue1, smf.VoIP)
 	if err != nil {
 		fmt.Println("❌ Session failed:", err)
-		return
+		return false
 	}
 	fmt.Printf("✓ Session %d established: VoIP (1 Mbps, 10ms latency)\n", voipSession.SessionID)
 
@@ -86,10 +231,10 @@ func main() {
 	fmt.Println("  Moving UE1 from (110, 110) → (180, 180)...")
 	if err := amfInstance.MoveUE(ue1, 180, 180); err != nil {
 		fmt.Println("❌ Move failed:", err)
-		return
+		return false
 	}
 	fmt.Printf("✓ UE1 moved to (%.0f, %.0f)\n", ue1.X, ue1.Y)
-	fmt.Printf("✓ Handover completed! Now connected to gNodeB-%d\n", amfInstance.RegisteredUEs[ue1.IMSI].GNodeBID)
+	fmt.Printf("✓ Handover completed! Now connected to gNodeB-%d, cell %d\n", ue1.GNodeBConnected, ue1.CellConnected)
 	fmt.Printf("✓ VoIP session still active: Session %d\n", voipSession.SessionID)
 
 	// Step 4: UE moves even further (only in range of gNodeB-2)
@@ -97,10 +242,10 @@ func main() {
 	fmt.Println("  Moving UE1 from (180, 180) → (210, 210)...")
 	if err := amfInstance.MoveUE(ue1, 210, 210); err != nil {
 		fmt.Println("❌ Move failed:", err)
-		return
+		return false
 	}
 	fmt.Printf("✓ UE1 moved to (%.0f, %.0f)\n", ue1.X, ue1.Y)
-	fmt.Printf("✓ Still connected to gNodeB-%d\n", amfInstance.RegisteredUEs[ue1.IMSI].GNodeBID)
+	fmt.Printf("✓ Still connected to gNodeB-%d, now on cell %d (crossed into another sector)\n", ue1.GNodeBConnected, ue1.CellConnected)
 
 	// Step 5: UE moves out of all range
 	fmt.Println("\n--- Step 5: UE Moves Out of Range ---")
@@ -112,28 +257,134 @@ func main() {
 	}
 	fmt.Printf("  UE1 final state: %s\n", ue1.State)
 
+	fmt.Println("\n=== Testing Link Adaptation: Cell Centre vs Cell Edge ===")
+
+	// Both UEs attach to gNodeB-1 and ask for a 50 Mbps VideoStreaming session
+	centreUE := ue.NewUE("987654321098765", 102, 102) // ~3m from gNodeB-1
+	edgeUE := ue.NewUE("208930000000001", 140, 130)   // 50m from gNodeB-1, facing gNodeB-2
+	for _, u := range []*ue.UE{centreUE, edgeUE} {
+		allUEs[u.IMSI] = u
+		if err := gnb1.ConnectUE(u); err != nil {
+			fmt.Println("❌ Connection failed:", err)
+			return false
+		}
+		if err := amfInstance.RegisterUE(u.IMSI, gnb1.ID); err != nil {
+			fmt.Println("❌ AMF registration failed:", err)
+			return false
+		}
+	}
+
+	for _, u := range []*ue.UE{centreUE, edgeUE} {
+		link, _ := amfInstance.EstimateLink(u)
+		fmt.Printf("\n[%s] at (%.0f, %.0f): RSRP %.1f dBm, SINR %.1f dB, CQI %d, MCS %d → %.1f Mbps\n",
+			u.IMSI, u.X, u.Y, link.RSRPDBm, link.SINRDB, link.CQI, link.MCS, link.ThroughputMbps)
+		if session, err := smfInstance.EstablishSession(u, smf.VideoStreaming); err != nil {
+			fmt.Println("❌ Expected error:", err)
+		} else {
+			fmt.Printf("✓ Session %d established: VideoStreaming (50 Mbps)\n", session.SessionID)
+		}
+	}
+
+	fmt.Println("\n=== Testing RRC States: RRC_IDLE vs RRC_INACTIVE ===")
+
+	// The centre UE sends a burst, goes quiet, then gets a push notification. Same pattern
+	// twice: once releasing to RRC_IDLE, once to RRC_INACTIVE with both gNodeBs in one RNA.
+	now := net.Clock.Now()
+	for _, useInactive := range []bool{false, true} {
+		for _, g := range gNodeBs {
+			g.RRC = ran.RRCConfig{InactivityTimer: ran.DefaultInactivityTimer, UseInactive: useInactive, RNA: []int{gnb1.ID, gnb2.ID}}
+		}
+		before := amfInstance.SignallingTotals()
+
+		amfInstance.UplinkData(centreUE, now)
+		net.Scheduler.RunFor(ran.DefaultInactivityTimer + time.Second) // the demo's clock runs as fast as possible
+		now = net.Clock.Now()
+		for _, u := range allUEs {
+			if u != centreUE && u.State == ue.Connected {
+				amfInstance.UplinkData(u, now) // everyone else keeps talking
+			}
+		}
+		amfInstance.TickRRC(now)
+		fmt.Printf("\n[%s] after %v of silence: %s / %s\n", centreUE.IMSI, ran.DefaultInactivityTimer,
+			centreUE.State, amfInstance.RegisteredUEs[centreUE.IMSI].CMState)
+
+		if err := amfInstance.DownlinkData(centreUE, now); err != nil {
+			fmt.Println("❌ Paging failed:", err)
+			return false
+		}
+		after := amfInstance.SignallingTotals()
+		fmt.Printf("✓ Paged and back to %s, signalling for the cycle: %d RRC, %d NGAP, %d XnAP, %d paging\n",
+			centreUE.State, after.RRC-before.RRC, after.NGAP-before.NGAP, after.XnAP-before.XnAP, after.Paging-before.Paging)
+	}
+
+	fmt.Println("\n=== Testing Site Outage: gNodeB-1 Fails and Recovers ===")
+
+	outage, err := amfInstance.FailGNodeB(gnb1.ID)
+	if err != nil {
+		fmt.Println("❌ Outage failed:", err)
+		return false
+	}
+	fmt.Printf("\n✗ gNodeB-%d down: %d UEs affected, %d re-established (recovery %v), %d dropped, %d sessions released\n",
+		outage.GNodeBID, outage.AffectedUEs, len(outage.Reestablished), outage.RecoveryTime, len(outage.Dropped), len(outage.DroppedSessions))
+
+	restore, err := amfInstance.RestoreGNodeB(gnb1.ID)
+	if err != nil {
+		fmt.Println("❌ Restore failed:", err)
+		return false
+	}
+	fmt.Printf("✓ gNodeB-%d back: %d UEs re-attached through RACH, last one after %v, %d still stranded\n",
+		restore.GNodeBID, len(restore.Reattached), restore.RecoveryTime, len(restore.Failed))
+
+	fmt.Println("\n=== Testing Random Access: Mass Attach ===")
+
+	// Everyone in a stadium (or a recovering site) tries to attach in the same instant
+	for _, crowd := range []int{50, 500} {
+		var requests []ran.RACHRequest
+		for i := 0; i < crowd; i++ {
+			requests = append(requests, ran.RACHRequest{IMSI: fmt.Sprintf("20893%010d", i)})
+		}
+		report, err := ran.SimulateRACH(requests, amfInstance.RACH) // draws from the seeded RACH stream
+		if err != nil {
+			fmt.Println("❌ Random access failed:", err)
+			return false
+		}
+		fmt.Printf("\n%d UEs at once: %.0f%% attached, %d collisions over %d PRACH occasions\n",
+			crowd, report.SuccessRate()*100, report.Collisions, report.Occasions)
+		fmt.Printf("  attach latency p50 %v, p95 %v, p99 %v\n",
+			report.LatencyPercentile(50), report.LatencyPercentile(95), report.LatencyPercentile(99))
+	}
+
 	// Final Summary
 	fmt.Println("\n=== Final Network Status ===")
 	fmt.Printf("\nAMF Registered UEs: %d\n", len(amfInstance.RegisteredUEs))
-	for imsi, regUE := range amfInstance.RegisteredUEs {
-		fmt.Printf("  - IMSI: %s, gNodeB: %d\n", imsi, regUE.GNodeBID)
+	imsis := make([]string, 0, len(amfInstance.RegisteredUEs))
+	for imsi := range amfInstance.RegisteredUEs {
+		imsis = append(imsis, imsi)
+	}
+	sort.Strings(imsis)
+	for _, imsi := range imsis {
+		fmt.Printf("  - IMSI: %s, gNodeB: %d\n", imsi, amfInstance.RegisteredUEs[imsi].GNodeBID)
 	}
 
 	fmt.Printf("\ngNodeB-1 Connected UEs: %d\n", len(gnb1.ConnectedUEs))
 	fmt.Printf("gNodeB-2 Connected UEs: %d\n", len(gnb2.ConnectedUEs))
 
+	fmt.Printf("\nHandovers: %d\n", len(amfInstance.Handovers))
+	for _, ho := range amfInstance.Handovers {
+		fmt.Printf("  - IMSI: %s, %s: gNB-%d/cell-%d → gNB-%d/cell-%d\n",
+			ho.IMSI, ho.Type, ho.FromGNodeBID, ho.FromCellID, ho.ToGNodeBID, ho.ToCellID)
+	}
+
 	fmt.Printf("\nSMF Active Sessions: %d\n", len(smfInstance.Sessions))
-	for id, session := range smfInstance.Sessions {
+	ids := make([]int, 0, len(smfInstance.Sessions))
+	for id := range smfInstance.Sessions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		session := smfInstance.Sessions[id]
 		fmt.Printf("  - Session %d: UE %s, Type: %s, State: %s\n",
 			id, session.UE.IMSI, session.SessionType, session.State)
 	}
-
-	// Start API server
-	handler := api.NewHandler(amfInstance, smfInstance, udmInstance, gNodeBs, allUEs)
-	handler.RegisterRoutes()
-
-	fmt.Println("\n=== Starting API Server ===")
-	fmt.Println("API running on http://localhost:8080")
-	fmt.Println("Try: curl http://localhost:8080/api/ues")
-	http.ListenAndServe(":8080", nil)
+	return true
 }
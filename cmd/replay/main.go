@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+const usage = `Usage: replay [-at T] [-events] [-imsi IMSI] [-json] <journal.jsonl>
+
+Rebuilds the network state from a journal written by netsim5g -journal and shows it
+as it was at simulated time T: UEs, sessions and failed gNodeBs.
+
+T is a duration since the start of the simulation (30s, 1m30s) or an RFC3339 time;
+without -at the state after the last event is shown.
+`
+
+func main() {
+	at := flag.String("at", "", "simulated time to rebuild the state at (default: end of the journal)")
+	listEvents := flag.Bool("events", false, "also list the events up to that time, with their causes")
+	imsi := flag.String("imsi", "", "only show this UE")
+	asJSON := flag.Bool("json", false, "print the state as JSON")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *at, *imsi, *listEvents, *asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, at, imsi string, listEvents, asJSON bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+	events, err := journal.Read(f)
+	if err != nil {
+		return err
+	}
+
+	until, err := parseAt(at)
+	if err != nil {
+		return err
+	}
+	state := journal.Replay(events, until)
+	if imsi != "" {
+		ue, exists := state.UEs[imsi]
+		state.UEs = map[string]journal.UEState{}
+		if exists {
+			state.UEs[imsi] = ue
+		}
+		for id, session := range state.Sessions {
+			if session.IMSI != imsi {
+				delete(state.Sessions, id)
+			}
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(state)
+	}
+	if listEvents {
+		printEvents(os.Stdout, events, state.LastSeq, imsi)
+	}
+	printState(os.Stdout, state)
+	return nil
+}
+
+// parseAt reads a time given as a duration since sim.Epoch or as RFC3339
+func parseAt(at string) (time.Time, error) {
+	if at == "" {
+		return time.Unix(1<<62, 0), nil // end of the journal
+	}
+	if d, err := time.ParseDuration(at); err == nil {
+		return sim.Epoch.Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-at %q is neither a duration nor an RFC3339 time", at)
+	}
+	return t, nil
+}
+
+// printEvents lists events up to and including lastSeq, those of imsi only if set
+func printEvents(w io.Writer, events []journal.Event, lastSeq uint64, imsi string) {
+	fmt.Fprintln(w, "Events:")
+	for _, e := range events {
+		if e.Seq > lastSeq {
+			break
+		}
+		if imsi != "" && e.IMSI != imsi {
+			continue
+		}
+		fmt.Fprintln(w, "  "+describe(e))
+	}
+	fmt.Fprintln(w)
+}
+
+func describe(e journal.Event) string {
+	parts := []string{fmt.Sprintf("t=%-8v #%-4d %s", e.Time.Sub(sim.Epoch), e.Seq, e.Kind)}
+	if e.IMSI != "" {
+		parts = append(parts, e.IMSI)
+	}
+	if e.Session > 0 {
+		parts = append(parts, fmt.Sprintf("session %d (%s)", e.Session, e.SessionType))
+	} else if e.SessionType != "" {
+		parts = append(parts, e.SessionType)
+	}
+	switch {
+	case e.From != nil && e.To != nil:
+		parts = append(parts, fmt.Sprintf("gNodeB-%d/%d → gNodeB-%d/%d", e.From.GNodeB, e.From.Cell, e.To.GNodeB, e.To.Cell))
+	case e.To != nil:
+		parts = append(parts, fmt.Sprintf("on gNodeB-%d/%d", e.To.GNodeB, e.To.Cell))
+	case e.GNodeB > 0:
+		parts = append(parts, fmt.Sprintf("gNodeB-%d", e.GNodeB))
+	}
+	if e.Detail != "" {
+		parts = append(parts, "("+e.Detail+")")
+	}
+	if e.Error != "" {
+		parts = append(parts, "error: "+e.Error)
+	}
+	if e.Cause > 0 {
+		parts = append(parts, fmt.Sprintf("← #%d", e.Cause))
+	}
+	return strings.Join(parts, " ")
+}
+
+func printState(w io.Writer, s *journal.State) {
+	if s.LastSeq == 0 {
+		fmt.Fprintln(w, "No events at that time")
+		return
+	}
+	fmt.Fprintf(w, "State at t=%v (%s), after event #%d\n", s.Time.Sub(sim.Epoch), s.Time.Format(time.RFC3339Nano), s.LastSeq)
+
+	down := "none"
+	if len(s.Down) > 0 {
+		down = strings.Trim(fmt.Sprint(s.Down), "[]")
+	}
+	fmt.Fprintf(w, "gNodeBs down: %s\n", down)
+
+	fmt.Fprintf(w, "UEs (%d):\n", len(s.UEs))
+	for _, imsi := range s.IMSIs() {
+		u := s.UEs[imsi]
+		where := "-"
+		if u.GNodeB >= 0 {
+			where = fmt.Sprintf("gNodeB-%d/%d", u.GNodeB, u.Cell)
+		}
+		registration := "not registered"
+		if u.Registered {
+			registration = fmt.Sprintf("%s via gNodeB-%d", u.CM, u.Anchor)
+		}
+		fmt.Fprintf(w, "  %s  %-12s %-12s (%.0f, %.0f)  %s\n", imsi, u.State, where, u.X, u.Y, registration)
+	}
+
+	sessions := s.SessionList()
+	fmt.Fprintf(w, "Sessions (%d):\n", len(sessions))
+	for _, session := range sessions {
+		fmt.Fprintf(w, "  #%d %-14s %s\n", session.ID, session.Type, session.IMSI)
+	}
+	fmt.Fprintf(w, "Handovers: %d, failed procedures: %d\n", s.Handovers, s.Failures)
+}
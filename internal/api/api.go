@@ -1,33 +1,51 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/rizpur/NetSim5G/internal/core/amf"
 	"github.com/rizpur/NetSim5G/internal/core/smf"
 	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/coverage"
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/radio"
 	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/traffic"
 	"github.com/rizpur/NetSim5G/internal/ue"
 )
 
 // Handler holds references to all network components
 type Handler struct {
-	AMF     *amf.AMF
-	SMF     *smf.SMF
-	UDM     *udm.UDM
-	GNodeBs map[int]*ran.GNodeB
-	AllUEs  map[string]*ue.UE // All UEs that exist (connected or not)
+	AMF       *amf.AMF
+	SMF       *smf.SMF
+	UDM       *udm.UDM
+	GNodeBs   map[int]*ran.GNodeB
+	AllUEs    map[string]*ue.UE // All UEs that exist (connected or not)
+	Scheduler *sim.Scheduler    // runs delayed requests on the simulation clock
+	RNG       *sim.RNG          // its seed is sent with every response
+	Network   *network.Network  // its lock keeps requests and simulation events apart
 }
 
-// NewHandler creates a new API handler
-func NewHandler(amf *amf.AMF, smf *smf.SMF, udm *udm.UDM, gnbs map[int]*ran.GNodeB, allUEs map[string]*ue.UE) *Handler {
+// NewHandler creates a new API handler for a network
+func NewHandler(net *network.Network) *Handler {
 	return &Handler{
-		AMF:     amf,
-		SMF:     smf,
-		UDM:     udm,
-		GNodeBs: gnbs,
-		AllUEs:  allUEs,
+		AMF:       net.AMF,
+		SMF:       net.SMF,
+		UDM:       net.UDM,
+		GNodeBs:   net.GNodeBs,
+		AllUEs:    net.UEs,
+		Scheduler: net.Scheduler,
+		RNG:       net.RNG,
+		Network:   net,
 	}
 }
 
@@ -35,8 +53,11 @@ func NewHandler(amf *amf.AMF, smf *smf.SMF, udm *udm.UDM, gnbs map[int]*ran.GNod
 func (h *Handler) enableCORS(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Sim-Seed, X-Sim-Time, X-Sim-Elapsed, X-Journal-Seq")
+		w.Header().Set("X-Sim-Seed", strconv.FormatInt(h.RNG.Seed(), 10)) // replay with -seed
+		h.stampTime(w)
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -48,42 +69,125 @@ func (h *Handler) enableCORS(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// stampTime puts the current simulated time in the response headers, as a timestamp
+// and as seconds since the start of the simulation
+func (h *Handler) stampTime(w http.ResponseWriter) {
+	w.Header().Set("X-Sim-Time", h.Scheduler.Clock.Now().Format(time.RFC3339Nano))
+	w.Header().Set("X-Sim-Elapsed", strconv.FormatFloat(h.Scheduler.Clock.Elapsed().Seconds(), 'f', -1, 64))
+}
+
+// locked runs the handler with exclusive access to the network
+func (h *Handler) locked(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.Network.Update(func() {
+			h.stampTime(w) // the time of the state the handler sees
+			next(w, r)
+		})
+	}
+}
+
+// viewing runs a read-only handler on a consistent view of the network; several can run at once
+func (h *Handler) viewing(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.Network.View(func() {
+			h.stampTime(w)
+			next(w, r)
+		})
+	}
+}
+
 // RegisterRoutes sets up all HTTP endpoints
 func (h *Handler) RegisterRoutes() {
-	http.HandleFunc("/api/gnodebs", h.enableCORS(h.getGNodeBs))
-	http.HandleFunc("/api/ues", h.enableCORS(h.getUEs))
-	http.HandleFunc("/api/sessions", h.enableCORS(h.getSessions))
+	http.HandleFunc("/api/gnodebs", h.enableCORS(h.gnodebs)) // GET lists, POST creates
+	http.HandleFunc("/api/gnodebs/outage", h.enableCORS(h.locked(h.postOutage)))
+	http.HandleFunc("/api/gnodebs/{id}", h.enableCORS(h.locked(h.gnodeb))) // PUT updates, DELETE removes
+	http.HandleFunc("/api/ues", h.enableCORS(h.ues))                       // GET lists, POST creates
+	http.HandleFunc("/api/ues/{imsi}", h.enableCORS(h.locked(h.deleteUE)))
+	http.HandleFunc("/api/ues/{imsi}/{action}", h.enableCORS(h.locked(h.postUEAction)))
+	http.HandleFunc("/api/sessions", h.enableCORS(h.sessions)) // GET lists, POST establishes
+	http.HandleFunc("/api/sessions/{id}", h.enableCORS(h.locked(h.deleteSession)))
+	http.HandleFunc("/api/subscribers", h.enableCORS(h.viewing(h.getSubscribers)))
+	http.HandleFunc("/api/events", h.enableCORS(h.getEvents)) // the journal has its own lock
+	http.HandleFunc("/api/handovers", h.enableCORS(h.viewing(h.getHandovers)))
+	http.HandleFunc("/api/traffic", h.enableCORS(h.viewing(h.getTraffic)))
+	http.HandleFunc("/api/geojson", h.enableCORS(h.viewing(h.getGeoJSON)))
+	http.HandleFunc("/api/coverage", h.enableCORS(h.viewing(h.getCoverage)))
+	http.HandleFunc("/api/sim", h.enableCORS(h.simControl)) // takes the network lock itself when stepping
+	http.HandleFunc("/api/snapshot", h.enableCORS(h.snapshot))
+	http.HandleFunc("/metrics", h.viewing(h.getMetrics))
+}
+
+// CellResponse is a cell as the API shows it
+type CellResponse struct {
+	ID           int     `json:"id"`
+	PCI          int     `json:"pci"`
+	Azimuth      float64 `json:"azimuth"`
+	Beamwidth    float64 `json:"beamwidth"`
+	Tilt         float64 `json:"tilt"`
+	FrequencyGHz float64 `json:"frequencyGHz"`
+	BandwidthMHz float64 `json:"bandwidthMHz"`
+	ConnectedUEs int     `json:"connectedUEs"`
+	MaxCap       int     `json:"maxCap"`
+}
+
+// GNodeBResponse is a gNodeB as the API shows it
+type GNodeBResponse struct {
+	ID           int                 `json:"id"`
+	X            float64             `json:"x"`
+	Y            float64             `json:"y"`
+	Position     *geo.LatLon         `json:"position,omitempty"` // lat/lon, for a geographic topology
+	Range        float64             `json:"range"`
+	Down         bool                `json:"down"`
+	ConnectedUEs int                 `json:"connectedUEs"`
+	InactiveUEs  int                 `json:"inactiveUEs"` // RRC_INACTIVE UEs anchored here
+	MaxCap       int                 `json:"maxCap"`
+	Cells        []CellResponse      `json:"cells"`
+	Signalling   ran.SignallingStats `json:"signalling"`
+}
+
+// describeGNodeB builds the API view of a gNodeB; callers hold the network lock
+func (h *Handler) describeGNodeB(gnb *ran.GNodeB) GNodeBResponse {
+	var cells []CellResponse
+	for _, c := range gnb.Cells {
+		cells = append(cells, CellResponse{
+			ID:           c.ID,
+			PCI:          c.PCI,
+			Azimuth:      c.Antenna.AzimuthDeg,
+			Beamwidth:    c.Antenna.BeamwidthDeg,
+			Tilt:         c.Antenna.TiltDeg,
+			FrequencyGHz: c.Carrier.FrequencyGHz,
+			BandwidthMHz: c.Carrier.BandwidthMHz,
+			ConnectedUEs: len(c.ConnectedUEs),
+			MaxCap:       c.MaxCap,
+		})
+	}
+	return GNodeBResponse{
+		ID:           gnb.ID,
+		X:            gnb.X,
+		Y:            gnb.Y,
+		Position:     h.latLon(gnb.X, gnb.Y),
+		Range:        gnb.Range,
+		Down:         gnb.Down,
+		ConnectedUEs: len(gnb.ConnectedUEs),
+		InactiveUEs:  len(gnb.Inactive),
+		MaxCap:       gnb.MaxCap(),
+		Cells:        cells,
+		Signalling:   gnb.Signalling,
+	}
 }
 
 // GET /api/gnodebs - returns all gNodeBs with their state
 func (h *Handler) getGNodeBs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
-	// Create response slice
-	type GNodeBResponse struct {
-		ID           int     `json:"id"`
-		X            float64 `json:"x"`
-		Y            float64 `json:"y"`
-		Range        float64 `json:"range"`
-		ConnectedUEs int     `json:"connectedUEs"`
-		MaxCap       int     `json:"maxCap"`
-	}
-
 	var response []GNodeBResponse
 
-	// Loop through all gNodeBs and build response
-	for _, gnb := range h.GNodeBs {
-		response = append(response, GNodeBResponse{
-			ID:           gnb.ID,
-			X:            gnb.X,
-			Y:            gnb.Y,
-			Range:        gnb.Range,
-			ConnectedUEs: len(gnb.ConnectedUEs),
-			MaxCap:       gnb.MaxCap,
-		})
+	// Loop through all gNodeBs and build response, in ID order
+	for _, gnb := range ran.SortedByID(h.GNodeBs) {
+		response = append(response, h.describeGNodeB(gnb))
 	}
 
 	// Send JSON response
@@ -91,64 +195,394 @@ func (h *Handler) getGNodeBs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// latLon returns where x,y lies on the globe, or nil if the topology is not geographic
+func (h *Handler) latLon(x, y float64) *geo.LatLon {
+	if h.Network == nil || h.Network.Geo == nil {
+		return nil
+	}
+	pos := h.Network.Geo.FromXY(x, y)
+	return &pos
+}
+
+// UEResponse is a UE as the API shows it
+type UEResponse struct {
+	IMSI            string             `json:"imsi"`
+	X               float64            `json:"x"`
+	Y               float64            `json:"y"`
+	Position        *geo.LatLon        `json:"position,omitempty"` // lat/lon, for a geographic topology
+	GNodeBConnected int                `json:"gNodeBConnected"`
+	CellConnected   int                `json:"cellConnected"`
+	State           ue.UEState         `json:"state"`
+	Speed           float64            `json:"speed"`              // m/s
+	Heading         float64            `json:"heading"`            // radians, 0 = +x
+	Mobility        string             `json:"mobility,omitempty"` // model name, if the UE moves on its own
+	CMState         string             `json:"cmState,omitempty"`  // only for registered UEs
+	Link            *radio.LinkMetrics `json:"link,omitempty"`     // only for registered UEs
+	Building        string             `json:"building,omitempty"` // the building the UE is in
+	LOS             *bool              `json:"los,omitempty"`      // line of sight to the serving cell, when there are buildings
+}
+
+// describeUE builds the API view of a UE; callers hold the network lock
+func (h *Handler) describeUE(u *ue.UE) UEResponse {
+	resp := UEResponse{
+		IMSI:            u.IMSI,
+		X:               u.X,
+		Y:               u.Y,
+		Position:        h.latLon(u.X, u.Y),
+		GNodeBConnected: u.GNodeBConnected,
+		CellConnected:   u.CellConnected,
+		State:           u.State,
+		Speed:           u.Speed,
+		Heading:         u.Heading,
+	}
+	if u.Mobility != nil {
+		resp.Mobility = u.Mobility.Name()
+	}
+	if regUE, exists := h.AMF.RegisteredUEs[u.IMSI]; exists {
+		resp.CMState = regUE.CMState.String()
+	}
+	if link, err := h.AMF.EstimateLink(u); err == nil {
+		resp.Link = &link
+	}
+	if h.Network != nil {
+		if b := h.Network.Indoors(u); b != nil {
+			resp.Building = b.Name
+		}
+		if path, ok := h.Network.ServingPath(u); ok {
+			resp.LOS = &path.LOS
+		}
+	}
+	return resp
+}
+
 // GET /api/ues - returns all UEs with their state
 func (h *Handler) getUEs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
-	type UEResponse struct {
-		IMSI            string     `json:"imsi"`
-		X               float64    `json:"x"`
-		Y               float64    `json:"y"`
-		GNodeBConnected int        `json:"gNodeBConnected"`
-		State           ue.UEState `json:"state"`
-	}
-
 	var response []UEResponse
 
 	// Loop through ALL UEs (connected or not)
 	for _, ue := range h.AllUEs {
-		response = append(response, UEResponse{
-			IMSI:            ue.IMSI,
-			X:               ue.X,
-			Y:               ue.Y,
-			GNodeBConnected: ue.GNodeBConnected,
-			State:           ue.State,
-		})
+		response = append(response, h.describeUE(ue))
 	}
+	sort.Slice(response, func(i, j int) bool { return response[i].IMSI < response[j].IMSI })
 
 	// Send JSON response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// SessionResponse is a PDU session as the API shows it
+type SessionResponse struct {
+	SessionID   int    `json:"sessionID"`
+	UEIMSI      string `json:"ueIMSI"`
+	SessionType string `json:"sessionType"`
+	State       string `json:"state"`
+	MaxBitRate  int    `json:"maxBitRate"` // Mbps
+}
+
 // GET /api/sessions - returns all active PDU sessions
 func (h *Handler) getSessions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
-	type SessionResponse struct {
-		SessionID   int    `json:"sessionID"`
-		UEIMSI      string `json:"ueIMSI"`
-		SessionType string `json:"sessionType"`
-		State       string `json:"state"`
-	}
-
 	var response []SessionResponse
 
 	for _, session := range h.SMF.Sessions {
 		response = append(response, SessionResponse{
 			SessionID:   session.SessionID,
 			UEIMSI:      session.UE.IMSI,
-			SessionType: string(session.SessionType),
-			State:       string(session.State),
+			SessionType: session.SessionType.String(),
+			State:       session.State.String(),
+			MaxBitRate:  session.QoS.MaxBitRate,
 		})
 	}
+	sort.Slice(response, func(i, j int) bool { return response[i].SessionID < response[j].SessionID })
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// GET /api/traffic - returns what every session's traffic got: throughput, delay,
+// jitter, loss and video stalls. ?imsi= limits it to one UE's active sessions.
+func (h *Handler) getTraffic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	engine := h.Network.Traffic
+	if engine == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "traffic is not simulated, start the server with -traffic")
+		return
+	}
+
+	now := h.Scheduler.Clock.Now()
+	response := engine.Report(now)
+	if imsi := r.URL.Query().Get("imsi"); imsi != "" {
+		response = engine.SessionsOf(imsi, now)
+	}
+	if response == nil {
+		response = []traffic.Stats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /api/geojson - returns gNodeBs, cell coverage, UEs and serving links as a
+// GeoJSON FeatureCollection, for GIS tools
+func (h *Handler) getGeoJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	response, err := h.Network.GeoJSON()
+	if err != nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /api/coverage - returns a coverage map: the best server, RSRP, SINR and throughput
+// over a grid. Query: format=csv|geojson|png (default csv), step=<m> (default about
+// 100x100 points), area=minX,minY,maxX,maxY (default every gNodeB's range), and for
+// png metric=rsrp|sinr|throughput|server and scale=<pixels per point>
+func (h *Handler) getCoverage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	query := r.URL.Query()
+
+	sites := ran.SortedByID(h.GNodeBs)
+	area := coverage.Around(sites)
+	if s := query.Get("area"); s != "" {
+		var err error
+		if area, err = coverage.ParseArea(s); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+	}
+	var step float64
+	var scale int
+	for key, dst := range map[string]interface{}{"step": &step, "scale": &scale} {
+		if s := query.Get(key); s != "" {
+			if _, err := fmt.Sscan(s, dst); err != nil {
+				writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid %s %q", key, s))
+				return
+			}
+		}
+	}
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = coverage.MetricRSRP
+	}
+
+	grid, err := coverage.Compute(h.GNodeBs, area, step, h.AMF.LinkAdaptation)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	var body bytes.Buffer
+	switch format := query.Get("format"); format {
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		err = grid.WriteCSV(&body, h.Network.Geo)
+	case "geojson":
+		if h.Network.Geo == nil {
+			writeError(w, http.StatusNotFound, CodeNotFound, "the topology has no geographic origin; give sites as lat/lon or set origin")
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		err = json.NewEncoder(&body).Encode(grid.GeoJSON(h.Network.Geo))
+	case "png":
+		w.Header().Set("Content-Type", "image/png")
+		err = grid.WritePNG(&body, metric, scale)
+	default:
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("unknown format %q, use csv, geojson or png", format))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	body.WriteTo(w)
+}
+
+// GET /api/handovers - returns the handover history, oldest first
+func (h *Handler) getHandovers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+
+	type HandoverResponse struct {
+		IMSI       string    `json:"imsi"`
+		Type       string    `json:"type"` // "intra-gNodeB" or "inter-gNodeB"
+		FromGNodeB int       `json:"fromGNodeB"`
+		FromCell   int       `json:"fromCell"`
+		ToGNodeB   int       `json:"toGNodeB"`
+		ToCell     int       `json:"toCell"`
+		Timestamp  time.Time `json:"timestamp"`
+	}
+
+	var response []HandoverResponse
+
+	for _, ho := range h.AMF.Handovers {
+		response = append(response, HandoverResponse{
+			IMSI:       ho.IMSI,
+			Type:       ho.Type.String(),
+			FromGNodeB: ho.FromGNodeBID,
+			FromCell:   ho.FromCellID,
+			ToGNodeB:   ho.ToGNodeBID,
+			ToCell:     ho.ToCellID,
+			Timestamp:  ho.Timestamp,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /api/gnodebs/outage - fails or restores a gNodeB, now or after a delay in simulated time.
+// Body: {"gnodebId": 1, "action": "fail"|"restore", "delaySeconds": 0, "durationSeconds": 0}
+// A fail with durationSeconds > 0 restores the gNodeB automatically afterwards.
+func (h *Handler) postOutage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w)
+		return
+	}
+
+	var req struct {
+		GNodeBID        int     `json:"gnodebId"`
+		Action          string  `json:"action"`
+		DelaySeconds    float64 `json:"delaySeconds"`
+		DurationSeconds float64 `json:"durationSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if req.Action != "fail" && req.Action != "restore" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, `action must be "fail" or "restore"`)
+		return
+	}
+	if _, exists := h.GNodeBs[req.GNodeBID]; !exists {
+		writeError(w, http.StatusNotFound, CodeNotFound, "gNodeB not found")
+		return
+	}
+
+	seconds := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+	// The client got its 202 long ago, so a scheduled action that fails (say the gNodeB
+	// was removed meanwhile) goes to the journal
+	scheduled := func(action string) func(time.Time) {
+		return func(time.Time) {
+			if _, err := h.applyOutage(req.GNodeBID, action); err != nil {
+				h.Network.Journal.Record(journal.Event{Kind: journal.OutageFailed, GNodeB: req.GNodeBID, Detail: action, Error: err.Error()})
+			}
+		}
+	}
+
+	// Scheduled: run later in simulated time; the scheduler holds the network lock for us
+	if req.DelaySeconds > 0 {
+		h.Scheduler.After(seconds(req.DelaySeconds), sim.PriorityNormal, req.Action+" gNodeB", scheduled(req.Action))
+	}
+	if req.Action == "fail" && req.DurationSeconds > 0 {
+		h.Scheduler.After(seconds(req.DelaySeconds+req.DurationSeconds), sim.PriorityNormal, "restore gNodeB", scheduled("restore"))
+	}
+	if req.DelaySeconds > 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	response, err := h.applyOutage(req.GNodeBID, req.Action)
+	if err != nil {
+		writeCoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// applyOutage fails or restores a gNodeB and returns the JSON report; callers hold the network lock
+func (h *Handler) applyOutage(id int, action string) (interface{}, error) {
+	type OutageResponse struct {
+		GNodeBID        int      `json:"gnodebId"`
+		AffectedUEs     int      `json:"affectedUEs"`
+		Reestablished   []string `json:"reestablished"`
+		Dropped         []string `json:"dropped"`
+		InactiveLost    int      `json:"inactiveLost"`
+		DroppedSessions []int    `json:"droppedSessions"`
+		RecoveryTimeMs  float64  `json:"recoveryTimeMs"`
+	}
+
+	type FailedUE struct {
+		IMSI  string `json:"imsi"`
+		Error string `json:"error"`
+	}
+
+	type RestoreResponse struct {
+		GNodeBID       int        `json:"gnodebId"`
+		Reattached     []string   `json:"reattached"`
+		Failed         []FailedUE `json:"failed"` // still stranded
+		RACHCollisions int        `json:"rachCollisions"`
+		AttachP50Ms    float64    `json:"attachP50Ms"`
+		AttachP95Ms    float64    `json:"attachP95Ms"`
+		RecoveryTimeMs float64    `json:"recoveryTimeMs"`
+	}
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+	if action == "fail" {
+		report, err := h.AMF.FailGNodeB(id)
+		if err != nil {
+			return nil, err
+		}
+		return OutageResponse{
+			GNodeBID:        report.GNodeBID,
+			AffectedUEs:     report.AffectedUEs,
+			Reestablished:   report.Reestablished,
+			Dropped:         report.Dropped,
+			InactiveLost:    report.InactiveLost,
+			DroppedSessions: report.DroppedSessions,
+			RecoveryTimeMs:  ms(report.RecoveryTime),
+		}, nil
+	}
+
+	report, err := h.AMF.RestoreGNodeB(id)
+	if err != nil {
+		return nil, err
+	}
+	failed := []FailedUE{}
+	for _, f := range report.Failed {
+		failed = append(failed, FailedUE{IMSI: f.IMSI, Error: f.Err.Error()})
+	}
+	return RestoreResponse{
+		GNodeBID:       report.GNodeBID,
+		Reattached:     report.Reattached,
+		Failed:         failed,
+		RACHCollisions: report.RACH.Collisions,
+		AttachP50Ms:    ms(report.RACH.LatencyPercentile(50)),
+		AttachP95Ms:    ms(report.RACH.LatencyPercentile(95)),
+		RecoveryTimeMs: ms(report.RecoveryTime),
+	}, nil
+}
+
+// getMetrics serves the KPIs for Prometheus to scrape
+func (h *Handler) getMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	h.Network.WriteMetrics(w)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// TestConcurrentRequests runs mutating requests, readers and the scheduler together.
+// Run it with -race: the network lock must keep them apart.
+func TestConcurrentRequests(t *testing.T) {
+	// Requests must really run in parallel for the race detector to see them overlap
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// Step 1: A network with registered UEs and its clock running, as cmd/netsim5g serves it
+	topology, err := config.Load("../configs/topology.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := network.New(topology, "../configs/subscribers.json", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imsis := []string{"123456789012345", "987654321098765", "208930000000001"}
+	for _, imsi := range imsis {
+		u, err := net.AddUE(imsi, 120, 120)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := net.AMF.Attach(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	net.Scheduler.Every(time.Second, sim.PriorityHigh, "rrc timers", func(now time.Time) {
+		net.AMF.TickRRC(now)
+	})
+	net.StartMobility(network.DefaultMobilityTick)
+	net.Scheduler.SetSpeed(100)
+	stop := make(chan struct{})
+	served := make(chan struct{})
+	go func() {
+		net.Scheduler.Serve(stop)
+		close(served)
+	}()
+	defer func() {
+		close(stop)
+		<-served
+	}()
+
+	server := httptest.NewServer(routes(net))
+	defer server.Close()
+
+	// Step 2: Mutators and readers, each on its own goroutine with its own connection: a
+	// shared client would order the requests through its connection pool and hide races
+	const rounds = 20
+	mutators := []func(i int) request{
+		func(i int) request {
+			return request{"POST", "/api/ues/" + imsis[i%len(imsis)] + "/move", fmt.Sprintf(`{"x": %d, "y": %d}`, 100+i*5, 100+i*5)}
+		},
+		func(i int) request {
+			if i%2 == 0 {
+				return request{"POST", "/api/ues/" + imsis[i%len(imsis)] + "/detach", ""}
+			}
+			return request{"POST", "/api/ues/" + imsis[i%len(imsis)] + "/attach", ""}
+		},
+		func(i int) request {
+			if i%2 == 0 {
+				return request{"POST", "/api/sessions", fmt.Sprintf(`{"imsi": %q, "sessionType": "IoT"}`, imsis[i%len(imsis)])}
+			}
+			return request{"DELETE", fmt.Sprintf("/api/sessions/%d", i), ""}
+		},
+		func(i int) request {
+			imsi := fmt.Sprintf("99999%010d", i)
+			if i%2 == 0 {
+				return request{"POST", "/api/ues", fmt.Sprintf(`{"imsi": %q, "x": 150, "y": 150}`, imsi)}
+			}
+			return request{"DELETE", fmt.Sprintf("/api/ues/99999%010d", i-1), ""}
+		},
+		func(i int) request {
+			if i%2 == 0 {
+				return request{"POST", "/api/gnodebs", fmt.Sprintf(`{"x": %d, "y": 400, "range": 100}`, 300+i*10)}
+			}
+			return request{"PUT", "/api/gnodebs/2", fmt.Sprintf(`{"range": %d}`, 50+i)}
+		},
+		func(i int) request {
+			action := "fail"
+			if i%2 == 1 {
+				action = "restore"
+			}
+			return request{"POST", "/api/gnodebs/outage", fmt.Sprintf(`{"gnodebId": 1, "action": %q}`, action)}
+		},
+	}
+	readers := []string{"/api/gnodebs", "/api/ues", "/api/sessions", "/api/events", "/api/handovers", "/api/snapshot", "/metrics"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, (len(mutators)+len(readers))*rounds)
+	for _, mutate := range mutators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := &http.Client{Transport: &http.Transport{}}
+			for i := 0; i < rounds; i++ {
+				errs <- send(client, server.URL, mutate(i))
+			}
+		}()
+	}
+	for _, path := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := &http.Client{Transport: &http.Transport{}}
+			for i := 0; i < rounds; i++ {
+				errs <- send(client, server.URL, request{"GET", path, ""})
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Step 3: The RAN and the UEs still agree on who is connected where
+	net.View(func() {
+		for imsi, u := range net.UEs {
+			if u.State != ue.Connected {
+				continue
+			}
+			g, exists := net.GNodeBs[u.GNodeBConnected]
+			if !exists {
+				t.Errorf("UE %s is connected to gNodeB %d, which does not exist", imsi, u.GNodeBConnected)
+			} else if _, held := g.ConnectedUEs[imsi]; !held {
+				t.Errorf("UE %s is connected to gNodeB %d, which does not hold it", imsi, g.ID)
+			}
+		}
+	})
+}
+
+// handler serves every test, see routes
+var handler *Handler
+
+// routes serves net on the default mux. Routes go on it once and keep pointing at the
+// same handler, which each call points at a new network.
+func routes(net *network.Network) http.Handler {
+	if handler == nil {
+		handler = NewHandler(net)
+		handler.RegisterRoutes()
+	} else {
+		*handler = *NewHandler(net)
+	}
+	return http.DefaultServeMux
+}
+
+type request struct {
+	method, path, body string
+}
+
+// send makes one request; rejections are fine, server errors other than an unavailable
+// gNodeB are not
+func send(client *http.Client, base string, r request) error {
+	req, err := http.NewRequest(r.method, base+r.path, bytes.NewBufferString(r.body))
+	if err != nil {
+		return err
+	}
+	if r.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 500 && resp.StatusCode != http.StatusServiceUnavailable {
+		return fmt.Errorf("%s %s: %s: %s", r.method, r.path, resp.Status, data)
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// UE actions of POST /api/ues/{imsi}/{action}
+const (
+	UEMove       = "move"       // body: {"x", "y"} or {"lat", "lon"}
+	UEConnect    = "connect"    // RRC only, body: {"gnodebId"}
+	UERegister   = "register"   // NAS registration through the gNodeB the UE is connected to
+	UEAttach     = "attach"     // connect to the best cell in range and register
+	UEDetach     = "detach"     // deregister and release the radio connection
+	UEDisconnect = "disconnect" // release the radio connection; a registered UE goes idle
+)
+
+// position is a place given either way: x,y in metres or lat/lon
+type position struct {
+	X   *float64 `json:"x"`
+	Y   *float64 `json:"y"`
+	Lat *float64 `json:"lat"`
+	Lon *float64 `json:"lon"`
+}
+
+// xy resolves the position on the network's plane
+func (h *Handler) xy(p position) (x, y float64, err error) {
+	switch {
+	case p.X != nil && p.Y != nil && p.Lat == nil && p.Lon == nil:
+		return *p.X, *p.Y, nil
+	case p.Lat != nil && p.Lon != nil && p.X == nil && p.Y == nil:
+		pos := geo.LatLon{Lat: *p.Lat, Lon: *p.Lon}
+		if !pos.Valid() {
+			return 0, 0, fmt.Errorf("lat must be -90..90 and lon -180..180, got %s", pos)
+		}
+		return h.Network.ToXY(pos)
+	}
+	return 0, 0, fmt.Errorf("give x and y, or lat and lon")
+}
+
+// /api/ues - GET lists the UEs, POST creates one.
+// POST body: {"imsi": "...", "x": 0, "y": 0} or with "lat" and "lon" instead of x and y
+func (h *Handler) ues(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		h.viewing(h.getUEs)(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		methodNotAllowed(w)
+		return
+	}
+	h.locked(h.postUE)(w, r)
+}
+
+func (h *Handler) postUE(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IMSI string `json:"imsi"`
+		position
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if req.IMSI == "" || strings.Trim(req.IMSI, "0123456789") != "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "imsi must be digits")
+		return
+	}
+	x, y, err := h.xy(req.position)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	u, err := h.Network.AddUE(req.IMSI, x, y)
+	if err != nil {
+		writeCoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h.describeUE(u))
+}
+
+// DELETE /api/ues/{imsi} - removes a UE: it is deregistered, which releases its
+// sessions, and its radio connection is dropped
+func (h *Handler) deleteUE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		methodNotAllowed(w)
+		return
+	}
+	imsi := r.PathValue("imsi")
+	if _, exists := h.AllUEs[imsi]; !exists {
+		writeError(w, http.StatusNotFound, CodeNotFound, "UE not found")
+		return
+	}
+	if err := h.Network.RemoveUE(imsi); err != nil {
+		writeCoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/ues/{imsi}/{action} - moves, connects, registers, attaches, detaches or disconnects a UE
+// and returns it as it is afterwards (see the UE* actions for the bodies)
+func (h *Handler) postUEAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w)
+		return
+	}
+	u, exists := h.AllUEs[r.PathValue("imsi")]
+	if !exists {
+		writeError(w, http.StatusNotFound, CodeNotFound, "UE not found")
+		return
+	}
+	var req struct {
+		position
+		GNodeBID int `json:"gnodebId"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+	}
+
+	var err error
+	switch action := r.PathValue("action"); action {
+	case UEMove:
+		var x, y float64
+		if x, y, err = h.xy(req.position); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+		err = h.AMF.MoveUE(u, x, y)
+	case UEConnect:
+		g, exists := h.GNodeBs[req.GNodeBID]
+		if !exists {
+			writeError(w, http.StatusNotFound, CodeNotFound, "gNodeB not found")
+			return
+		}
+		err = g.ConnectUE(u)
+	case UERegister:
+		if u.State != ue.Connected {
+			err = fmt.Errorf("UE %s: %w, connect it first", u.IMSI, ran.ErrNotConnected)
+		} else {
+			err = h.AMF.RegisterUE(u.IMSI, u.GNodeBConnected)
+		}
+	case UEAttach:
+		err = h.AMF.Attach(u)
+	case UEDetach:
+		err = h.AMF.DeregisterUE(u)
+	case UEDisconnect:
+		err = h.AMF.ReleaseUE(u)
+	default:
+		writeError(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("unknown action %q, use %s, %s, %s, %s, %s or %s",
+			action, UEMove, UEConnect, UERegister, UEAttach, UEDetach, UEDisconnect))
+		return
+	}
+	if err != nil {
+		writeCoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.describeUE(u))
+}
+
+// /api/sessions - GET lists the PDU sessions, POST establishes one.
+// POST body: {"imsi": "...", "sessionType": "VoIP"|"VideoStreaming"|"WebBrowsing"|"IoT"}
+func (h *Handler) sessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		h.viewing(h.getSessions)(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		methodNotAllowed(w)
+		return
+	}
+	h.locked(h.postSession)(w, r)
+}
+
+func (h *Handler) postSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IMSI        string `json:"imsi"`
+		SessionType string `json:"sessionType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	sessionType, err := smf.ParseSessionType(req.SessionType)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	u, exists := h.AllUEs[req.IMSI]
+	if !exists {
+		writeError(w, http.StatusNotFound, CodeNotFound, "UE not found")
+		return
+	}
+
+	// A registered UE that is not connected does a service request first
+	if _, registered := h.AMF.RegisteredUEs[u.IMSI]; registered {
+		if err := h.AMF.UplinkData(u, h.Scheduler.Clock.Now()); err != nil {
+			writeCoreError(w, err)
+			return
+		}
+	}
+	session, err := h.SMF.EstablishSession(u, sessionType)
+	if err != nil {
+		writeCoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(SessionResponse{
+		SessionID:   session.SessionID,
+		UEIMSI:      u.IMSI,
+		SessionType: session.SessionType.String(),
+		State:       session.State.String(),
+		MaxBitRate:  session.QoS.MaxBitRate,
+	})
+}
+
+// DELETE /api/sessions/{id} - terminates a PDU session
+func (h *Handler) deleteSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		methodNotAllowed(w)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "session id must be a number")
+		return
+	}
+	if _, exists := h.SMF.Sessions[id]; !exists {
+		writeError(w, http.StatusNotFound, CodeNotFound, "session not found")
+		return
+	}
+	if err := h.SMF.TerminateSession(id); err != nil {
+		writeCoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/subscribers - returns the subscriber database, by IMSI
+func (h *Handler) getSubscribers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	response := h.UDM.State()
+	if response == nil {
+		response = []udm.Subscriber{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /api/events - returns the journal events kept in memory, oldest first.
+// ?since=<seq> returns only later events, ?limit=N only the last N of them;
+// ?imsi= and ?kind= (comma-separated) filter them. The X-Journal-Seq header is the
+// sequence number to poll from next.
+func (h *Handler) getEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	query := r.URL.Query()
+	var since uint64
+	limit := -1
+	if s := query.Get("since"); s != "" {
+		var err error
+		if since, err = strconv.ParseUint(s, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "since must be an event sequence number")
+			return
+		}
+	}
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "limit must be a number >= 0")
+			return
+		}
+	}
+	kinds := make(map[journal.Kind]bool)
+	if s := query.Get("kind"); s != "" {
+		for _, kind := range strings.Split(s, ",") {
+			kinds[journal.Kind(strings.TrimSpace(kind))] = true
+		}
+	}
+	imsi := query.Get("imsi")
+
+	// Read the latest Seq first: events recorded meanwhile are returned again next time
+	// rather than skipped
+	last := h.Network.Journal.Last()
+	response := []journal.Event{}
+	for _, e := range h.Network.Journal.Since(since) {
+		if (imsi == "" || e.IMSI == imsi) && (len(kinds) == 0 || kinds[e.Kind]) {
+			response = append(response, e)
+		}
+	}
+	if limit >= 0 && len(response) > limit {
+		response = response[len(response)-limit:]
+	}
+
+	w.Header().Set("X-Journal-Seq", strconv.FormatUint(last, 10)) // pass as since to get only newer events
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/ran"
+)
+
+// ErrorResponse is the body of every error: a message for people and a code for programs
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Error codes
+const (
+	CodeBadRequest           = "bad_request"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict" // the request does not fit the current state
+	CodeSubscriberNotFound   = "subscriber_not_found"
+	CodeSubscriptionInactive = "subscription_inactive"
+	CodeNotRegistered        = "not_registered"
+	CodeNotConnected         = "not_connected"
+	CodeNoCoverage           = "no_coverage"
+	CodeGNodeBDown           = "gnodeb_down"
+	CodeCellFull             = "cell_full"
+	CodeNotAllowed           = "not_allowed" // barred by an allow-list
+	CodeQuotaExceeded        = "quota_exceeded"
+	CodeLinkCapacity         = "link_capacity"
+	CodeUnreachable          = "unreachable"
+	CodeGNodeBInUse          = "gnodeb_in_use"
+	CodeInvalidSnapshot      = "invalid_snapshot"
+)
+
+// coreErrors maps what the core network functions return to a status and a code; the
+// first match wins
+var coreErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{udm.ErrSubscriberNotFound, http.StatusForbidden, CodeSubscriberNotFound},
+	{amf.ErrSubscriptionInactive, http.StatusForbidden, CodeSubscriptionInactive},
+	{ran.ErrNotAllowed, http.StatusForbidden, CodeNotAllowed},
+	{smf.ErrQuotaExceeded, http.StatusForbidden, CodeQuotaExceeded},
+	{smf.ErrSessionNotFound, http.StatusNotFound, CodeNotFound},
+	{amf.ErrGNodeBNotFound, http.StatusNotFound, CodeNotFound},
+	{ran.ErrDown, http.StatusServiceUnavailable, CodeGNodeBDown},
+	{ran.ErrCapacity, http.StatusServiceUnavailable, CodeCellFull},
+	{amf.ErrNotRegistered, http.StatusConflict, CodeNotRegistered},
+	{ran.ErrNotConnected, http.StatusConflict, CodeNotConnected},
+	{amf.ErrNoCoverage, http.StatusConflict, CodeNoCoverage},
+	{smf.ErrLinkCapacity, http.StatusConflict, CodeLinkCapacity},
+	{amf.ErrUnreachable, http.StatusConflict, CodeUnreachable},
+	{amf.ErrGNodeBInUse, http.StatusConflict, CodeGNodeBInUse},
+}
+
+// writeError sends an ErrorResponse
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Code: code})
+}
+
+// writeCoreError sends an error from a core procedure; anything not in coreErrors is a
+// conflict with the current state of the network
+func writeCoreError(w http.ResponseWriter, err error) {
+	for _, known := range coreErrors {
+		if errors.Is(err, known.err) {
+			writeError(w, known.status, known.code, err.Error())
+			return
+		}
+	}
+	writeError(w, http.StatusConflict, CodeConflict, err.Error())
+}
+
+// methodNotAllowed answers a request with a method the endpoint does not take
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/network"
+)
+
+func TestErrorResponses(t *testing.T) {
+	// Step 1: gNodeB 1 serves a registered UE; a second UE has no subscription
+	topology, err := config.Load("../configs/topology.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := network.New(topology, "../configs/subscribers.json", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := net.AddUE("123456789012345", 110, 110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := net.AMF.Attach(u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := net.AddUE("999990000000001", 110, 110); err != nil {
+		t.Fatal(err)
+	}
+	mux := routes(net)
+
+	// Step 2: Each request is turned away with its status and code
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		status       int
+		code         string
+	}{
+		{"IMSI with letters", "POST", "/api/ues", `{"imsi": "12345abc", "x": 0, "y": 0}`, http.StatusBadRequest, CodeBadRequest},
+		{"no IMSI", "POST", "/api/ues", `{"x": 0, "y": 0}`, http.StatusBadRequest, CodeBadRequest},
+		{"UE without a position", "POST", "/api/ues", `{"imsi": "208930000000001"}`, http.StatusBadRequest, CodeBadRequest},
+		{"UE that exists", "POST", "/api/ues", `{"imsi": "123456789012345", "x": 0, "y": 0}`, http.StatusConflict, CodeConflict},
+		{"unknown action", "POST", "/api/ues/123456789012345/teleport", "", http.StatusNotFound, CodeNotFound},
+		{"action on an unknown UE", "POST", "/api/ues/555/attach", "", http.StatusNotFound, CodeNotFound},
+		{"gNodeB serving UEs", "DELETE", "/api/gnodebs/1", "", http.StatusConflict, CodeGNodeBInUse},
+		{"unknown gNodeB", "DELETE", "/api/gnodebs/99", "", http.StatusNotFound, CodeNotFound},
+		{"gNodeB id not a number", "PUT", "/api/gnodebs/one", `{"range": 10}`, http.StatusBadRequest, CodeBadRequest},
+		{"zero range", "PUT", "/api/gnodebs/2", `{"range": 0}`, http.StatusBadRequest, CodeBadRequest},
+		{"negative range", "PUT", "/api/gnodebs/2", `{"range": -5}`, http.StatusBadRequest, CodeBadRequest},
+		{"session without a subscription", "POST", "/api/sessions", `{"imsi": "999990000000001", "sessionType": "IoT"}`, http.StatusForbidden, CodeSubscriberNotFound},
+		{"unknown session type", "POST", "/api/sessions", `{"imsi": "123456789012345", "sessionType": "Fax"}`, http.StatusBadRequest, CodeBadRequest},
+		{"invalid JSON", "POST", "/api/sessions", `{"imsi": `, http.StatusBadRequest, CodeBadRequest},
+		{"method", "PATCH", "/api/ues", "", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("%d with a body that is not an error: %v", w.Code, err)
+			}
+			if w.Code != tt.status || resp.Code != tt.code {
+				t.Errorf("got %d %q (%s), want %d %q", w.Code, resp.Code, resp.Error, tt.status, tt.code)
+			}
+			if resp.Error == "" {
+				t.Error("no error message")
+			}
+		})
+	}
+
+	// Step 3: Nothing changed
+	g := net.GNodeBs[2]
+	if len(net.GNodeBs) != 2 || g.Range != 50 || len(net.UEs) != 2 || len(net.SMF.Sessions) != 0 {
+		t.Errorf("rejected requests changed the network: %d gNodeBs, range %g, %d UEs, %d sessions",
+			len(net.GNodeBs), g.Range, len(net.UEs), len(net.SMF.Sessions))
+	}
+}
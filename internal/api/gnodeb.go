@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+)
+
+// defaultMaxCap is the capacity of the single cell of a gNodeB created without cells
+const defaultMaxCap = 100
+
+// cellRequest is one sector of a gNodeB to create; it uses the default carrier and power
+type cellRequest struct {
+	PCI       int     `json:"pci"`
+	Azimuth   float64 `json:"azimuth"`
+	Beamwidth float64 `json:"beamwidth"` // 0 or 360 = omnidirectional
+	Tilt      float64 `json:"tilt"`
+	MaxCap    int     `json:"maxCap"`
+}
+
+// /api/gnodebs - GET lists the gNodeBs, POST creates one.
+// POST body: {"x": 0, "y": 0} (or "lat" and "lon"), "range", "height" and "tac", and
+// either "maxCap" for one omnidirectional cell or "cells": [{"pci", "azimuth",
+// "beamwidth", "tilt", "maxCap"}]. Omitted values take the topology file defaults.
+func (h *Handler) gnodebs(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		h.viewing(h.getGNodeBs)(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		methodNotAllowed(w)
+		return
+	}
+	h.locked(h.postGNodeB)(w, r)
+}
+
+func (h *Handler) postGNodeB(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Decode over the defaults, so omitted fields keep them
+	req := struct {
+		position
+		Range   float64       `json:"range"`
+		HeightM float64       `json:"height"`
+		TAC     int           `json:"tac"`
+		MaxCap  int           `json:"maxCap"`
+		Cells   []cellRequest `json:"cells"`
+	}{Range: config.DefaultSiteRange, HeightM: ran.DefaultHeightM, TAC: ran.DefaultTAC}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	x, y, err := h.xy(req.position)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	if req.MaxCap != 0 && req.Cells != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "give maxCap or cells, not both")
+		return
+	}
+	if req.Cells == nil {
+		req.Cells = []cellRequest{{PCI: (3 * len(h.GNodeBs)) % 1008, MaxCap: defaultMaxCap}}
+		if req.MaxCap != 0 {
+			req.Cells[0].MaxCap = req.MaxCap
+		}
+	}
+
+	// Step 2: Check the values as a topology file's would be
+	if msg := checkGNodeB(req.Range, req.HeightM, req.TAC, req.Cells); msg != "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, msg)
+		return
+	}
+
+	// Step 3: Build it and put it on air
+	var cells []ran.CellConfig
+	for _, c := range req.Cells {
+		beamwidth := c.Beamwidth
+		if beamwidth == 0 {
+			beamwidth = radio.OmniBeamwidth
+		}
+		cells = append(cells, ran.CellConfig{
+			PCI:        c.PCI,
+			Antenna:    radio.AntennaPattern{AzimuthDeg: c.Azimuth, BeamwidthDeg: beamwidth, TiltDeg: c.Tilt},
+			Carrier:    ran.DefaultCarrier,
+			TxPowerDBm: ran.DefaultTxPowerDBm,
+			MaxCap:     c.MaxCap,
+		})
+	}
+	g, err := ran.NewSectorGNodeB(x, y, req.Range, cells)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	g.HeightM = req.HeightM
+	g.TAC = req.TAC
+	h.Network.AddGNodeB(g)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h.describeGNodeB(g))
+}
+
+// checkGNodeB returns what is wrong with a gNodeB to create, or ""
+func checkGNodeB(rangeVal, heightM float64, tac int, cells []cellRequest) string {
+	switch {
+	case rangeVal <= 0:
+		return fmt.Sprintf("range must be > 0, got %g", rangeVal)
+	case heightM < 0:
+		return fmt.Sprintf("height must be >= 0, got %g", heightM)
+	case tac < 0 || tac > 0xFFFFFF:
+		return fmt.Sprintf("tac must fit in 24 bits, got %d", tac)
+	case len(cells) == 0:
+		return "needs at least one cell"
+	}
+	pcis := make(map[int]bool)
+	for i, c := range cells {
+		switch {
+		case c.PCI < 0 || c.PCI > 1007:
+			return fmt.Sprintf("cells[%d]: pci must be 0-1007, got %d", i, c.PCI)
+		case pcis[c.PCI]:
+			return fmt.Sprintf("cells[%d]: pci %d is already used by another cell of this gNodeB", i, c.PCI)
+		case c.MaxCap < 1:
+			return fmt.Sprintf("cells[%d]: maxCap must be >= 1, got %d", i, c.MaxCap)
+		case c.Beamwidth < 0 || c.Beamwidth > 360:
+			return fmt.Sprintf("cells[%d]: beamwidth must be 0-360, got %g", i, c.Beamwidth)
+		}
+		pcis[c.PCI] = true
+	}
+	return ""
+}
+
+// /api/gnodebs/{id} - PUT moves a gNodeB or changes its range, DELETE removes it.
+// PUT body: {"x", "y"} or {"lat", "lon"}, and/or {"range"}; returns the gNodeB.
+// DELETE only takes a gNodeB serving no UE: fail it first to hand them over.
+func (h *Handler) gnodeb(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" && r.Method != "DELETE" {
+		methodNotAllowed(w)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "gNodeB id must be a number")
+		return
+	}
+	g, exists := h.GNodeBs[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, CodeNotFound, "gNodeB not found")
+		return
+	}
+
+	if r.Method == "DELETE" {
+		if err := h.Network.RemoveGNodeB(id); err != nil {
+			writeCoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req struct {
+		position
+		Range *float64 `json:"range"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	x, y, rangeVal := g.X, g.Y, g.Range
+	if req.X != nil || req.Y != nil || req.Lat != nil || req.Lon != nil {
+		if x, y, err = h.xy(req.position); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Range != nil {
+		if rangeVal = *req.Range; rangeVal <= 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("range must be > 0, got %g", rangeVal))
+			return
+		}
+	}
+	h.Network.MoveGNodeB(g, x, y, rangeVal)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.describeGNodeB(g))
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SimStatus describes the simulation clock and scheduler
+type SimStatus struct {
+	Time             time.Time `json:"time"`
+	ElapsedSeconds   float64   `json:"elapsedSeconds"`
+	Paused           bool      `json:"paused"`
+	Speed            float64   `json:"speed"` // 0 = as fast as possible, 1 = real time
+	PendingEvents    int       `json:"pendingEvents"`
+	ProcessedEvents  int       `json:"processedEvents"`
+	NextEventSeconds *float64  `json:"nextEventSeconds,omitempty"` // elapsed time of the next queued event
+	Seed             int64     `json:"seed"`
+	Ran              int       `json:"ran,omitempty"` // events run by this step
+}
+
+func (h *Handler) simStatus() SimStatus {
+	clock := h.Scheduler.Clock
+	status := SimStatus{
+		Time:            clock.Now(),
+		ElapsedSeconds:  clock.Elapsed().Seconds(),
+		Paused:          h.Scheduler.Paused(),
+		Speed:           h.Scheduler.Speed(),
+		PendingEvents:   h.Scheduler.Pending(),
+		ProcessedEvents: h.Scheduler.Processed(),
+		Seed:            h.RNG.Seed(),
+	}
+	if next, ok := h.Scheduler.Next(); ok {
+		seconds := next.Sub(clock.Start()).Seconds()
+		status.NextEventSeconds = &seconds
+	}
+	return status
+}
+
+// GET  /api/sim - simulation clock status
+// POST /api/sim - controls the clock.
+// Body: {"action": "pause"|"resume"|"step"|"speed", "events": 1, "seconds": 0, "speed": 1}
+// step needs a paused simulation and runs either the next "events" events (default 1)
+// or everything in the next "seconds" of simulated time.
+func (h *Handler) simControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		methodNotAllowed(w)
+		return
+	}
+
+	ran := 0
+	if r.Method == "POST" {
+		var req struct {
+			Action  string   `json:"action"`
+			Events  int      `json:"events"`
+			Seconds float64  `json:"seconds"`
+			Speed   *float64 `json:"speed"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+
+		switch req.Action {
+		case "pause":
+			h.Scheduler.Pause()
+		case "resume":
+			h.Scheduler.Resume()
+		case "speed":
+			if req.Speed == nil || *req.Speed < 0 {
+				writeError(w, http.StatusBadRequest, CodeBadRequest, "speed must be given and >= 0")
+				return
+			}
+			h.Scheduler.SetSpeed(*req.Speed)
+		case "step":
+			if !h.Scheduler.Paused() {
+				writeError(w, http.StatusConflict, CodeConflict, "pause the simulation before stepping")
+				return
+			}
+			if req.Events < 0 || req.Seconds < 0 || (req.Events > 0 && req.Seconds > 0) {
+				writeError(w, http.StatusBadRequest, CodeBadRequest, `give either "events" or "seconds", not negative`)
+				return
+			}
+			// The scheduler takes the network lock per event, so this handler must not hold it
+			if req.Seconds > 0 {
+				ran = h.Scheduler.RunFor(time.Duration(req.Seconds * float64(time.Second)))
+			} else {
+				if req.Events == 0 {
+					req.Events = 1
+				}
+				for ran < req.Events && h.Scheduler.Step() {
+					ran++
+				}
+			}
+		default:
+			writeError(w, http.StatusBadRequest, CodeBadRequest, `action must be "pause", "resume", "step" or "speed"`)
+			return
+		}
+	}
+
+	status := h.simStatus()
+	status.Ran = ran
+	h.stampTime(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rizpur/NetSim5G/internal/network"
+)
+
+// GET  /api/snapshot - the complete network state as a versioned snapshot
+// POST /api/snapshot - replaces the network state with the snapshot in the body
+func (h *Handler) snapshot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		var snap *network.Snapshot
+		h.Network.View(func() {
+			h.stampTime(w)
+			snap = h.Network.Snapshot()
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snap)
+
+	case "POST":
+		var snap network.Snapshot
+		if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid snapshot: "+err.Error())
+			return
+		}
+		var err error
+		h.Network.Update(func() {
+			err = h.Network.Restore(&snap)
+			h.stampTime(w)
+		})
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, CodeInvalidSnapshot, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.simStatus())
+
+	default:
+		methodNotAllowed(w)
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/network"
+)
+
+func TestPostSnapshotRejectsVersion(t *testing.T) {
+	topology, err := config.Load("../configs/topology.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := network.New(topology, "../configs/subscribers.json", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(net)
+
+	snap := net.Snapshot()
+	snap.Version = network.SnapshotVersion + 1
+	body, _ := json.Marshal(snap)
+	w := httptest.NewRecorder()
+	h.snapshot(w, httptest.NewRequest("POST", "/api/snapshot", strings.NewReader(string(body))))
+
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusUnprocessableEntity || resp.Code != CodeInvalidSnapshot {
+		t.Errorf("got %d %q, want %d %q", w.Code, resp.Code, http.StatusUnprocessableEntity, CodeInvalidSnapshot)
+	}
+}
@@ -0,0 +1,322 @@
+// Package buildings puts the built environment into the radio model. Buildings are
+// footprints extruded to a height; every link is traced through them to find out
+// whether it has line of sight, whether the UE is indoors, and what that costs:
+// NLOS path loss (or diffraction over the rooftops), wall penetration and indoor
+// loss, following 3GPP TR 38.901.
+package buildings
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+)
+
+// indoorLossDBPerM is the loss inside a building per metre from the outer wall
+// (TR 38.901 7.4.3.1)
+const indoorLossDBPerM = 0.5
+
+// Material is the class of a building's outer walls
+type Material string
+
+// Materials of TR 38.901 Table 7.4.3-1, and the two building mixes of Table 7.4.3-2
+const (
+	Glass    Material = "glass"    // standard multi-pane glass
+	IRRGlass Material = "irrGlass" // infrared reflective (coated) glass
+	Concrete Material = "concrete"
+	Wood     Material = "wood"
+	LowLoss  Material = "lowLoss"  // 30% glass, 70% concrete: older buildings
+	HighLoss Material = "highLoss" // 70% IRR glass, 30% concrete: modern energy-efficient buildings
+)
+
+// DefaultMaterial is used when a building does not say
+const DefaultMaterial = LowLoss
+
+// Materials lists every material class
+var Materials = []Material{Glass, IRRGlass, Concrete, Wood, LowLoss, HighLoss}
+
+// Valid reports whether m is a known material class
+func (m Material) Valid() bool {
+	for _, known := range Materials {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PenetrationLossDB returns the loss through an outer wall at freqGHz
+func (m Material) PenetrationLossDB(freqGHz float64) float64 {
+	glass := 2 + 0.2*freqGHz
+	irrGlass := 23 + 0.3*freqGHz
+	concrete := 5 + 4*freqGHz
+	mix := func(a, la, b, lb float64) float64 {
+		return 5 - 10*math.Log10(a*math.Pow(10, -la/10)+b*math.Pow(10, -lb/10))
+	}
+	switch m {
+	case Glass:
+		return glass
+	case IRRGlass:
+		return irrGlass
+	case Concrete:
+		return concrete
+	case Wood:
+		return 4.85 + 0.12*freqGHz
+	case HighLoss:
+		return mix(0.7, irrGlass, 0.3, concrete)
+	}
+	return mix(0.3, glass, 0.7, concrete)
+}
+
+// Point is a position on the simulation plane, in metres
+type Point struct{ X, Y float64 }
+
+// Building is a footprint (a simple polygon, either orientation, not closed) raised
+// to HeightM
+type Building struct {
+	Name      string
+	Footprint []Point
+	HeightM   float64
+	Material  Material
+
+	minX, minY, maxX, maxY float64 // bounding box
+	order                  int     // position in the map
+}
+
+// NewBuilding checks the footprint and returns the building
+func NewBuilding(name string, footprint []Point, heightM float64, material Material) (*Building, error) {
+	if len(footprint) > 1 && footprint[0] == footprint[len(footprint)-1] {
+		footprint = footprint[:len(footprint)-1] // GeoJSON rings repeat the first point
+	}
+	switch {
+	case len(footprint) < 3:
+		return nil, fmt.Errorf("building %s: footprint needs at least 3 corners", name)
+	case heightM <= 0:
+		return nil, fmt.Errorf("building %s: height must be > 0", name)
+	case !material.Valid():
+		return nil, fmt.Errorf("building %s: unknown material %q", name, material)
+	}
+	b := &Building{Name: name, Footprint: footprint, HeightM: heightM, Material: material,
+		minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+	for _, p := range footprint {
+		b.minX, b.minY = math.Min(b.minX, p.X), math.Min(b.minY, p.Y)
+		b.maxX, b.maxY = math.Max(b.maxX, p.X), math.Max(b.maxY, p.Y)
+	}
+	return b, nil
+}
+
+// Contains reports whether (x,y) is inside the footprint
+func (b *Building) Contains(x, y float64) bool {
+	if x < b.minX || x > b.maxX || y < b.minY || y > b.maxY {
+		return false
+	}
+	inside := false
+	for i, j := 0, len(b.Footprint)-1; i < len(b.Footprint); j, i = i, i+1 {
+		p, q := b.Footprint[i], b.Footprint[j]
+		if (p.Y > y) != (q.Y > y) && x < (q.X-p.X)*(y-p.Y)/(q.Y-p.Y)+p.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Encloses reports whether a point at height h is inside the building (below its roof)
+func (b *Building) Encloses(x, y, h float64) bool {
+	return h < b.HeightM && b.Contains(x, y)
+}
+
+// crossings returns where the segment from a to c crosses the footprint's outline, as
+// fractions of its length, sorted
+func (b *Building) crossings(a, c Point) []float64 {
+	var ts []float64
+	dx, dy := c.X-a.X, c.Y-a.Y
+	for i, j := 0, len(b.Footprint)-1; i < len(b.Footprint); j, i = i, i+1 {
+		p, q := b.Footprint[j], b.Footprint[i]
+		ex, ey := q.X-p.X, q.Y-p.Y
+		denom := dx*ey - dy*ex
+		if denom == 0 {
+			continue // parallel
+		}
+		t := ((p.X-a.X)*ey - (p.Y-a.Y)*ex) / denom
+		s := ((p.X-a.X)*dy - (p.Y-a.Y)*dx) / denom
+		if t >= 0 && t <= 1 && s >= 0 && s <= 1 {
+			ts = append(ts, t)
+		}
+	}
+	sort.Float64s(ts)
+	return ts
+}
+
+// Endpoint is one end of a link: a position and an antenna height above ground
+type Endpoint struct {
+	X, Y, H float64
+}
+
+// Path is what lies between the two ends of a link
+type Path struct {
+	LOS           bool      // no building cuts the direct ray
+	Obstructions  int       // buildings cutting it
+	Indoor        *Building // the building the receiver is in; nil = outdoors
+	IndoorM       float64   // distance travelled inside buildings the ends are in
+	PenetrationDB float64   // outer walls of the buildings the ends are in
+	IndoorDB      float64
+	DiffractionDB float64 // over the worst rooftop, when diffraction is modelled and the path is NLOS
+	PathLossDB    float64 // distance and LOS/NLOS (or diffraction), without the building entry losses
+	LossDB        float64 // everything: PathLossDB + PenetrationDB + IndoorDB
+}
+
+// Map is the set of buildings links are traced through
+type Map struct {
+	Buildings   []*Building
+	Diffraction bool // NLOS links get knife-edge diffraction over the worst rooftop instead of the NLOS model
+	buckets     map[[2]int][]*Building
+}
+
+// bucketSize (m) of the grid that finds the buildings near a link
+const bucketSize = 100.0
+
+// NewMap indexes the buildings
+func NewMap(buildings []*Building, diffraction bool) *Map {
+	m := &Map{Buildings: buildings, Diffraction: diffraction, buckets: make(map[[2]int][]*Building)}
+	for n, b := range buildings {
+		b.order = n
+		for i := bucketOf(b.minX); i <= bucketOf(b.maxX); i++ {
+			for j := bucketOf(b.minY); j <= bucketOf(b.maxY); j++ {
+				m.buckets[[2]int{i, j}] = append(m.buckets[[2]int{i, j}], b)
+			}
+		}
+	}
+	return m
+}
+
+func bucketOf(v float64) int {
+	return int(math.Floor(v / bucketSize))
+}
+
+// near returns the buildings whose bounding box overlaps the segment's, in map order
+func (m *Map) near(a, c Point) []*Building {
+	minX, maxX := math.Min(a.X, c.X), math.Max(a.X, c.X)
+	minY, maxY := math.Min(a.Y, c.Y), math.Max(a.Y, c.Y)
+	overlaps := func(b *Building) bool {
+		return b.maxX >= minX && b.minX <= maxX && b.maxY >= minY && b.minY <= maxY
+	}
+
+	var found []*Building
+	span := (bucketOf(maxX) - bucketOf(minX) + 1) * (bucketOf(maxY) - bucketOf(minY) + 1)
+	if span > len(m.Buildings) {
+		for _, b := range m.Buildings {
+			if overlaps(b) {
+				found = append(found, b)
+			}
+		}
+		return found
+	}
+	seen := make(map[*Building]bool)
+	for i := bucketOf(minX); i <= bucketOf(maxX); i++ {
+		for j := bucketOf(minY); j <= bucketOf(maxY); j++ {
+			for _, b := range m.buckets[[2]int{i, j}] {
+				if !seen[b] && overlaps(b) {
+					seen[b] = true
+					found = append(found, b)
+				}
+			}
+		}
+	}
+	// Keep the map order, so losses add up the same way every run
+	sort.Slice(found, func(i, j int) bool { return found[i].order < found[j].order })
+	return found
+}
+
+// At returns the building a point at height h is in, or nil outdoors
+func (m *Map) At(x, y, h float64) *Building {
+	for _, b := range m.near(Point{x, y}, Point{x, y}) {
+		if b.Encloses(x, y, h) {
+			return b
+		}
+	}
+	return nil
+}
+
+// Trace follows the direct ray from tx to rx on a carrier of freqGHz. A building the
+// ray passes below the roof of blocks it; a building one end is in (and the other
+// is not) adds its wall and the distance walked inside instead. Two ends in the same
+// building see each other.
+func (m *Map) Trace(tx, rx Endpoint, freqGHz float64) Path {
+	a, c := Point{tx.X, tx.Y}, Point{rx.X, rx.Y}
+	distance := math.Hypot(c.X-a.X, c.Y-a.Y)
+	height := func(t float64) float64 { return tx.H + (rx.H-tx.H)*t } // of the ray
+	wavelength := 0.299792458 / freqGHz
+	p := Path{LOS: true}
+	worstV := math.Inf(-1)
+
+	for _, b := range m.near(a, c) {
+		txIn, rxIn := b.Encloses(tx.X, tx.Y, tx.H), b.Encloses(rx.X, rx.Y, rx.H)
+		if txIn && rxIn {
+			continue
+		}
+		ts := b.crossings(a, c)
+		if rxIn {
+			p.Indoor = b
+			p.PenetrationDB += b.Material.PenetrationLossDB(freqGHz)
+			if len(ts) > 0 {
+				p.IndoorM += (1 - ts[len(ts)-1]) * distance
+			}
+		}
+		if txIn {
+			p.PenetrationDB += b.Material.PenetrationLossDB(freqGHz)
+			if len(ts) > 0 {
+				p.IndoorM += ts[0] * distance
+			}
+		}
+
+		// Every stretch of the ray over the footprint, other than the ends' own, must
+		// clear the roof. The ray is straight, so its lowest point is at one end.
+		bounds := append(append([]float64{0}, ts...), 1)
+		blocks := false
+		for i := 0; i+1 < len(bounds); i++ {
+			from, to := bounds[i], bounds[i+1]
+			mid := (from + to) / 2
+			if to-from < 1e-9 || !b.Contains(a.X+(c.X-a.X)*mid, a.Y+(c.Y-a.Y)*mid) {
+				continue
+			}
+			if (txIn && from == 0) || (rxIn && to == 1) {
+				continue
+			}
+			t := from
+			if height(to) < height(from) {
+				t = to
+			}
+			clearance := b.HeightM - height(t)
+			if clearance <= 0 {
+				continue
+			}
+			blocks = true
+			d1, d2 := math.Max(t*distance, 1), math.Max((1-t)*distance, 1)
+			worstV = math.Max(worstV, clearance*math.Sqrt(2/wavelength*(1/d1+1/d2)))
+		}
+		if blocks {
+			p.LOS = false
+			p.Obstructions++
+		}
+	}
+
+	p.PathLossDB = radio.PathLoss(distance, freqGHz)
+	if !p.LOS {
+		if m.Diffraction {
+			p.DiffractionDB = radio.KnifeEdgeLoss(worstV)
+			p.PathLossDB += p.DiffractionDB
+		} else {
+			p.PathLossDB = radio.PathLossNLOS(distance, freqGHz)
+		}
+	}
+	p.IndoorDB = indoorLossDBPerM * p.IndoorM
+	p.LossDB = p.PathLossDB + p.PenetrationDB + p.IndoorDB
+	return p
+}
+
+// PathLoss returns the total loss (dB) between a site antenna at (txX,txY) txH metres
+// up and a UE antenna at (rxX,rxY) rxH metres up
+func (m *Map) PathLoss(txX, txY, txH, rxX, rxY, rxH, freqGHz float64) float64 {
+	return m.Trace(Endpoint{txX, txY, txH}, Endpoint{rxX, rxY, rxH}, freqGHz).LossDB
+}
@@ -0,0 +1,206 @@
+package buildings
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+)
+
+// block is a 20 m square footprint centred on (x,y)
+func block(t *testing.T, name string, x, y, heightM float64, material Material) *Building {
+	t.Helper()
+	b, err := NewBuilding(name, []Point{{x - 10, y - 10}, {x + 10, y - 10}, {x + 10, y + 10}, {x - 10, y + 10}}, heightM, material)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPenetrationLoss(t *testing.T) {
+	// TR 38.901 Tables 7.4.3-1 and 7.4.3-2 at 3.5 GHz, worked by hand
+	tests := []struct {
+		material Material
+		want     float64
+	}{
+		{Glass, 2.7},
+		{IRRGlass, 24.05},
+		{Concrete, 19},
+		{Wood, 5.27},
+		{LowLoss, 12.70},
+		{HighLoss, 26.85},
+	}
+	for _, tt := range tests {
+		if got := tt.material.PenetrationLossDB(3.5); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: %.3f dB, want %.2f", tt.material, got, tt.want)
+		}
+	}
+	// Every wall costs more at higher frequencies
+	for _, m := range Materials {
+		if lo, hi := m.PenetrationLossDB(0.7), m.PenetrationLossDB(28); hi <= lo {
+			t.Errorf("%s: %.2f dB at 28 GHz, not more than %.2f at 700 MHz", m, hi, lo)
+		}
+	}
+	if Material("steel").Valid() || !DefaultMaterial.Valid() {
+		t.Error("Valid does not match the material list")
+	}
+}
+
+func TestNewBuilding(t *testing.T) {
+	square := []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	tests := []struct {
+		name      string
+		footprint []Point
+		height    float64
+		material  Material
+		err       string
+	}{
+		{"square", square, 10, Concrete, ""},
+		{"closed ring", append(square, square[0]), 10, Concrete, ""},
+		{"two corners", square[:2], 10, Concrete, "building b: footprint needs at least 3 corners"},
+		{"closed triangle of two corners", []Point{{0, 0}, {1, 1}, {0, 0}}, 10, Concrete, "building b: footprint needs at least 3 corners"},
+		{"no height", square, 0, Concrete, "building b: height must be > 0"},
+		{"unknown material", square, 10, "steel", `building b: unknown material "steel"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBuilding("b", tt.footprint, tt.height, tt.material)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("got %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b.Footprint) != 4 {
+				t.Errorf("footprint has %d corners, want 4", len(b.Footprint))
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	// An L, clockwise: the notch at the top right is outside
+	l, err := NewBuilding("L", []Point{{0, 0}, {0, 20}, {10, 20}, {10, 10}, {20, 10}, {20, 0}}, 10, Concrete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		x, y   float64
+		inside bool
+	}{
+		{5, 5, true}, {5, 15, true}, {15, 5, true},
+		{15, 15, false}, {-1, 5, false}, {25, 5, false}, {5, 25, false},
+	} {
+		if got := l.Contains(tt.x, tt.y); got != tt.inside {
+			t.Errorf("Contains(%g, %g) = %v, want %v", tt.x, tt.y, got, tt.inside)
+		}
+	}
+	if !l.Encloses(5, 5, 9) || l.Encloses(5, 5, 10) {
+		t.Error("Encloses does not stop at the roof")
+	}
+}
+
+func TestTrace(t *testing.T) {
+	const freq = 3.5
+	tower := block(t, "tower", 50, 0, 20, LowLoss)
+	m := NewMap([]*Building{tower}, false)
+	wall := LowLoss.PenetrationLossDB(freq)
+
+	tests := []struct {
+		name         string
+		tx, rx       Endpoint
+		los          bool
+		obstructions int
+		indoor       bool
+		indoorM      float64
+		penetration  float64
+	}{
+		// The ray from 25 m down to 1.5 m is 15.6 m high at the near wall: under the roof
+		{"through the footprint", Endpoint{0, 0, 25}, Endpoint{100, 0, 1.5}, false, 1, false, 0, 0},
+		{"over the roof", Endpoint{0, 0, 60}, Endpoint{100, 0, 1.5}, true, 0, false, 0, 0},
+		{"beside the footprint", Endpoint{0, 20, 25}, Endpoint{100, 20, 1.5}, true, 0, false, 0, 0},
+		{"ending short of it", Endpoint{0, 0, 25}, Endpoint{39, 0, 1.5}, true, 0, false, 0, 0},
+		// The UE's own building: one wall and 10 m inside, but the ray is not blocked
+		{"into the building", Endpoint{0, 0, 25}, Endpoint{50, 0, 1.5}, true, 0, true, 10, wall},
+		{"out of the building", Endpoint{50, 0, 1.5}, Endpoint{0, 0, 25}, true, 0, false, 10, wall},
+		{"on the roof", Endpoint{0, 0, 25}, Endpoint{50, 0, 21}, true, 0, false, 0, 0},
+		{"both inside", Endpoint{45, 0, 3}, Endpoint{55, 5, 1.5}, true, 0, false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := m.Trace(tt.tx, tt.rx, freq)
+			if p.LOS != tt.los || p.Obstructions != tt.obstructions {
+				t.Errorf("LOS %v with %d obstructions, want %v with %d", p.LOS, p.Obstructions, tt.los, tt.obstructions)
+			}
+			if (p.Indoor != nil) != tt.indoor {
+				t.Errorf("indoor in %v, want indoor %v", p.Indoor, tt.indoor)
+			}
+			if math.Abs(p.IndoorM-tt.indoorM) > 1e-9 || math.Abs(p.PenetrationDB-tt.penetration) > 1e-9 {
+				t.Errorf("%g m indoors behind %.2f dB of wall, want %g m and %.2f dB", p.IndoorM, p.PenetrationDB, tt.indoorM, tt.penetration)
+			}
+			distance := math.Hypot(tt.rx.X-tt.tx.X, tt.rx.Y-tt.tx.Y)
+			want := radio.PathLoss(distance, freq)
+			if !tt.los {
+				want = radio.PathLossNLOS(distance, freq)
+			}
+			if p.PathLossDB != want {
+				t.Errorf("path loss %.2f dB, want %.2f", p.PathLossDB, want)
+			}
+			if math.Abs(p.IndoorDB-indoorLossDBPerM*tt.indoorM) > 1e-9 || p.LossDB != p.PathLossDB+p.PenetrationDB+p.IndoorDB {
+				t.Errorf("losses do not add up: %+v", p)
+			}
+			if got := m.PathLoss(tt.tx.X, tt.tx.Y, tt.tx.H, tt.rx.X, tt.rx.Y, tt.rx.H, freq); got != p.LossDB {
+				t.Errorf("PathLoss %.2f dB, Trace %.2f", got, p.LossDB)
+			}
+		})
+	}
+
+	// Two blocks in a row are two obstructions, and NLOS costs more than LOS
+	m = NewMap([]*Building{tower, block(t, "annex", 150, 0, 20, Glass)}, false)
+	p := m.Trace(Endpoint{0, 0, 25}, Endpoint{200, 0, 1.5}, freq)
+	if p.LOS || p.Obstructions != 2 || p.PathLossDB <= radio.PathLoss(200, freq) {
+		t.Errorf("through two blocks: %+v", p)
+	}
+
+	// A UE in the glass annex behind the tower pays for the tower's shadow and the
+	// annex's wall
+	p = m.Trace(Endpoint{0, 0, 25}, Endpoint{150, 0, 1.5}, freq)
+	if p.LOS || p.Obstructions != 1 || p.Indoor == nil || p.Indoor.Name != "annex" ||
+		p.PenetrationDB != Glass.PenetrationLossDB(freq) || math.Abs(p.IndoorM-10) > 1e-9 {
+		t.Errorf("into the annex behind the tower: %+v", p)
+	}
+	if b := m.At(150, 0, 1.5); b == nil || b.Name != "annex" {
+		t.Errorf("At inside the annex gave %v", b)
+	}
+	if b := m.At(100, 0, 1.5); b != nil {
+		t.Errorf("At between the blocks gave %s", b.Name)
+	}
+}
+
+func TestTraceDiffraction(t *testing.T) {
+	const freq = 3.5
+	tower := block(t, "tower", 50, 0, 20, Concrete)
+	tx, rx := Endpoint{0, 0, 25}, Endpoint{100, 0, 1.5}
+
+	p := NewMap([]*Building{tower}, true).Trace(tx, rx, freq)
+	if p.LOS || p.DiffractionDB <= 0 {
+		t.Fatalf("diffraction over the tower: %+v", p)
+	}
+	if want := radio.PathLoss(100, freq) + p.DiffractionDB; p.PathLossDB != want {
+		t.Errorf("path loss %.2f dB, want free space plus diffraction, %.2f", p.PathLossDB, want)
+	}
+
+	// A taller tower casts a deeper shadow
+	taller := NewMap([]*Building{block(t, "taller", 50, 0, 40, Concrete)}, true).Trace(tx, rx, freq)
+	if taller.DiffractionDB <= p.DiffractionDB {
+		t.Errorf("diffraction %.2f dB over 40 m, not more than %.2f over 20 m", taller.DiffractionDB, p.DiffractionDB)
+	}
+
+	// Line of sight has no diffraction
+	if p := NewMap([]*Building{tower}, true).Trace(Endpoint{0, 0, 60}, rx, freq); !p.LOS || p.DiffractionDB != 0 {
+		t.Errorf("over the roof: %+v", p)
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rizpur/NetSim5G/internal/buildings"
+	"github.com/rizpur/NetSim5G/internal/geo"
+)
+
+// Defaults for buildings that do not say
+const (
+	DefaultBuildingHeight = 10.0 // m
+	storeyHeightM         = 3.0  // per storey, when a building gives levels instead of height
+)
+
+// LoadBuildings reads building footprints from a GeoJSON file: one Polygon feature
+// per building (holes are ignored), in lon/lat, placed on the plane with projection.
+// Properties, all optional: name, height (m) or levels (storeys of 3 m), and material
+// (see buildings.Materials). Other properties are ignored, so OpenStreetMap exports
+// load as they are.
+func LoadBuildings(path string, projection *geo.Projection) ([]*buildings.Building, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buildings file: %w", err)
+	}
+	if projection == nil {
+		return nil, &Error{File: path, Msg: "buildings are in lat/lon, but the topology has no geographic origin; give sites as lat/lon or set origin"}
+	}
+	root, err := parseJSON(data)
+	if cfgErr, ok := err.(*Error); ok {
+		cfgErr.File = path
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{file: path}
+	if root.kind != mapNode || root.fields["type"] == nil || root.fields["type"].value != "FeatureCollection" ||
+		root.fields["features"] == nil {
+		return nil, d.errorf(root, "not a GeoJSON FeatureCollection")
+	}
+	var found []*buildings.Building
+	err = d.list(root.fields["features"], "features", func(i int, feature *node) error {
+		what := fmt.Sprintf("features[%d]", i)
+		if feature.kind != mapNode || feature.fields["geometry"] == nil {
+			return d.errorf(feature, "%s: not a feature with a geometry", what)
+		}
+		geometry := feature.fields["geometry"]
+		if geometry.kind != mapNode || geometry.fields["type"] == nil || geometry.fields["type"].value != "Polygon" {
+			return d.errorf(geometry, "%s: only Polygon geometries are supported", what)
+		}
+		rings := geometry.fields["coordinates"]
+		if rings == nil || rings.kind != listNode || len(rings.items) == 0 {
+			return d.errorf(geometry, "%s: a Polygon needs coordinates", what)
+		}
+
+		// Step 1: The outer ring, [lon, lat] pairs
+		var footprint []buildings.Point
+		err := d.list(rings.items[0], what+".coordinates", func(j int, corner *node) error {
+			var lonLat []float64
+			err := d.list(corner, what+".coordinates", func(k int, v *node) error {
+				var f float64
+				err := d.float(v, what+".coordinates", &f)
+				lonLat = append(lonLat, f)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if len(lonLat) < 2 {
+				return d.errorf(corner, "%s: corners must be [lon, lat]", what)
+			}
+			x, y := projection.ToXY(geo.LatLon{Lon: lonLat[0], Lat: lonLat[1]})
+			footprint = append(footprint, buildings.Point{X: x, Y: y})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Step 2: Properties
+		name := fmt.Sprintf("building %d", i+1)
+		height, levels := 0.0, 0.0
+		material := string(buildings.DefaultMaterial)
+		if props := feature.fields["properties"]; props != nil && props.kind == mapNode {
+			set := func(key string, decode func(*node) error) error {
+				if v := props.fields[key]; v != nil && v.kind == scalarNode && v.value != "null" {
+					return decode(v)
+				}
+				return nil
+			}
+			for _, err := range []error{
+				set("name", func(v *node) error { return d.string(v, what+".name", &name) }),
+				set("height", func(v *node) error { return d.float(v, what+".height", &height) }),
+				set("levels", func(v *node) error { return d.float(v, what+".levels", &levels) }),
+				set("material", func(v *node) error { return d.string(v, what+".material", &material) }),
+			} {
+				if err != nil {
+					return err
+				}
+			}
+		}
+		switch {
+		case height == 0 && levels > 0:
+			height = levels * storeyHeightM
+		case height == 0:
+			height = DefaultBuildingHeight
+		}
+
+		b, err := buildings.NewBuilding(name, footprint, height, buildings.Material(material))
+		if err != nil {
+			return d.errorf(feature, "%s: %v", what, err)
+		}
+		found = append(found, b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
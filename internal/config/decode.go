@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// decoder converts nodes into Go values, reporting errors with file and line
+type decoder struct {
+	file string
+}
+
+func (d *decoder) errorf(n *node, format string, args ...interface{}) error {
+	return &Error{File: d.file, Line: n.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// fields calls the handler registered for every key of a mapping. Unknown keys are errors,
+// so typos in config files do not get silently ignored.
+func (d *decoder) fields(n *node, what string, handlers map[string]func(*node) error) error {
+	if n.kind != mapNode {
+		return d.errorf(n, "%s must be a mapping, got %s", what, n.kind)
+	}
+	for _, key := range n.keys {
+		handler, known := handlers[key]
+		if !known {
+			return d.errorf(n.fields[key], "unknown field %q in %s", key, what)
+		}
+		if err := handler(n.fields[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) scalar(n *node, what string) error {
+	if n.kind != scalarNode {
+		return d.errorf(n, "%s must be %s, got %s", what, scalarNode, n.kind)
+	}
+	return nil
+}
+
+func (d *decoder) float(n *node, what string, dst *float64) error {
+	if err := d.scalar(n, what); err != nil {
+		return err
+	}
+	v, err := strconv.ParseFloat(n.value, 64)
+	if err != nil || n.quoted {
+		return d.errorf(n, "%s must be a number, got %q", what, n.value)
+	}
+	*dst = v
+	return nil
+}
+
+func (d *decoder) int(n *node, what string, dst *int) error {
+	if err := d.scalar(n, what); err != nil {
+		return err
+	}
+	v, err := strconv.Atoi(n.value)
+	if err != nil || n.quoted {
+		return d.errorf(n, "%s must be an integer, got %q", what, n.value)
+	}
+	*dst = v
+	return nil
+}
+
+func (d *decoder) bool(n *node, what string, dst *bool) error {
+	if err := d.scalar(n, what); err != nil {
+		return err
+	}
+	v, err := strconv.ParseBool(n.value)
+	if err != nil || n.quoted {
+		return d.errorf(n, "%s must be true or false, got %q", what, n.value)
+	}
+	*dst = v
+	return nil
+}
+
+func (d *decoder) string(n *node, what string, dst *string) error {
+	if err := d.scalar(n, what); err != nil {
+		return err
+	}
+	*dst = n.value
+	return nil
+}
+
+func (d *decoder) list(n *node, what string, each func(i int, item *node) error) error {
+	if n.kind != listNode {
+		return d.errorf(n, "%s must be a list, got %s", what, n.kind)
+	}
+	for i, item := range n.items {
+		if err := each(i, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) strings(n *node, what string, dst *[]string) error {
+	out := []string{}
+	err := d.list(n, what, func(i int, item *node) error {
+		var s string
+		if err := d.string(item, fmt.Sprintf("%s[%d]", what, i), &s); err != nil {
+			return err
+		}
+		out = append(out, s)
+		return nil
+	})
+	*dst = out
+	return err
+}
+
+func (d *decoder) ints(n *node, what string, dst *[]int) error {
+	var out []int
+	err := d.list(n, what, func(i int, item *node) error {
+		var v int
+		if err := d.int(item, fmt.Sprintf("%s[%d]", what, i), &v); err != nil {
+			return err
+		}
+		out = append(out, v)
+		return nil
+	})
+	*dst = out
+	return err
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Error is a configuration problem pinned to a line of the source file
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Msg)
+}
+
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	mapNode
+	listNode
+)
+
+func (k nodeKind) String() string {
+	return [...]string{"a value", "a mapping", "a list"}[k]
+}
+
+// node is a parsed JSON or YAML value that remembers the line it came from,
+// so decoding and validation errors can point at the offending line
+type node struct {
+	kind   nodeKind
+	line   int
+	value  string // scalars, without quotes
+	quoted bool   // scalar was a quoted string
+	keys   []string
+	fields map[string]*node
+	items  []*node
+}
+
+func newMap(line int) *node {
+	return &node{kind: mapNode, line: line, fields: make(map[string]*node)}
+}
+
+// set adds a key to a mapping node, rejecting duplicates
+func (n *node) set(key string, child *node, line int) error {
+	if _, exists := n.fields[key]; exists {
+		return &Error{Line: line, Msg: fmt.Sprintf("duplicate key %q", key)}
+	}
+	n.keys = append(n.keys, key)
+	n.fields[key] = child
+	return nil
+}
+
+// parseJSON turns a JSON document into a node tree
+func parseJSON(data []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	lineAt := func(offset int64) int {
+		if offset > int64(len(data)) {
+			offset = int64(len(data))
+		}
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+	wrap := func(err error) error {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return &Error{Line: lineAt(syntax.Offset), Msg: syntax.Error()}
+		}
+		if errors.Is(err, io.EOF) {
+			return &Error{Line: lineAt(int64(len(data))), Msg: "unexpected end of JSON input"}
+		}
+		return &Error{Line: lineAt(dec.InputOffset()), Msg: err.Error()}
+	}
+
+	var parse func() (*node, error)
+	parse = func() (*node, error) {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, wrap(err)
+		}
+		line := lineAt(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case json.Delim:
+			if t == '{' {
+				n := newMap(line)
+				for dec.More() {
+					keyTok, err := dec.Token()
+					if err != nil {
+						return nil, wrap(err)
+					}
+					keyLine := lineAt(dec.InputOffset())
+					child, err := parse()
+					if err != nil {
+						return nil, err
+					}
+					if err := n.set(keyTok.(string), child, keyLine); err != nil {
+						return nil, err
+					}
+				}
+				if _, err := dec.Token(); err != nil { // closing }
+					return nil, wrap(err)
+				}
+				return n, nil
+			}
+			n := &node{kind: listNode, line: line}
+			for dec.More() {
+				child, err := parse()
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, child)
+			}
+			if _, err := dec.Token(); err != nil { // closing ]
+				return nil, wrap(err)
+			}
+			return n, nil
+		case string:
+			return &node{kind: scalarNode, line: line, value: t, quoted: true}, nil
+		case json.Number:
+			return &node{kind: scalarNode, line: line, value: t.String()}, nil
+		case bool:
+			return &node{kind: scalarNode, line: line, value: fmt.Sprint(t)}, nil
+		default: // null
+			return &node{kind: scalarNode, line: line}, nil
+		}
+	}
+
+	root, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, &Error{Line: lineAt(dec.InputOffset()), Msg: "unexpected data after the top-level value"}
+	}
+	return root, nil
+}
+
+// yamlLine is one meaningful line of a YAML document
+type yamlLine struct {
+	indent int
+	text   string
+	num    int
+}
+
+// parseYAML turns a YAML document into a node tree. Only the subset used by config
+// files is supported: block mappings, block lists ("- "), inline lists ([a, b]),
+// quoted and plain scalars, and # comments. No anchors, tags or multi-line strings.
+func parseYAML(data []byte) (*node, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := stripComment(raw)
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.TrimSpace(trimmed) == "" || trimmed == "---" {
+			continue
+		}
+		if leading := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]; strings.Contains(leading, "\t") {
+			return nil, &Error{Line: i + 1, Msg: "tabs are not allowed for indentation"}
+		}
+		lines = append(lines, yamlLine{indent: len(text) - len(trimmed), text: strings.TrimRight(trimmed, " "), num: i + 1})
+	}
+	if len(lines) == 0 {
+		return nil, &Error{Line: 1, Msg: "document is empty"}
+	}
+
+	p := &yamlParser{lines: lines}
+	root, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, &Error{Line: p.lines[p.pos].num, Msg: "unexpected indentation"}
+	}
+	return root, nil
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or list starting at the current line
+func (p *yamlParser) block(indent int) (*node, error) {
+	if isListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (*node, error) {
+	n := newMap(p.lines[p.pos].num)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		l := p.lines[p.pos]
+		if isListItem(l.text) {
+			return nil, &Error{Line: l.num, Msg: "list item where a key was expected"}
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, &Error{Line: l.num, Msg: fmt.Sprintf("expected \"key: value\", got %q", l.text)}
+		}
+		p.pos++
+
+		var child *node
+		var err error
+		if rest != "" {
+			child, err = parseScalar(rest, l.num)
+		} else if p.pos < len(p.lines) && (p.lines[p.pos].indent > indent ||
+			(p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text))) {
+			child, err = p.block(p.lines[p.pos].indent)
+		} else {
+			child = &node{kind: scalarNode, line: l.num} // empty value
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := n.set(key, child, l.num); err != nil {
+			return nil, err
+		}
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, &Error{Line: p.lines[p.pos].num, Msg: "unexpected indentation"}
+	}
+	return n, nil
+}
+
+func (p *yamlParser) list(indent int) (*node, error) {
+	n := &node{kind: listNode, line: p.lines[p.pos].num}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+		l := p.lines[p.pos]
+		rest := strings.TrimLeft(l.text[1:], " ")
+
+		var child *node
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				return nil, &Error{Line: l.num, Msg: "empty list item"}
+			}
+			child, err = p.block(p.lines[p.pos].indent)
+		case isMappingStart(rest):
+			// "- key: value" opens a mapping whose keys line up with "key"
+			contentIndent := indent + len(l.text) - len(rest)
+			p.lines[p.pos] = yamlLine{indent: contentIndent, text: rest, num: l.num}
+			child, err = p.mapping(contentIndent)
+		default:
+			p.pos++
+			child, err = parseScalar(rest, l.num)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, child)
+	}
+	return n, nil
+}
+
+func isMappingStart(text string) bool {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") || strings.HasPrefix(text, "[") {
+		return false
+	}
+	_, _, ok := splitKey(text)
+	return ok
+}
+
+// splitKey splits "key: value" (or "key:") into its parts
+func splitKey(text string) (key, rest string, ok bool) {
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		idx = len(text) - 1
+	}
+	key = strings.TrimSpace(text[:idx])
+	if key == "" || strings.ContainsAny(key, "\"'[]{}") {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(text[idx+1:]), true
+}
+
+// parseScalar parses a plain or quoted scalar, or an inline list of scalars
+func parseScalar(text string, line int) (*node, error) {
+	if strings.HasPrefix(text, "[") {
+		if !strings.HasSuffix(text, "]") {
+			return nil, &Error{Line: line, Msg: "unterminated inline list"}
+		}
+		n := &node{kind: listNode, line: line}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return n, nil
+		}
+		for _, part := range strings.Split(inner, ",") {
+			item, err := parseScalar(strings.TrimSpace(part), line)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		return n, nil
+	}
+	if strings.HasPrefix(text, "{") {
+		return nil, &Error{Line: line, Msg: "inline mappings are not supported, use an indented block"}
+	}
+
+	for _, q := range []string{"\"", "'"} {
+		if strings.HasPrefix(text, q) {
+			if len(text) < 2 || !strings.HasSuffix(text, q) {
+				return nil, &Error{Line: line, Msg: "unterminated quoted string"}
+			}
+			return &node{kind: scalarNode, line: line, value: text[1 : len(text)-1], quoted: true}, nil
+		}
+	}
+	if text == "~" || text == "null" {
+		return &node{kind: scalarNode, line: line}, nil
+	}
+	return &node{kind: scalarNode, line: line, value: text}, nil
+}
+
+// stripComment removes a # comment that is not inside quotes
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
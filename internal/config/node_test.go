@@ -0,0 +1,171 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// site is a valid one-gNodeB topology; the rejection cases below edit one line of it,
+// so the gNodeB entry is on line 2 and its cell on line 9
+const site = `gnodebs:
+  - x: 0
+    y: 0
+    range: 500
+    height: 25
+    tac: 1
+    inactivityTimer: 10
+    cells:
+      - pci: 1
+        maxCap: 10
+        beamwidth: 120
+        frequencyGHz: 3.5
+        numerology: 1
+        bandwidthMHz: 100
+`
+
+func TestParseValid(t *testing.T) {
+	yaml := `# two sites
+gnodebs:
+  - x: 0
+    y: 0
+    range: 500
+    tac: 7          # tracking area
+    rna: [2]
+    useInactive: true
+    inactivityTimer: 2.5
+    slices:
+      - sst: 1
+        sd: "00000a"
+    allowedIMSIs:
+      - '001010000000001'
+    cells:
+      - pci: 1
+        maxCap: 10
+        azimuth: 120
+        beamwidth: 65
+  - x: 800
+    y: -100
+    range: 300
+    cells:
+      - pci: 2
+        maxCap: 5
+`
+	json := `{
+  "gnodebs": [
+    {
+      "x": 0, "y": 0, "range": 500, "tac": 7, "rna": [2],
+      "useInactive": true, "inactivityTimer": 2.5,
+      "slices": [{"sst": 1, "sd": "00000a"}],
+      "allowedIMSIs": ["001010000000001"],
+      "cells": [{"pci": 1, "maxCap": 10, "azimuth": 120, "beamwidth": 65}]
+    },
+    {"x": 800, "y": -100, "range": 300, "cells": [{"pci": 2, "maxCap": 5}]}
+  ]
+}`
+	for name, data := range map[string]string{"t.yaml": yaml, "t.json": json} {
+		topology, err := Parse(name, []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(topology.GNodeBs) != 2 {
+			t.Fatalf("%s: got %d gNodeBs, want 2", name, len(topology.GNodeBs))
+		}
+		g := topology.GNodeBs[0]
+		switch {
+		case g.Range != 500 || g.TAC != 7 || !g.UseInactive || g.InactivityTimer != 2500*time.Millisecond:
+			t.Errorf("%s: gnodebs[0] = %+v", name, g)
+		case len(g.RNA) != 1 || g.RNA[0] != 2:
+			t.Errorf("%s: rna = %v, want [2]", name, g.RNA)
+		case len(g.Slices) != 1 || g.Slices[0].SST != 1 || g.Slices[0].SD != "00000a":
+			t.Errorf("%s: slices = %+v", name, g.Slices)
+		case len(g.AllowedIMSIs) != 1 || g.AllowedIMSIs[0] != "001010000000001":
+			t.Errorf("%s: allowedIMSIs = %v", name, g.AllowedIMSIs)
+		case len(g.Cells) != 1 || g.Cells[0].AzimuthDeg != 120 || g.Cells[0].BeamwidthDeg != 65 || g.Cells[0].MaxCap != 10:
+			t.Errorf("%s: cells = %+v", name, g.Cells)
+		}
+		// Omitted values take the defaults
+		if g2 := topology.GNodeBs[1]; g2.X != 800 || g2.Y != -100 || g2.Cells[0].BeamwidthDeg != 360 || g2.AllowedIMSIs != nil {
+			t.Errorf("%s: gnodebs[1] = %+v", name, g2)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, file, data string
+		want             string
+	}{
+		// Syntax
+		{"unsupported format", "t.txt", site, "t.txt: unsupported format, use .json, .yaml or .yml"},
+		{"empty yaml", "t.yaml", "# nothing\n", "t.yaml:1: document is empty"},
+		{"over-indented key", "t.yaml", strings.Replace(site, "    y: 0", "      y: 0", 1), "t.yaml:3: unexpected indentation"},
+		{"tab indentation", "t.yaml", strings.Replace(site, "    y: 0", "\ty: 0", 1), "t.yaml:3: tabs are not allowed for indentation"},
+		{"list item in a mapping", "t.yaml", "gnodebs:\n  - x: 0\n    - y: 0\n", "t.yaml:3: list item where a key was expected"},
+		{"not a key", "t.yaml", strings.Replace(site, "    y: 0", "    y 0", 1), `t.yaml:3: expected "key: value", got "y 0"`},
+		{"empty list item", "t.yaml", "gnodebs:\n  -\n", "t.yaml:2: empty list item"},
+		{"unterminated inline list", "t.yaml", strings.Replace(site, "    tac: 1", "    rna: [1", 1), "t.yaml:6: unterminated inline list"},
+		{"unterminated string", "t.yaml", strings.Replace(site, "    tac: 1", "    allowedIMSIsFile: \"a.txt", 1), "t.yaml:6: unterminated quoted string"},
+		{"inline mapping", "t.yaml", "origin: {lat: 1, lon: 2}\n", "t.yaml:1: inline mappings are not supported, use an indented block"},
+		{"duplicate yaml key", "t.yaml", strings.Replace(site, "    tac: 1", "    tac: 1\n    tac: 2", 1), `t.yaml:7: duplicate key "tac"`},
+		{"duplicate json key", "t.json", "{\n  \"gnodebs\": [],\n  \"gnodebs\": []\n}", `t.json:3: duplicate key "gnodebs"`},
+		{"json syntax", "t.json", "{\n  \"gnodebs\": [\n    {\"x\": 0 \"y\": 0}\n  ]\n}", "t.json:3: invalid character '\"' after object key:value pair"},
+		{"json truncated", "t.json", "{\n  \"gnodebs\": [\n", "t.json:3: unexpected end of JSON input"},
+		{"json trailing data", "t.json", "{\"gnodebs\": []}\n{}", "t.json:2: unexpected data after the top-level value"},
+
+		// Types
+		{"quoted number", "t.yaml", strings.Replace(site, "range: 500", `range: "500"`, 1), `t.yaml:4: gnodebs[0].range must be a number, got "500"`},
+		{"word for a number", "t.yaml", strings.Replace(site, "height: 25", "height: tall", 1), `t.yaml:5: gnodebs[0].height must be a number, got "tall"`},
+		{"fraction for an integer", "t.yaml", strings.Replace(site, "tac: 1", "tac: 1.5", 1), `t.yaml:6: gnodebs[0].tac must be an integer, got "1.5"`},
+		{"word for a bool", "t.yaml", strings.Replace(site, "tac: 1", "useInactive: yes", 1), `t.yaml:6: gnodebs[0].useInactive must be true or false, got "yes"`},
+		{"scalar for a list", "t.yaml", strings.Replace(site, "tac: 1", "rna: 2", 1), "t.yaml:6: gnodebs[0].rna must be a list, got a value"},
+		{"list for a scalar", "t.yaml", strings.Replace(site, "tac: 1", "tac: [1]", 1), "t.yaml:6: gnodebs[0].tac must be a value, got a list"},
+		{"scalar for a mapping", "t.yaml", "gnodebs:\n  - 5\n", "t.yaml:2: gnodebs[0] must be a mapping, got a value"},
+		{"json wrong type", "t.json", "{\n  \"gnodebs\": [\n    {\"x\": 0, \"y\": 0, \"range\": \"far\", \"cells\": []}\n  ]\n}", `t.json:3: gnodebs[0].range must be a number, got "far"`},
+		{"unknown field", "t.yaml", strings.Replace(site, "tac: 1", "tax: 1", 1), `t.yaml:6: unknown field "tax" in gnodebs[0]`},
+
+		// Required and exclusive fields
+		{"missing range", "t.yaml", strings.Replace(site, "    range: 500\n", "", 1), `t.yaml:2: gnodebs[0]: missing required field "range"`},
+		{"missing cells", "t.yaml", "gnodebs:\n  - x: 0\n    y: 0\n    range: 500\n", `t.yaml:2: gnodebs[0]: missing required field "cells"`},
+		{"missing pci", "t.yaml", strings.Replace(site, "pci: 1", "azimuth: 0", 1), `t.yaml:9: gnodebs[0].cells[0]: missing required field "pci"`},
+		{"x,y and lat,lon", "t.yaml", strings.Replace(site, "tac: 1", "lat: 52", 1), "t.yaml:2: gnodebs[0]: give x,y or lat,lon, not both"},
+		{"lat without lon", "t.yaml", strings.Replace(site, "x: 0\n    y: 0", "lat: 52", 1), "t.yaml:2: gnodebs[0]: needs both lat and lon"},
+		{"lat out of range", "t.yaml", strings.Replace(site, "x: 0\n    y: 0", "lat: 95\n    lon: 0", 1), "t.yaml:2: gnodebs[0]: lat must be -90..90 and lon -180..180, got 95.000000,0.000000"},
+		{"origin without lon", "t.yaml", "origin:\n  lat: 52\n" + site, "t.yaml:2: origin: needs lat and lon"},
+
+		// Validation
+		{"no gNodeBs", "t.yaml", "gnodebs: []\n", "t.yaml: topology has no gNodeBs"},
+		{"diffraction without buildings", "t.yaml", "diffraction: true\n" + site, "t.yaml: diffraction needs a buildings file"},
+		{"range", "t.yaml", strings.Replace(site, "range: 500", "range: 0", 1), "t.yaml:2: gnodebs[0]: range must be > 0, got 0"},
+		{"height", "t.yaml", strings.Replace(site, "height: 25", "height: -1", 1), "t.yaml:2: gnodebs[0]: height must be >= 0, got -1"},
+		{"tac", "t.yaml", strings.Replace(site, "tac: 1", "tac: 16777216", 1), "t.yaml:2: gnodebs[0]: tac must fit in 24 bits, got 16777216"},
+		{"inactivity timer", "t.yaml", strings.Replace(site, "inactivityTimer: 10", "inactivityTimer: -1", 1), "t.yaml:2: gnodebs[0]: inactivityTimer must be >= 0"},
+		{"no cells", "t.yaml", "gnodebs:\n  - x: 0\n    y: 0\n    range: 500\n    cells: []\n", "t.yaml:2: gnodebs[0]: needs at least one cell"},
+		{"too far from the origin", "t.yaml", "origin:\n  lat: 0\n  lon: 0\n" + strings.Replace(site, "x: 0\n    y: 0", "lat: 0\n    lon: 2", 1),
+			"t.yaml:5: gnodebs[0]: 0.000000,2.000000 is 222 km from the origin 0.000000,0.000000, more than the 100 km the projection is accurate for"},
+		{"allow-list and file", "t.yaml", strings.Replace(site, "tac: 1", "allowedIMSIs: ['1']\n    allowedIMSIsFile: a.txt", 1), "t.yaml:2: gnodebs[0]: set either allowedIMSIs or allowedIMSIsFile, not both"},
+		{"IMSI not digits", "t.yaml", strings.Replace(site, "tac: 1", "allowedIMSIs: [12a]", 1), `t.yaml:2: gnodebs[0]: IMSI "12a" must only contain digits`},
+		{"slice sst", "t.yaml", strings.Replace(site, "tac: 1", "slices:\n      - sst: 0", 1), "t.yaml:2: gnodebs[0]: slice sst must be 1-255, got 0"},
+		{"slice sd", "t.yaml", strings.Replace(site, "tac: 1", "slices:\n      - sst: 1\n        sd: xyz", 1), `t.yaml:2: gnodebs[0]: slice sd must be 6 hex digits, got "xyz"`},
+		{"rna", "t.yaml", strings.Replace(site, "tac: 1", "rna: [2]", 1), "t.yaml:2: gnodebs[0]: rna refers to gNodeB 2, topology has 1"},
+		{"pci", "t.yaml", strings.Replace(site, "pci: 1", "pci: 1008", 1), "t.yaml:9: gnodebs[0].cells[0]: pci must be 0-1007, got 1008"},
+		{"duplicate pci", "t.yaml", site + "      - pci: 1\n        maxCap: 10\n", "t.yaml:15: gnodebs[0].cells[1]: pci 1 is already used by another cell of this gNodeB"},
+		{"maxCap", "t.yaml", strings.Replace(site, "maxCap: 10", "maxCap: 0", 1), "t.yaml:9: gnodebs[0].cells[0]: maxCap must be >= 1, got 0"},
+		{"beamwidth", "t.yaml", strings.Replace(site, "beamwidth: 120", "beamwidth: 400", 1), "t.yaml:9: gnodebs[0].cells[0]: beamwidth must be 0-360, got 400"},
+		{"frequency", "t.yaml", strings.Replace(site, "frequencyGHz: 3.5", "frequencyGHz: 0", 1), "t.yaml:9: gnodebs[0].cells[0]: frequencyGHz must be > 0"},
+		{"numerology", "t.yaml", strings.Replace(site, "numerology: 1", "numerology: 5", 1), "t.yaml:9: gnodebs[0].cells[0]: numerology must be 0-4, got 5"},
+		{"bandwidth", "t.yaml", strings.Replace(site, "bandwidthMHz: 100", "bandwidthMHz: 0.2", 1), "t.yaml:9: gnodebs[0].cells[0]: bandwidthMHz 0.2 is too narrow for numerology 1"},
+		{"cell allow-list", "t.yaml", site + "        allowedIMSIs: ['']\n", `t.yaml:9: gnodebs[0].cells[0]: IMSI "" must only contain digits`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.file, []byte(tt.data))
+			if err == nil {
+				t.Fatalf("got no error, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got  %q\nwant %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/geo"
+)
+
+// Scenario actions
+const (
+	ActionAttach           = "attach"           // connect to the best cell (or gnodeb) and register
+	ActionDetach           = "detach"           // deregister and release the radio connection
+	ActionMove             = "move"             // move to x,y, linearly over duration if set
+	ActionEstablishSession = "establishSession" // sessionType
+	ActionTerminateSession = "terminateSession" // sessionType
+	ActionUplinkData       = "uplinkData"
+	ActionDownlinkData     = "downlinkData"
+	ActionUpdateSubscriber = "updateSubscriber" // status and/or maxDataRate
+	ActionFailGNodeB       = "failGNodeB"       // gnodeb
+	ActionRestoreGNodeB    = "restoreGNodeB"    // gnodeb
+	ActionExpect           = "expect"           // assertions on ue (and/or gnodeb)
+)
+
+// Mobility models a scenario UE can follow
+const (
+	MobilityStatic         = "static"         // stays put
+	MobilityRandomWalk     = "randomWalk"     // new random direction and speed every interval
+	MobilityRandomWaypoint = "randomWaypoint" // straight to a random point, pause, repeat
+	MobilityGaussMarkov    = "gaussMarkov"    // speed and heading drift smoothly (alpha = memory)
+	MobilityManhattan      = "manhattan"      // along a street grid of block metres
+	MobilityTrace          = "trace"          // replays positions from a CSV file
+)
+
+var mobilityModels = map[string]bool{
+	MobilityStatic: true, MobilityRandomWalk: true, MobilityRandomWaypoint: true,
+	MobilityGaussMarkov: true, MobilityManhattan: true, MobilityTrace: true,
+}
+
+// Mobility profiles give speed defaults in m/s
+var mobilityProfiles = map[string][2]float64{
+	"pedestrian": {0.5, 2},
+	"cyclist":    {3, 8},
+	"vehicle":    {8, 20},
+}
+
+var scenarioActions = map[string]bool{
+	ActionAttach: true, ActionDetach: true, ActionMove: true, ActionEstablishSession: true,
+	ActionTerminateSession: true, ActionUplinkData: true, ActionDownlinkData: true,
+	ActionUpdateSubscriber: true, ActionFailGNodeB: true, ActionRestoreGNodeB: true, ActionExpect: true,
+}
+
+// Scenario is a timeline of UE and network events with assertions
+type Scenario struct {
+	Name         string
+	Topology     string // path, relative to the scenario file; "" = caller decides
+	Subscribers  string // path, relative to the scenario file; "" = caller decides
+	Seed         *int64 // random seed; nil = caller decides
+	UEs          []ScenarioUE
+	UEsFile      string // .geojson or .csv of more UEs (see LoadUEs), relative to the scenario file
+	Events       []Event
+	MobilityTick time.Duration // how often mobility models move their UEs; 0 = every second
+	Traffic      bool          // sessions carry traffic of their type (see package traffic)
+	file         string
+}
+
+// ScenarioUE declares a UE and where it starts
+type ScenarioUE struct {
+	IMSI     string
+	X, Y     float64
+	Position *geo.LatLon // lat/lon instead of x,y; needs a geographic topology
+	Mobility *Mobility   // nil = the UE only moves on move events
+	Line     int
+}
+
+// Mobility describes how a UE moves on its own. Which fields matter depends on Model;
+// speeds left at 0 come from Profile (pedestrian when not set).
+type Mobility struct {
+	Model    string
+	Profile  string        // pedestrian, cyclist or vehicle
+	MinSpeed float64       // m/s
+	MaxSpeed float64       // m/s
+	Pause    time.Duration // randomWaypoint: wait at each waypoint
+	Interval time.Duration // randomWalk, gaussMarkov: how often direction and speed change
+	Alpha    float64       // gaussMarkov: 0 = memoryless, 1 = straight line; default 0.75
+	Block    float64       // manhattan: street spacing in metres; default 50
+	Trace    string        // trace: CSV file of time,imsi,x,y rows, relative to the scenario file
+	Line     int
+}
+
+// Speeds returns the speed range, filled in from the profile where not set
+func (m *Mobility) Speeds() (min, max float64) {
+	profile := mobilityProfiles[m.Profile]
+	if m.Profile == "" {
+		profile = mobilityProfiles["pedestrian"]
+	}
+	min, max = m.MinSpeed, m.MaxSpeed
+	if min == 0 {
+		min = profile[0]
+	}
+	if max == 0 {
+		max = math.Max(profile[1], min)
+	}
+	return min, max
+}
+
+// Event is one timed step of a scenario. Which fields matter depends on Action.
+type Event struct {
+	At          time.Duration
+	Action      string
+	UE          string
+	GNodeB      int // position in the topology file, 1 = first; 0 = not set
+	X, Y        float64
+	Position    *geo.LatLon   // move: lat/lon instead of x,y
+	Duration    time.Duration // move: spread the movement over this long
+	SessionType string
+	Status      string
+	MaxDataRate int
+	ExpectError bool // the action must fail
+	Expect      Expectation
+	Line        int
+}
+
+// Expectation lists what an expect event checks; nil fields are not checked
+type Expectation struct {
+	ServingGNodeB     *int // position in the topology, 0 = not served
+	ServingCell       *int
+	State             *string // RRC state: connected, idle, inactive, disconnected
+	CMState           *string // CM-IDLE or CM-CONNECTED
+	Registered        *bool
+	Sessions          *int
+	MinThroughputMbps *float64
+	MaxPacketLoss     *float64 // lost over sent packets of the UE's sessions, 0-1; needs traffic
+	MaxDelayMs        *float64 // mean delay of the UE's sessions; needs traffic
+	MaxStalls         *int     // video stalls of the UE's sessions; needs traffic
+	LOS               *bool    // line of sight to the serving cell; needs buildings
+	Building          *string  // name of the building the UE is in, "" = outdoors
+	GNodeBDown        *bool    // with gnodeb
+}
+
+// File returns the scenario's source path
+func (s *Scenario) File() string {
+	return s.file
+}
+
+// Resolve returns a path from the scenario file relative to the scenario's directory
+func (s *Scenario) Resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(s.file), path)
+}
+
+// LoadScenario reads a scenario file (.json, .yaml or .yml)
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	return ParseScenario(path, data)
+}
+
+// ParseScenario decodes and validates a scenario; name is used for the format and in error messages
+func ParseScenario(name string, data []byte) (*Scenario, error) {
+	root, err := parseDocument(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{file: name}
+	s := &Scenario{file: name}
+	err = d.fields(root, "scenario", map[string]func(*node) error{
+		"name":        func(n *node) error { return d.string(n, "name", &s.Name) },
+		"topology":    func(n *node) error { return d.string(n, "topology", &s.Topology) },
+		"subscribers": func(n *node) error { return d.string(n, "subscribers", &s.Subscribers) },
+		"seed": func(n *node) error {
+			var seed int
+			err := d.int(n, "seed", &seed)
+			s.Seed = new(int64)
+			*s.Seed = int64(seed)
+			return err
+		},
+		"mobilityTick": func(n *node) error { return d.duration(n, "mobilityTick", &s.MobilityTick) },
+		"traffic":      func(n *node) error { return d.bool(n, "traffic", &s.Traffic) },
+		"uesFile":      func(n *node) error { return d.string(n, "uesFile", &s.UEsFile) },
+		"ues": func(n *node) error {
+			return d.list(n, "ues", func(i int, item *node) error {
+				u, err := d.scenarioUE(item, fmt.Sprintf("ues[%d]", i))
+				s.UEs = append(s.UEs, u)
+				return err
+			})
+		},
+		"events": func(n *node) error {
+			return d.list(n, "events", func(i int, item *node) error {
+				e, err := d.event(item, fmt.Sprintf("events[%d]", i))
+				s.Events = append(s.Events, e)
+				return err
+			})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.UEsFile != "" {
+		ues, err := LoadUEs(s.Resolve(s.UEsFile))
+		if err != nil {
+			return nil, err
+		}
+		s.UEs = append(s.UEs, ues...)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (d *decoder) scenarioUE(n *node, what string) (ScenarioUE, error) {
+	u := ScenarioUE{Line: n.line}
+	planar := false
+	err := d.fields(n, what, map[string]func(*node) error{
+		"imsi": func(v *node) error { return d.string(v, what+".imsi", &u.IMSI) },
+		"x":    func(v *node) error { planar = true; return d.float(v, what+".x", &u.X) },
+		"y":    func(v *node) error { planar = true; return d.float(v, what+".y", &u.Y) },
+		"lat":  func(v *node) error { return d.coordinate(v, what, "lat", &u.Position) },
+		"lon":  func(v *node) error { return d.coordinate(v, what, "lon", &u.Position) },
+		"mobility": func(v *node) error {
+			m, err := d.mobility(v, what+".mobility")
+			u.Mobility = &m
+			return err
+		},
+	})
+	if err == nil && planar && u.Position != nil {
+		return u, d.errorf(n, "%s: give x,y or lat,lon, not both", what)
+	}
+	return u, err
+}
+
+// coordinate decodes the lat or lon (key) of *pos, creating it on first use. The other
+// half starts as NaN so a position given only one of them fails validation.
+func (d *decoder) coordinate(n *node, what, key string, pos **geo.LatLon) error {
+	if *pos == nil {
+		*pos = &geo.LatLon{Lat: math.NaN(), Lon: math.NaN()}
+	}
+	if key == "lat" {
+		return d.float(n, what+".lat", &(*pos).Lat)
+	}
+	return d.float(n, what+".lon", &(*pos).Lon)
+}
+
+func (d *decoder) mobility(n *node, what string) (Mobility, error) {
+	m := Mobility{Line: n.line}
+	speed := 0.0
+	err := d.fields(n, what, map[string]func(*node) error{
+		"model":    func(v *node) error { return d.string(v, what+".model", &m.Model) },
+		"profile":  func(v *node) error { return d.string(v, what+".profile", &m.Profile) },
+		"speed":    func(v *node) error { return d.float(v, what+".speed", &speed) },
+		"minSpeed": func(v *node) error { return d.float(v, what+".minSpeed", &m.MinSpeed) },
+		"maxSpeed": func(v *node) error { return d.float(v, what+".maxSpeed", &m.MaxSpeed) },
+		"pause":    func(v *node) error { return d.duration(v, what+".pause", &m.Pause) },
+		"interval": func(v *node) error { return d.duration(v, what+".interval", &m.Interval) },
+		"alpha":    func(v *node) error { return d.float(v, what+".alpha", &m.Alpha) },
+		"block":    func(v *node) error { return d.float(v, what+".block", &m.Block) },
+		"trace":    func(v *node) error { return d.string(v, what+".trace", &m.Trace) },
+	})
+	if speed != 0 { // a fixed speed is a range of one
+		m.MinSpeed, m.MaxSpeed = speed, speed
+	}
+	return m, err
+}
+
+// duration accepts seconds as a number (30, 0.5) or a Go duration string ("1m30s")
+func (d *decoder) duration(n *node, what string, dst *time.Duration) error {
+	if err := d.scalar(n, what); err != nil {
+		return err
+	}
+	if seconds, err := strconv.ParseFloat(n.value, 64); err == nil {
+		*dst = time.Duration(seconds * float64(time.Second))
+		return nil
+	}
+	v, err := time.ParseDuration(n.value)
+	if err != nil {
+		return d.errorf(n, "%s must be seconds or a duration like \"1m30s\", got %q", what, n.value)
+	}
+	*dst = v
+	return nil
+}
+
+func (d *decoder) event(n *node, what string) (Event, error) {
+	e := Event{Line: n.line}
+	ex := &e.Expect
+	planar := false
+	err := d.fields(n, what, map[string]func(*node) error{
+		"at":          func(v *node) error { return d.duration(v, what+".at", &e.At) },
+		"action":      func(v *node) error { return d.string(v, what+".action", &e.Action) },
+		"ue":          func(v *node) error { return d.string(v, what+".ue", &e.UE) },
+		"gnodeb":      func(v *node) error { return d.int(v, what+".gnodeb", &e.GNodeB) },
+		"x":           func(v *node) error { planar = true; return d.float(v, what+".x", &e.X) },
+		"y":           func(v *node) error { planar = true; return d.float(v, what+".y", &e.Y) },
+		"lat":         func(v *node) error { return d.coordinate(v, what, "lat", &e.Position) },
+		"lon":         func(v *node) error { return d.coordinate(v, what, "lon", &e.Position) },
+		"duration":    func(v *node) error { return d.duration(v, what+".duration", &e.Duration) },
+		"sessionType": func(v *node) error { return d.string(v, what+".sessionType", &e.SessionType) },
+		"status":      func(v *node) error { return d.string(v, what+".status", &e.Status) },
+		"maxDataRate": func(v *node) error { return d.int(v, what+".maxDataRate", &e.MaxDataRate) },
+		"expectError": func(v *node) error { return d.bool(v, what+".expectError", &e.ExpectError) },
+		"servingGNodeB": func(v *node) error {
+			ex.ServingGNodeB = new(int)
+			return d.int(v, what+".servingGNodeB", ex.ServingGNodeB)
+		},
+		"servingCell": func(v *node) error {
+			ex.ServingCell = new(int)
+			return d.int(v, what+".servingCell", ex.ServingCell)
+		},
+		"state": func(v *node) error {
+			ex.State = new(string)
+			return d.string(v, what+".state", ex.State)
+		},
+		"cmState": func(v *node) error {
+			ex.CMState = new(string)
+			return d.string(v, what+".cmState", ex.CMState)
+		},
+		"registered": func(v *node) error {
+			ex.Registered = new(bool)
+			return d.bool(v, what+".registered", ex.Registered)
+		},
+		"sessions": func(v *node) error {
+			ex.Sessions = new(int)
+			return d.int(v, what+".sessions", ex.Sessions)
+		},
+		"minThroughputMbps": func(v *node) error {
+			ex.MinThroughputMbps = new(float64)
+			return d.float(v, what+".minThroughputMbps", ex.MinThroughputMbps)
+		},
+		"maxPacketLoss": func(v *node) error {
+			ex.MaxPacketLoss = new(float64)
+			return d.float(v, what+".maxPacketLoss", ex.MaxPacketLoss)
+		},
+		"maxDelayMs": func(v *node) error {
+			ex.MaxDelayMs = new(float64)
+			return d.float(v, what+".maxDelayMs", ex.MaxDelayMs)
+		},
+		"maxStalls": func(v *node) error {
+			ex.MaxStalls = new(int)
+			return d.int(v, what+".maxStalls", ex.MaxStalls)
+		},
+		"los": func(v *node) error {
+			ex.LOS = new(bool)
+			return d.bool(v, what+".los", ex.LOS)
+		},
+		"building": func(v *node) error {
+			ex.Building = new(string)
+			return d.string(v, what+".building", ex.Building)
+		},
+		"down": func(v *node) error {
+			ex.GNodeBDown = new(bool)
+			return d.bool(v, what+".down", ex.GNodeBDown)
+		},
+	})
+	if err == nil && planar && e.Position != nil {
+		return e, d.errorf(n, "%s: give x,y or lat,lon, not both", what)
+	}
+	return e, err
+}
+
+// Validate checks every event refers to a declared UE and has what its action needs
+func (s *Scenario) Validate() error {
+	errorf := func(line int, format string, args ...interface{}) error {
+		return &Error{File: s.file, Line: line, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	declared := make(map[string]bool)
+	for i, u := range s.UEs {
+		if u.IMSI == "" {
+			return errorf(u.Line, "ues[%d]: missing imsi", i)
+		}
+		if declared[u.IMSI] {
+			return errorf(u.Line, "ues[%d]: UE %s declared twice", i, u.IMSI)
+		}
+		declared[u.IMSI] = true
+		if u.Position != nil && !u.Position.Valid() {
+			return errorf(u.Line, "ues[%d]: needs both lat (-90..90) and lon (-180..180)", i)
+		}
+		if m := u.Mobility; m != nil {
+			_, knownProfile := mobilityProfiles[m.Profile]
+			min, max := m.Speeds()
+			switch {
+			case !mobilityModels[m.Model]:
+				return errorf(m.Line, "ues[%d].mobility: unknown model %q", i, m.Model)
+			case m.Profile != "" && !knownProfile:
+				return errorf(m.Line, "ues[%d].mobility: unknown profile %q (pedestrian, cyclist or vehicle)", i, m.Profile)
+			case min < 0 || max < min:
+				return errorf(m.Line, "ues[%d].mobility: need 0 <= minSpeed <= maxSpeed", i)
+			case m.Alpha < 0 || m.Alpha > 1:
+				return errorf(m.Line, "ues[%d].mobility: alpha must be between 0 and 1", i)
+			case m.Block < 0 || m.Pause < 0 || m.Interval < 0:
+				return errorf(m.Line, "ues[%d].mobility: block, pause and interval must be >= 0", i)
+			case m.Model == MobilityTrace && m.Trace == "":
+				return errorf(m.Line, "ues[%d].mobility: the trace model needs a trace file", i)
+			}
+		}
+	}
+	if s.MobilityTick < 0 {
+		return errorf(0, "mobilityTick must be >= 0")
+	}
+
+	for i, e := range s.Events {
+		what := fmt.Sprintf("events[%d]", i)
+		needsUE := e.Action != ActionFailGNodeB && e.Action != ActionRestoreGNodeB && e.Action != ActionExpect
+		switch {
+		case e.Action == "":
+			return errorf(e.Line, "%s: missing action", what)
+		case !scenarioActions[e.Action]:
+			return errorf(e.Line, "%s: unknown action %q", what, e.Action)
+		case e.At < 0:
+			return errorf(e.Line, "%s: at must be >= 0", what)
+		case needsUE && e.UE == "":
+			return errorf(e.Line, "%s: %s needs a ue", what, e.Action)
+		case e.UE != "" && !declared[e.UE]:
+			return errorf(e.Line, "%s: UE %s is not declared in ues", what, e.UE)
+		case (e.Action == ActionFailGNodeB || e.Action == ActionRestoreGNodeB) && e.GNodeB < 1:
+			return errorf(e.Line, "%s: %s needs a gnodeb (position in the topology, 1 = first)", what, e.Action)
+		case (e.Action == ActionEstablishSession || e.Action == ActionTerminateSession) && e.SessionType == "":
+			return errorf(e.Line, "%s: %s needs a sessionType", what, e.Action)
+		case e.Action == ActionUpdateSubscriber && e.Status == "" && e.MaxDataRate == 0:
+			return errorf(e.Line, "%s: updateSubscriber needs a status or maxDataRate", what)
+		case e.Action == ActionExpect && e.UE == "" && e.GNodeB == 0:
+			return errorf(e.Line, "%s: expect needs a ue or a gnodeb", what)
+		case e.Duration < 0:
+			return errorf(e.Line, "%s: duration must be >= 0", what)
+		case e.Position != nil && !e.Position.Valid():
+			return errorf(e.Line, "%s: needs both lat (-90..90) and lon (-180..180)", what)
+		case e.Position != nil && e.Action != ActionMove:
+			return errorf(e.Line, "%s: lat/lon only apply to move", what)
+		case (e.Expect.MaxPacketLoss != nil || e.Expect.MaxDelayMs != nil || e.Expect.MaxStalls != nil) && !s.Traffic:
+			return errorf(e.Line, "%s: maxPacketLoss, maxDelayMs and maxStalls need traffic: true", what)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+)
+
+// Defaults for what planning tool exports usually leave out
+const (
+	DefaultSiteRange  = 1000.0 // m
+	DefaultSiteMaxCap = 100
+)
+
+// Site and UE files (.geojson or .csv) hold one record per cell or per UE: a GeoJSON
+// Point feature with the values as properties, or a CSV row with the values as
+// columns (header first, # starts a comment). Keys are the topology's: site, height,
+// range, tac, pci, azimuth, beamwidth, tilt, frequencyGHz, bandwidthMHz, numerology,
+// txPower (or power), maxCap; positions are lat/lon (x/y in metres in a CSV), and imsi
+// for UEs. Other keys are ignored, so exports from planning tools load as they are.
+// Cells with the same site (or, without one, the same position) form one gNodeB.
+
+// siteColumns are the keys site files understand; the rest are ignored
+var siteColumns = map[string]bool{
+	"site": true, "lat": true, "lon": true, "x": true, "y": true, "height": true, "range": true, "tac": true,
+	"pci": true, "azimuth": true, "beamwidth": true, "tilt": true, "frequencyGHz": true, "bandwidthMHz": true,
+	"numerology": true, "txPower": true, "power": true, "maxCap": true, "imsi": true,
+}
+
+// record is one feature or row: its known values by key, and where it came from
+type record struct {
+	values   map[string]*node
+	position *geo.LatLon // from a GeoJSON geometry
+	line     int
+}
+
+// isSiteFile reports whether a file holds records rather than a topology document
+func isSiteFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".geojson", ".csv":
+		return true
+	}
+	return false
+}
+
+// parseSites builds a topology from a site file, one gNodeB per site, in file order
+func parseSites(name string, data []byte) (*Topology, error) {
+	d := &decoder{file: name}
+	records, err := d.records(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, &Error{File: name, Msg: "no sites"}
+	}
+
+	t := &Topology{file: name, dir: "."}
+	bySite := make(map[string]int) // site key -> index in t.GNodeBs
+	for i, r := range records {
+		what := fmt.Sprintf("record %d", i+1)
+		x, y, pos, err := d.recordPosition(r, what)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%g,%g", x, y)
+		if pos != nil {
+			key = pos.String()
+		}
+		if v := r.values["site"]; v != nil {
+			key = "site " + v.value
+		}
+
+		index, exists := bySite[key]
+		if !exists {
+			index = len(t.GNodeBs)
+			bySite[key] = index
+			g := GNodeB{X: x, Y: y, Position: pos, HeightM: ran.DefaultHeightM, Range: DefaultSiteRange,
+				TAC: ran.DefaultTAC, InactivityTimer: ran.DefaultInactivityTimer, line: r.line}
+			err := d.recordFields(r, what, map[string]func(*node) error{
+				"height": func(v *node) error { return d.float(v, what+".height", &g.HeightM) },
+				"range":  func(v *node) error { return d.float(v, what+".range", &g.Range) },
+				"tac":    func(v *node) error { return d.int(v, what+".tac", &g.TAC) },
+			})
+			if err != nil {
+				return nil, err
+			}
+			t.GNodeBs = append(t.GNodeBs, g)
+		}
+		g := &t.GNodeBs[index]
+		moved := x != g.X || y != g.Y || (pos == nil) != (g.Position == nil) || (pos != nil && *pos != *g.Position)
+		if exists && moved {
+			return nil, &Error{File: name, Line: r.line, Msg: fmt.Sprintf("%s: %s is not where the site's first cell is (line %d)", what, key, g.line)}
+		}
+
+		c, err := d.recordCell(r, what, len(g.Cells))
+		if err != nil {
+			return nil, err
+		}
+		g.Cells = append(g.Cells, c)
+	}
+
+	t.locate()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// recordCell decodes the cell values of a record; pci defaults to the cell's index in its site
+func (d *decoder) recordCell(r record, what string, index int) (Cell, error) {
+	c := Cell{
+		PCI:          index,
+		BeamwidthDeg: radio.OmniBeamwidth,
+		TiltDeg:      ran.DefaultTiltDeg,
+		FrequencyGHz: ran.DefaultCarrier.FrequencyGHz,
+		BandwidthMHz: ran.DefaultCarrier.BandwidthMHz,
+		Numerology:   ran.DefaultCarrier.Numerology,
+		TxPowerDBm:   ran.DefaultTxPowerDBm,
+		MaxCap:       DefaultSiteMaxCap,
+		line:         r.line,
+	}
+	if r.values["azimuth"] != nil {
+		c.BeamwidthDeg = radio.DefaultSectorBeamwidth // a cell pointing somewhere is a sector
+	}
+	err := d.recordFields(r, what, map[string]func(*node) error{
+		"pci":          func(v *node) error { return d.int(v, what+".pci", &c.PCI) },
+		"azimuth":      func(v *node) error { return d.float(v, what+".azimuth", &c.AzimuthDeg) },
+		"beamwidth":    func(v *node) error { return d.float(v, what+".beamwidth", &c.BeamwidthDeg) },
+		"tilt":         func(v *node) error { return d.float(v, what+".tilt", &c.TiltDeg) },
+		"frequencyGHz": func(v *node) error { return d.float(v, what+".frequencyGHz", &c.FrequencyGHz) },
+		"bandwidthMHz": func(v *node) error { return d.float(v, what+".bandwidthMHz", &c.BandwidthMHz) },
+		"numerology":   func(v *node) error { return d.int(v, what+".numerology", &c.Numerology) },
+		"txPower":      func(v *node) error { return d.float(v, what+".txPower", &c.TxPowerDBm) },
+		"power":        func(v *node) error { return d.float(v, what+".power", &c.TxPowerDBm) },
+		"maxCap":       func(v *node) error { return d.int(v, what+".maxCap", &c.MaxCap) },
+	})
+	return c, err
+}
+
+// recordFields calls the handlers of the keys the record has, in a fixed order
+func (d *decoder) recordFields(r record, what string, handlers map[string]func(*node) error) error {
+	keys := make([]string, 0, len(handlers))
+	for key := range handlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if v := r.values[key]; v != nil {
+			if err := handlers[key](v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordPosition returns where a record is: lat/lon from the geometry or columns, or
+// x/y in metres. pos is nil for x/y.
+func (d *decoder) recordPosition(r record, what string) (x, y float64, pos *geo.LatLon, err error) {
+	v := r.values
+	switch {
+	case r.position != nil:
+		pos = r.position
+	case v["lat"] != nil && v["lon"] != nil:
+		pos = &geo.LatLon{}
+		if err = d.float(v["lat"], what+".lat", &pos.Lat); err == nil {
+			err = d.float(v["lon"], what+".lon", &pos.Lon)
+		}
+	case v["x"] != nil && v["y"] != nil:
+		if err = d.float(v["x"], what+".x", &x); err == nil {
+			err = d.float(v["y"], what+".y", &y)
+		}
+	default:
+		err = &Error{File: d.file, Line: r.line, Msg: what + ": needs a position (lat,lon or x,y)"}
+	}
+	if err == nil && pos != nil && !pos.Valid() {
+		err = &Error{File: d.file, Line: r.line, Msg: fmt.Sprintf("%s: lat must be -90..90 and lon -180..180, got %s", what, pos)}
+	}
+	return x, y, pos, err
+}
+
+// records reads the features of a GeoJSON file or the rows of a CSV file
+func (d *decoder) records(name string, data []byte) ([]record, error) {
+	if strings.ToLower(filepath.Ext(name)) == ".csv" {
+		return d.csvRecords(data)
+	}
+	root, err := parseJSON(data)
+	if cfgErr, ok := err.(*Error); ok {
+		cfgErr.File = name
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.geoJSONRecords(root)
+}
+
+func (d *decoder) csvRecords(data []byte) ([]record, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var header []string
+	var records []record
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			return nil, &Error{File: d.file, Line: parseErr.Line, Msg: parseErr.Err.Error()}
+		}
+		if err != nil {
+			return nil, &Error{File: d.file, Msg: err.Error()}
+		}
+		line, _ := r.FieldPos(0)
+		if header == nil {
+			header = row
+			continue
+		}
+		if len(row) != len(header) {
+			return nil, &Error{File: d.file, Line: line, Msg: fmt.Sprintf("%d columns, the header has %d", len(row), len(header))}
+		}
+		rec := record{values: make(map[string]*node), line: line}
+		for i, column := range header {
+			column = strings.TrimSpace(column)
+			if value := strings.TrimSpace(row[i]); siteColumns[column] && value != "" {
+				rec.values[column] = &node{kind: scalarNode, line: line, value: value}
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// geoJSONRecords reads the Point features of a FeatureCollection
+func (d *decoder) geoJSONRecords(root *node) ([]record, error) {
+	if root.kind != mapNode || root.fields["type"] == nil || root.fields["type"].value != "FeatureCollection" ||
+		root.fields["features"] == nil {
+		return nil, d.errorf(root, "not a GeoJSON FeatureCollection")
+	}
+	var records []record
+	err := d.list(root.fields["features"], "features", func(i int, feature *node) error {
+		what := fmt.Sprintf("features[%d]", i)
+		if feature.kind != mapNode || feature.fields["geometry"] == nil {
+			return d.errorf(feature, "%s: not a feature with a geometry", what)
+		}
+		geometry := feature.fields["geometry"]
+		if geometry.kind != mapNode || geometry.fields["type"] == nil || geometry.fields["type"].value != "Point" {
+			return d.errorf(geometry, "%s: only Point geometries are supported", what)
+		}
+
+		// GeoJSON coordinates are [lon, lat] or [lon, lat, alt]
+		var coordinates []float64
+		err := d.list(geometry.fields["coordinates"], what+".coordinates", func(j int, v *node) error {
+			var f float64
+			err := d.float(v, what+".coordinates", &f)
+			coordinates = append(coordinates, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(coordinates) < 2 || len(coordinates) > 3 {
+			return d.errorf(geometry, "%s: coordinates must be [lon, lat] or [lon, lat, alt]", what)
+		}
+		rec := record{values: make(map[string]*node), position: &geo.LatLon{Lon: coordinates[0], Lat: coordinates[1]}, line: feature.line}
+		if len(coordinates) == 3 {
+			rec.position.Alt = coordinates[2]
+		}
+		if props := feature.fields["properties"]; props != nil && props.kind == mapNode {
+			for _, key := range props.keys {
+				// null (an unquoted empty value) is left out, like an empty CSV column
+				if v := props.fields[key]; siteColumns[key] && v.kind == scalarNode && (v.value != "" || v.quoted) {
+					rec.values[key] = v
+				}
+			}
+		}
+		records = append(records, rec)
+		return nil
+	})
+	return records, err
+}
+
+// LoadUEs reads UE positions from a .geojson or .csv file: an imsi and a position per
+// record (see the site files above), in file order
+func LoadUEs(path string) ([]ScenarioUE, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UE file: %w", err)
+	}
+	if !isSiteFile(path) {
+		return nil, &Error{File: path, Msg: "unsupported format, use .geojson or .csv"}
+	}
+	d := &decoder{file: path}
+	records, err := d.records(path, data)
+	if err != nil {
+		return nil, err
+	}
+	var ues []ScenarioUE
+	for i, r := range records {
+		what := fmt.Sprintf("record %d", i+1)
+		u := ScenarioUE{Line: r.line}
+		if u.X, u.Y, u.Position, err = d.recordPosition(r, what); err != nil {
+			return nil, err
+		}
+		if r.values["imsi"] == nil {
+			return nil, &Error{File: path, Line: r.line, Msg: what + ": missing imsi"}
+		}
+		u.IMSI = r.values["imsi"].value
+		ues = append(ues, u)
+	}
+	return ues, nil
+}
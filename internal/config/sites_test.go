@@ -0,0 +1,173 @@
+package config
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+)
+
+// sitesCSV has a three-sector site and an omni site in x/y metres; the rejection
+// cases below edit one row of it, so the first site's rows are on lines 3 to 5
+const sitesCSV = `# exported from the planning tool
+site,x,y,azimuth,pci,maxCap,vendor
+A,0,0,0,1,20,acme
+A,0,0,120,2,20,acme
+A,0,0,240,3,20,acme
+B,500,0,,,,acme
+`
+
+// sitesGeoJSON has the same site twice, once with an altitude, and an unrelated property
+const sitesGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.35, 48.85]}, "properties": {"site": "P", "azimuth": 0, "range": 800}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.35, 48.85]}, "properties": {"site": "P", "azimuth": 180, "owner": "x"}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.36, 48.86, 35]}, "properties": {"tac": 7, "txPower": null}}
+  ]
+}`
+
+func TestParseSites(t *testing.T) {
+	// Step 1: CSV rows group into sites by name, and what the export leaves out
+	// takes the defaults
+	topo, err := Parse("sites.csv", []byte(sitesCSV))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(topo.GNodeBs) != 2 || len(topo.GNodeBs[0].Cells) != 3 || len(topo.GNodeBs[1].Cells) != 1 {
+		t.Fatalf("got %d gNodeBs, want 2 with 3 and 1 cells", len(topo.GNodeBs))
+	}
+	a, b := topo.GNodeBs[0], topo.GNodeBs[1]
+	if a.Range != DefaultSiteRange || a.HeightM != ran.DefaultHeightM || a.TAC != ran.DefaultTAC || a.Position != nil {
+		t.Errorf("site A is %+v, want the defaults in x/y", a)
+	}
+	if c := a.Cells[1]; c.PCI != 2 || c.AzimuthDeg != 120 || c.BeamwidthDeg != radio.DefaultSectorBeamwidth || c.MaxCap != 20 {
+		t.Errorf("site A cell 2 is %+v, want a sector at 120 with pci 2", c)
+	}
+	if c := b.Cells[0]; b.X != 500 || c.PCI != 0 || c.BeamwidthDeg != radio.OmniBeamwidth || c.MaxCap != DefaultSiteMaxCap {
+		t.Errorf("site B is at %g with cell %+v, want an omni cell with pci 0", b.X, c)
+	}
+
+	// Step 2: GeoJSON points group by site, or by position without one, and the
+	// origin is their centre
+	topo, err = Parse("sites.geojson", []byte(sitesGeoJSON))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(topo.GNodeBs) != 2 || len(topo.GNodeBs[0].Cells) != 2 {
+		t.Fatalf("got %d gNodeBs, want 2 with the first having 2 cells", len(topo.GNodeBs))
+	}
+	p, q := topo.GNodeBs[0], topo.GNodeBs[1]
+	if p.Range != 800 || p.Cells[1].AzimuthDeg != 180 || q.TAC != 7 || q.Position.Alt != 35 {
+		t.Errorf("sites are %+v and %+v", p, q)
+	}
+	if q.Cells[0].TxPowerDBm != ran.DefaultTxPowerDBm {
+		t.Errorf("null txPower gave %g, want the default", q.Cells[0].TxPowerDBm)
+	}
+	if o := topo.Origin; o == nil || math.Abs(o.Lat-48.855) > 1e-9 || math.Abs(o.Lon-2.355) > 1e-9 {
+		t.Errorf("origin %v, want 48.855,2.355", o)
+	}
+	// 0.005 degrees each way: 367 m east-west and 556 m north-south at 49N
+	if math.Abs(p.X+367) > 1 || math.Abs(q.X-367) > 1 || math.Abs(p.Y+556) > 1 || math.Abs(q.Y-556) > 1 {
+		t.Errorf("sites at %.1f,%.1f and %.1f,%.1f, want either side of the origin", p.X, p.Y, q.X, q.Y)
+	}
+}
+
+func TestParseSitesErrors(t *testing.T) {
+	feature := func(geometry, properties string) string {
+		return `{"type": "FeatureCollection", "features": [
+  {"type": "Feature", "geometry": ` + geometry + `, "properties": ` + properties + `}
+]}`
+	}
+	point := `{"type": "Point", "coordinates": [2.35, 48.85]}`
+	tests := []struct {
+		name, file, data string
+		want             string
+	}{
+		// CSV
+		{"csv header only", "s.csv", "site,x,y\n", "s.csv: no sites"},
+		{"csv short row", "s.csv", strings.Replace(sitesCSV, "A,0,0,120,2,20,acme", "A,0,0,120", 1), "s.csv:4: 4 columns, the header has 7"},
+		{"csv quote", "s.csv", strings.Replace(sitesCSV, "A,0,0,120,2,20,acme", `A,0,0,1"20,2,20,acme`, 1), "s.csv:4: bare \" in non-quoted-field"},
+		{"csv no position", "s.csv", "site,pci\nA,1\n", "s.csv:2: record 1: needs a position (lat,lon or x,y)"},
+		{"csv word for a number", "s.csv", strings.Replace(sitesCSV, "A,0,0,120,2,20", "A,0,0,east,2,20", 1), `s.csv:4: record 2.azimuth must be a number, got "east"`},
+		{"csv fraction for an integer", "s.csv", strings.Replace(sitesCSV, "A,0,0,120,2,20", "A,0,0,120,2,2.5", 1), `s.csv:4: record 2.maxCap must be an integer, got "2.5"`},
+		{"csv site moved", "s.csv", strings.Replace(sitesCSV, "A,0,0,240", "A,10,0,240", 1), "s.csv:5: record 3: site A is not where the site's first cell is (line 3)"},
+		{"csv lat out of range", "s.csv", "lat,lon\n91,0\n", "s.csv:2: record 1: lat must be -90..90 and lon -180..180, got 91.000000,0.000000"},
+		{"csv duplicate pci", "s.csv", strings.Replace(sitesCSV, "A,0,0,240,3", "A,0,0,240,2", 1), "s.csv:5: gnodebs[0].cells[2]: pci 2 is already used by another cell of this gNodeB"},
+		{"csv invalid range", "s.csv", "x,y,range\n0,0,-5\n", "s.csv:2: gnodebs[0]: range must be > 0, got -5"},
+
+		// GeoJSON
+		{"json syntax", "s.geojson", "{\"type\": \"FeatureCollection\",\n\"features\": [}", "s.geojson:2: invalid character '}' looking for beginning of value"},
+		{"not a collection", "s.geojson", `{"type": "Feature"}`, "s.geojson:1: not a GeoJSON FeatureCollection"},
+		{"no features", "s.geojson", `{"type": "FeatureCollection", "features": []}`, "s.geojson: no sites"},
+		{"features not a list", "s.geojson", `{"type": "FeatureCollection", "features": 1}`, "s.geojson:1: features must be a list, got a value"},
+		{"feature without geometry", "s.geojson", `{"type": "FeatureCollection", "features": [{"type": "Feature"}]}`, "s.geojson:1: features[0]: not a feature with a geometry"},
+		{"polygon", "s.geojson", feature(`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 1], [0, 0]]]}`, "{}"), "s.geojson:2: features[0]: only Point geometries are supported"},
+		{"one coordinate", "s.geojson", feature(`{"type": "Point", "coordinates": [2.35]}`, "{}"), "s.geojson:2: features[0]: coordinates must be [lon, lat] or [lon, lat, alt]"},
+		{"word for a coordinate", "s.geojson", feature(`{"type": "Point", "coordinates": [2.35, "north"]}`, "{}"), `s.geojson:2: features[0].coordinates must be a number, got "north"`},
+		{"lat and lon swapped", "s.geojson", feature(`{"type": "Point", "coordinates": [48.85, 182.35]}`, "{}"), "s.geojson:2: record 1: lat must be -90..90 and lon -180..180, got 182.350000,48.850000"},
+		{"string for a number", "s.geojson", feature(point, `{"range": "far"}`), `s.geojson:2: record 1.range must be a number, got "far"`},
+		{"invalid tac", "s.geojson", feature(point, `{"tac": 16777216}`), "s.geojson:2: gnodebs[0]: tac must fit in 24 bits, got 16777216"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.file, []byte(tt.data))
+			if err == nil {
+				t.Fatalf("got no error, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got  %q\nwant %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadUEs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	ues, err := LoadUEs(write("ues.csv", "imsi,x,y,speed\n001010000000001,10,20,3\n001010000000002,-5,0,\n"))
+	if err != nil {
+		t.Fatalf("LoadUEs: %v", err)
+	}
+	if len(ues) != 2 || ues[0].IMSI != "001010000000001" || ues[0].X != 10 || ues[0].Y != 20 || ues[1].Line != 3 {
+		t.Errorf("got %+v", ues)
+	}
+	ues, err = LoadUEs(write("ues.geojson", `{"type": "FeatureCollection", "features": [
+  {"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.35, 48.85]}, "properties": {"imsi": "001010000000003"}}
+]}`))
+	if err != nil {
+		t.Fatalf("LoadUEs: %v", err)
+	}
+	if len(ues) != 1 || ues[0].Position == nil || ues[0].Position.Lat != 48.85 || ues[0].IMSI != "001010000000003" {
+		t.Errorf("got %+v", ues)
+	}
+
+	for _, tt := range []struct{ name, file, data, want string }{
+		{"unsupported format", "ues.yaml", "imsi: 1\n", "ues.yaml: unsupported format, use .geojson or .csv"},
+		{"missing imsi", "ues.csv", "x,y\n1,2\n", "ues.csv:2: record 1: missing imsi"},
+		{"missing position", "ues.csv", "imsi,x\n001010000000001,2\n", "ues.csv:2: record 1: needs a position (lat,lon or x,y)"},
+		{"bad number", "ues.csv", "imsi,x,y\n001010000000001,2,up\n", `ues.csv:2: record 1.y must be a number, got "up"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := write(tt.file, tt.data)
+			_, err := LoadUEs(path)
+			if want := filepath.Join(dir, tt.want); err == nil || err.Error() != want {
+				t.Errorf("got %v, want %q", err, want)
+			}
+		})
+	}
+	if _, err := LoadUEs(filepath.Join(dir, "missing.csv")); err == nil || !strings.HasPrefix(err.Error(), "failed to read UE file: ") {
+		t.Errorf("missing file gave %v", err)
+	}
+}
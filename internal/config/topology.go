@@ -0,0 +1,486 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/buildings"
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+)
+
+// MaxProjectionRange (m) is how far from the origin a geographic site may be: further
+// out, the flat projection the simulator works on is no longer accurate
+const MaxProjectionRange = 100_000.0
+
+// Topology describes the radio network: every gNodeB and its cells
+type Topology struct {
+	GNodeBs []GNodeB
+	Origin  *geo.LatLon // where x,y = 0,0 lies; nil = abstract plane, or the centre of the lat/lon sites
+
+	// BuildingsFile is a GeoJSON file of building footprints (see LoadBuildings); links
+	// are traced through them. Diffraction models NLOS links as diffraction over the
+	// worst rooftop instead of with the NLOS path loss model.
+	BuildingsFile string
+	Diffraction   bool
+
+	file      string
+	dir       string         // allow-list and buildings files are resolved relative to the topology file
+	buildings *buildings.Map // loaded on first use
+}
+
+// GNodeB is one site of the topology
+type GNodeB struct {
+	X, Y             float64     // metres east and north of the origin
+	Position         *geo.LatLon // set when the file gives lat/lon; X and Y are then projected from it
+	HeightM          float64
+	Range            float64
+	TAC              int
+	Slices           []ran.Slice
+	RNA              []int // other gNodeBs by position in the file (1 = first)
+	UseInactive      bool
+	InactivityTimer  time.Duration
+	AllowedIMSIs     []string // nil = open access
+	AllowedIMSIsFile string
+	Cells            []Cell
+	line             int
+}
+
+// Cell is one sector of a gNodeB
+type Cell struct {
+	PCI              int
+	AzimuthDeg       float64
+	BeamwidthDeg     float64 // 360 = omnidirectional
+	TiltDeg          float64
+	FrequencyGHz     float64
+	BandwidthMHz     float64
+	Numerology       int
+	TxPowerDBm       float64
+	MaxCap           int
+	AllowedIMSIs     []string // nil = use the gNodeB's allow-list
+	AllowedIMSIsFile string
+	line             int
+}
+
+// Load reads a topology file. The format follows the extension: .json, .yaml/.yml for
+// the YAML subset described in parseYAML, or a .geojson/.csv site file (see sites.go).
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topology file: %w", err)
+	}
+	t, err := Parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+	t.dir = filepath.Dir(path)
+	return t, nil
+}
+
+// Parse decodes and validates a topology; name is used for the format and in error messages
+func Parse(name string, data []byte) (*Topology, error) {
+	if isSiteFile(name) {
+		return parseSites(name, data)
+	}
+	root, err := parseDocument(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{file: name}
+	t := &Topology{file: name, dir: "."}
+	err = d.fields(root, "topology", map[string]func(*node) error{
+		"origin": func(n *node) error {
+			origin, err := d.latLon(n, "origin")
+			t.Origin = &origin
+			return err
+		},
+		"buildings":   func(n *node) error { return d.string(n, "buildings", &t.BuildingsFile) },
+		"diffraction": func(n *node) error { return d.bool(n, "diffraction", &t.Diffraction) },
+		"gnodebs": func(n *node) error {
+			return d.list(n, "gnodebs", func(i int, item *node) error {
+				g, err := d.gNodeB(item, fmt.Sprintf("gnodebs[%d]", i))
+				t.GNodeBs = append(t.GNodeBs, g)
+				return err
+			})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.locate()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// locate projects lat/lon sites onto the plane. Without an origin in the file, the
+// centre of those sites becomes the origin.
+func (t *Topology) locate() {
+	var sites []*GNodeB
+	for i := range t.GNodeBs {
+		if t.GNodeBs[i].Position != nil {
+			sites = append(sites, &t.GNodeBs[i])
+		}
+	}
+	if len(sites) == 0 {
+		return
+	}
+	if t.Origin == nil {
+		var centre geo.LatLon
+		for _, g := range sites {
+			centre.Lat += g.Position.Lat / float64(len(sites))
+			centre.Lon += g.Position.Lon / float64(len(sites))
+		}
+		t.Origin = &centre
+	}
+	projection := t.Projection()
+	for _, g := range sites {
+		g.X, g.Y = projection.ToXY(*g.Position)
+	}
+}
+
+// Projection maps lat/lon to the topology's plane; nil if the topology is not geographic
+func (t *Topology) Projection() *geo.Projection {
+	if t.Origin == nil {
+		return nil
+	}
+	return geo.NewProjection(*t.Origin)
+}
+
+// latLon decodes {lat, lon, alt}; lat and lon are required
+func (d *decoder) latLon(n *node, what string) (geo.LatLon, error) {
+	var p geo.LatLon
+	seen := make(map[string]bool)
+	err := d.fields(n, what, map[string]func(*node) error{
+		"lat": func(v *node) error { seen["lat"] = true; return d.float(v, what+".lat", &p.Lat) },
+		"lon": func(v *node) error { seen["lon"] = true; return d.float(v, what+".lon", &p.Lon) },
+		"alt": func(v *node) error { return d.float(v, what+".alt", &p.Alt) },
+	})
+	if err != nil {
+		return p, err
+	}
+	if !seen["lat"] || !seen["lon"] {
+		return p, d.errorf(n, "%s: needs lat and lon", what)
+	}
+	if !p.Valid() {
+		return p, d.errorf(n, "%s: lat must be -90..90 and lon -180..180, got %s", what, p)
+	}
+	return p, nil
+}
+
+// parseDocument picks the parser from the file extension
+func parseDocument(name string, data []byte) (*node, error) {
+	var root *node
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		root, err = parseJSON(data)
+	case ".yaml", ".yml":
+		root, err = parseYAML(data)
+	default:
+		return nil, &Error{File: name, Msg: "unsupported format, use .json, .yaml or .yml"}
+	}
+	if cfgErr, ok := err.(*Error); ok {
+		cfgErr.File = name
+	}
+	return root, err
+}
+
+func (d *decoder) gNodeB(n *node, what string) (GNodeB, error) {
+	g := GNodeB{HeightM: ran.DefaultHeightM, TAC: ran.DefaultTAC, InactivityTimer: ran.DefaultInactivityTimer, line: n.line}
+	seen := make(map[string]bool)
+	track := func(key string, f func(*node) error) func(*node) error {
+		return func(v *node) error { seen[key] = true; return f(v) }
+	}
+
+	err := d.fields(n, what, map[string]func(*node) error{
+		"x": track("x", func(v *node) error { return d.float(v, what+".x", &g.X) }),
+		"y": track("y", func(v *node) error { return d.float(v, what+".y", &g.Y) }),
+		"lat": track("lat", func(v *node) error {
+			if g.Position == nil {
+				g.Position = &geo.LatLon{}
+			}
+			return d.float(v, what+".lat", &g.Position.Lat)
+		}),
+		"lon": track("lon", func(v *node) error {
+			if g.Position == nil {
+				g.Position = &geo.LatLon{}
+			}
+			return d.float(v, what+".lon", &g.Position.Lon)
+		}),
+		"height": func(v *node) error { return d.float(v, what+".height", &g.HeightM) },
+		"range":  track("range", func(v *node) error { return d.float(v, what+".range", &g.Range) }),
+		"tac":    func(v *node) error { return d.int(v, what+".tac", &g.TAC) },
+		"rna":    func(v *node) error { return d.ints(v, what+".rna", &g.RNA) },
+		"slices": func(v *node) error {
+			return d.list(v, what+".slices", func(i int, item *node) error {
+				var s ran.Slice
+				sliceWhat := fmt.Sprintf("%s.slices[%d]", what, i)
+				err := d.fields(item, sliceWhat, map[string]func(*node) error{
+					"sst": func(v *node) error { return d.int(v, sliceWhat+".sst", &s.SST) },
+					"sd":  func(v *node) error { return d.string(v, sliceWhat+".sd", &s.SD) },
+				})
+				g.Slices = append(g.Slices, s)
+				return err
+			})
+		},
+		"useInactive": func(v *node) error { return d.bool(v, what+".useInactive", &g.UseInactive) },
+		"inactivityTimer": func(v *node) error {
+			var seconds float64
+			err := d.float(v, what+".inactivityTimer", &seconds)
+			g.InactivityTimer = time.Duration(seconds * float64(time.Second))
+			return err
+		},
+		"allowedIMSIs":     func(v *node) error { return d.strings(v, what+".allowedIMSIs", &g.AllowedIMSIs) },
+		"allowedIMSIsFile": func(v *node) error { return d.string(v, what+".allowedIMSIsFile", &g.AllowedIMSIsFile) },
+		"cells": track("cells", func(v *node) error {
+			return d.list(v, what+".cells", func(i int, item *node) error {
+				c, err := d.cell(item, fmt.Sprintf("%s.cells[%d]", what, i))
+				g.Cells = append(g.Cells, c)
+				return err
+			})
+		}),
+	})
+	if err != nil {
+		return g, err
+	}
+	// A site is placed either on the plane or on the globe
+	switch {
+	case (seen["x"] || seen["y"]) && (seen["lat"] || seen["lon"]):
+		return g, d.errorf(n, "%s: give x,y or lat,lon, not both", what)
+	case seen["lat"] != seen["lon"]:
+		return g, d.errorf(n, "%s: needs both lat and lon", what)
+	case seen["lat"] && !g.Position.Valid():
+		return g, d.errorf(n, "%s: lat must be -90..90 and lon -180..180, got %s", what, g.Position)
+	case seen["lat"]:
+		seen["x"], seen["y"] = true, true
+	}
+	for _, key := range []string{"x", "y", "range", "cells"} {
+		if !seen[key] {
+			return g, d.errorf(n, "%s: missing required field %q", what, key)
+		}
+	}
+	return g, nil
+}
+
+func (d *decoder) cell(n *node, what string) (Cell, error) {
+	c := Cell{
+		BeamwidthDeg: radio.OmniBeamwidth,
+		TiltDeg:      ran.DefaultTiltDeg,
+		FrequencyGHz: ran.DefaultCarrier.FrequencyGHz,
+		BandwidthMHz: ran.DefaultCarrier.BandwidthMHz,
+		Numerology:   ran.DefaultCarrier.Numerology,
+		TxPowerDBm:   ran.DefaultTxPowerDBm,
+		line:         n.line,
+	}
+	seen := make(map[string]bool)
+	track := func(key string, f func(*node) error) func(*node) error {
+		return func(v *node) error { seen[key] = true; return f(v) }
+	}
+
+	err := d.fields(n, what, map[string]func(*node) error{
+		"pci":              track("pci", func(v *node) error { return d.int(v, what+".pci", &c.PCI) }),
+		"azimuth":          func(v *node) error { return d.float(v, what+".azimuth", &c.AzimuthDeg) },
+		"beamwidth":        func(v *node) error { return d.float(v, what+".beamwidth", &c.BeamwidthDeg) },
+		"tilt":             func(v *node) error { return d.float(v, what+".tilt", &c.TiltDeg) },
+		"frequencyGHz":     func(v *node) error { return d.float(v, what+".frequencyGHz", &c.FrequencyGHz) },
+		"bandwidthMHz":     func(v *node) error { return d.float(v, what+".bandwidthMHz", &c.BandwidthMHz) },
+		"numerology":       func(v *node) error { return d.int(v, what+".numerology", &c.Numerology) },
+		"txPower":          func(v *node) error { return d.float(v, what+".txPower", &c.TxPowerDBm) },
+		"maxCap":           track("maxCap", func(v *node) error { return d.int(v, what+".maxCap", &c.MaxCap) }),
+		"allowedIMSIs":     func(v *node) error { return d.strings(v, what+".allowedIMSIs", &c.AllowedIMSIs) },
+		"allowedIMSIsFile": func(v *node) error { return d.string(v, what+".allowedIMSIsFile", &c.AllowedIMSIsFile) },
+	})
+	if err != nil {
+		return c, err
+	}
+	for _, key := range []string{"pci", "maxCap"} {
+		if !seen[key] {
+			return c, d.errorf(n, "%s: missing required field %q", what, key)
+		}
+	}
+	return c, nil
+}
+
+// Validate checks the values make sense together. Errors point at the gNodeB or cell entry.
+func (t *Topology) Validate() error {
+	errorf := func(line int, format string, args ...interface{}) error {
+		return &Error{File: t.file, Line: line, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	if len(t.GNodeBs) == 0 {
+		return errorf(0, "topology has no gNodeBs")
+	}
+	if t.Diffraction && t.BuildingsFile == "" {
+		return errorf(0, "diffraction needs a buildings file")
+	}
+	for i, g := range t.GNodeBs {
+		what := fmt.Sprintf("gnodebs[%d]", i)
+		switch {
+		case g.Range <= 0:
+			return errorf(g.line, "%s: range must be > 0, got %g", what, g.Range)
+		case g.HeightM < 0:
+			return errorf(g.line, "%s: height must be >= 0, got %g", what, g.HeightM)
+		case g.TAC < 0 || g.TAC > 0xFFFFFF:
+			return errorf(g.line, "%s: tac must fit in 24 bits, got %d", what, g.TAC)
+		case g.InactivityTimer < 0:
+			return errorf(g.line, "%s: inactivityTimer must be >= 0", what)
+		case len(g.Cells) == 0:
+			return errorf(g.line, "%s: needs at least one cell", what)
+		case g.Position != nil && geo.Distance(*t.Origin, *g.Position) > MaxProjectionRange:
+			return errorf(g.line, "%s: %s is %.0f km from the origin %s, more than the %.0f km the projection is accurate for",
+				what, g.Position, geo.Distance(*t.Origin, *g.Position)/1000, t.Origin, MaxProjectionRange/1000)
+		}
+		if err := checkAllowList(g.AllowedIMSIs, g.AllowedIMSIsFile); err != "" {
+			return errorf(g.line, "%s: %s", what, err)
+		}
+		for _, s := range g.Slices {
+			if s.SST < 1 || s.SST > 255 {
+				return errorf(g.line, "%s: slice sst must be 1-255, got %d", what, s.SST)
+			}
+			if s.SD != "" && (len(s.SD) != 6 || strings.Trim(strings.ToLower(s.SD), "0123456789abcdef") != "") {
+				return errorf(g.line, "%s: slice sd must be 6 hex digits, got %q", what, s.SD)
+			}
+		}
+		for _, id := range g.RNA {
+			if id < 1 || id > len(t.GNodeBs) {
+				return errorf(g.line, "%s: rna refers to gNodeB %d, topology has %d", what, id, len(t.GNodeBs))
+			}
+		}
+
+		pcis := make(map[int]bool)
+		for j, c := range g.Cells {
+			cellWhat := fmt.Sprintf("%s.cells[%d]", what, j)
+			carrier := radio.Carrier{FrequencyGHz: c.FrequencyGHz, BandwidthMHz: c.BandwidthMHz, Numerology: c.Numerology}
+			switch {
+			case c.PCI < 0 || c.PCI > 1007:
+				return errorf(c.line, "%s: pci must be 0-1007, got %d", cellWhat, c.PCI)
+			case pcis[c.PCI]:
+				return errorf(c.line, "%s: pci %d is already used by another cell of this gNodeB", cellWhat, c.PCI)
+			case c.MaxCap < 1:
+				return errorf(c.line, "%s: maxCap must be >= 1, got %d", cellWhat, c.MaxCap)
+			case c.BeamwidthDeg < 0 || c.BeamwidthDeg > 360:
+				return errorf(c.line, "%s: beamwidth must be 0-360, got %g", cellWhat, c.BeamwidthDeg)
+			case c.FrequencyGHz <= 0:
+				return errorf(c.line, "%s: frequencyGHz must be > 0", cellWhat)
+			case c.Numerology < 0 || c.Numerology > 4:
+				return errorf(c.line, "%s: numerology must be 0-4, got %d", cellWhat, c.Numerology)
+			case carrier.PRBs() < 1:
+				return errorf(c.line, "%s: bandwidthMHz %g is too narrow for numerology %d", cellWhat, c.BandwidthMHz, c.Numerology)
+			}
+			if err := checkAllowList(c.AllowedIMSIs, c.AllowedIMSIsFile); err != "" {
+				return errorf(c.line, "%s: %s", cellWhat, err)
+			}
+			pcis[c.PCI] = true
+		}
+	}
+	return nil
+}
+
+// checkAllowList returns a description of what is wrong with an allow-list, or ""
+func checkAllowList(imsis []string, file string) string {
+	if imsis != nil && file != "" {
+		return "set either allowedIMSIs or allowedIMSIsFile, not both"
+	}
+	for _, imsi := range imsis {
+		if imsi == "" || strings.Trim(imsi, "0123456789") != "" {
+			return fmt.Sprintf("IMSI %q must only contain digits", imsi)
+		}
+	}
+	return ""
+}
+
+// Build creates the gNodeBs, in file order
+func (t *Topology) Build() ([]*ran.GNodeB, error) {
+	var gnbs []*ran.GNodeB
+	for i, g := range t.GNodeBs {
+		var cells []ran.CellConfig
+		for _, c := range g.Cells {
+			allowed, err := t.allowList(c.AllowedIMSIs, c.AllowedIMSIsFile)
+			if err != nil {
+				return nil, err
+			}
+			cells = append(cells, ran.CellConfig{
+				PCI:          c.PCI,
+				Antenna:      radio.AntennaPattern{AzimuthDeg: c.AzimuthDeg, BeamwidthDeg: c.BeamwidthDeg, TiltDeg: c.TiltDeg},
+				Carrier:      radio.Carrier{FrequencyGHz: c.FrequencyGHz, BandwidthMHz: c.BandwidthMHz, Numerology: c.Numerology},
+				TxPowerDBm:   c.TxPowerDBm,
+				MaxCap:       c.MaxCap,
+				AllowedIMSIs: allowed,
+			})
+		}
+
+		gnb, err := ran.NewSectorGNodeB(g.X, g.Y, g.Range, cells)
+		if err != nil {
+			return nil, fmt.Errorf("gnodebs[%d]: %w", i, err)
+		}
+		gnb.HeightM = g.HeightM
+		gnb.TAC = g.TAC
+		gnb.Slices = g.Slices
+		gnb.RRC = ran.RRCConfig{InactivityTimer: g.InactivityTimer, UseInactive: g.UseInactive}
+		if gnb.AllowedIMSIs, err = t.allowList(g.AllowedIMSIs, g.AllowedIMSIsFile); err != nil {
+			return nil, err
+		}
+		gnbs = append(gnbs, gnb)
+	}
+
+	// Every site traces its links through the same buildings
+	environment, err := t.Buildings()
+	if err != nil {
+		return nil, err
+	}
+	if environment != nil {
+		for _, gnb := range gnbs {
+			gnb.Propagation = environment
+		}
+	}
+
+	// RNA entries are positions in the file, translate them to the IDs just assigned
+	for i, g := range t.GNodeBs {
+		for _, pos := range g.RNA {
+			gnbs[i].RRC.RNA = append(gnbs[i].RRC.RNA, gnbs[pos-1].ID)
+		}
+	}
+	return gnbs, nil
+}
+
+// Buildings loads the buildings file the first time it is asked for; nil without one
+func (t *Topology) Buildings() (*buildings.Map, error) {
+	if t.BuildingsFile == "" || t.buildings != nil {
+		return t.buildings, nil
+	}
+	file := t.BuildingsFile
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(t.dir, file)
+	}
+	found, err := LoadBuildings(file, t.Projection())
+	if err != nil {
+		return nil, err
+	}
+	t.buildings = buildings.NewMap(found, t.Diffraction)
+	return t.buildings, nil
+}
+
+// allowList turns an inline list or a file (relative to the topology) into a lookup map
+func (t *Topology) allowList(imsis []string, file string) (map[string]bool, error) {
+	if file != "" {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(t.dir, file)
+		}
+		return ran.LoadAllowedIMSIs(file)
+	}
+	if imsis == nil {
+		return nil, nil
+	}
+	allowed := make(map[string]bool)
+	for _, imsi := range imsis {
+		allowed[imsi] = true
+	}
+	return allowed, nil
+}
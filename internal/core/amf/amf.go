@@ -1,32 +1,104 @@
 package amf
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/radio"
 	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/sim"
 	"github.com/rizpur/NetSim5G/internal/ue"
 	"github.com/rizpur/NetSim5G/internal/utils"
 )
 
+// Errors the AMF procedures return or wrap, to tell failures apart with errors.Is
+var (
+	ErrNotRegistered        = errors.New("not registered") // as in "UE 001... is not registered"
+	ErrNoCoverage           = errors.New("no gNodeB in range of this UE")
+	ErrSubscriptionInactive = errors.New("subscription is not active")
+	ErrUnreachable          = errors.New("unreachable")
+	ErrGNodeBNotFound       = errors.New("not found") // as in "gNodeB 3 not found"
+	ErrGNodeBInUse          = errors.New("in use")    // still serving UEs, so it cannot be removed
+)
+
+// Default A3 offset: a neighbour cell must be this much stronger than the serving one
+const DefaultHandoverHysteresisDB = 3.0
+
+// CMState is the NAS connection management state of a registered UE. A UE in
+// RRC_INACTIVE keeps its N2 connection, so the AMF still sees it as CM-CONNECTED.
+type CMState int
+
+const (
+	CMIdle CMState = iota
+	CMConnected
+)
+
+func (c CMState) String() string {
+	return [...]string{"CM-IDLE", "CM-CONNECTED"}[c]
+}
+
 type RegisteredUE struct {
 	IMSI      string
-	GNodeBID  int
+	GNodeBID  int // serving gNodeB, or anchor while RRC_INACTIVE
+	CellID    int // serving cell within GNodeBID
+	TAC       int // tracking area the UE was last seen in, paged here when CM-IDLE
+	CMState   CMState
 	Timestamp time.Time
 }
 
+// HandoverType distinguishes sector changes from moves between gNodeBs
+type HandoverType int
+
+const (
+	IntraGNodeB HandoverType = iota // between sectors of the same gNodeB
+	InterGNodeB
+)
+
+func (h HandoverType) String() string {
+	return [...]string{"intra-gNodeB", "inter-gNodeB"}[h]
+}
+
+// HandoverRecord is one completed handover
+type HandoverRecord struct {
+	IMSI         string
+	Type         HandoverType
+	FromGNodeBID int
+	FromCellID   int
+	ToGNodeBID   int
+	ToCellID     int
+	Timestamp    time.Time
+}
+
 type AMF struct {
-	RegisteredUEs map[string]*RegisteredUE // key = IMSI
-	ActiveGNodeBs map[int]*ran.GNodeB
-	udm           *udm.UDM // for subscriber validation
+	RegisteredUEs        map[string]*RegisteredUE // key = IMSI
+	ActiveGNodeBs        map[int]*ran.GNodeB
+	Index                *ran.Index           // where ActiveGNodeBs are, kept up to date by RegisterGNodeB and UnregisterGNodeB
+	Handovers            []HandoverRecord     // history, oldest first
+	LinkAdaptation       radio.LinkAdaptation // how per-UE throughput is estimated
+	HandoverHysteresisDB float64
+	Outage               OutageConfig
+	RACH                 ran.RACHConfig   // used when stranded UEs re-attach after an outage
+	Sessions             SessionNotifier  // nil = sessions are never released by the AMF
+	Clock                *sim.Clock       // simulated time for timestamps
+	Journal              *journal.Journal // nil = procedures are not journaled
+	udm                  *udm.UDM         // for subscriber validation
+	stranded             map[int][]*ue.UE // UEs dropped by an outage, key = failed gNodeB ID
 }
 
 func NewAMF(udm *udm.UDM) *AMF {
 	return &AMF{
-		RegisteredUEs: make(map[string]*RegisteredUE),
-		ActiveGNodeBs: make(map[int]*ran.GNodeB),
-		udm:           udm,
+		RegisteredUEs:        make(map[string]*RegisteredUE),
+		ActiveGNodeBs:        make(map[int]*ran.GNodeB),
+		Index:                ran.NewIndex(ran.DefaultBucketSize),
+		HandoverHysteresisDB: DefaultHandoverHysteresisDB,
+		Outage:               DefaultOutageConfig,
+		RACH:                 ran.DefaultRACHConfig,
+		Clock:                sim.NewClock(sim.Epoch),
+		udm:                  udm,
+		stranded:             make(map[int][]*ue.UE),
 	}
 }
 
@@ -36,33 +108,133 @@ func (a *AMF) RegisterUE(imsi string, gnbID int) error {
 	// Check with UDM if subscriber exists / l'abonné existe ?
 	subscriber, err := a.udm.GetSubscriber(imsi)
 	if err != nil {
-		return fmt.Errorf("registration failed: %w", err)
+		err = fmt.Errorf("registration failed: %w", err)
+		a.Journal.Record(journal.Event{Kind: journal.RegistrationFailed, IMSI: imsi, GNodeB: gnbID, Error: err.Error()})
+		return err
 	}
 
 	// check if subscription is active / son abonnement est il actif ?
 	if subscriber.SubscriptionStatus != "active" {
-		return fmt.Errorf("registration failed: subscription status is %s", subscriber.SubscriptionStatus)
+		err = fmt.Errorf("registration failed: %w (status %s)", ErrSubscriptionInactive, subscriber.SubscriptionStatus)
+		a.Journal.Record(journal.Event{Kind: journal.RegistrationFailed, IMSI: imsi, GNodeB: gnbID, Error: err.Error()})
+		return err
 	}
 
-	// Step 3: Register the UE
-	a.RegisteredUEs[imsi] = &RegisteredUE{
+	// Step 3: Register the UE, remembering which cell and tracking area it came in on
+	regUE := &RegisteredUE{
 		IMSI:      imsi,
 		GNodeBID:  gnbID,
-		Timestamp: time.Now(),
+		CellID:    -1,
+		CMState:   CMConnected, // registration always comes in over a radio connection
+		Timestamp: a.Clock.Now(),
+	}
+	if g, exists := a.ActiveGNodeBs[gnbID]; exists {
+		regUE.TAC = g.TAC
+		if c := g.ServingCell(imsi); c != nil {
+			regUE.CellID = c.ID
+		}
+	}
+	a.RegisteredUEs[imsi] = regUE
+
+	// Step 4: Journal it, with the UE's radio state when it is connected here
+	e := journal.Event{Kind: journal.Registered, IMSI: imsi, GNodeB: gnbID}
+	if g, exists := a.ActiveGNodeBs[gnbID]; exists {
+		if u, connected := g.ConnectedUEs[imsi]; connected {
+			e.UE = a.DescribeUE(u)
+		}
+	}
+	a.Journal.Record(e)
+	return nil
+}
+
+// Attach connects the UE to the strongest cell in range and registers it
+func (a *AMF) Attach(u *ue.UE) error {
+	target := a.bestCell(u, nil)
+	if target == nil {
+		return a.failed(journal.ConnectionFailed, u, fmt.Errorf("attach failed: %w", ErrNoCoverage))
+	}
+	g := a.ActiveGNodeBs[target.GNodeBID()]
+	if err := g.ConnectUEToCell(u, target); err != nil {
+		return a.failed(journal.ConnectionFailed, u, fmt.Errorf("attach failed: %w", err))
+	}
+
+	// The registration follows from the RRC setup
+	a.Journal.Begin(a.connectedEvent(u, "RRC setup"))
+	defer a.Journal.End()
+	if err := a.RegisterUE(u.IMSI, g.ID); err != nil {
+		g.Disconnect(u)
+		a.record(journal.Released, u, journal.Event{GNodeB: g.ID, Detail: "registration rejected"})
+		return err
 	}
+	return nil
+}
 
+// DeregisterUE removes the UE's registration, releases its sessions and its radio connection
+func (a *AMF) DeregisterUE(u *ue.UE) error {
+	regUE, exists := a.RegisteredUEs[u.IMSI]
+	if !exists {
+		return fmt.Errorf("deregistration failed: UE %s is %w", u.IMSI, ErrNotRegistered)
+	}
+	if g, exists := a.ActiveGNodeBs[regUE.GNodeBID]; exists {
+		switch u.State {
+		case ue.Connected:
+			g.Disconnect(u)
+		case ue.Inactive:
+			g.DropInactive(u)
+		}
+	}
+	delete(a.RegisteredUEs, u.IMSI)
+	u.State = ue.Disconnected
+	u.GNodeBConnected = -1
+	u.CellConnected = -1
+
+	a.Journal.Begin(journal.Event{Kind: journal.Deregistered, IMSI: u.IMSI, GNodeB: regUE.GNodeBID, UE: a.DescribeUE(u)})
+	defer a.Journal.End()
+	if a.Sessions != nil {
+		a.Sessions.ReleaseUESessions(u.IMSI)
+	}
 	return nil
 }
 
+// servedBy records the gNodeB and cell now serving (or anchoring) the UE
+func (r *RegisteredUE) servedBy(g *ran.GNodeB, cellID int) {
+	r.GNodeBID = g.ID
+	r.CellID = cellID
+	r.TAC = g.TAC
+}
+
 func (a *AMF) RegisterGNodeB(g *ran.GNodeB) {
 	//append this to mapping somehow
 	a.ActiveGNodeBs[g.ID] = g
+	a.Index.Add(g)
+}
 
+// UnregisterGNodeB takes a gNodeB out of the network. It must not be serving or
+// anchoring any UE (fail it first to move them away).
+func (a *AMF) UnregisterGNodeB(id int) error {
+	g, exists := a.ActiveGNodeBs[id]
+	if !exists {
+		return fmt.Errorf("gNodeB %d %w", id, ErrGNodeBNotFound)
+	}
+	if len(g.ConnectedUEs) > 0 || len(g.Inactive) > 0 {
+		return fmt.Errorf("gNodeB %d is %w: it still serves %d UEs and anchors %d", id, ErrGNodeBInUse, len(g.ConnectedUEs), len(g.Inactive))
+	}
+	for _, regUE := range a.RegisteredUEs {
+		if regUE.GNodeBID == id {
+			return fmt.Errorf("gNodeB %d is %w: it is the last known gNodeB of UE %s", id, ErrGNodeBInUse, regUE.IMSI)
+		}
+	}
+	delete(a.ActiveGNodeBs, id)
+	delete(a.stranded, id)
+	a.Index.Remove(g)
+	return nil
 }
 
 func (a *AMF) MoveUE(u *ue.UE, newX, newY float64) error {
 	u.X = newX
 	u.Y = newY
+	a.Journal.Begin(journal.Event{Kind: journal.UEMoved, IMSI: u.IMSI, UE: a.DescribeUE(u)})
+	defer a.Journal.End()
 
 	regUE, exists := a.RegisteredUEs[u.IMSI]
 	if !exists {
@@ -74,38 +246,333 @@ func (a *AMF) MoveUE(u *ue.UE, newX, newY float64) error {
 		return fmt.Errorf("data inconsistency: UE registered to non-existent gNodeB %d", regUE.GNodeBID)
 	} // UE not connected to any GNodeB that exists
 
+	switch u.State {
+	case ue.Inactive:
+		return a.moveInactive(u, currentGNodeB)
+	case ue.Idle, ue.Disconnected:
+		return a.moveIdle(u, regUE)
+	}
+
+	serving := currentGNodeB.ServingCell(u.IMSI)
+	if serving == nil {
+		return fmt.Errorf("data inconsistency: UE registered to gNodeB %d but not attached to any of its cells", currentGNodeB.ID)
+	}
+
 	// if this UE is registered and has a current GNodeB dont throw an error, contineu
 	distance := utils.CalculateDistance(u.X, u.Y, currentGNodeB.X, currentGNodeB.Y)
-	if distance > currentGNodeB.Range {
-		a.Handover(u, currentGNodeB)
+	outOfRange := distance > currentGNodeB.Range
+
+	target := a.bestCell(u, nil)
+	if target == nil {
+		if outOfRange {
+			return ErrNoCoverage
+		}
+		return nil
+	}
+	if target == serving {
+		return nil
+	}
+	// Still in coverage: only hand over when the neighbour is clearly better (A3 event)
+	if !outOfRange && target.RSRP(u) < serving.RSRP(u)+a.HandoverHysteresisDB {
+		return nil
+	}
+
+	return a.Handover(u, target)
+}
+
+// moveInactive handles mobility in RRC_INACTIVE: nothing happens while the UE stays inside
+// its RAN notification area, leaving it triggers an RNA update towards the new gNodeB
+func (a *AMF) moveInactive(u *ue.UE, anchor *ran.GNodeB) error {
+	target := a.bestCell(u, nil)
+	if target == nil || anchor.InRNA(u.IMSI, target.GNodeBID()) {
+		return nil
+	}
+	newG := a.ActiveGNodeBs[target.GNodeBID()]
+	if err := newG.UpdateRNA(u, anchor, target, a.Clock.Now()); err != nil {
+		return fmt.Errorf("RNA update failed: %w", err)
+	}
+	a.RegisteredUEs[u.IMSI].servedBy(newG, -1)
+	a.record(journal.RNAUpdate, u, journal.Event{GNodeB: newG.ID})
+	return nil
+}
+
+// moveIdle handles mobility in RRC_IDLE: cell reselection is free, but camping on a cell
+// of another tracking area triggers a mobility registration update so paging still works
+func (a *AMF) moveIdle(u *ue.UE, regUE *RegisteredUE) error {
+	target := a.bestCell(u, nil)
+	if target == nil {
+		return nil
+	}
+	g := a.ActiveGNodeBs[target.GNodeBID()]
+	if g.TAC == regUE.TAC {
+		return nil
+	}
+	g.Signalling.RRC += 4  // RRC setup (3) + release
+	g.Signalling.NGAP += 4 // Initial UE Message, Downlink NAS Transport, UE Context Release Command / Complete
+	regUE.servedBy(g, -1)
+	a.record(journal.RegistrationUpdate, u, journal.Event{GNodeB: g.ID})
+	return nil
+}
+
+// bestCell returns the strongest cell (by RSRP) the UE may attach to: its gNodeB is in
+// range, allows the IMSI, passes filter (nil = any), and the cell has capacity left
+// (or already serves the UE)
+func (a *AMF) bestCell(u *ue.UE, filter func(*ran.GNodeB) bool) *ran.Cell {
+	var best *ran.Cell
+	var bestRSRP float64
+
+	// Only gNodeBs whose range covers the UE, in ID order: on equal RSRP the lowest
+	// gNodeB ID (then cell ID) wins
+	for _, g := range a.Index.Covering(u.X, u.Y) {
+		if g.Down {
+			continue
+		}
+		if filter != nil && !filter(g) {
+			continue
+		}
+		for _, c := range g.Cells {
+			if !c.Allows(u.IMSI) {
+				continue
+			}
+			if len(c.ConnectedUEs) >= c.MaxCap {
+				if _, serving := c.ConnectedUEs[u.IMSI]; !serving {
+					continue
+				}
+			}
+			if rsrp := c.RSRP(u); best == nil || rsrp > bestRSRP {
+				best, bestRSRP = c, rsrp
+			}
+		}
+	}
+	return best
+}
+
+// Handover moves a registered UE to the target cell. Sectors of the same gNodeB keep the
+// UE context (intra-gNodeB); anything else is a full inter-gNodeB handover.
+func (a *AMF) Handover(u *ue.UE, target *ran.Cell) error {
+	regUE, exists := a.RegisteredUEs[u.IMSI]
+	if !exists {
+		return fmt.Errorf("handover failed: UE %s is %w", u.IMSI, ErrNotRegistered)
+	}
+	oldG, exists := a.ActiveGNodeBs[regUE.GNodeBID]
+	if !exists {
+		return fmt.Errorf("data inconsistency: UE registered to non-existent gNodeB %d", regUE.GNodeBID)
+	}
+	newG, exists := a.ActiveGNodeBs[target.GNodeBID()]
+	if !exists {
+		return fmt.Errorf("handover failed: target gNodeB %d is not active", target.GNodeBID())
+	}
+
+	record := HandoverRecord{
+		IMSI:         u.IMSI,
+		FromGNodeBID: oldG.ID,
+		FromCellID:   regUE.CellID,
+		ToGNodeBID:   newG.ID,
+		ToCellID:     target.ID,
+	}
+
+	e := journal.Event{
+		From: &journal.CellRef{GNodeB: oldG.ID, Cell: regUE.CellID},
+		To:   &journal.CellRef{GNodeB: newG.ID, Cell: target.ID},
+	}
+
+	if newG == oldG {
+		record.Type = IntraGNodeB
+		if err := oldG.SwitchCell(u, target); err != nil {
+			e.Error = fmt.Sprintf("handover failed: %v", err)
+			a.record(journal.HandoverFailed, u, e)
+			return fmt.Errorf("handover failed: %w", err)
+		}
+	} else {
+		record.Type = InterGNodeB
+		if err := newG.HandoverIn(u, oldG, target); err != nil {
+			e.Error = fmt.Sprintf("handover failed: %v", err)
+			a.record(journal.HandoverFailed, u, e)
+			return fmt.Errorf("handover failed: %w", err)
+		}
 	}
 
+	// just need to update serving gNodeB / cell, no new registration
+	regUE.servedBy(newG, target.ID)
+	record.Timestamp = a.Clock.Now()
+	a.Handovers = append(a.Handovers, record)
+	e.Detail = record.Type.String()
+	a.record(journal.Handover, u, e)
 	return nil
 }
 
-func (a *AMF) Handover(u *ue.UE, oldG *ran.GNodeB) error {
-	// get all GNodeB position
-	//calculate
-	var closestGNodeB *ran.GNodeB
-	var closestDistance float64 = 2000.00
+// EstimateLink returns the radio link quality of a registered UE towards its serving gNodeB
+func (a *AMF) EstimateLink(u *ue.UE) (radio.LinkMetrics, error) {
+	regUE, exists := a.RegisteredUEs[u.IMSI]
+	if !exists {
+		return radio.LinkMetrics{}, fmt.Errorf("UE %s is %w", u.IMSI, ErrNotRegistered)
+	}
+	g, exists := a.ActiveGNodeBs[regUE.GNodeBID]
+	if !exists {
+		return radio.LinkMetrics{}, fmt.Errorf("data inconsistency: UE registered to non-existent gNodeB %d", regUE.GNodeBID)
+	}
+	serving := g.ServingCell(u.IMSI)
+	if serving == nil {
+		return radio.LinkMetrics{}, fmt.Errorf("UE %s is not attached to any cell of gNodeB %d", u.IMSI, g.ID)
+	}
+	return serving.LinkTo(u, a.ActiveGNodeBs, a.LinkAdaptation), nil
+}
+
+// TickRRC runs the inactivity timers of every gNodeB. UEs released to RRC_IDLE
+// lose their N2 connection and become CM-IDLE; RRC_INACTIVE ones stay CM-CONNECTED.
+func (a *AMF) TickRRC(now time.Time) {
+	for _, g := range ran.SortedByID(a.ActiveGNodeBs) {
+		for _, u := range g.ExpireInactivity(now) {
+			if regUE, exists := a.RegisteredUEs[u.IMSI]; exists && u.State == ue.Idle {
+				regUE.CMState = CMIdle
+			}
+			a.record(journal.Released, u, journal.Event{GNodeB: g.ID, Detail: "inactivity"})
+		}
+	}
+}
+
+// ReleaseUE releases a connected UE's radio connection on request. A registered UE
+// stays registered, in RRC_IDLE and CM-IDLE; one that is not just disconnects.
+func (a *AMF) ReleaseUE(u *ue.UE) error {
+	g, exists := a.ActiveGNodeBs[u.GNodeBConnected]
+	if !exists || u.State != ue.Connected {
+		return fmt.Errorf("UE %s: %w", u.IMSI, ran.ErrNotConnected)
+	}
+	regUE, registered := a.RegisteredUEs[u.IMSI]
+	if !registered {
+		return g.Disconnect(u)
+	}
+	if err := g.Release(u); err != nil {
+		return err
+	}
+	regUE.CMState = CMIdle
+	a.record(journal.Released, u, journal.Event{GNodeB: g.ID, Detail: "requested"})
+	return nil
+}
 
+// DownlinkData delivers traffic towards a UE. Connected UEs just restart their inactivity
+// timer; RRC_INACTIVE UEs are paged by their anchor across the RAN notification area;
+// CM-IDLE UEs are paged by the AMF across all gNodeBs.
+func (a *AMF) DownlinkData(u *ue.UE, now time.Time) error {
+	regUE, exists := a.RegisteredUEs[u.IMSI]
+	if !exists {
+		return fmt.Errorf("UE %s is %w", u.IMSI, ErrNotRegistered)
+	}
+
+	switch u.State {
+	case ue.Connected:
+		if g, exists := a.ActiveGNodeBs[regUE.GNodeBID]; exists {
+			g.NoteActivity(u, now)
+		}
+		return nil
+
+	case ue.Inactive:
+		anchor, exists := a.ActiveGNodeBs[regUE.GNodeBID]
+		if !exists {
+			return fmt.Errorf("data inconsistency: UE anchored at non-existent gNodeB %d", regUE.GNodeBID)
+		}
+		inRNA := func(g *ran.GNodeB) bool { return anchor.InRNA(u.IMSI, g.ID) }
+
+		// RAN paging: every gNodeB of the notification area pages the UE
+		for _, g := range a.ActiveGNodeBs {
+			if inRNA(g) {
+				g.Signalling.Paging++
+			}
+		}
+		a.Journal.Begin(journal.Event{Kind: journal.Paging, IMSI: u.IMSI, GNodeB: anchor.ID, Detail: "RAN"})
+		err := a.wake(u, now, inRNA)
+		if err != nil {
+			// Not found in the RNA: the anchor gives up the context and the AMF takes over
+			anchor.DropInactive(u)
+			regUE.CMState = CMIdle
+			a.record(journal.PagingFailed, u, journal.Event{GNodeB: anchor.ID, Error: err.Error()})
+		}
+		a.Journal.End()
+		if err == nil {
+			return nil
+		}
+	}
+
+	// CN paging over every gNodeB of the UE's tracking area
+	inTA := func(g *ran.GNodeB) bool { return g.TAC == regUE.TAC }
 	for _, g := range a.ActiveGNodeBs {
-		distance := utils.CalculateDistance(u.X, u.Y, g.X, g.Y)
-		if distance <= closestDistance && distance <= g.Range {
-			closestDistance = distance
-			closestGNodeB = g
+		if inTA(g) {
+			g.Signalling.Paging++
 		}
 	}
+	a.Journal.Begin(journal.Event{Kind: journal.Paging, IMSI: u.IMSI, Detail: fmt.Sprintf("CN, TAC %d", regUE.TAC)})
+	defer a.Journal.End()
+	if err := a.wake(u, now, inTA); err != nil {
+		return a.failed(journal.PagingFailed, u, fmt.Errorf("paging failed: %w", err))
+	}
+	return nil
+}
 
-	if closestDistance == 2000.00 {
-		// no gNodeB in range of UE,
-		return fmt.Errorf("No GNodeB in range of this UE")
+// UplinkData handles traffic from a UE. Non-connected UEs resume or set up a
+// connection themselves, no paging needed.
+func (a *AMF) UplinkData(u *ue.UE, now time.Time) error {
+	regUE, exists := a.RegisteredUEs[u.IMSI]
+	if !exists {
+		return fmt.Errorf("UE %s is %w", u.IMSI, ErrNotRegistered)
+	}
+	if u.State == ue.Connected {
+		if g, exists := a.ActiveGNodeBs[regUE.GNodeBID]; exists {
+			g.NoteActivity(u, now)
+		}
+		return nil
+	}
+	return a.wake(u, now, nil)
+}
+
+// wake brings an RRC_INACTIVE or RRC_IDLE UE back to RRC_CONNECTED on the best cell it can reach
+func (a *AMF) wake(u *ue.UE, now time.Time, filter func(*ran.GNodeB) bool) error {
+	regUE := a.RegisteredUEs[u.IMSI]
+	target := a.bestCell(u, filter)
+	if target == nil {
+		return fmt.Errorf("UE %s is %w", u.IMSI, ErrUnreachable)
+	}
+	g := a.ActiveGNodeBs[target.GNodeBID()]
+
+	how := "service request"
+	if u.State == ue.Inactive {
+		how = "resume"
+		anchor, exists := a.ActiveGNodeBs[regUE.GNodeBID]
+		if !exists {
+			return fmt.Errorf("data inconsistency: UE anchored at non-existent gNodeB %d", regUE.GNodeBID)
+		}
+		if err := g.Resume(u, anchor, target, now); err != nil {
+			return err
+		}
 	} else {
-		oldG.Disconnect(u)
-		closestGNodeB.ConnectUE(u)
-		// a.RegisterUE(u.IMSI, closestGNodeB.ID) just need to update gNodeB ID
-		a.RegisteredUEs[u.IMSI].GNodeBID = closestGNodeB.ID
+		if err := g.ConnectUEToCell(u, target); err != nil { // service request
+			return err
+		}
+		g.NoteActivity(u, now)
 	}
+
+	regUE.servedBy(g, target.ID)
+	regUE.CMState = CMConnected
+	a.Journal.Record(a.connectedEvent(u, how))
 	return nil
+}
+
+// connectedEvent describes a UE that just got an RRC connection
+func (a *AMF) connectedEvent(u *ue.UE, how string) journal.Event {
+	return journal.Event{
+		Kind:   journal.Connected,
+		IMSI:   u.IMSI,
+		GNodeB: u.GNodeBConnected,
+		To:     &journal.CellRef{GNodeB: u.GNodeBConnected, Cell: u.CellConnected},
+		UE:     a.DescribeUE(u),
+		Detail: how,
+	}
+}
 
+// SignallingTotals sums control plane message counts over all gNodeBs
+func (a *AMF) SignallingTotals() ran.SignallingStats {
+	var total ran.SignallingStats
+	for _, g := range a.ActiveGNodeBs {
+		total = total.Add(g.Signalling)
+	}
+	return total
 }
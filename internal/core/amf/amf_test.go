@@ -0,0 +1,113 @@
+package amf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// benchSizes are the UE counts the benchmarks run at, with one gNodeB per 10 UEs
+var benchSizes = []int{100, 1000, 10000}
+
+// gridNetwork is an AMF with gNodeBs 500 m apart on a square grid, each covering 600 m
+// so neighbours overlap, and registered UEs spread over the sites
+type gridNetwork struct {
+	amf     *AMF
+	gNodeBs []*ran.GNodeB // in grid order, row by row
+	perRow  int
+	ues     []*ue.UE
+}
+
+func newGridNetwork(b *testing.B, ues int) *gridNetwork {
+	b.Helper()
+	subscribers := &udm.UDM{Subscribers: make(map[string]*udm.Subscriber)}
+	n := &gridNetwork{amf: NewAMF(subscribers), perRow: 2}
+	for n.perRow*n.perRow*10 < ues {
+		n.perRow++
+	}
+	for i := 0; i < n.perRow*n.perRow; i++ {
+		cell := ran.CellConfig{PCI: i % 1008, Carrier: ran.DefaultCarrier, TxPowerDBm: ran.DefaultTxPowerDBm, MaxCap: ues}
+		g, err := ran.NewSectorGNodeB(float64(i%n.perRow)*500, float64(i/n.perRow)*500, 600, []ran.CellConfig{cell})
+		if err != nil {
+			b.Fatal(err)
+		}
+		n.amf.RegisterGNodeB(g)
+		n.gNodeBs = append(n.gNodeBs, g)
+	}
+	for i := 0; i < ues; i++ {
+		imsi := fmt.Sprintf("99999%010d", i+1)
+		subscribers.Subscribers[imsi] = &udm.Subscriber{IMSI: imsi, SubscriptionStatus: "active", MaxDataRate: 100}
+		g := n.gNodeBs[i%len(n.gNodeBs)]
+		u := ue.NewUE(imsi, g.X+10, g.Y+10)
+		if err := n.amf.Attach(u); err != nil {
+			b.Fatal(err)
+		}
+		n.ues = append(n.ues, u)
+	}
+	return n
+}
+
+// neighbour returns the gNodeB next to the one at grid index i, along the row
+func (n *gridNetwork) neighbour(i int) *ran.GNodeB {
+	if i%n.perRow+1 < n.perRow {
+		return n.gNodeBs[i+1]
+	}
+	return n.gNodeBs[i-1]
+}
+
+// BenchmarkAttach times a registered UE deregistering and attaching again
+func BenchmarkAttach(b *testing.B) {
+	for _, ues := range benchSizes {
+		b.Run(fmt.Sprintf("ues=%d", ues), func(b *testing.B) {
+			n := newGridNetwork(b, ues)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				u := n.ues[i%len(n.ues)]
+				if err := n.amf.DeregisterUE(u); err != nil {
+					b.Fatal(err)
+				}
+				if err := n.amf.Attach(u); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMoveUE times moves that each end in an inter-gNodeB handover: every UE
+// shuttles between points 20% and 80% of the way from its gNodeB to the neighbour
+func BenchmarkMoveUE(b *testing.B) {
+	for _, ues := range benchSizes {
+		b.Run(fmt.Sprintf("ues=%d", ues), func(b *testing.B) {
+			n := newGridNetwork(b, ues)
+			type shuttle struct{ ax, ay, bx, by float64 }
+			shuttles := make([]shuttle, len(n.ues))
+			for i, u := range n.ues {
+				g, to := n.gNodeBs[i%len(n.gNodeBs)], n.neighbour(i%len(n.gNodeBs))
+				shuttles[i] = shuttle{g.X + 0.2*(to.X-g.X), g.Y + 0.2*(to.Y-g.Y), g.X + 0.8*(to.X-g.X), g.Y + 0.8*(to.Y-g.Y)}
+				if err := n.amf.MoveUE(u, shuttles[i].ax, shuttles[i].ay); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				u, s := n.ues[i%len(n.ues)], shuttles[i%len(n.ues)]
+				x, y := s.bx, s.by
+				if (i/len(n.ues))%2 == 1 {
+					x, y = s.ax, s.ay
+				}
+				if err := n.amf.MoveUE(u, x, y); err != nil {
+					b.Fatal(err)
+				}
+				if len(n.amf.Handovers) > 1000 {
+					n.amf.Handovers = n.amf.Handovers[:0] // do not let them pile up
+				}
+			}
+		})
+	}
+}
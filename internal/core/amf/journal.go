@@ -0,0 +1,37 @@
+package amf
+
+import (
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// DescribeUE gives the journal view of a UE as it is now
+func (a *AMF) DescribeUE(u *ue.UE) *journal.UEState {
+	s := &journal.UEState{
+		X:      u.X,
+		Y:      u.Y,
+		State:  u.State.String(),
+		GNodeB: u.GNodeBConnected,
+		Cell:   u.CellConnected,
+	}
+	if regUE, exists := a.RegisteredUEs[u.IMSI]; exists {
+		s.Registered = true
+		s.CM = regUE.CMState.String()
+		s.Anchor = regUE.GNodeBID
+	}
+	return s
+}
+
+// record journals an event about u, with its state afterwards
+func (a *AMF) record(kind journal.Kind, u *ue.UE, e journal.Event) uint64 {
+	e.Kind = kind
+	e.IMSI = u.IMSI
+	e.UE = a.DescribeUE(u)
+	return a.Journal.Record(e)
+}
+
+// failed journals a procedure that did not go through
+func (a *AMF) failed(kind journal.Kind, u *ue.UE, err error) error {
+	a.record(kind, u, journal.Event{Error: err.Error()})
+	return err
+}
@@ -0,0 +1,200 @@
+package amf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// SessionNotifier is told when a UE loses radio coverage for good (implemented by the SMF)
+type SessionNotifier interface {
+	ReleaseUESessions(imsi string) []int
+}
+
+// OutageConfig holds the timers that drive recovery from a site failure
+type OutageConfig struct {
+	RLFDetection    time.Duration // T310 expiry after N310 out-of-sync indications
+	Reestablishment time.Duration // cell search + RACH + RRCReestablishment on a neighbour
+}
+
+var DefaultOutageConfig = OutageConfig{
+	RLFDetection:    1 * time.Second,
+	Reestablishment: 100 * time.Millisecond,
+}
+
+// OutageReport is the blast radius of a gNodeB failure
+type OutageReport struct {
+	GNodeBID        int
+	AffectedUEs     int           // connected when the site went down
+	Reestablished   []string      // recovered on a neighbour, sessions preserved
+	Dropped         []string      // no neighbour in range: CM-IDLE, sessions released
+	InactiveLost    int           // RRC_INACTIVE contexts anchored at the site
+	DroppedSessions []int         // PDU sessions released by the SMF
+	RecoveryTime    time.Duration // until the last re-established UE was back
+}
+
+// RestoreReport describes the mass re-attach after a gNodeB comes back
+type RestoreReport struct {
+	GNodeBID     int
+	RACH         ran.RACHReport
+	Reattached   []string
+	Failed       []RestoreFailure // still stranded, in IMSI order
+	RecoveryTime time.Duration    // until the last stranded UE was back
+}
+
+// RestoreFailure is a stranded UE that did not re-attach when its gNodeB came back. It
+// stays stranded, so the next restore of the gNodeB tries it again.
+type RestoreFailure struct {
+	IMSI string
+	Err  error // wraps ErrNoCoverage, ran.ErrRACHFailed or why the cell turned it away
+}
+
+// FailGNodeB takes a gNodeB off air. Connected UEs suffer radio link failure and try RRC
+// re-establishment on the best neighbour cell; UEs with no neighbour in range become CM-IDLE
+// and their sessions are released. Inactive UEs anchored at the site fall back to CM-IDLE
+// but keep their sessions, they will be found by CN paging.
+func (a *AMF) FailGNodeB(id int) (OutageReport, error) {
+	g, exists := a.ActiveGNodeBs[id]
+	if !exists {
+		return OutageReport{}, fmt.Errorf("gNodeB %d %w", id, ErrGNodeBNotFound)
+	}
+	if g.Down {
+		return OutageReport{}, fmt.Errorf("gNodeB %d is already down", id)
+	}
+
+	connected, inactive := g.Fail()
+	report := OutageReport{GNodeBID: id, AffectedUEs: len(connected), InactiveLost: len(inactive)}
+	a.Journal.Begin(journal.Event{Kind: journal.GNodeBFailed, GNodeB: id})
+	defer a.Journal.End()
+
+	for _, u := range inactive {
+		if regUE, exists := a.RegisteredUEs[u.IMSI]; exists {
+			regUE.CMState = CMIdle
+		}
+		a.record(journal.Released, u, journal.Event{GNodeB: id, Detail: "anchor gNodeB failed"})
+	}
+
+	// Fail lists UEs in IMSI order, so neighbour capacity is handed out the same way every run
+	for _, u := range connected {
+		a.recoverUE(u, id, &report)
+	}
+
+	if len(report.Reestablished) > 0 {
+		report.RecoveryTime = a.Outage.RLFDetection + a.Outage.Reestablishment
+	}
+	return report, nil
+}
+
+// recoverUE handles the radio link failure of one UE of a failed gNodeB
+func (a *AMF) recoverUE(u *ue.UE, failedID int, report *OutageReport) {
+	a.Journal.Begin(journal.Event{Kind: journal.RadioLinkFailure, IMSI: u.IMSI, GNodeB: failedID})
+	defer a.Journal.End()
+
+	regUE, registered := a.RegisteredUEs[u.IMSI]
+	if target := a.bestCell(u, nil); target != nil {
+		newG := a.ActiveGNodeBs[target.GNodeBID()]
+		if err := newG.Reestablish(u, target); err == nil {
+			if registered {
+				regUE.servedBy(newG, target.ID)
+			}
+			report.Reestablished = append(report.Reestablished, u.IMSI)
+			a.record(journal.Reestablished, u, journal.Event{GNodeB: newG.ID, To: &journal.CellRef{GNodeB: newG.ID, Cell: target.ID}})
+			return
+		}
+	}
+
+	// Radio link failure with nowhere to go
+	u.State = ue.Idle
+	a.stranded[failedID] = append(a.stranded[failedID], u)
+	report.Dropped = append(report.Dropped, u.IMSI)
+	if registered {
+		regUE.CMState = CMIdle
+	}
+	a.record(journal.Dropped, u, journal.Event{GNodeB: failedID})
+	if a.Sessions != nil {
+		report.DroppedSessions = append(report.DroppedSessions, a.Sessions.ReleaseUESessions(u.IMSI)...)
+	}
+}
+
+// RestoreGNodeB puts a failed gNodeB back on air. UEs stranded by its outage that are
+// still in its coverage re-attach all at once through random access; the others, and
+// those that lose random access or are turned away by the cell, are reported as failed.
+func (a *AMF) RestoreGNodeB(id int) (RestoreReport, error) {
+	g, exists := a.ActiveGNodeBs[id]
+	if !exists {
+		return RestoreReport{}, fmt.Errorf("gNodeB %d %w", id, ErrGNodeBNotFound)
+	}
+	if !g.Down {
+		return RestoreReport{}, fmt.Errorf("gNodeB %d is not down", id)
+	}
+	if err := a.RACH.Validate(); err != nil {
+		return RestoreReport{}, fmt.Errorf("gNodeB %d cannot be restored: %w", id, err) // stranded UEs re-attach through RACH
+	}
+	g.Restore()
+	a.Journal.Begin(journal.Event{Kind: journal.GNodeBRestored, GNodeB: id})
+	defer a.Journal.End()
+
+	var waiting []*ue.UE
+	failed := make(map[string]error)
+	for _, u := range a.stranded[id] {
+		if u.State == ue.Connected {
+			continue // recovered somewhere else in the meantime
+		}
+		if target := a.bestCell(u, func(c *ran.GNodeB) bool { return c == g }); target != nil {
+			waiting = append(waiting, u)
+		} else {
+			failed[u.IMSI] = fmt.Errorf("out of range of gNodeB %d: %w", id, ErrNoCoverage)
+		}
+	}
+	stranded := a.stranded[id]
+	delete(a.stranded, id)
+
+	rach, rejected, _ := g.MassAttach(waiting, nil, a.RACH) // the config was checked above
+	report := RestoreReport{GNodeBID: id, RACH: rach}
+	for _, res := range rach.Results {
+		u := findUE(waiting, res.IMSI)
+		if !res.Success {
+			failed[u.IMSI] = fmt.Errorf("%w after %d preambles", ran.ErrRACHFailed, res.Attempts)
+			continue
+		}
+		if err := rejected[u.IMSI]; err != nil {
+			failed[u.IMSI] = err
+			continue
+		}
+		if regUE, exists := a.RegisteredUEs[u.IMSI]; exists {
+			regUE.servedBy(g, u.CellConnected)
+			regUE.CMState = CMConnected
+		}
+		report.Reattached = append(report.Reattached, u.IMSI)
+		a.Journal.Record(a.connectedEvent(u, "re-attach"))
+		if res.Latency > report.RecoveryTime {
+			report.RecoveryTime = res.Latency
+		}
+	}
+
+	// The UEs left behind stay stranded, in the order they were dropped
+	for _, u := range stranded {
+		if err, exists := failed[u.IMSI]; exists {
+			a.stranded[id] = append(a.stranded[id], u)
+			report.Failed = append(report.Failed, RestoreFailure{IMSI: u.IMSI, Err: err})
+		}
+	}
+	sort.Slice(report.Failed, func(i, j int) bool { return report.Failed[i].IMSI < report.Failed[j].IMSI })
+	for _, f := range report.Failed {
+		a.record(journal.ConnectionFailed, findUE(stranded, f.IMSI), journal.Event{GNodeB: id, Detail: "re-attach", Error: f.Err.Error()})
+	}
+	return report, nil
+}
+
+func findUE(ues []*ue.UE, imsi string) *ue.UE {
+	for _, u := range ues {
+		if u.IMSI == imsi {
+			return u
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package amf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// strandedSite is one gNodeB whose outage strands every UE, with nowhere else to go
+func strandedSite(t *testing.T, ues, maxCap int) (*AMF, *ran.GNodeB, []*ue.UE) {
+	t.Helper()
+	subscribers := &udm.UDM{Subscribers: make(map[string]*udm.Subscriber)}
+	a := NewAMF(subscribers)
+	cell := ran.CellConfig{PCI: 1, Carrier: ran.DefaultCarrier, TxPowerDBm: ran.DefaultTxPowerDBm, MaxCap: ues}
+	g, err := ran.NewSectorGNodeB(0, 0, 500, []ran.CellConfig{cell})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.RegisterGNodeB(g)
+	var all []*ue.UE
+	for i := 0; i < ues; i++ {
+		imsi := fmt.Sprintf("99999%010d", i+1)
+		subscribers.Subscribers[imsi] = &udm.Subscriber{IMSI: imsi, SubscriptionStatus: "active", MaxDataRate: 100}
+		u := ue.NewUE(imsi, 10*float64(i+1), 0)
+		if err := a.Attach(u); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, u)
+	}
+	report, err := a.FailGNodeB(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Dropped) != ues {
+		t.Fatalf("%d UEs dropped, want %d", len(report.Dropped), ues)
+	}
+	g.Cells[0].MaxCap = maxCap
+	return a, g, all
+}
+
+func TestRestoreGNodeBReportsFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		ues        int
+		maxCap     int
+		setup      func(a *AMF, ues []*ue.UE)
+		reattached int
+		want       error // of every failed UE
+	}{
+		{"all back", 3, 3, func(*AMF, []*ue.UE) {}, 3, nil},
+		{"out of range", 2, 2, func(_ *AMF, ues []*ue.UE) { ues[1].X = 10_000 }, 1, ErrNoCoverage},
+		{"cell full", 3, 1, func(*AMF, []*ue.UE) {}, 1, ran.ErrCapacity},
+		{"random access lost", 2, 2, func(a *AMF, _ []*ue.UE) {
+			a.RACH.Rand = nil
+			a.RACH.Preambles = 1 // both pick it, and there is no second try
+			a.RACH.MaxAttempts = 1
+		}, 0, ran.ErrRACHFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, g, ues := strandedSite(t, tt.ues, tt.maxCap)
+			tt.setup(a, ues)
+			report, err := a.RestoreGNodeB(g.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Reattached) != tt.reattached {
+				t.Errorf("%d UEs reattached, want %d", len(report.Reattached), tt.reattached)
+			}
+			// Every UE is accounted for, and those left behind stay stranded
+			if len(report.Reattached)+len(report.Failed) != tt.ues {
+				t.Errorf("%d reattached + %d failed, want %d UEs", len(report.Reattached), len(report.Failed), tt.ues)
+			}
+			if len(a.stranded[g.ID]) != len(report.Failed) {
+				t.Errorf("%d UEs still stranded, want %d", len(a.stranded[g.ID]), len(report.Failed))
+			}
+			for _, f := range report.Failed {
+				if !errors.Is(f.Err, tt.want) {
+					t.Errorf("UE %s failed with %v, want %v", f.IMSI, f.Err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// A UE that could not re-attach is tried again at the next restore
+func TestRestoreGNodeBRetriesStranded(t *testing.T) {
+	a, g, ues := strandedSite(t, 2, 2)
+	ues[1].X = 10_000
+	if report, _ := a.RestoreGNodeB(g.ID); len(report.Failed) != 1 {
+		t.Fatalf("%d UEs failed, want 1", len(report.Failed))
+	}
+
+	ues[1].X = 20
+	if _, err := a.FailGNodeB(g.ID); err != nil {
+		t.Fatal(err)
+	}
+	report, err := a.RestoreGNodeB(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Reattached) != 2 || len(report.Failed) != 0 || len(a.stranded[g.ID]) != 0 {
+		t.Errorf("reattached %v, failed %v, stranded %d; want both back", report.Reattached, report.Failed, len(a.stranded[g.ID]))
+	}
+}
@@ -0,0 +1,74 @@
+package amf
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// State is the AMF's part of a network snapshot. gNodeBs are saved by the RAN and
+// re-registered on restore; UEs are referred to by IMSI.
+type State struct {
+	RegisteredUEs        []RegisteredUE       `json:"registeredUEs"`
+	Handovers            []HandoverRecord     `json:"handovers"`
+	LinkAdaptation       radio.LinkAdaptation `json:"linkAdaptation"`
+	HandoverHysteresisDB float64              `json:"handoverHysteresisDB"`
+	Outage               OutageConfig         `json:"outage"`
+	RACH                 ran.RACHConfig       `json:"rach"`
+	Stranded             map[int][]string     `json:"stranded,omitempty"` // failed gNodeB ID -> IMSIs waiting for it
+}
+
+// State captures the AMF for a snapshot
+func (a *AMF) State() State {
+	s := State{
+		Handovers:            a.Handovers,
+		LinkAdaptation:       a.LinkAdaptation,
+		HandoverHysteresisDB: a.HandoverHysteresisDB,
+		Outage:               a.Outage,
+		RACH:                 a.RACH,
+		Stranded:             make(map[int][]string),
+	}
+	for _, regUE := range a.RegisteredUEs {
+		s.RegisteredUEs = append(s.RegisteredUEs, *regUE)
+	}
+	sort.Slice(s.RegisteredUEs, func(i, j int) bool { return s.RegisteredUEs[i].IMSI < s.RegisteredUEs[j].IMSI })
+	for id, ues := range a.stranded {
+		for _, u := range ues {
+			s.Stranded[id] = append(s.Stranded[id], u.IMSI)
+		}
+	}
+	return s
+}
+
+// Restore replaces the AMF's state with a saved one. gNodeBs must already be registered;
+// the clock, UDM, session notifier and the configuration (link adaptation, hysteresis,
+// outage and RACH) are kept: they belong to the running process, like its flags. The
+// snapshot's configuration is only there to see what it was taken with.
+func (a *AMF) Restore(s State, ues map[string]*ue.UE) error {
+	registered := make(map[string]*RegisteredUE)
+	for i := range s.RegisteredUEs {
+		regUE := s.RegisteredUEs[i]
+		if _, exists := ues[regUE.IMSI]; !exists {
+			return fmt.Errorf("AMF: registered UE %s does not exist", regUE.IMSI)
+		}
+		registered[regUE.IMSI] = &regUE
+	}
+	stranded := make(map[int][]*ue.UE)
+	for id, imsis := range s.Stranded {
+		for _, imsi := range imsis {
+			u, exists := ues[imsi]
+			if !exists {
+				return fmt.Errorf("AMF: stranded UE %s does not exist", imsi)
+			}
+			stranded[id] = append(stranded[id], u)
+		}
+	}
+
+	a.RegisteredUEs = registered
+	a.Handovers = s.Handovers
+	a.stranded = stranded
+	return nil
+}
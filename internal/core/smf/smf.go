@@ -1,9 +1,14 @@
 package smf
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/radio"
 	"github.com/rizpur/NetSim5G/internal/ue"
 )
 
@@ -14,10 +19,21 @@ const (
 	VoIP SessionType = iota
 	VideoStreaming
 	WebBrowsing
+	IoT // small periodic reports from sensors and meters
 )
 
 func (s SessionType) String() string {
-	return [...]string{"VoIP", "VideoStreaming", "WebBrowsing"}[s]
+	return [...]string{"VoIP", "VideoStreaming", "WebBrowsing", "IoT"}[s]
+}
+
+// ParseSessionType converts a name such as "VoIP" back to its SessionType
+func ParseSessionType(name string) (SessionType, error) {
+	for t := VoIP; t <= IoT; t++ {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown session type %q", name)
 }
 
 // SessionState represents the state of a PDU session
@@ -56,34 +72,99 @@ var QoSProfiles = map[SessionType]QoSProfile{
 		Latency:    300, // 300ms - its ok
 		Priority:   10,  // Lowest priority (best effort)
 	},
+	IoT: {
+		MaxBitRate: 1,    // 1 Mbps - reports are a few hundred bytes
+		Latency:    1000, // 1s - nobody is waiting for a meter reading
+		Priority:   8,
+	},
+}
+
+// SetupTiming models how long PDU session establishment takes: the core's N11/N4
+// exchanges with the UPF, plus NAS and RRC round trips over the radio, which take
+// longer on poor links because of HARQ retransmissions
+type SetupTiming struct {
+	Core       time.Duration // AMF <-> SMF <-> UPF
+	RadioRTT   time.Duration // one NAS or RRC round trip on a good link
+	RoundTrips int           // PDU Session Establishment Request/Accept, RRCReconfiguration/Complete
+}
+
+var DefaultSetupTiming = SetupTiming{
+	Core:       20 * time.Millisecond,
+	RadioRTT:   10 * time.Millisecond,
+	RoundTrips: 2,
+}
+
+// Latency is the setup time over a link with the given CQI (0 = unknown, counted as
+// good). Radio round trips stretch up to 4× at CQI 1.
+func (t SetupTiming) Latency(cqi int) time.Duration {
+	stretch := 1.0
+	if cqi > 0 && cqi < 15 {
+		stretch = 1 + 3*float64(15-cqi)/14
+	}
+	return t.Core + time.Duration(float64(t.RoundTrips)*stretch*float64(t.RadioRTT))
 }
 
 // PDUSession represents a data session between UE and network
 type PDUSession struct {
-	SessionID   int
-	UE          *ue.UE
-	SessionType SessionType
-	QoS         QoSProfile
-	State       SessionState
+	SessionID    int
+	UE           *ue.UE
+	SessionType  SessionType
+	QoS          QoSProfile
+	State        SessionState
+	SetupLatency time.Duration // how long establishment took
+}
+
+// LinkEstimator reports how much throughput a UE's radio link can sustain (implemented by the AMF)
+type LinkEstimator interface {
+	EstimateLink(u *ue.UE) (radio.LinkMetrics, error)
 }
 
+// Errors EstablishSession and TerminateSession wrap, to tell failures apart with errors.Is
+var (
+	ErrQuotaExceeded   = errors.New("exceeds subscriber limit") // the subscriber's max data rate
+	ErrLinkCapacity    = errors.New("radio link too weak")      // for all of the UE's sessions
+	ErrSessionNotFound = errors.New("not found")                // as in "session 3 not found"
+)
+
 // SMF manages PDU sessions
 type SMF struct {
 	Sessions      map[int]*PDUSession // key = SessionID
+	Journal       *journal.Journal    // nil = sessions are not journaled
+	Setup         SetupTiming
 	udm           *udm.UDM
+	links         LinkEstimator // nil = admit on subscriber limits only
 	nextSessionID int
 }
 
-func NewSMF(udm *udm.UDM) *SMF {
+func NewSMF(udm *udm.UDM, links LinkEstimator) *SMF {
 	return &SMF{
 		Sessions:      make(map[int]*PDUSession),
 		udm:           udm,
+		links:         links,
+		Setup:         DefaultSetupTiming,
 		nextSessionID: 1, // Start session IDs at 1
 	}
 }
 
 // EstablishSession creates a new PDU session for a UE
 func (s *SMF) EstablishSession(u *ue.UE, sessionType SessionType) (*PDUSession, error) {
+	session, err := s.establish(u, sessionType)
+	if err != nil {
+		s.Journal.Record(journal.Event{Kind: journal.SessionRejected, IMSI: u.IMSI, SessionType: sessionType.String(), Error: err.Error()})
+		return nil, err
+	}
+	s.Journal.Record(journal.Event{
+		Kind:        journal.SessionEstablished,
+		IMSI:        u.IMSI,
+		Session:     session.SessionID,
+		SessionType: sessionType.String(),
+		LatencyMs:   float64(session.SetupLatency) / float64(time.Millisecond),
+		Detail:      fmt.Sprintf("%d Mbps, %d ms, priority %d", session.QoS.MaxBitRate, session.QoS.Latency, session.QoS.Priority),
+	})
+	return session, nil
+}
+
+func (s *SMF) establish(u *ue.UE, sessionType SessionType) (*PDUSession, error) {
 	// Step 1: Get subscriber info from UDM
 	subscriber, err := s.udm.GetSubscriber(u.IMSI)
 	if err != nil {
@@ -103,17 +184,32 @@ func (s *SMF) EstablishSession(u *ue.UE, sessionType SessionType) (*PDUSession,
 
 	// Step 4: Check if adding this session would exceed subscriber's limit
 	if totalAllocated+qosProfile.MaxBitRate > subscriber.MaxDataRate {
-		return nil, fmt.Errorf("session establishment failed: total bandwidth would be %d Mbps (current: %d + new: %d), exceeds subscriber limit of %d Mbps",
-			totalAllocated+qosProfile.MaxBitRate, totalAllocated, qosProfile.MaxBitRate, subscriber.MaxDataRate)
+		return nil, fmt.Errorf("session establishment failed: total bandwidth would be %d Mbps (current: %d + new: %d), %w of %d Mbps",
+			totalAllocated+qosProfile.MaxBitRate, totalAllocated, qosProfile.MaxBitRate, ErrQuotaExceeded, subscriber.MaxDataRate)
 	}
 
-	// Step 5: Create the session
+	// Step 5: Check the radio link can actually carry all sessions at this position
+	cqi := 0
+	if s.links != nil {
+		link, err := s.links.EstimateLink(u)
+		if err != nil {
+			return nil, fmt.Errorf("session establishment failed: %w", err)
+		}
+		if float64(totalAllocated+qosProfile.MaxBitRate) > link.ThroughputMbps {
+			return nil, fmt.Errorf("session establishment failed: %w: link supports %.1f Mbps (SINR %.1f dB, CQI %d, MCS %d, %d PRBs), sessions need %d Mbps",
+				ErrLinkCapacity, link.ThroughputMbps, link.SINRDB, link.CQI, link.MCS, link.PRBs, totalAllocated+qosProfile.MaxBitRate)
+		}
+		cqi = link.CQI
+	}
+
+	// Step 6: Create the session
 	session := &PDUSession{
-		SessionID:   s.nextSessionID,
-		UE:          u,
-		SessionType: sessionType,
-		QoS:         qosProfile,
-		State:       Active,
+		SessionID:    s.nextSessionID,
+		UE:           u,
+		SessionType:  sessionType,
+		QoS:          qosProfile,
+		State:        Active,
+		SetupLatency: s.Setup.Latency(cqi),
 	}
 
 	s.Sessions[s.nextSessionID] = session
@@ -126,10 +222,44 @@ func (s *SMF) EstablishSession(u *ue.UE, sessionType SessionType) (*PDUSession,
 func (s *SMF) TerminateSession(sessionID int) error {
 	session, exists := s.Sessions[sessionID]
 	if !exists {
-		return fmt.Errorf("session %d not found", sessionID)
+		return fmt.Errorf("session %d %w", sessionID, ErrSessionNotFound)
 	}
 
 	session.State = Inactive
 	delete(s.Sessions, sessionID)
+	s.recordRelease(session)
 	return nil
 }
+
+// SessionsOf returns the active sessions of a UE, ordered by session ID
+func (s *SMF) SessionsOf(imsi string) []*PDUSession {
+	var sessions []*PDUSession
+	for _, session := range s.Sessions {
+		if session.UE.IMSI == imsi {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID < sessions[j].SessionID })
+	return sessions
+}
+
+// ReleaseUESessions ends every session of a UE that lost coverage and returns their IDs
+func (s *SMF) ReleaseUESessions(imsi string) []int {
+	var released []int
+	for _, session := range s.SessionsOf(imsi) {
+		session.State = Inactive
+		delete(s.Sessions, session.SessionID)
+		released = append(released, session.SessionID)
+		s.recordRelease(session)
+	}
+	return released
+}
+
+func (s *SMF) recordRelease(session *PDUSession) {
+	s.Journal.Record(journal.Event{
+		Kind:        journal.SessionReleased,
+		IMSI:        session.UE.IMSI,
+		Session:     session.SessionID,
+		SessionType: session.SessionType.String(),
+	})
+}
@@ -0,0 +1,65 @@
+package smf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// BenchmarkEstablishSession times SMF.EstablishSession, with the AMF checking the radio
+// link, and SMF.TerminateSession while every UE already holds an IoT session
+func BenchmarkEstablishSession(b *testing.B) {
+	for _, ues := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("ues=%d", ues), func(b *testing.B) {
+			// One gNodeB per 10 UEs, 500 m apart, every UE close to its site
+			subscribers := &udm.UDM{Subscribers: make(map[string]*udm.Subscriber)}
+			a := amf.NewAMF(subscribers)
+			s := NewSMF(subscribers, a)
+			a.Sessions = s
+			perRow := 2
+			for perRow*perRow*10 < ues {
+				perRow++
+			}
+			var gNodeBs []*ran.GNodeB
+			for i := 0; i < perRow*perRow; i++ {
+				cell := ran.CellConfig{PCI: i % 1008, Carrier: ran.DefaultCarrier, TxPowerDBm: ran.DefaultTxPowerDBm, MaxCap: ues}
+				g, err := ran.NewSectorGNodeB(float64(i%perRow)*500, float64(i/perRow)*500, 600, []ran.CellConfig{cell})
+				if err != nil {
+					b.Fatal(err)
+				}
+				a.RegisterGNodeB(g)
+				gNodeBs = append(gNodeBs, g)
+			}
+			var all []*ue.UE
+			for i := 0; i < ues; i++ {
+				imsi := fmt.Sprintf("99999%010d", i+1)
+				subscribers.Subscribers[imsi] = &udm.Subscriber{IMSI: imsi, SubscriptionStatus: "active", MaxDataRate: 100}
+				g := gNodeBs[i%len(gNodeBs)]
+				u := ue.NewUE(imsi, g.X+10, g.Y+10)
+				if err := a.Attach(u); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := s.EstablishSession(u, IoT); err != nil {
+					b.Fatal(err)
+				}
+				all = append(all, u)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				session, err := s.EstablishSession(all[i%len(all)], VoIP)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := s.TerminateSession(session.SessionID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package smf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// State is the SMF's part of a network snapshot
+type State struct {
+	Sessions      []SavedSession `json:"sessions"`
+	NextSessionID int            `json:"nextSessionId"`
+}
+
+// SavedSession is a saved PDU session
+type SavedSession struct {
+	SessionID   int        `json:"sessionId"`
+	IMSI        string     `json:"imsi"`
+	SessionType string     `json:"sessionType"`
+	QoS         QoSProfile `json:"qos"`
+	State       string     `json:"state"`
+	SetupMs     float64    `json:"setupMs,omitempty"` // establishment latency
+}
+
+// State captures the SMF for a snapshot
+func (s *SMF) State() State {
+	state := State{NextSessionID: s.nextSessionID}
+	for _, session := range s.Sessions {
+		state.Sessions = append(state.Sessions, SavedSession{
+			SessionID:   session.SessionID,
+			IMSI:        session.UE.IMSI,
+			SessionType: session.SessionType.String(),
+			QoS:         session.QoS,
+			State:       session.State.String(),
+			SetupMs:     float64(session.SetupLatency) / float64(time.Millisecond),
+		})
+	}
+	sort.Slice(state.Sessions, func(i, j int) bool { return state.Sessions[i].SessionID < state.Sessions[j].SessionID })
+	return state
+}
+
+// Restore replaces the SMF's sessions with saved ones
+func (s *SMF) Restore(state State, ues map[string]*ue.UE) error {
+	sessions := make(map[int]*PDUSession)
+	for _, saved := range state.Sessions {
+		u, exists := ues[saved.IMSI]
+		if !exists {
+			return fmt.Errorf("SMF: session %d belongs to unknown UE %s", saved.SessionID, saved.IMSI)
+		}
+		sessionType, err := ParseSessionType(saved.SessionType)
+		if err != nil {
+			return fmt.Errorf("SMF: session %d: %w", saved.SessionID, err)
+		}
+		sessionState := Active
+		if saved.State == Inactive.String() {
+			sessionState = Inactive
+		}
+		sessions[saved.SessionID] = &PDUSession{
+			SessionID:    saved.SessionID,
+			UE:           u,
+			SessionType:  sessionType,
+			QoS:          saved.QoS,
+			State:        sessionState,
+			SetupLatency: time.Duration(saved.SetupMs * float64(time.Millisecond)),
+		}
+	}
+	s.Sessions = sessions
+	s.nextSessionID = state.NextSessionID
+	return nil
+}
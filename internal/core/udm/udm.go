@@ -2,8 +2,12 @@ package udm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+
+	"github.com/rizpur/NetSim5G/internal/journal"
 )
 
 type Subscriber struct {
@@ -13,16 +17,21 @@ type Subscriber struct {
 	MaxDataRate        int    `json:"max_data_rate"`
 }
 
+// ErrSubscriberNotFound is wrapped by every error for an IMSI the UDM does not know
+var ErrSubscriberNotFound = errors.New("subscriber not found")
+
 type UDM struct {
 	Subscribers map[string]*Subscriber // key = IMSI
+	Journal     *journal.Journal       // nil = changes are not journaled
 }
 
 type subscriberFile struct {
 	Subscribers []Subscriber `json:"subscribers"`
 }
 
-func NewUDM() (*UDM, error) {
-	file, err := os.Open("internal/configs/subscribers.json")
+// NewUDM loads the subscriber database from a JSON file
+func NewUDM(path string) (*UDM, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open subscribers file: %w", err)
 	}
@@ -50,7 +59,55 @@ func NewUDM() (*UDM, error) {
 func (u *UDM) GetSubscriber(imsi string) (*Subscriber, error) {
 	sub, exists := u.Subscribers[imsi]
 	if !exists {
-		return nil, fmt.Errorf("subscriber not found: %s", imsi)
+		return nil, fmt.Errorf("%w: %s", ErrSubscriberNotFound, imsi)
 	}
 	return sub, nil
 }
+
+// AddSubscriber puts a new subscriber in the database
+func (u *UDM) AddSubscriber(sub Subscriber) error {
+	if _, exists := u.Subscribers[sub.IMSI]; exists {
+		return fmt.Errorf("subscriber %s already exists", sub.IMSI)
+	}
+	u.Subscribers[sub.IMSI] = &sub
+	return nil
+}
+
+// UpdateSubscriber changes a subscription. Empty status and zero maxDataRate are left unchanged.
+func (u *UDM) UpdateSubscriber(imsi, status string, maxDataRate int) error {
+	sub, err := u.GetSubscriber(imsi)
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		sub.SubscriptionStatus = status
+	}
+	if maxDataRate > 0 {
+		sub.MaxDataRate = maxDataRate
+	}
+	u.Journal.Record(journal.Event{
+		Kind:   journal.SubscriberUpdated,
+		IMSI:   imsi,
+		Detail: fmt.Sprintf("status %s, max %d Mbps", sub.SubscriptionStatus, sub.MaxDataRate),
+	})
+	return nil
+}
+
+// State returns every subscriber ordered by IMSI, for snapshots
+func (u *UDM) State() []Subscriber {
+	subscribers := make([]Subscriber, 0, len(u.Subscribers))
+	for _, sub := range u.Subscribers {
+		subscribers = append(subscribers, *sub)
+	}
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].IMSI < subscribers[j].IMSI })
+	return subscribers
+}
+
+// Restore replaces the subscriber database with a saved one
+func (u *UDM) Restore(subscribers []Subscriber) {
+	u.Subscribers = make(map[string]*Subscriber)
+	for i := range subscribers {
+		sub := subscribers[i]
+		u.Subscribers[sub.IMSI] = &sub
+	}
+}
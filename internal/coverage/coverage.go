@@ -0,0 +1,132 @@
+// Package coverage maps what the network offers over an area: at every point of a
+// grid, the best-serving cell by RSRP and the SINR and throughput a UE standing there
+// would get. Grids are exported as CSV, GeoJSON or a PNG heatmap.
+package coverage
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// MaxPoints caps the size of a grid, so a fine step over a city cannot stall the simulator
+const MaxPoints = 250_000
+
+// DefaultPoints is about how many points a grid has when no step is given
+const DefaultPoints = 100 * 100
+
+// Area is a rectangle of the simulation plane, in metres
+type Area struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Around returns the smallest area holding every gNodeB's coverage range
+func Around(gnbs []*ran.GNodeB) Area {
+	a := Area{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, g := range gnbs {
+		a.MinX, a.MinY = math.Min(a.MinX, g.X-g.Range), math.Min(a.MinY, g.Y-g.Range)
+		a.MaxX, a.MaxY = math.Max(a.MaxX, g.X+g.Range), math.Max(a.MaxY, g.Y+g.Range)
+	}
+	return a
+}
+
+// Point is what a UE standing at X,Y would get. GNodeB is 0 where no cell is on air.
+type Point struct {
+	X, Y           float64
+	GNodeB         int // best server by RSRP, whether or not in range
+	Cell           int // its index within the gNodeB
+	PCI            int
+	InRange        bool // the best server's range reaches here, so the simulator would serve a UE
+	RSRPDBm        float64
+	SINRDB         float64
+	ThroughputMbps float64 // with the PRBs shared with the UEs the cell already serves
+}
+
+// Grid is a coverage map: Cols x Rows points at the centres of Step-sized squares,
+// row by row from the bottom (MinY) up
+type Grid struct {
+	Area   Area
+	Step   float64
+	Cols   int
+	Rows   int
+	Points []Point
+	Sites  []*ran.GNodeB // the gNodeBs evaluated, by ID
+}
+
+// At returns the point in column i (from MinX) and row j (from MinY)
+func (g *Grid) At(i, j int) *Point {
+	return &g.Points[j*g.Cols+i]
+}
+
+// Compute evaluates every point of area, step metres apart (0 = about DefaultPoints
+// points), against the gNodeBs as they are now: cells of gNodeBs that are down
+// neither serve nor interfere. Allow-lists and cell capacity are ignored; this is a
+// map of the radio, not of who may attach.
+func Compute(gnbs map[int]*ran.GNodeB, area Area, step float64, la radio.LinkAdaptation) (*Grid, error) {
+	width, height := area.MaxX-area.MinX, area.MaxY-area.MinY
+	switch {
+	case len(gnbs) == 0:
+		return nil, fmt.Errorf("no gNodeBs to map")
+	case !(width > 0 && height > 0):
+		return nil, fmt.Errorf("empty area %g,%g - %g,%g", area.MinX, area.MinY, area.MaxX, area.MaxY)
+	case step < 0:
+		return nil, fmt.Errorf("step must be > 0, got %g", step)
+	case step == 0:
+		step = math.Sqrt(width * height / DefaultPoints)
+	}
+	cols, rows := int(math.Ceil(width/step)), int(math.Ceil(height/step))
+	if cols*rows > MaxPoints {
+		return nil, fmt.Errorf("a %g m step gives %d x %d points, more than %d; use a larger step or a smaller area",
+			step, cols, rows, MaxPoints)
+	}
+
+	grid := &Grid{Area: area, Step: step, Cols: cols, Rows: rows, Points: make([]Point, 0, cols*rows), Sites: ran.SortedByID(gnbs)}
+	probe := ue.NewUE("", 0, 0)
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			probe.X = area.MinX + (float64(i)+0.5)*step
+			probe.Y = area.MinY + (float64(j)+0.5)*step
+			grid.Points = append(grid.Points, evaluate(probe, grid.Sites, gnbs, la))
+		}
+	}
+	return grid, nil
+}
+
+// evaluate finds the strongest cell at the probe's position and the link it would give.
+// On equal RSRP the lowest gNodeB ID (then cell) wins, as in cell selection.
+func evaluate(probe *ue.UE, sites []*ran.GNodeB, gnbs map[int]*ran.GNodeB, la radio.LinkAdaptation) Point {
+	p := Point{X: probe.X, Y: probe.Y}
+	var best *ran.Cell
+	var bestRSRP float64
+	for _, g := range sites {
+		if g.Down {
+			continue
+		}
+		for _, c := range g.Cells {
+			if rsrp := c.RSRP(probe); best == nil || rsrp > bestRSRP {
+				best, bestRSRP = c, rsrp
+			}
+		}
+	}
+	if best == nil {
+		return p
+	}
+	site := gnbs[best.GNodeBID()]
+	link := best.LinkTo(probe, gnbs, la)
+	p.GNodeB, p.Cell, p.PCI = site.ID, best.ID, best.PCI
+	p.InRange = math.Hypot(probe.X-site.X, probe.Y-site.Y) <= site.Range
+	p.RSRPDBm, p.SINRDB, p.ThroughputMbps = link.RSRPDBm, link.SINRDB, link.ThroughputMbps
+	return p
+}
+
+// ParseArea reads "minX,minY,maxX,maxY" in metres
+func ParseArea(s string) (Area, error) {
+	var a Area
+	if _, err := fmt.Sscanf(s, "%g,%g,%g,%g", &a.MinX, &a.MinY, &a.MaxX, &a.MaxY); err != nil {
+		return Area{}, fmt.Errorf("area must be minX,minY,maxX,maxY, got %q", s)
+	}
+	return a, nil
+}
@@ -0,0 +1,255 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// sites returns two omni gNodeBs 1 km apart with 400 m ranges, and a three-sector
+// site between them, by ID
+func sites(t *testing.T) map[int]*ran.GNodeB {
+	t.Helper()
+	gnbs := make(map[int]*ran.GNodeB)
+	for _, x := range []float64{0, 1000} {
+		g, err := ran.NewGNodeB(x, 0, 400, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gnbs[g.ID] = g
+	}
+	var cells []ran.CellConfig
+	for i, azimuth := range []float64{0, 120, 240} {
+		cells = append(cells, ran.CellConfig{
+			PCI:        500 + i,
+			Antenna:    radio.AntennaPattern{AzimuthDeg: azimuth, BeamwidthDeg: radio.DefaultSectorBeamwidth},
+			Carrier:    ran.DefaultCarrier,
+			TxPowerDBm: ran.DefaultTxPowerDBm,
+			MaxCap:     10,
+		})
+	}
+	g, err := ran.NewSectorGNodeB(500, 600, 300, cells)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gnbs[g.ID] = g
+	return gnbs
+}
+
+func TestComputeSize(t *testing.T) {
+	gnbs := sites(t)
+	tests := []struct {
+		name       string
+		area       Area
+		step       float64
+		cols, rows int
+		err        string
+	}{
+		{"exact fit", Area{0, 0, 1000, 500}, 100, 10, 5, ""},
+		{"partial squares round up", Area{0, 0, 1050, 510}, 100, 11, 6, ""},
+		{"negative coordinates", Area{-500, -300, 500, 300}, 50, 20, 12, ""},
+		{"default step", Area{0, 0, 2000, 2000}, 0, 100, 100, ""},
+		{"default step, not square", Area{0, 0, 4000, 1000}, 0, 200, 50, ""},
+		{"at the cap", Area{0, 0, 500, 500}, 1, 500, 500, ""},
+		{"over the cap", Area{0, 0, 501, 500}, 1, 0, 0, "a 1 m step gives 501 x 500 points, more than 250000; use a larger step or a smaller area"},
+		{"empty area", Area{0, 0, 0, 100}, 10, 0, 0, "empty area 0,0 - 0,100"},
+		{"inverted area", Area{100, 0, 0, 100}, 10, 0, 0, "empty area 100,0 - 0,100"},
+		{"negative step", Area{0, 0, 100, 100}, -1, 0, 0, "step must be > 0, got -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid, err := Compute(gnbs, tt.area, tt.step, radio.LinkAdaptation{})
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if grid.Cols != tt.cols || grid.Rows != tt.rows || len(grid.Points) != tt.cols*tt.rows {
+				t.Fatalf("%d x %d grid of %d points, want %d x %d", grid.Cols, grid.Rows, len(grid.Points), tt.cols, tt.rows)
+			}
+			// Points sit at the centres of their squares, row by row from the bottom
+			first, last := grid.At(0, 0), grid.At(grid.Cols-1, grid.Rows-1)
+			half := grid.Step / 2
+			if first.X != tt.area.MinX+half || first.Y != tt.area.MinY+half {
+				t.Errorf("first point at %g,%g, want %g,%g", first.X, first.Y, tt.area.MinX+half, tt.area.MinY+half)
+			}
+			if want := tt.area.MinX + (float64(grid.Cols)-0.5)*grid.Step; math.Abs(last.X-want) > 1e-9 {
+				t.Errorf("last column at x %g, want %g", last.X, want)
+			}
+			if p := grid.At(1, 0); p.Y != first.Y || p.X <= first.X {
+				t.Errorf("second point at %g,%g, want the next column of the bottom row", p.X, p.Y)
+			}
+		})
+	}
+
+	if _, err := Compute(nil, Area{0, 0, 1, 1}, 1, radio.LinkAdaptation{}); err == nil || err.Error() != "no gNodeBs to map" {
+		t.Errorf("no gNodeBs gave %v", err)
+	}
+}
+
+func TestComputeBestServer(t *testing.T) {
+	gnbs := sites(t)
+	sorted := ran.SortedByID(gnbs)
+	west, east, sectors := sorted[0], sorted[1], sorted[2]
+	grid, err := Compute(gnbs, Around(sorted), 50, radio.LinkAdaptation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := grid.Area; a != (Area{-400, -400, 1400, 900}) {
+		t.Errorf("area around the sites %+v", a)
+	}
+
+	// Step 1: every point's best server is the strongest cell a scan of them all finds,
+	// and its values are the link to that cell
+	probe := ue.NewUE("", 0, 0)
+	for _, p := range grid.Points {
+		probe.X, probe.Y = p.X, p.Y
+		var best *ran.Cell
+		var bestRSRP float64
+		for _, g := range sorted {
+			for _, c := range g.Cells {
+				if rsrp := c.RSRP(probe); best == nil || rsrp > bestRSRP {
+					best, bestRSRP = c, rsrp
+				}
+			}
+		}
+		if p.GNodeB != best.GNodeBID() || p.Cell != best.ID || p.PCI != best.PCI {
+			t.Fatalf("point %g,%g served by gNodeB %d cell %d, want gNodeB %d cell %d", p.X, p.Y, p.GNodeB, p.Cell, best.GNodeBID(), best.ID)
+		}
+		link := best.LinkTo(probe, gnbs, radio.LinkAdaptation{})
+		if p.RSRPDBm != bestRSRP || p.SINRDB != link.SINRDB || p.ThroughputMbps != link.ThroughputMbps {
+			t.Fatalf("point %g,%g: %+v, want %+v", p.X, p.Y, p, link)
+		}
+		site := gnbs[p.GNodeB]
+		if inRange := math.Hypot(p.X-site.X, p.Y-site.Y) <= site.Range; p.InRange != inRange {
+			t.Fatalf("point %g,%g in range %v, want %v", p.X, p.Y, p.InRange, inRange)
+		}
+	}
+
+	// Step 2: spot checks: next to each omni site, and in front of each sector
+	at := func(x, y float64) *Point {
+		return grid.At(int((x-grid.Area.MinX)/grid.Step), int((y-grid.Area.MinY)/grid.Step))
+	}
+	for _, tt := range []struct {
+		x, y   float64
+		gnodeb int
+		pci    int
+	}{
+		{25, -25, west.ID, west.Cells[0].PCI},
+		{975, -25, east.ID, east.Cells[0].PCI},
+		{525, 825, sectors.ID, 500},              // north
+		{725, 525, sectors.ID, 501},              // east-south-east
+		{275, 525, sectors.ID, 502},              // west-south-west
+		{-375, -375, west.ID, west.Cells[0].PCI}, // far corner: beyond range, still a best server
+	} {
+		p := at(tt.x, tt.y)
+		if p.GNodeB != tt.gnodeb || p.PCI != tt.pci {
+			t.Errorf("point %g,%g served by gNodeB %d PCI %d, want gNodeB %d PCI %d", p.X, p.Y, p.GNodeB, p.PCI, tt.gnodeb, tt.pci)
+		}
+	}
+	if p := at(-375, -375); p.InRange {
+		t.Errorf("far corner %g,%g is in range", p.X, p.Y)
+	}
+	if near, far := at(25, -25), at(375, -25); near.RSRPDBm <= far.RSRPDBm || near.SINRDB <= far.SINRDB {
+		t.Errorf("RSRP %.1f and SINR %.1f next to the site, %.1f and %.1f 350 m away", near.RSRPDBm, near.SINRDB, far.RSRPDBm, far.SINRDB)
+	}
+
+	// Step 3: a site that is down neither serves nor interferes, and with every site
+	// down nothing is on air
+	east.Down = true
+	grid, err = Compute(gnbs, Area{900, -100, 1100, 100}, 50, radio.LinkAdaptation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range grid.Points {
+		if p.GNodeB == east.ID {
+			t.Fatalf("point %g,%g served by a gNodeB that is down", p.X, p.Y)
+		}
+	}
+	west.Down, sectors.Down = true, true
+	grid, err = Compute(gnbs, Area{900, -100, 1100, 100}, 50, radio.LinkAdaptation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := grid.At(0, 0); *p != (Point{X: p.X, Y: p.Y}) {
+		t.Errorf("with every site down, point is %+v", p)
+	}
+}
+
+func TestExport(t *testing.T) {
+	gnbs := sites(t)
+	grid, err := Compute(gnbs, Area{-200, -100, 200, 100}, 100, radio.LinkAdaptation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CSV: a header, then one row per point; lat/lon only with a projection
+	for _, projection := range []*geo.Projection{nil, geo.NewProjection(geo.LatLon{Lat: 52, Lon: 5})} {
+		var buf bytes.Buffer
+		if err := grid.WriteCSV(&buf, projection); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns := 9
+		if projection != nil {
+			columns = 11
+		}
+		if len(rows) != 1+len(grid.Points) || len(rows[0]) != columns {
+			t.Fatalf("%d rows of %d columns, want %d of %d", len(rows), len(rows[0]), 1+len(grid.Points), columns)
+		}
+		if row := strings.Join(rows[1][:2], ","); row != "-150.00,-50.00" {
+			t.Errorf("first point %s, want -150.00,-50.00", row)
+		}
+	}
+
+	// GeoJSON: one closed square per point
+	fc := grid.GeoJSON(geo.NewProjection(geo.LatLon{Lat: 52, Lon: 5}))
+	if len(fc.Features) != len(grid.Points) {
+		t.Fatalf("%d features, want %d", len(fc.Features), len(grid.Points))
+	}
+	if ring := fc.Features[0].Geometry.Coordinates.([][][]float64)[0]; len(ring) != 5 {
+		t.Errorf("square of %d positions, want 5", len(ring))
+	}
+
+	// PNG: scale pixels per point, for every metric
+	for _, metric := range Metrics {
+		var buf bytes.Buffer
+		if err := grid.WritePNG(&buf, metric, 3); err != nil {
+			t.Fatalf("%s: %v", metric, err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", metric, err)
+		}
+		if b := img.Bounds(); b.Dx() != 3*grid.Cols || b.Dy() != 3*grid.Rows {
+			t.Errorf("%s: %d x %d image, want %d x %d", metric, b.Dx(), b.Dy(), 3*grid.Cols, 3*grid.Rows)
+		}
+	}
+	if err := grid.WritePNG(&bytes.Buffer{}, "noise", 1); err == nil {
+		t.Error("unknown metric gave no error")
+	}
+}
+
+func TestParseArea(t *testing.T) {
+	if a, err := ParseArea("-10,-20.5,30,40"); err != nil || a != (Area{-10, -20.5, 30, 40}) {
+		t.Errorf("got %+v, %v", a, err)
+	}
+	if _, err := ParseArea("1,2,3"); err == nil || err.Error() != `area must be minX,minY,maxX,maxY, got "1,2,3"` {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package coverage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/rizpur/NetSim5G/internal/geo"
+)
+
+// Metrics a heatmap can show
+const (
+	MetricRSRP       = "rsrp"
+	MetricSINR       = "sinr"
+	MetricThroughput = "throughput"
+	MetricServer     = "server" // one colour per best-serving gNodeB
+)
+
+// Metrics lists every metric, the default first
+var Metrics = []string{MetricRSRP, MetricSINR, MetricThroughput, MetricServer}
+
+// Colour scales: the value drawn red, and the value drawn green
+var scales = map[string][2]float64{
+	MetricRSRP: {-120, -70}, // dBm
+	MetricSINR: {-5, 25},    // dB
+}
+
+// WriteCSV writes one row per point, bottom row first. With a projection, every row
+// also gives the point as lat/lon.
+func (g *Grid) WriteCSV(w io.Writer, projection *geo.Projection) error {
+	out := csv.NewWriter(w)
+	header := []string{"x", "y"}
+	if projection != nil {
+		header = append(header, "lat", "lon")
+	}
+	header = append(header, "gnodeb", "cell", "pci", "inRange", "rsrpDBm", "sinrDB", "throughputMbps")
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	number := func(v float64, decimals int) string { return strconv.FormatFloat(v, 'f', decimals, 64) }
+	for _, p := range g.Points {
+		row := []string{number(p.X, 2), number(p.Y, 2)}
+		if projection != nil {
+			pos := projection.FromXY(p.X, p.Y)
+			row = append(row, number(pos.Lat, 7), number(pos.Lon, 7))
+		}
+		if p.GNodeB == 0 {
+			row = append(row, "", "", "", "false", "", "", "")
+		} else {
+			row = append(row, strconv.Itoa(p.GNodeB), strconv.Itoa(p.Cell), strconv.Itoa(p.PCI), strconv.FormatBool(p.InRange),
+				number(p.RSRPDBm, 2), number(p.SINRDB, 2), number(p.ThroughputMbps, 2))
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// GeoJSON returns the grid as one square Polygon per point, with the point's values
+// as properties
+func (g *Grid) GeoJSON(projection *geo.Projection) *geo.FeatureCollection {
+	fc := geo.NewFeatureCollection()
+	half := g.Step / 2
+	for _, p := range g.Points {
+		square := []geo.LatLon{
+			projection.FromXY(p.X-half, p.Y-half),
+			projection.FromXY(p.X+half, p.Y-half),
+			projection.FromXY(p.X+half, p.Y+half),
+			projection.FromXY(p.X-half, p.Y+half),
+		}
+		props := map[string]interface{}{"x": math.Round(p.X*100) / 100, "y": math.Round(p.Y*100) / 100, "inRange": p.InRange}
+		if p.GNodeB != 0 {
+			props["gnodeb"], props["cell"], props["pci"] = p.GNodeB, p.Cell, p.PCI
+			props["rsrpDBm"] = math.Round(p.RSRPDBm*100) / 100
+			props["sinrDB"] = math.Round(p.SINRDB*100) / 100
+			props["throughputMbps"] = math.Round(p.ThroughputMbps*100) / 100
+		}
+		fc.Add(geo.Polygon(square), props)
+	}
+	return fc
+}
+
+// WritePNG renders metric as a heatmap, scale pixels per point (0 = fit about 800
+// pixels), north up. Values run from red (poor) through yellow to green (good);
+// throughput is scaled to the best point of the grid. Points beyond the best server's
+// range are faded and the gNodeBs are marked in black.
+func (g *Grid) WritePNG(w io.Writer, metric string, scale int) error {
+	if scale <= 0 {
+		scale = int(math.Max(1, math.Floor(800/math.Max(float64(g.Cols), float64(g.Rows)))))
+	}
+	if g.Cols*g.Rows*scale*scale > 64*MaxPoints {
+		return fmt.Errorf("a %d x %d image is too large, use a smaller scale", g.Cols*scale, g.Rows*scale)
+	}
+
+	// Step 1: How each point is coloured
+	var shade func(p *Point) color.RGBA
+	switch metric {
+	case MetricRSRP, MetricSINR, MetricThroughput:
+		low, high := scales[metric][0], scales[metric][1]
+		value := func(p *Point) float64 { return p.RSRPDBm }
+		if metric == MetricSINR {
+			value = func(p *Point) float64 { return p.SINRDB }
+		}
+		if metric == MetricThroughput {
+			value = func(p *Point) float64 { return p.ThroughputMbps }
+			for _, p := range g.Points {
+				high = math.Max(high, p.ThroughputMbps)
+			}
+		}
+		shade = func(p *Point) color.RGBA { return ramp((value(p) - low) / (high - low)) }
+	case MetricServer:
+		order := make(map[int]int)
+		for i, site := range g.Sites {
+			order[site.ID] = i
+		}
+		shade = func(p *Point) color.RGBA { return palette[order[p.GNodeB]%len(palette)] }
+	default:
+		return fmt.Errorf("unknown metric %q, use one of %v", metric, Metrics)
+	}
+
+	// Step 2: Points, with row 0 of the image at the top (MaxY)
+	img := image.NewRGBA(image.Rect(0, 0, g.Cols*scale, g.Rows*scale))
+	for j := 0; j < g.Rows; j++ {
+		for i := 0; i < g.Cols; i++ {
+			p := g.At(i, j)
+			c := color.RGBA{200, 200, 200, 255} // nothing on air
+			if p.GNodeB != 0 {
+				c = shade(p)
+				if !p.InRange {
+					c = fade(c)
+				}
+			}
+			fill(img, i*scale, (g.Rows-1-j)*scale, scale, c)
+		}
+	}
+
+	// Step 3: Sites
+	marker := int(math.Max(3, float64(scale)))
+	for _, site := range g.Sites {
+		px := int((site.X - g.Area.MinX) / g.Step * float64(scale))
+		py := int((g.Area.MaxY - site.Y) / g.Step * float64(scale))
+		fill(img, px-marker/2, py-marker/2, marker, color.RGBA{0, 0, 0, 255})
+	}
+	return png.Encode(w, img)
+}
+
+// ramp maps 0..1 to red, yellow, green
+func ramp(v float64) color.RGBA {
+	v = math.Max(0, math.Min(1, v))
+	if v < 0.5 {
+		return color.RGBA{220, uint8(220 * v * 2), 0, 255}
+	}
+	return color.RGBA{uint8(220 * (1 - v) * 2), 200, 0, 255}
+}
+
+// fade blends a colour halfway to white
+func fade(c color.RGBA) color.RGBA {
+	return color.RGBA{c.R/2 + 128, c.G/2 + 128, c.B/2 + 128, 255}
+}
+
+// fill paints a size x size square; the parts outside the image are dropped
+func fill(img *image.RGBA, x, y, size int, c color.RGBA) {
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			if image.Pt(x+dx, y+dy).In(img.Rect) {
+				img.SetRGBA(x+dx, y+dy, c)
+			}
+		}
+	}
+}
+
+// palette tells best servers apart; it repeats after 12 gNodeBs
+var palette = []color.RGBA{
+	{31, 119, 180, 255}, {255, 127, 14, 255}, {44, 160, 44, 255}, {214, 39, 40, 255},
+	{148, 103, 189, 255}, {140, 86, 75, 255}, {227, 119, 194, 255}, {127, 127, 127, 255},
+	{188, 189, 34, 255}, {23, 190, 207, 255}, {174, 199, 232, 255}, {255, 187, 120, 255},
+}
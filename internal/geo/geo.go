@@ -0,0 +1,127 @@
+// Package geo converts between WGS84 latitude/longitude and the flat X/Y metres the
+// simulator works in. Positions are projected onto the local East-North-Up tangent
+// plane at an origin, so X is metres east and Y metres north of it; over the few
+// kilometres of a radio network the projection is accurate to millimetres.
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// WGS84 ellipsoid
+const (
+	semiMajorAxis = 6378137.0
+	flattening    = 1 / 298.257223563
+	eccentricity2 = flattening * (2 - flattening)
+)
+
+// MeanEarthRadius (m) is used for great-circle distances
+const MeanEarthRadius = 6371008.8
+
+// LatLon is a WGS84 position in degrees, with altitude in metres above the ellipsoid
+type LatLon struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	Alt float64 `json:"alt,omitempty"`
+}
+
+func (p LatLon) String() string {
+	return fmt.Sprintf("%.6f,%.6f", p.Lat, p.Lon)
+}
+
+// Valid reports whether the latitude and longitude are in range
+func (p LatLon) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Distance returns the great-circle distance between two positions in metres: the
+// haversine formula on a sphere of MeanEarthRadius, within 0.5% of the distance on
+// the ellipsoid at any range. Altitude is ignored.
+func Distance(a, b LatLon) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * MeanEarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Projection maps positions to and from the ENU plane at Origin
+type Projection struct {
+	Origin         LatLon
+	x0, y0, z0     float64 // origin in ECEF
+	sinLat, cosLat float64
+	sinLon, cosLon float64
+}
+
+// NewProjection returns the ENU projection centred on origin
+func NewProjection(origin LatLon) *Projection {
+	p := &Projection{Origin: origin}
+	p.x0, p.y0, p.z0 = toECEF(origin)
+	p.sinLat, p.cosLat = math.Sincos(radians(origin.Lat))
+	p.sinLon, p.cosLon = math.Sincos(radians(origin.Lon))
+	return p
+}
+
+// ToENU returns a position's offset from the origin in metres: east, north and up
+func (p *Projection) ToENU(pos LatLon) (east, north, up float64) {
+	x, y, z := toECEF(pos)
+	dx, dy, dz := x-p.x0, y-p.y0, z-p.z0
+	east = -p.sinLon*dx + p.cosLon*dy
+	north = -p.sinLat*p.cosLon*dx - p.sinLat*p.sinLon*dy + p.cosLat*dz
+	up = p.cosLat*p.cosLon*dx + p.cosLat*p.sinLon*dy + p.sinLat*dz
+	return east, north, up
+}
+
+// FromENU returns the position at an offset from the origin
+func (p *Projection) FromENU(east, north, up float64) LatLon {
+	dx := -p.sinLon*east - p.sinLat*p.cosLon*north + p.cosLat*p.cosLon*up
+	dy := p.cosLon*east - p.sinLat*p.sinLon*north + p.cosLat*p.sinLon*up
+	dz := p.cosLat*north + p.sinLat*up
+	return fromECEF(p.x0+dx, p.y0+dy, p.z0+dz)
+}
+
+// ToXY projects a position onto the simulation plane
+func (p *Projection) ToXY(pos LatLon) (x, y float64) {
+	x, y, _ = p.ToENU(pos)
+	return x, y
+}
+
+// FromXY returns the position of a point of the simulation plane, on the ground
+// (the altitude of the origin)
+func (p *Projection) FromXY(x, y float64) LatLon {
+	pos := p.FromENU(x, y, 0)
+	// The tangent plane rises above the ellipsoid away from the origin; keep the
+	// horizontal position and report the origin's altitude
+	pos.Alt = p.Origin.Alt
+	return pos
+}
+
+// toECEF converts a geodetic position to Earth-centred, Earth-fixed coordinates
+func toECEF(pos LatLon) (x, y, z float64) {
+	sinLat, cosLat := math.Sincos(radians(pos.Lat))
+	sinLon, cosLon := math.Sincos(radians(pos.Lon))
+	n := semiMajorAxis / math.Sqrt(1-eccentricity2*sinLat*sinLat) // prime vertical radius
+	x = (n + pos.Alt) * cosLat * cosLon
+	y = (n + pos.Alt) * cosLat * sinLon
+	z = (n*(1-eccentricity2) + pos.Alt) * sinLat
+	return x, y, z
+}
+
+// fromECEF converts back to geodetic, iterating on the latitude (converges to well
+// below a millimetre in a few rounds anywhere near the surface)
+func fromECEF(x, y, z float64) LatLon {
+	lon := math.Atan2(y, x)
+	p := math.Hypot(x, y)
+	lat := math.Atan2(z, p*(1-eccentricity2))
+	alt := 0.0
+	for i := 0; i < 5; i++ {
+		sinLat := math.Sin(lat)
+		n := semiMajorAxis / math.Sqrt(1-eccentricity2*sinLat*sinLat)
+		alt = p/math.Cos(lat) - n
+		lat = math.Atan2(z, p*(1-eccentricity2*n/(n+alt)))
+	}
+	return LatLon{Lat: degrees(lat), Lon: degrees(lon), Alt: alt}
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
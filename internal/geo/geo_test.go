@@ -0,0 +1,119 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	paris  = LatLon{Lat: 48.8566, Lon: 2.3522}
+	london = LatLon{Lat: 51.5074, Lon: -0.1278}
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b LatLon
+		want float64 // metres
+		tol  float64
+	}{
+		{"same point", paris, paris, 0, 1e-9},
+		{"one degree of meridian", LatLon{}, LatLon{Lat: 1}, math.Pi * MeanEarthRadius / 180, 1e-6},
+		{"one degree of equator", LatLon{}, LatLon{Lon: 1}, math.Pi * MeanEarthRadius / 180, 1e-6},
+		{"antipodes", LatLon{Lat: 10, Lon: 20}, LatLon{Lat: -10, Lon: -160}, math.Pi * MeanEarthRadius, 1e-6},
+		{"pole to pole", LatLon{Lat: 90}, LatLon{Lat: -90}, math.Pi * MeanEarthRadius, 1e-6},
+		{"across the antimeridian", LatLon{Lon: 179.5}, LatLon{Lon: -179.5}, math.Pi * MeanEarthRadius / 180, 1e-6},
+		// Paris to London, 343.6 km on the sphere
+		{"Paris to London", paris, london, 343557, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Distance(tt.a, tt.b); math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("Distance(%v, %v) = %.3f m, want %.3f", tt.a, tt.b, got, tt.want)
+			}
+			if got, back := Distance(tt.a, tt.b), Distance(tt.b, tt.a); got != back {
+				t.Errorf("Distance is not symmetric: %v one way, %v back", got, back)
+			}
+		})
+	}
+}
+
+func TestProjectionRoundTrip(t *testing.T) {
+	for _, origin := range []LatLon{paris, {Lat: -33.8688, Lon: 151.2093, Alt: 58}, {Lat: 0, Lon: 0}, {Lat: 78.2232, Lon: 15.6267}} {
+		p := NewProjection(origin)
+		// Step 1: the origin is at 0,0,0
+		if e, n, u := p.ToENU(origin); math.Abs(e) > 1e-6 || math.Abs(n) > 1e-6 || math.Abs(u) > 1e-6 {
+			t.Errorf("origin %v projects to %g,%g,%g, want 0,0,0", origin, e, n, u)
+		}
+		// Step 2: points of the plane come back where they were. FromXY drops them onto
+		// the ground, which pulls them in by a few centimetres at 10 km.
+		for _, xy := range [][2]float64{{0, 0}, {1000, 0}, {0, -1000}, {-2500, 4000}, {10000, 10000}} {
+			pos := p.FromXY(xy[0], xy[1])
+			tol := 1e-3 + 5e-6*math.Hypot(xy[0], xy[1])
+			if x, y := p.ToXY(pos); math.Abs(x-xy[0]) > tol || math.Abs(y-xy[1]) > tol {
+				t.Errorf("origin %v: %v -> %v -> %.6f,%.6f", origin, xy, pos, x, y)
+			}
+			if pos.Alt != origin.Alt {
+				t.Errorf("origin %v: FromXY altitude %g, want the origin's", origin, pos.Alt)
+			}
+		}
+		// Step 3: and positions survive ENU and back, altitude included
+		pos := LatLon{Lat: origin.Lat + 0.02, Lon: origin.Lon - 0.03, Alt: 120}
+		back := p.FromENU(p.ToENU(pos))
+		if math.Abs(back.Lat-pos.Lat) > 1e-9 || math.Abs(back.Lon-pos.Lon) > 1e-9 || math.Abs(back.Alt-pos.Alt) > 1e-6 {
+			t.Errorf("origin %v: %+v -> ENU -> %+v", origin, pos, back)
+		}
+	}
+}
+
+func TestProjectionAxes(t *testing.T) {
+	p := NewProjection(paris)
+
+	// X is east, Y is north
+	x, y := p.ToXY(LatLon{Lat: paris.Lat + 0.01, Lon: paris.Lon})
+	// A hundredth of a degree of latitude is 1112 m at 49N on the ellipsoid
+	if math.Abs(x) > 1e-3 || math.Abs(y-1112.07) > 0.01 {
+		t.Errorf("0.01 deg north projects to %.3f,%.3f, want 0,1112.07", x, y)
+	}
+	x, y = p.ToXY(LatLon{Lat: paris.Lat, Lon: paris.Lon + 0.01})
+	if x < 700 || x > 760 || math.Abs(y) > 0.1 {
+		t.Errorf("0.01 deg east projects to %.3f,%.3f, want about 733,0", x, y)
+	}
+
+	// Over a few kilometres the plane and the great circle agree to within 0.5%
+	pos := p.FromXY(3000, -4000)
+	if d := Distance(paris, pos); math.Abs(d-5000) > 25 {
+		t.Errorf("3000,-4000 is %.1f m from the origin, want 5000", d)
+	}
+}
+
+func TestSector(t *testing.T) {
+	p := NewProjection(paris)
+	// A 120 degree sector facing east: the centre, then arcs every 10 degrees
+	ring := p.Sector(0, 0, 1000, 90, 120)
+	if len(ring) != 14 {
+		t.Fatalf("sector has %d points, want 14", len(ring))
+	}
+	if x, y := p.ToXY(ring[0]); math.Abs(x) > 1e-6 || math.Abs(y) > 1e-6 {
+		t.Errorf("sector starts at %g,%g, want the centre", x, y)
+	}
+	for _, pos := range ring[1:] {
+		x, y := p.ToXY(pos)
+		if r := math.Hypot(x, y); math.Abs(r-1000) > 1e-3 || x < 0 {
+			t.Errorf("arc point at %.3f,%.3f, want 1000 m east of the centre", x, y)
+		}
+	}
+	// A full circle has no centre point
+	if circle := p.Sector(0, 0, 1000, 0, 360); len(circle) != 37 {
+		t.Errorf("circle has %d points, want 37", len(circle))
+	}
+
+	poly := Polygon(ring)
+	rings := poly.Coordinates.([][][]float64)
+	if first, last := rings[0][0], rings[0][len(rings[0])-1]; len(rings[0]) != 15 || first[0] != last[0] || first[1] != last[1] {
+		t.Errorf("polygon ring of %d positions from %v to %v, want 15 and closed", len(rings[0]), first, last)
+	}
+	if pt := Point(LatLon{Lat: 1.123456789, Lon: 2}).Coordinates.([]float64); pt[0] != 2 || pt[1] != 1.1234568 {
+		t.Errorf("point coordinates %v, want [2 1.1234568]", pt)
+	}
+}
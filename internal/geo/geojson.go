@@ -0,0 +1,87 @@
+package geo
+
+import "math"
+
+// FeatureCollection is a GeoJSON document (RFC 7946)
+type FeatureCollection struct {
+	Type     string     `json:"type"` // always "FeatureCollection"
+	Features []*Feature `json:"features"`
+}
+
+// Feature is a geometry with properties
+type Feature struct {
+	Type       string                 `json:"type"` // always "Feature"
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a Point ([lon, lat]), LineString ([][lon, lat]) or Polygon ([][][lon, lat])
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// NewFeatureCollection returns an empty collection
+func NewFeatureCollection() *FeatureCollection {
+	return &FeatureCollection{Type: "FeatureCollection", Features: []*Feature{}}
+}
+
+// Add appends a feature with the given geometry and properties
+func (c *FeatureCollection) Add(geometry Geometry, properties map[string]interface{}) {
+	c.Features = append(c.Features, &Feature{Type: "Feature", Geometry: geometry, Properties: properties})
+}
+
+// Point is the geometry of one position
+func Point(p LatLon) Geometry {
+	return Geometry{Type: "Point", Coordinates: position(p)}
+}
+
+// LineString is the geometry of a line through the positions
+func LineString(points ...LatLon) Geometry {
+	line := make([][]float64, len(points))
+	for i, p := range points {
+		line[i] = position(p)
+	}
+	return Geometry{Type: "LineString", Coordinates: line}
+}
+
+// Polygon is the geometry of an area bounded by ring. The ring is closed if it is not
+// already, and should run counterclockwise.
+func Polygon(ring []LatLon) Geometry {
+	closed := make([][]float64, 0, len(ring)+1)
+	for _, p := range ring {
+		closed = append(closed, position(p))
+	}
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		closed = append(closed, position(ring[0]))
+	}
+	return Geometry{Type: "Polygon", Coordinates: [][][]float64{closed}}
+}
+
+// Sector returns the ring of a circular sector of the plane around x,y: radius metres,
+// centred on azimuth (degrees clockwise from north, as antennas point) and
+// beamwidth degrees wide; 360 gives a circle. Arcs are drawn every 10 degrees at most.
+func (p *Projection) Sector(x, y, radius, azimuth, beamwidth float64) []LatLon {
+	var ring []LatLon
+	if beamwidth < 360 {
+		ring = append(ring, p.FromXY(x, y))
+	} else {
+		beamwidth = 360
+	}
+	steps := int(math.Ceil(beamwidth / 10))
+	for i := 0; i <= steps; i++ {
+		bearing := azimuth + beamwidth/2 - beamwidth*float64(i)/float64(steps) // counterclockwise
+		ring = append(ring, p.FromXY(x+radius*math.Sin(radians(bearing)), y+radius*math.Cos(radians(bearing))))
+	}
+	return ring
+}
+
+// position is a GeoJSON position: longitude first
+func position(p LatLon) []float64 {
+	// 7 decimals is about a centimetre, more than the simulation resolves
+	round := func(v float64) float64 { return math.Round(v*1e7) / 1e7 }
+	if p.Alt != 0 {
+		return []float64{round(p.Lon), round(p.Lat), math.Round(p.Alt*100) / 100}
+	}
+	return []float64{round(p.Lon), round(p.Lat)}
+}
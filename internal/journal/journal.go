@@ -0,0 +1,261 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+// Kind names what happened
+type Kind string
+
+const (
+	UECreated          Kind = "ueCreated"
+	UEMoved            Kind = "ueMoved"
+	UERemoved          Kind = "ueRemoved"
+	Connected          Kind = "connected"          // RRC setup, resume or service request (Detail says which)
+	ConnectionFailed   Kind = "connectionFailed"   // no cell could take the UE
+	Released           Kind = "released"           // inactivity timer: RRC_IDLE or RRC_INACTIVE
+	Registered         Kind = "registered"         // NAS registration accepted
+	RegistrationFailed Kind = "registrationFailed" // rejected by the UDM check
+	RegistrationUpdate Kind = "registrationUpdate" // idle UE camped in a new tracking area
+	RNAUpdate          Kind = "rnaUpdate"          // inactive UE left its RAN notification area
+	Deregistered       Kind = "deregistered"
+	Paging             Kind = "paging"
+	PagingFailed       Kind = "pagingFailed"
+	Handover           Kind = "handover"
+	HandoverFailed     Kind = "handoverFailed"
+	SessionEstablished Kind = "sessionEstablished"
+	SessionRejected    Kind = "sessionRejected"
+	SessionReleased    Kind = "sessionReleased"
+	GNodeBFailed       Kind = "gnodebFailed"
+	GNodeBRestored     Kind = "gnodebRestored"
+	OutageFailed       Kind = "outageFailed" // a scheduled fail or restore could not run (Detail says which)
+	GNodeBAdded        Kind = "gnodebAdded"
+	GNodeBUpdated      Kind = "gnodebUpdated" // moved, or its range changed
+	GNodeBRemoved      Kind = "gnodebRemoved"
+	RadioLinkFailure   Kind = "radioLinkFailure"
+	Reestablished      Kind = "reestablished"
+	Dropped            Kind = "dropped" // radio link failure with no neighbour: RRC_IDLE, sessions released
+	SubscriberUpdated  Kind = "subscriberUpdated"
+	SnapshotRestored   Kind = "snapshotRestored" // replay starts over from the events that follow
+)
+
+// UEState is a UE as it is right after the event
+type UEState struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	State      string  `json:"state"`            // RRC state
+	GNodeB     int     `json:"gnodeb"`           // serving gNodeB, -1 = none
+	Cell       int     `json:"cell"`             // serving cell, -1 = none
+	Registered bool    `json:"registered"`       // known to the AMF
+	CM         string  `json:"cm,omitempty"`     // CM state, when registered
+	Anchor     int     `json:"anchor,omitempty"` // AMF's serving or anchor gNodeB, when registered
+}
+
+// CellRef points at one cell
+type CellRef struct {
+	GNodeB int `json:"gnodeb"`
+	Cell   int `json:"cell"`
+}
+
+// Event is one journal entry. Seq numbers are unique and increasing; Cause is the
+// Seq of the event that led to this one (a handover caused by a move, a session
+// released by a radio link failure), 0 when it was triggered from outside.
+type Event struct {
+	Seq         uint64    `json:"seq"`
+	Time        time.Time `json:"time"` // simulated time
+	Kind        Kind      `json:"kind"`
+	Cause       uint64    `json:"cause,omitempty"`
+	IMSI        string    `json:"imsi,omitempty"`
+	GNodeB      int       `json:"gnodeb,omitempty"`
+	From        *CellRef  `json:"from,omitempty"`
+	To          *CellRef  `json:"to,omitempty"`
+	Session     int       `json:"session,omitempty"`
+	SessionType string    `json:"sessionType,omitempty"`
+	UE          *UEState  `json:"ue,omitempty"`
+	LatencyMs   float64   `json:"latencyMs,omitempty"` // how long the procedure took
+	Detail      string    `json:"detail,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// MaxRecent is how many events a journal keeps in memory for Since
+const MaxRecent = 10000
+
+// Journal collects the events of one network, stamps them with simulated time and
+// writes them as JSON lines. Every method works on a nil Journal and does nothing,
+// so components may be used without one.
+type Journal struct {
+	Clock  *sim.Clock
+	mu     sync.Mutex
+	out    *bufio.Writer
+	err    error // first write error, later events are kept in memory only
+	recent []Event
+	seq    uint64
+	causes []uint64 // events currently open with Begin, innermost last
+	watch  []func(Event)
+}
+
+func New(clock *sim.Clock) *Journal {
+	return &Journal{Clock: clock}
+}
+
+// SetOutput starts writing every new event to w as one JSON object per line;
+// nil stops writing. Call Flush before closing w.
+func (j *Journal) SetOutput(w io.Writer) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.flush()
+	j.out, j.err = nil, nil
+	if w != nil {
+		j.out = bufio.NewWriter(w)
+	}
+}
+
+// Subscribe calls fn with every event recorded from now on, in order. fn runs on the
+// goroutine that records the event and must not record events itself.
+func (j *Journal) Subscribe(fn func(Event)) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.watch = append(j.watch, fn)
+}
+
+// Record stamps e with the next Seq and the current simulated time, links it to the
+// innermost open event, stores it and tells subscribers. It returns the new Seq.
+func (j *Journal) Record(e Event) uint64 {
+	if j == nil {
+		return 0
+	}
+	e = j.store(e)
+	j.mu.Lock()
+	watch := j.watch
+	j.mu.Unlock()
+	for _, fn := range watch {
+		fn(e)
+	}
+	return e.Seq
+}
+
+func (j *Journal) store(e Event) Event {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.seq++
+	e.Seq = j.seq
+	e.Time = j.Clock.Now()
+	if e.Cause == 0 && len(j.causes) > 0 {
+		e.Cause = j.causes[len(j.causes)-1]
+	}
+
+	j.recent = append(j.recent, e)
+	if len(j.recent) > MaxRecent {
+		j.recent = j.recent[len(j.recent)-MaxRecent:]
+	}
+	if j.out != nil && j.err == nil {
+		data, err := json.Marshal(e)
+		if err == nil {
+			_, err = j.out.Write(append(data, '\n'))
+		}
+		j.err = err
+	}
+	return e
+}
+
+// Begin records e and makes it the cause of everything recorded until the matching End
+func (j *Journal) Begin(e Event) uint64 {
+	if j == nil {
+		return 0
+	}
+	seq := j.Record(e)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.causes = append(j.causes, seq)
+	return seq
+}
+
+// End closes the innermost event opened with Begin
+func (j *Journal) End() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.causes) > 0 {
+		j.causes = j.causes[:len(j.causes)-1]
+	}
+}
+
+// Since returns the events kept in memory with a Seq above seq, oldest first
+func (j *Journal) Since(seq uint64) []Event {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var events []Event
+	for _, e := range j.recent {
+		if e.Seq > seq {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Last returns the Seq of the latest event, 0 before the first
+func (j *Journal) Last() uint64 {
+	if j == nil {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// Flush writes buffered events out and reports the first write error, if any
+func (j *Journal) Flush() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.flush()
+	return j.err
+}
+
+func (j *Journal) flush() {
+	if j.out != nil && j.err == nil {
+		j.err = j.out.Flush()
+	}
+}
+
+// Read parses a journal written as JSON lines
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("journal line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,123 @@
+package journal
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+func TestJournalCauses(t *testing.T) {
+	j := New(sim.NewClock(sim.Epoch))
+	var seen []uint64
+	j.Subscribe(func(e Event) { seen = append(seen, e.Seq) })
+
+	// A move leads to a handover, whose admission releases a session; then an
+	// unrelated attach
+	move := j.Begin(Event{Kind: UEMoved, IMSI: "1"})
+	measured := j.Record(Event{Kind: Connected, IMSI: "1"})
+	handover := j.Begin(Event{Kind: Handover, IMSI: "1"})
+	released := j.Record(Event{Kind: SessionReleased, IMSI: "1"})
+	explicit := j.Record(Event{Kind: Paging, IMSI: "2", Cause: move})
+	j.End()
+	after := j.Record(Event{Kind: Registered, IMSI: "1"})
+	j.End()
+	outside := j.Record(Event{Kind: Connected, IMSI: "2"})
+	j.End() // unmatched: no effect
+	last := j.Record(Event{Kind: Registered, IMSI: "2"})
+
+	want := map[uint64]uint64{
+		move:     0,
+		measured: move,
+		handover: move,
+		released: handover,
+		explicit: move, // a cause given by the caller is kept
+		after:    move,
+		outside:  0,
+		last:     0,
+	}
+	events := j.Since(0)
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Seq != uint64(i+1) {
+			t.Errorf("event %d has seq %d, want %d", i, e.Seq, i+1)
+		}
+		if e.Cause != want[e.Seq] {
+			t.Errorf("%s (seq %d) caused by %d, want %d", e.Kind, e.Seq, e.Cause, want[e.Seq])
+		}
+	}
+	if want := []uint64{1, 2, 3, 4, 5, 6, 7, 8}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("subscriber saw %v, want %v", seen, want)
+	}
+}
+
+func TestJournalRecent(t *testing.T) {
+	clock := sim.NewClock(sim.Epoch)
+	j := New(clock)
+	for i := 0; i < MaxRecent+5; i++ {
+		clock.Reset(sim.Epoch, sim.Epoch.Add(time.Duration(i)*time.Millisecond))
+		j.Record(Event{Kind: UEMoved})
+	}
+
+	// Only the latest MaxRecent events are kept, oldest first
+	if j.Last() != MaxRecent+5 {
+		t.Errorf("last seq %d, want %d", j.Last(), MaxRecent+5)
+	}
+	kept := j.Since(0)
+	if len(kept) != MaxRecent || kept[0].Seq != 6 || kept[len(kept)-1].Seq != MaxRecent+5 {
+		t.Errorf("kept %d events from seq %d, want %d from 6", len(kept), kept[0].Seq, MaxRecent)
+	}
+	if want := sim.Epoch.Add(5 * time.Millisecond); !kept[0].Time.Equal(want) {
+		t.Errorf("oldest event at %v, want %v", kept[0].Time, want)
+	}
+
+	// Since returns what came after a seq
+	for _, tt := range []struct {
+		since uint64
+		count int
+	}{
+		{j.Last(), 0},
+		{j.Last() - 3, 3},
+		{MaxRecent, 5},
+		{MaxRecent + 100, 0},
+	} {
+		events := j.Since(tt.since)
+		if len(events) != tt.count {
+			t.Errorf("Since(%d) returned %d events, want %d", tt.since, len(events), tt.count)
+		}
+		for _, e := range events {
+			if e.Seq <= tt.since {
+				t.Errorf("Since(%d) returned seq %d", tt.since, e.Seq)
+			}
+		}
+	}
+}
+
+// A nil journal is a valid one that keeps nothing
+func TestJournalNil(t *testing.T) {
+	var j *Journal
+	j.SetOutput(&bytes.Buffer{})
+	j.Subscribe(func(Event) { t.Error("subscriber of a nil journal called") })
+	if j.Begin(Event{Kind: UEMoved}) != 0 || j.Record(Event{Kind: UEMoved}) != 0 {
+		t.Error("a nil journal handed out a seq")
+	}
+	j.End()
+	if j.Since(0) != nil || j.Last() != 0 || j.Flush() != nil {
+		t.Error("a nil journal returned something")
+	}
+}
+
+func TestRead(t *testing.T) {
+	if _, err := Read(strings.NewReader(`{"seq":1,"kind":"ueMoved"}` + "\n\n" + `{"seq":2,`)); err == nil || !strings.Contains(err.Error(), "journal line 3") {
+		t.Errorf("got error %v, want one on journal line 3", err)
+	}
+	events, err := Read(strings.NewReader(""))
+	if err != nil || len(events) != 0 {
+		t.Errorf("empty journal gave %v, %v", events, err)
+	}
+}
@@ -0,0 +1,111 @@
+package journal
+
+import (
+	"sort"
+	"time"
+)
+
+// State is the network as rebuilt from a journal at one point in simulated time
+type State struct {
+	Time      time.Time          `json:"time"`    // time of the last event applied
+	LastSeq   uint64             `json:"lastSeq"` // 0 = nothing applied yet
+	Events    int                `json:"events"`  // events applied since the start or the last snapshot restore
+	UEs       map[string]UEState `json:"ues"`     // key = IMSI
+	Sessions  map[int]Session    `json:"sessions"`
+	Down      []int              `json:"down"` // failed gNodeB IDs, sorted
+	Handovers int                `json:"handovers"`
+	Failures  int                `json:"failures"` // rejected or failed procedures
+}
+
+// Session is an active PDU session
+type Session struct {
+	ID   int    `json:"id"`
+	IMSI string `json:"imsi"`
+	Type string `json:"type"`
+}
+
+func NewState() *State {
+	return &State{UEs: make(map[string]UEState), Sessions: make(map[int]Session), Down: []int{}}
+}
+
+// Replay applies events in journal order up to and including time at. Events of a
+// restored snapshot start the state over, so later events may carry earlier times;
+// replay stops at the first event past at.
+func Replay(events []Event, at time.Time) *State {
+	s := NewState()
+	for _, e := range events {
+		if e.Time.After(at) {
+			break
+		}
+		s.Apply(e)
+	}
+	return s
+}
+
+// Apply updates the state with one event
+func (s *State) Apply(e Event) {
+	if e.Kind == SnapshotRestored {
+		*s = *NewState()
+	}
+	s.Time = e.Time
+	s.LastSeq = e.Seq
+	s.Events++
+
+	// Events about a UE carry its whole state afterwards
+	if e.IMSI != "" && e.UE != nil {
+		s.UEs[e.IMSI] = *e.UE
+	}
+
+	switch e.Kind {
+	case UERemoved:
+		delete(s.UEs, e.IMSI)
+	case GNodeBRemoved:
+		s.setDown(e.GNodeB, false)
+	case Handover:
+		s.Handovers++
+	case SessionEstablished:
+		s.Sessions[e.Session] = Session{ID: e.Session, IMSI: e.IMSI, Type: e.SessionType}
+	case SessionReleased:
+		delete(s.Sessions, e.Session)
+	case GNodeBFailed:
+		s.setDown(e.GNodeB, true)
+	case GNodeBRestored:
+		s.setDown(e.GNodeB, false)
+	case ConnectionFailed, RegistrationFailed, HandoverFailed, SessionRejected, PagingFailed, OutageFailed:
+		s.Failures++
+	}
+}
+
+func (s *State) setDown(id int, down bool) {
+	ids := []int{}
+	for _, d := range s.Down {
+		if d != id {
+			ids = append(ids, d)
+		}
+	}
+	if down {
+		ids = append(ids, id)
+		sort.Ints(ids)
+	}
+	s.Down = ids
+}
+
+// IMSIs returns the UEs of the state in IMSI order
+func (s *State) IMSIs() []string {
+	imsis := make([]string, 0, len(s.UEs))
+	for imsi := range s.UEs {
+		imsis = append(imsis, imsi)
+	}
+	sort.Strings(imsis)
+	return imsis
+}
+
+// SessionList returns the active sessions ordered by ID
+func (s *State) SessionList() []Session {
+	sessions := make([]Session, 0, len(s.Sessions))
+	for _, session := range s.Sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	return sessions
+}
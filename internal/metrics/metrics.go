@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metric families and writes them in the Prometheus text format
+// (version 0.0.4). It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family // in registration order
+	byName   map[string]*family
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string // counter, gauge or histogram
+	labels  []string
+	buckets []float64 // upper bounds, histograms only
+	series  map[string]*series
+}
+
+// series is one combination of label values
+type series struct {
+	values []string
+	value  float64  // counters and gauges
+	counts []uint64 // histograms: observations per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*family)}
+}
+
+// Counter only goes up
+type Counter struct {
+	r *Registry
+	f *family
+}
+
+// Gauge goes up and down
+type Gauge struct {
+	r *Registry
+	f *family
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	r *Registry
+	f *family
+}
+
+// Counter registers a counter with the given label names
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r, r.register(name, help, "counter", labels, nil)}
+}
+
+// Gauge registers a gauge with the given label names
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r, r.register(name, help, "gauge", labels, nil)}
+}
+
+// Histogram registers a histogram with the given bucket upper bounds (ascending;
+// +Inf is implied) and label names
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r, r.register(name, help, "histogram", labels, buckets)}
+}
+
+// register adds a family; registering the same name twice is a programming error
+func (r *Registry) register(name, help, kind string, labels []string, buckets []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.byName[name]; exists {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metric %s: buckets must be ascending", name))
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.families = append(r.families, f)
+	r.byName[name] = f
+	return f
+}
+
+// get returns the series for the label values, creating it. Call with r.mu held.
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s: %d label values for %d labels", f.name, len(values), len(f.labels)))
+	}
+	key := strings.Join(values, "\xff")
+	s, exists := f.series[key]
+	if !exists {
+		s = &series{values: append([]string(nil), values...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Inc adds one to the series with these label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v (>= 0) to the series with these label values
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metric %s: counters cannot decrease", c.f.name))
+	}
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.f.get(values).value += v
+}
+
+// Set sets the series with these label values
+func (g *Gauge) Set(v float64, values ...string) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+	g.f.get(values).value = v
+}
+
+// Add changes the series with these label values by v
+func (g *Gauge) Add(v float64, values ...string) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+	g.f.get(values).value += v
+}
+
+// Reset drops every series, for gauges rebuilt from scratch on each scrape
+func (g *Gauge) Reset() {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+	g.f.series = make(map[string]*series)
+}
+
+// Observe records one value in the series with these label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+	s := h.f.get(values)
+	i := sort.SearchFloat64s(h.f.buckets, v) // first bound >= v, or len = +Inf
+	s.counts[i]++
+	s.count++
+	s.sum += v
+}
+
+// Sum adds up every series of a family: values of counters and gauges, observed values
+// of histograms. Unknown names give 0.
+func (r *Registry) Sum(name string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0.0
+	if f, exists := r.byName[name]; exists {
+		for _, s := range f.series {
+			total += s.value + s.sum
+		}
+	}
+	return total
+}
+
+// Count returns how many values a histogram observed, over all its series
+func (r *Registry) Count(name string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := uint64(0)
+	if f, exists := r.byName[name]; exists {
+		for _, s := range f.series {
+			total += s.count
+		}
+	}
+	return total
+}
+
+// Max returns the largest value among the series of a counter or gauge, 0 if none
+func (r *Registry) Max(name string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	largest := 0.0
+	if f, exists := r.byName[name]; exists {
+		for _, s := range f.series {
+			largest = math.Max(largest, s.value)
+		}
+	}
+	return largest
+}
+
+// WriteText writes every family in the Prometheus text exposition format. Series are
+// sorted by label values, so the same state always gives the same output.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+	for _, f := range r.families {
+		fmt.Fprintf(out, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(out, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != "histogram" {
+				fmt.Fprintf(out, "%s%s %s\n", f.name, labelSet(f.labels, s.values, "", ""), formatValue(s.value))
+				continue
+			}
+			cumulative := uint64(0)
+			for i, bound := range f.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(out, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, "le", formatValue(bound)), cumulative)
+			}
+			fmt.Fprintf(out, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, "le", "+Inf"), s.count)
+			fmt.Fprintf(out, "%s_sum%s %s\n", f.name, labelSet(f.labels, s.values, "", ""), formatValue(s.sum))
+			fmt.Fprintf(out, "%s_count%s %d\n", f.name, labelSet(f.labels, s.values, "", ""), s.count)
+		}
+	}
+	return out.Flush()
+}
+
+// labelSet renders {a="1",b="2"}, with an extra label appended when extraName is set
+func labelSet(names, values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
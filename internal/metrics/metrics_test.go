@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests by path.\nBackslash \\ too.", "path", "method")
+	requests.Inc(`/a "quoted"`, "GET")
+	requests.Add(2, `C:\dir`+"\n", "POST")
+	temperature := r.Gauge("temperature", "Current temperature.")
+	temperature.Set(-1.5)
+	latency := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 0.5, 1}, "type")
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2} {
+		latency.Observe(v, "fast")
+	}
+	latency.Observe(5, "slow")
+	r.Gauge("unused", "No series yet.", "label")
+
+	var out bytes.Buffer
+	if err := r.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	// Label values escape \, " and newlines, help texts \ and newlines; buckets count
+	// everything up to their bound (inclusive), +Inf everything
+	want := `# HELP requests_total Requests by path.\nBackslash \\ too.
+# TYPE requests_total counter
+requests_total{path="/a \"quoted\"",method="GET"} 1
+requests_total{path="C:\\dir\n",method="POST"} 2
+# HELP temperature Current temperature.
+# TYPE temperature gauge
+temperature -1.5
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{type="fast",le="0.1"} 2
+latency_seconds_bucket{type="fast",le="0.5"} 3
+latency_seconds_bucket{type="fast",le="1"} 4
+latency_seconds_bucket{type="fast",le="+Inf"} 5
+latency_seconds_sum{type="fast"} 3.15
+latency_seconds_count{type="fast"} 5
+latency_seconds_bucket{type="slow",le="0.1"} 0
+latency_seconds_bucket{type="slow",le="0.5"} 0
+latency_seconds_bucket{type="slow",le="1"} 0
+latency_seconds_bucket{type="slow",le="+Inf"} 1
+latency_seconds_sum{type="slow"} 5
+latency_seconds_count{type="slow"} 1
+# HELP unused No series yet.
+# TYPE unused gauge
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if r.Sum("requests_total") != 3 || r.Sum("latency_seconds") != 8.15 || r.Count("latency_seconds") != 6 || r.Max("requests_total") != 2 {
+		t.Errorf("sum %g and %g, count %d, max %g; want 3, 8.15, 6 and 2",
+			r.Sum("requests_total"), r.Sum("latency_seconds"), r.Count("latency_seconds"), r.Max("requests_total"))
+	}
+}
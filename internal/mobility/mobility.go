@@ -0,0 +1,106 @@
+// Package mobility moves UEs on their own as simulated time passes. Each UE gets its
+// own model instance (see New); the network advances them all on a fixed tick and
+// applies the new positions through the AMF, so cell reselection and handover follow.
+package mobility
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// Area is the rectangle UEs move in; models keep their UEs inside it
+type Area struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Contains reports whether x,y is inside the area
+func (a Area) Contains(x, y float64) bool {
+	return x >= a.MinX && x <= a.MaxX && y >= a.MinY && y <= a.MaxY
+}
+
+// Clamp returns the point of the area closest to x,y
+func (a Area) Clamp(x, y float64) (float64, float64) {
+	return math.Max(a.MinX, math.Min(a.MaxX, x)), math.Max(a.MinY, math.Min(a.MaxY, y))
+}
+
+// Random returns a uniformly random point of the area
+func (a Area) Random(rng *rand.Rand) (float64, float64) {
+	return a.MinX + rng.Float64()*(a.MaxX-a.MinX), a.MinY + rng.Float64()*(a.MaxY-a.MinY)
+}
+
+// Center returns the middle of the area
+func (a Area) Center() (float64, float64) {
+	return (a.MinX + a.MaxX) / 2, (a.MinY + a.MaxY) / 2
+}
+
+// Defaults for settings a model needs but the spec leaves at 0
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultAlpha    = 0.75
+	DefaultBlock    = 50.0
+)
+
+// New builds the model spec describes for the UE imsi. Every random draw comes from
+// rng, so give all models the same stream and create them in a fixed order.
+func New(spec config.Mobility, imsi string, area Area, rng *rand.Rand) (ue.Mobility, error) {
+	minSpeed, maxSpeed := spec.Speeds()
+	interval := spec.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	switch spec.Model {
+	case config.MobilityStatic:
+		return Static{}, nil
+	case config.MobilityRandomWalk:
+		return &RandomWalk{Area: area, MinSpeed: minSpeed, MaxSpeed: maxSpeed, Interval: interval, Rand: rng}, nil
+	case config.MobilityRandomWaypoint:
+		return &RandomWaypoint{Area: area, MinSpeed: minSpeed, MaxSpeed: maxSpeed, Pause: spec.Pause, Rand: rng}, nil
+	case config.MobilityGaussMarkov:
+		alpha := spec.Alpha
+		if alpha == 0 {
+			alpha = DefaultAlpha
+		}
+		return &GaussMarkov{
+			Area:          area,
+			Alpha:         alpha,
+			MeanSpeed:     (minSpeed + maxSpeed) / 2,
+			SpeedStdDev:   (maxSpeed - minSpeed) / 2,
+			HeadingStdDev: math.Pi / 4,
+			Interval:      interval,
+			Rand:          rng,
+		}, nil
+	case config.MobilityManhattan:
+		block := spec.Block
+		if block == 0 {
+			block = DefaultBlock
+		}
+		return &Manhattan{Area: area, Block: block, MinSpeed: minSpeed, MaxSpeed: maxSpeed, Rand: rng}, nil
+	case config.MobilityTrace:
+		traces, err := LoadTraces(spec.Trace)
+		if err != nil {
+			return nil, err
+		}
+		points, found := traces[imsi]
+		if !found {
+			return nil, fmt.Errorf("trace %s has no positions for UE %s", spec.Trace, imsi)
+		}
+		return &Trace{Points: points}, nil
+	}
+	return nil, fmt.Errorf("unknown mobility model %q", spec.Model)
+}
+
+// step moves x,y by distance along heading
+func step(x, y, heading, distance float64) (float64, float64) {
+	return x + distance*math.Cos(heading), y + distance*math.Sin(heading)
+}
+
+// between returns a uniformly random value in [min, max]
+func between(rng *rand.Rand, min, max float64) float64 {
+	return min + rng.Float64()*(max-min)
+}
@@ -0,0 +1,130 @@
+package mobility
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// walk advances a model from x,y for steps ticks of dt and returns the positions
+func walk(m ue.Mobility, x, y float64, dt time.Duration, steps int) (*ue.UE, [][2]float64) {
+	u := ue.NewUE("999990000000001", x, y)
+	var path [][2]float64
+	for i := 0; i < steps; i++ {
+		u.X, u.Y = m.Advance(u, dt)
+		path = append(path, [2]float64{u.X, u.Y})
+	}
+	return u, path
+}
+
+func TestModelsStayInArea(t *testing.T) {
+	area := Area{MinX: -50, MinY: 0, MaxX: 150, MaxY: 100}
+	starts := [][2]float64{{0, 50}, {-50, 0}, {150, 100}, {149.9, 0.1}}
+	for _, model := range []string{config.MobilityRandomWalk, config.MobilityRandomWaypoint, config.MobilityGaussMarkov, config.MobilityManhattan} {
+		// Vehicles cross the area in seconds; a 7s tick leaves it in one step
+		for _, dt := range []time.Duration{100 * time.Millisecond, time.Second, 7 * time.Second} {
+			for _, start := range starts {
+				spec := config.Mobility{Model: model, Profile: "vehicle", Interval: 3 * time.Second, Pause: 2 * time.Second, Block: 40}
+				m, err := New(spec, "", area, rand.New(rand.NewSource(int64(dt))))
+				if err != nil {
+					t.Fatal(err)
+				}
+				u, path := walk(m, start[0], start[1], dt, 500)
+				moved := 0.0
+				for i, p := range path {
+					if !area.Contains(p[0], p[1]) {
+						t.Fatalf("%s from %v every %v: left the area at step %d: %v", model, start, dt, i, p)
+					}
+					if model == config.MobilityManhattan && !onStreet(area, 40, p) {
+						t.Fatalf("%s from %v every %v: off the streets at step %d: %v", model, start, dt, i, p)
+					}
+					if i > 0 {
+						moved += math.Hypot(p[0]-path[i-1][0], p[1]-path[i-1][1])
+					}
+				}
+				// Each model keeps moving at vehicle speeds (8-20 m/s, Gauss-Markov about them)
+				if moved < 500*dt.Seconds() {
+					t.Errorf("%s from %v every %v: moved only %.0f m", model, start, dt, moved)
+				}
+				if model != config.MobilityGaussMarkov && u.Speed != 0 && (u.Speed < 8 || u.Speed > 20) {
+					t.Errorf("%s: speed %g m/s, want 8-20", model, u.Speed)
+				}
+				if u.Heading < 0 || u.Heading >= 2*math.Pi {
+					t.Errorf("%s: heading %g, want [0, 2π)", model, u.Heading)
+				}
+			}
+		}
+	}
+}
+
+// onStreet reports whether p is on a street of a grid block metres apart
+func onStreet(area Area, block float64, p [2]float64) bool {
+	const eps = 1e-6
+	onGrid := func(v, lo float64) bool {
+		r := math.Mod(v-lo, block)
+		return r < eps || block-r < eps
+	}
+	return onGrid(p[0], area.MinX) || onGrid(p[1], area.MinY)
+}
+
+func TestModelsReproducible(t *testing.T) {
+	area := Area{MaxX: 500, MaxY: 500}
+	for _, model := range []string{config.MobilityRandomWalk, config.MobilityRandomWaypoint, config.MobilityGaussMarkov, config.MobilityManhattan} {
+		paths := make([][][2]float64, 3)
+		for i, seed := range []int64{1, 1, 2} {
+			m, err := New(config.Mobility{Model: model}, "", area, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, paths[i] = walk(m, 250, 250, time.Second, 100)
+		}
+		if paths[0][99] != paths[1][99] {
+			t.Errorf("%s: same seed, ended at %v and %v", model, paths[0][99], paths[1][99])
+		}
+		if paths[0][99] == paths[2][99] {
+			t.Errorf("%s: different seeds, both ended at %v", model, paths[0][99])
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	area := Area{MaxX: 100, MaxY: 100}
+	rng := rand.New(rand.NewSource(1))
+
+	m, _ := New(config.Mobility{Model: config.MobilityGaussMarkov, MinSpeed: 1, MaxSpeed: 3}, "", area, rng)
+	if gm := m.(*GaussMarkov); gm.Alpha != DefaultAlpha || gm.Interval != DefaultInterval || gm.MeanSpeed != 2 || gm.SpeedStdDev != 1 {
+		t.Errorf("Gauss-Markov %+v, want the default alpha and interval, mean 2 m/s ± 1", gm)
+	}
+	m, _ = New(config.Mobility{Model: config.MobilityManhattan}, "", area, rng)
+	if mh := m.(*Manhattan); mh.Block != DefaultBlock || mh.MinSpeed != 0.5 || mh.MaxSpeed != 2 {
+		t.Errorf("Manhattan %+v, want the default block at pedestrian speeds", mh)
+	}
+	m, _ = New(config.Mobility{Model: config.MobilityStatic}, "", area, rng)
+	if u, path := walk(m, 10, 20, time.Minute, 3); path[2] != [2]float64{10, 20} || u.Speed != 0 {
+		t.Errorf("static UE at %v with speed %g", path[2], u.Speed)
+	}
+
+	trace := "../configs/scenarios/mobility_trace.csv"
+	m, err := New(config.Mobility{Model: config.MobilityTrace, Trace: trace}, "123456789012345", area, rng)
+	if err != nil || m.Name() != config.MobilityTrace {
+		t.Errorf("trace model %v, %v", m, err)
+	}
+	for _, tt := range []struct {
+		spec config.Mobility
+		imsi string
+		err  string
+	}{
+		{config.Mobility{Model: "teleport"}, "", `unknown mobility model "teleport"`},
+		{config.Mobility{Model: config.MobilityTrace, Trace: trace}, "555", "trace " + trace + " has no positions for UE 555"},
+		{config.Mobility{Model: config.MobilityTrace, Trace: "missing.csv"}, "555", "failed to open trace file"},
+	} {
+		if _, err := New(tt.spec, tt.imsi, area, rng); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("New(%+v) = %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
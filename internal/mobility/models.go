@@ -0,0 +1,345 @@
+package mobility
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// Static keeps the UE where it is
+type Static struct{}
+
+func (Static) Name() string { return config.MobilityStatic }
+
+func (Static) Advance(u *ue.UE, dt time.Duration) (float64, float64) {
+	u.Speed = 0
+	return u.X, u.Y
+}
+
+// RandomWalk picks a random direction and speed every Interval and bounces off the
+// edges of the area
+type RandomWalk struct {
+	Area
+	MinSpeed, MaxSpeed float64
+	Interval           time.Duration
+	Rand               *rand.Rand
+	left               time.Duration // until the next change of direction
+}
+
+func (m *RandomWalk) Name() string { return config.MobilityRandomWalk }
+
+func (m *RandomWalk) Advance(u *ue.UE, dt time.Duration) (float64, float64) {
+	x, y := u.X, u.Y
+	for dt > 0 {
+		if m.left <= 0 {
+			u.Heading = m.Rand.Float64() * 2 * math.Pi
+			u.Speed = between(m.Rand, m.MinSpeed, m.MaxSpeed)
+			m.left = m.Interval
+		}
+		leg := min(dt, m.left)
+		x, y = step(x, y, u.Heading, u.Speed*leg.Seconds())
+		x, y, u.Heading = m.bounce(x, y, u.Heading)
+		dt -= leg
+		m.left -= leg
+	}
+	return x, y
+}
+
+// bounce reflects a point that left the area back in, mirroring the heading
+func (a Area) bounce(x, y, heading float64) (float64, float64, float64) {
+	if x < a.MinX || x > a.MaxX {
+		if x < a.MinX {
+			x = 2*a.MinX - x
+		} else {
+			x = 2*a.MaxX - x
+		}
+		heading = math.Pi - heading
+	}
+	if y < a.MinY || y > a.MaxY {
+		if y < a.MinY {
+			y = 2*a.MinY - y
+		} else {
+			y = 2*a.MaxY - y
+		}
+		heading = -heading
+	}
+	x, y = a.Clamp(x, y) // a step longer than the area itself
+	return x, y, normalize(heading)
+}
+
+// RandomWaypoint walks straight to a random point of the area at a random speed,
+// waits there for Pause, then picks the next point
+type RandomWaypoint struct {
+	Area
+	MinSpeed, MaxSpeed float64
+	Pause              time.Duration
+	Rand               *rand.Rand
+	moving             bool
+	targetX, targetY   float64
+	paused             time.Duration // left to wait at the current waypoint
+}
+
+func (m *RandomWaypoint) Name() string { return config.MobilityRandomWaypoint }
+
+func (m *RandomWaypoint) Advance(u *ue.UE, dt time.Duration) (float64, float64) {
+	x, y := u.X, u.Y
+	for dt > 0 {
+		if m.paused > 0 {
+			wait := min(dt, m.paused)
+			m.paused -= wait
+			dt -= wait
+			u.Speed = 0
+			continue
+		}
+		if !m.moving {
+			m.targetX, m.targetY = m.Random(m.Rand)
+			u.Speed = between(m.Rand, m.MinSpeed, m.MaxSpeed)
+			u.Heading = normalize(math.Atan2(m.targetY-y, m.targetX-x))
+			m.moving = true
+		}
+		if u.Speed <= 0 {
+			break
+		}
+
+		distance := math.Hypot(m.targetX-x, m.targetY-y)
+		needed := time.Duration(distance / u.Speed * float64(time.Second))
+		if needed > dt {
+			x, y = step(x, y, u.Heading, u.Speed*dt.Seconds())
+			break
+		}
+		// Arrived: wait, then pick the next waypoint
+		x, y = m.targetX, m.targetY
+		dt -= needed
+		m.moving = false
+		m.paused = m.Pause
+	}
+	return x, y
+}
+
+// GaussMarkov changes speed and heading every Interval by a blend of the previous
+// value, the mean and Gaussian noise: s' = αs + (1-α)s̄ + √(1-α²)·σ·N(0,1).
+// α near 1 gives smooth, nearly straight paths; 0 gives a random walk. Near the
+// edges of the area the mean heading turns towards the centre.
+type GaussMarkov struct {
+	Area
+	Alpha                  float64
+	MeanSpeed, SpeedStdDev float64
+	HeadingStdDev          float64 // radians
+	Interval               time.Duration
+	Rand                   *rand.Rand
+	started                bool
+	meanHeading            float64
+	left                   time.Duration // until the next update
+}
+
+func (m *GaussMarkov) Name() string { return config.MobilityGaussMarkov }
+
+func (m *GaussMarkov) Advance(u *ue.UE, dt time.Duration) (float64, float64) {
+	if !m.started {
+		m.meanHeading = m.Rand.Float64() * 2 * math.Pi
+		u.Speed, u.Heading = m.MeanSpeed, m.meanHeading
+		m.left = m.Interval
+		m.started = true
+	}
+	x, y := u.X, u.Y
+	for dt > 0 {
+		if m.left <= 0 {
+			m.update(u, x, y)
+			m.left = m.Interval
+		}
+		leg := min(dt, m.left)
+		x, y = m.Clamp(step(x, y, u.Heading, u.Speed*leg.Seconds()))
+		dt -= leg
+		m.left -= leg
+	}
+	return x, y
+}
+
+func (m *GaussMarkov) update(u *ue.UE, x, y float64) {
+	margin := 0.1 * math.Min(m.MaxX-m.MinX, m.MaxY-m.MinY)
+	if x < m.MinX+margin || x > m.MaxX-margin || y < m.MinY+margin || y > m.MaxY-margin {
+		cx, cy := m.Center()
+		m.meanHeading = math.Atan2(cy-y, cx-x)
+	}
+
+	a := m.Alpha
+	noise := math.Sqrt(1 - a*a)
+	u.Speed = math.Max(0, a*u.Speed+(1-a)*m.MeanSpeed+noise*m.SpeedStdDev*m.Rand.NormFloat64())
+	// Blend towards the mean the short way round the circle
+	mean := u.Heading + wrap(m.meanHeading-u.Heading)
+	u.Heading = normalize(a*u.Heading + (1-a)*mean + noise*m.HeadingStdDev*m.Rand.NormFloat64())
+}
+
+// Manhattan follows a grid of streets Block metres apart, starting from the area's
+// corner. At every intersection the UE goes straight with probability 1/2 and turns
+// left or right with 1/4 each, never leaving the area; it only turns back at a dead end.
+type Manhattan struct {
+	Area
+	Block              float64
+	MinSpeed, MaxSpeed float64
+	Rand               *rand.Rand
+	started            bool
+	direction          int // 0 = +x, 1 = +y, 2 = -x, 3 = -y
+}
+
+func (m *Manhattan) Name() string { return config.MobilityManhattan }
+
+// Unit vectors of the four street directions
+var streetDX, streetDY = [4]float64{1, 0, -1, 0}, [4]float64{0, 1, 0, -1}
+
+func (m *Manhattan) Advance(u *ue.UE, dt time.Duration) (float64, float64) {
+	x, y := u.X, u.Y
+	if !m.started {
+		x, y = m.start(u)
+		m.started = true
+	}
+
+	distance := u.Speed * dt.Seconds()
+	for distance > 0 {
+		next := m.toIntersection(x, y)
+		if distance < next {
+			x, y = x+streetDX[m.direction]*distance, y+streetDY[m.direction]*distance
+			break
+		}
+		x, y = m.snap(x+streetDX[m.direction]*next, y+streetDY[m.direction]*next)
+		distance -= next
+		if !m.turn(x, y) {
+			u.Speed = 0 // the area is smaller than one block: nowhere to go
+			break
+		}
+	}
+	u.Heading = float64(m.direction) * math.Pi / 2
+	return x, y
+}
+
+// start puts the UE on the nearest street, heading along it in a random direction
+func (m *Manhattan) start(u *ue.UE) (float64, float64) {
+	x, y := m.Clamp(u.X, u.Y)
+	gx, gy := m.snap(x, y)
+	u.Speed = between(m.Rand, m.MinSpeed, m.MaxSpeed)
+	if math.Abs(x-gx) < math.Abs(y-gy) {
+		m.direction = 1 + 2*m.Rand.Intn(2) // along the street x = gx
+		x = gx
+	} else {
+		m.direction = 2 * m.Rand.Intn(2) // along the street y = gy
+		y = gy
+	}
+	// Turn round if the next intersection is outside the area
+	next := m.toIntersection(x, y)
+	if !m.Contains(x+streetDX[m.direction]*next, y+streetDY[m.direction]*next) {
+		m.direction = (m.direction + 2) % 4
+	}
+	return x, y
+}
+
+// snap returns the nearest intersection inside the area
+func (m *Manhattan) snap(x, y float64) (float64, float64) {
+	grid := func(v, lo, hi float64) float64 {
+		g := lo + math.Round((v-lo)/m.Block)*m.Block
+		if g > hi {
+			g -= m.Block
+		}
+		return math.Max(lo, g)
+	}
+	return grid(x, m.MinX, m.MaxX), grid(y, m.MinY, m.MaxY)
+}
+
+// toIntersection returns the distance to the next crossing street ahead
+func (m *Manhattan) toIntersection(x, y float64) float64 {
+	pos, sign := x-m.MinX, streetDX[m.direction]
+	if sign == 0 {
+		pos, sign = y-m.MinY, streetDY[m.direction]
+	}
+	const eps = 1e-9
+	if sign > 0 {
+		return (math.Floor(pos/m.Block+eps)+1)*m.Block - pos
+	}
+	return pos - (math.Ceil(pos/m.Block-eps)-1)*m.Block
+}
+
+// turn picks the direction out of an intersection; false if every way leaves the area
+func (m *Manhattan) turn(x, y float64) bool {
+	open := func(d int) bool {
+		return m.Contains(x+streetDX[d]*m.Block, y+streetDY[d]*m.Block)
+	}
+	straight, left, right := m.direction, (m.direction+1)%4, (m.direction+3)%4
+	weights := map[int]float64{straight: 0.5, left: 0.25, right: 0.25}
+
+	total := 0.0
+	for _, d := range []int{straight, left, right} {
+		if open(d) {
+			total += weights[d]
+		}
+	}
+	if total == 0 {
+		back := (m.direction + 2) % 4
+		m.direction = back
+		return open(back)
+	}
+	r := m.Rand.Float64() * total
+	for _, d := range []int{straight, left, right} {
+		if !open(d) {
+			continue
+		}
+		m.direction = d // the last open one also catches rounding
+		if r < weights[d] {
+			break
+		}
+		r -= weights[d]
+	}
+	return true
+}
+
+// Trace replays recorded positions, interpolating linearly between them. Before
+// the first point the UE waits at it; after the last one it stays there.
+type Trace struct {
+	Points  []TracePoint // sorted by At
+	elapsed time.Duration
+}
+
+func (m *Trace) Name() string { return config.MobilityTrace }
+
+func (m *Trace) Advance(u *ue.UE, dt time.Duration) (float64, float64) {
+	m.elapsed += dt
+	x, y := m.Position(m.elapsed)
+	u.Speed = 0
+	if moved := math.Hypot(x-u.X, y-u.Y); moved > 0 && dt > 0 {
+		u.Speed = moved / dt.Seconds()
+		u.Heading = normalize(math.Atan2(y-u.Y, x-u.X))
+	}
+	return x, y
+}
+
+// Position returns where the trace is at t since the start of the simulation
+func (m *Trace) Position(t time.Duration) (float64, float64) {
+	points := m.Points
+	if t <= points[0].At {
+		return points[0].X, points[0].Y
+	}
+	for i := 1; i < len(points); i++ {
+		if t <= points[i].At {
+			a, b := points[i-1], points[i]
+			frac := float64(t-a.At) / float64(b.At-a.At)
+			return a.X + (b.X-a.X)*frac, a.Y + (b.Y-a.Y)*frac
+		}
+	}
+	last := points[len(points)-1]
+	return last.X, last.Y
+}
+
+// normalize maps an angle to [0, 2π)
+func normalize(angle float64) float64 {
+	angle = math.Mod(angle, 2*math.Pi)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return angle
+}
+
+// wrap maps an angle difference to [-π, π)
+func wrap(angle float64) float64 {
+	return normalize(angle+math.Pi) - math.Pi
+}
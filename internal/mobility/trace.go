@@ -0,0 +1,94 @@
+package mobility
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TracePoint is where a UE was at a time since the start of the simulation
+type TracePoint struct {
+	At   time.Duration
+	X, Y float64
+}
+
+// LoadTraces reads a CSV file of recorded positions, one per row, with a header
+// naming the columns time, imsi, x and y (in any order; other columns are ignored).
+// Time is seconds since the start or a duration like "1m30s". Points come back
+// grouped by IMSI and sorted by time.
+func LoadTraces(path string) (map[string][]TracePoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	defer f.Close()
+	return ReadTraces(path, f)
+}
+
+// ReadTraces parses traces from r; name is used in error messages
+func ReadTraces(name string, r io.Reader) (map[string][]TracePoint, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read header: %w", name, err)
+	}
+	column := make(map[string]int)
+	for i, h := range header {
+		column[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, c := range []string{"time", "imsi", "x", "y"} {
+		if _, found := column[c]; !found {
+			return nil, fmt.Errorf("%s: header has no %q column", name, c)
+		}
+	}
+
+	traces := make(map[string][]TracePoint)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		var p TracePoint
+		if p.At, err = parseTime(row[column["time"]]); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if p.X, err = strconv.ParseFloat(row[column["x"]], 64); err != nil {
+			return nil, fmt.Errorf("%s:%d: bad x: %w", name, line, err)
+		}
+		if p.Y, err = strconv.ParseFloat(row[column["y"]], 64); err != nil {
+			return nil, fmt.Errorf("%s:%d: bad y: %w", name, line, err)
+		}
+		imsi := row[column["imsi"]]
+		traces[imsi] = append(traces[imsi], p)
+	}
+
+	for _, points := range traces {
+		sort.SliceStable(points, func(i, j int) bool { return points[i].At < points[j].At })
+	}
+	return traces, nil
+}
+
+// parseTime accepts seconds (12, 0.5) or a Go duration ("1m30s")
+func parseTime(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad time %q: want seconds or a duration like \"1m30s\"", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,87 @@
+package mobility
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const traceCSV = `# columns in any order, extra ones ignored
+y, imsi, note, time, x
+0, 1, start, 0, 0
+100, 1, , 1m, 0
+# out of order
+0, 1, , 10, 100
+5, 2, other UE, 2.5, 5
+`
+
+func TestReadTraces(t *testing.T) {
+	traces, err := ReadTraces("walk.csv", strings.NewReader(traceCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]TracePoint{
+		"1": {{0, 0, 0}, {10 * time.Second, 100, 0}, {time.Minute, 0, 100}},
+		"2": {{2500 * time.Millisecond, 5, 5}},
+	}
+	if !reflect.DeepEqual(traces, want) {
+		t.Errorf("got %v, want %v", traces, want)
+	}
+
+	for _, tt := range []struct {
+		name, csv, err string
+	}{
+		{"empty", "", "walk.csv: failed to read header: EOF"},
+		{"no x column", "time,imsi,y\n0,1,0\n", `walk.csv: header has no "x" column`},
+		{"bad time", "time,imsi,x,y\n0,1,0,0\nsoon,1,0,0\n", `walk.csv:3: bad time "soon": want seconds or a duration like "1m30s"`},
+		{"bad x", "time,imsi,x,y\n0,1,east,0\n", "walk.csv:2: bad x"},
+		{"bad y", "time,imsi,x,y\n# comment\n0,1,0,north\n", "walk.csv:3: bad y"},
+		{"short row", "time,imsi,x,y\n0,1,0\n", "walk.csv: record on line 2: wrong number of fields"},
+	} {
+		if _, err := ReadTraces("walk.csv", strings.NewReader(tt.csv)); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestTraceInterpolation(t *testing.T) {
+	traces, err := ReadTraces("walk.csv", strings.NewReader(traceCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Trace{Points: traces["1"]}
+	for _, tt := range []struct {
+		at   time.Duration
+		x, y float64
+	}{
+		{-time.Second, 0, 0}, // waits at the first point
+		{0, 0, 0},
+		{2500 * time.Millisecond, 25, 0},
+		{10 * time.Second, 100, 0},
+		{35 * time.Second, 50, 50}, // halfway from (100,0) to (0,100)
+		{time.Minute, 0, 100},
+		{time.Hour, 0, 100}, // stays at the last point
+	} {
+		if x, y := m.Position(tt.at); math.Abs(x-tt.x) > 1e-9 || math.Abs(y-tt.y) > 1e-9 {
+			t.Errorf("at %v: %g,%g, want %g,%g", tt.at, x, y, tt.x, tt.y)
+		}
+	}
+
+	// Advancing follows the trace with the speed and heading of each step
+	u, path := walk(m, 0, 0, 5*time.Second, 14)
+	for i, want := range [][2]float64{{50, 0}, {100, 0}, {90, 10}} {
+		if math.Abs(path[i][0]-want[0]) > 1e-9 || math.Abs(path[i][1]-want[1]) > 1e-9 {
+			t.Errorf("step %d at %v, want %v", i, path[i], want)
+		}
+	}
+	if path[13] != [2]float64{0, 100} || u.Speed != 0 {
+		t.Errorf("ended at %v with speed %g, want at rest at 0,100", path[13], u.Speed)
+	}
+	m = &Trace{Points: traces["1"]}
+	u, _ = walk(m, 0, 0, 15*time.Second, 2)
+	if want := math.Hypot(30, 30) / 15; math.Abs(u.Speed-want) > 1e-9 || math.Abs(u.Heading-3*math.Pi/4) > 1e-9 {
+		t.Errorf("speed %g m/s, heading %g; want %g towards 3π/4", u.Speed, u.Heading, want)
+	}
+}
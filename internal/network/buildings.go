@@ -0,0 +1,34 @@
+package network
+
+import (
+	"github.com/rizpur/NetSim5G/internal/buildings"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// ServingPath traces the link from the UE's serving cell to the UE through the
+// buildings. ok is false when the network has no buildings or the UE is not served.
+// Hold a View.
+func (n *Network) ServingPath(u *ue.UE) (path buildings.Path, ok bool) {
+	g := n.GNodeBs[u.GNodeBConnected]
+	if n.Buildings == nil || u.State != ue.Connected || g == nil {
+		return buildings.Path{}, false
+	}
+	c := g.ServingCell(u.IMSI)
+	if c == nil {
+		return buildings.Path{}, false
+	}
+	return n.Buildings.Trace(
+		buildings.Endpoint{X: g.X, Y: g.Y, H: g.HeightM},
+		buildings.Endpoint{X: u.X, Y: u.Y, H: ran.UEHeightM},
+		c.Carrier.FrequencyGHz,
+	), true
+}
+
+// Indoors returns the building the UE is in, or nil outdoors or without buildings
+func (n *Network) Indoors(u *ue.UE) *buildings.Building {
+	if n.Buildings == nil {
+		return nil
+	}
+	return n.Buildings.At(u.X, u.Y, ran.UEHeightM)
+}
@@ -0,0 +1,127 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// ToXY places a lat/lon position on the network's plane
+func (n *Network) ToXY(pos geo.LatLon) (x, y float64, err error) {
+	if n.Geo == nil {
+		return 0, 0, fmt.Errorf("position %s given as lat/lon, but the topology has no geographic origin", pos)
+	}
+	x, y = n.Geo.ToXY(pos)
+	return x, y, nil
+}
+
+// GeoJSON returns the network as it is now, for GIS tools: every gNodeB as a point,
+// the coverage of each cell as a polygon (its sector out to the gNodeB's range), every
+// UE as a point and a line from each RRC_CONNECTED UE to its serving gNodeB, plus the
+// buildings' footprints. The "kind" property tells them apart: gnodeb, coverage,
+// building, ue or link. Needs a geographic topology. Hold a View.
+func (n *Network) GeoJSON() (*geo.FeatureCollection, error) {
+	if n.Geo == nil {
+		return nil, fmt.Errorf("the topology has no geographic origin; give sites as lat/lon or set origin")
+	}
+	fc := geo.NewFeatureCollection()
+
+	// Step 1: Coverage first, so GIS tools draw the sites and UEs on top
+	for _, g := range n.Sites {
+		for _, c := range g.Cells {
+			beamwidth := c.Antenna.BeamwidthDeg
+			if c.Antenna.Omni() {
+				beamwidth = 360
+			}
+			fc.Add(geo.Polygon(n.Geo.Sector(g.X, g.Y, g.Range, c.Antenna.AzimuthDeg, beamwidth)), map[string]interface{}{
+				"kind":         "coverage",
+				"gnodeb":       g.ID,
+				"cell":         c.ID,
+				"pci":          c.PCI,
+				"azimuth":      c.Antenna.AzimuthDeg,
+				"beamwidth":    beamwidth,
+				"connectedUEs": len(c.ConnectedUEs),
+				"down":         g.Down,
+			})
+		}
+	}
+
+	// Step 2: Buildings
+	if n.Buildings != nil {
+		for _, b := range n.Buildings.Buildings {
+			ring := make([]geo.LatLon, len(b.Footprint))
+			for i, p := range b.Footprint {
+				ring[i] = n.Geo.FromXY(p.X, p.Y)
+			}
+			fc.Add(geo.Polygon(ring), map[string]interface{}{
+				"kind":     "building",
+				"name":     b.Name,
+				"height":   b.HeightM,
+				"material": string(b.Material),
+			})
+		}
+	}
+
+	// Step 3: Sites
+	for _, g := range n.Sites {
+		fc.Add(geo.Point(n.Geo.FromXY(g.X, g.Y)), map[string]interface{}{
+			"kind":         "gnodeb",
+			"id":           g.ID,
+			"x":            g.X,
+			"y":            g.Y,
+			"height":       g.HeightM,
+			"range":        g.Range,
+			"cells":        len(g.Cells),
+			"connectedUEs": len(g.ConnectedUEs),
+			"down":         g.Down,
+		})
+	}
+
+	// Step 4: UEs, and the links of those being served, in IMSI order
+	imsis := make([]string, 0, len(n.UEs))
+	for imsi := range n.UEs {
+		imsis = append(imsis, imsi)
+	}
+	sort.Strings(imsis)
+	for _, imsi := range imsis {
+		u := n.UEs[imsi]
+		pos := n.Geo.FromXY(u.X, u.Y)
+		props := map[string]interface{}{
+			"kind":  "ue",
+			"imsi":  u.IMSI,
+			"x":     u.X,
+			"y":     u.Y,
+			"state": u.State.String(),
+		}
+		if u.Mobility != nil {
+			props["mobility"] = u.Mobility.Name()
+		}
+		if b := n.Indoors(u); b != nil {
+			props["building"] = b.Name
+		}
+		if registered, exists := n.AMF.RegisteredUEs[imsi]; exists {
+			props["cmState"] = registered.CMState.String()
+		}
+		g := n.GNodeBs[u.GNodeBConnected]
+		if u.State != ue.Connected || g == nil {
+			fc.Add(geo.Point(pos), props)
+			continue
+		}
+		props["gnodeb"], props["cell"] = g.ID, u.CellConnected
+		fc.Add(geo.Point(pos), props)
+
+		link := map[string]interface{}{"kind": "link", "imsi": u.IMSI, "gnodeb": g.ID, "cell": u.CellConnected}
+		if metrics, err := n.AMF.EstimateLink(u); err == nil {
+			link["rsrpDBm"] = metrics.RSRPDBm
+			link["sinrDB"] = metrics.SINRDB
+			link["throughputMbps"] = metrics.ThroughputMbps
+		}
+		if path, ok := n.ServingPath(u); ok {
+			link["los"] = path.LOS
+		}
+		fc.Add(geo.LineString(pos, n.Geo.FromXY(g.X, g.Y)), link)
+	}
+	return fc, nil
+}
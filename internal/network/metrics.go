@@ -0,0 +1,163 @@
+package network
+
+import (
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/metrics"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// Session establishment latency buckets, in seconds
+var sessionSetupBuckets = []float64{0.04, 0.05, 0.06, 0.07, 0.08, 0.1, 0.15, 0.25}
+
+// kpis are the network's metrics. Counters follow the journal; gauges describe the
+// current state and are refreshed on every scrape.
+type kpis struct {
+	scrape sync.Mutex // one scrape at a time, so gauges are not rebuilt under another
+
+	registrations       *metrics.Counter
+	handoverAttempts    *metrics.Counter
+	handoverSuccesses   *metrics.Counter
+	handoverFailures    *metrics.Counter
+	sessionsEstablished *metrics.Counter
+	sessionsRejected    *metrics.Counter
+	sessionsReleased    *metrics.Counter
+	droppedSessions     *metrics.Counter
+	radioLinkFailures   *metrics.Counter
+	sessionSetup        *metrics.Histogram
+
+	registrationRatio *metrics.Gauge
+	activeSessions    *metrics.Gauge
+	ues               *metrics.Gauge
+	gnodebUp          *metrics.Gauge
+	cellUEs           *metrics.Gauge
+	cellCapacity      *metrics.Gauge
+	cellLoad          *metrics.Gauge
+	simTime           *metrics.Gauge
+
+	registered, rejected float64
+	lastRLF              uint64 // sessions released under it were dropped
+	lastRestore          uint64 // events under it describe a restored snapshot, not new activity
+}
+
+func newKPIs(r *metrics.Registry) *kpis {
+	return &kpis{
+		registrations:       r.Counter("netsim_registrations_total", "Registration attempts by result.", "result"),
+		handoverAttempts:    r.Counter("netsim_handover_attempts_total", "Handover attempts by source and target gNodeB.", "from", "to"),
+		handoverSuccesses:   r.Counter("netsim_handover_successes_total", "Completed handovers by source and target gNodeB.", "from", "to"),
+		handoverFailures:    r.Counter("netsim_handover_failures_total", "Failed handovers by source and target gNodeB.", "from", "to"),
+		sessionsEstablished: r.Counter("netsim_sessions_established_total", "PDU sessions established by type.", "type"),
+		sessionsRejected:    r.Counter("netsim_sessions_rejected_total", "PDU session establishments rejected by type.", "type"),
+		sessionsReleased:    r.Counter("netsim_sessions_released_total", "PDU sessions released by type, dropped ones included.", "type"),
+		droppedSessions:     r.Counter("netsim_sessions_dropped_total", "PDU sessions released because their UE lost coverage, by type.", "type"),
+		radioLinkFailures:   r.Counter("netsim_radio_link_failures_total", "Radio link failures by the gNodeB that was serving the UE.", "gnodeb"),
+		sessionSetup:        r.Histogram("netsim_session_establishment_seconds", "PDU session establishment latency by type.", sessionSetupBuckets, "type"),
+
+		registrationRatio: r.Gauge("netsim_registration_success_ratio", "Accepted registrations over all attempts."),
+		activeSessions:    r.Gauge("netsim_active_sessions", "Active PDU sessions by type.", "type"),
+		ues:               r.Gauge("netsim_ues", "UEs by RRC state.", "state"),
+		gnodebUp:          r.Gauge("netsim_gnodeb_up", "1 if the gNodeB is on air, 0 during an outage.", "gnodeb"),
+		cellUEs:           r.Gauge("netsim_cell_connected_ues", "UEs connected to a cell.", "gnodeb", "cell"),
+		cellCapacity:      r.Gauge("netsim_cell_capacity_ues", "Maximum UEs a cell accepts (MaxCap).", "gnodeb", "cell"),
+		cellLoad:          r.Gauge("netsim_cell_load_ratio", "Connected UEs over MaxCap.", "gnodeb", "cell"),
+		simTime:           r.Gauge("netsim_sim_time_seconds", "Simulated time since the start of the simulation."),
+	}
+}
+
+// observe counts one journal event
+func (k *kpis) observe(e journal.Event) {
+	if e.Kind == journal.SnapshotRestored {
+		k.lastRestore = e.Seq
+	}
+	if e.Cause != 0 && e.Cause == k.lastRestore {
+		return
+	}
+
+	switch e.Kind {
+	case journal.Registered:
+		k.registrations.Inc("success")
+		k.registered++
+	case journal.RegistrationFailed:
+		k.registrations.Inc("failure")
+		k.rejected++
+	case journal.Handover, journal.HandoverFailed:
+		from, to := strconv.Itoa(e.From.GNodeB), strconv.Itoa(e.To.GNodeB)
+		k.handoverAttempts.Inc(from, to)
+		if e.Kind == journal.Handover {
+			k.handoverSuccesses.Inc(from, to)
+		} else {
+			k.handoverFailures.Inc(from, to)
+		}
+	case journal.SessionEstablished:
+		k.sessionsEstablished.Inc(e.SessionType)
+		k.sessionSetup.Observe(e.LatencyMs/1000, e.SessionType)
+	case journal.SessionRejected:
+		k.sessionsRejected.Inc(e.SessionType)
+	case journal.SessionReleased:
+		k.sessionsReleased.Inc(e.SessionType)
+		if e.Cause != 0 && e.Cause == k.lastRLF {
+			k.droppedSessions.Inc(e.SessionType)
+		}
+	case journal.RadioLinkFailure:
+		k.lastRLF = e.Seq
+		k.radioLinkFailures.Inc(strconv.Itoa(e.GNodeB))
+	}
+}
+
+// WriteMetrics refreshes the gauges from the current state and writes every metric in
+// the Prometheus text format. Hold at least a View.
+func (n *Network) WriteMetrics(w io.Writer) error {
+	n.kpis.scrape.Lock()
+	defer n.kpis.scrape.Unlock()
+	n.collect()
+	return n.Metrics.WriteText(w)
+}
+
+func (n *Network) collect() {
+	k := n.kpis
+	if attempts := k.registered + k.rejected; attempts > 0 {
+		k.registrationRatio.Set(k.registered / attempts)
+	}
+	k.simTime.Set(n.Clock.Elapsed().Seconds())
+
+	k.activeSessions.Reset()
+	for t := smf.VoIP; t <= smf.IoT; t++ {
+		k.activeSessions.Set(0, t.String())
+	}
+	for _, session := range n.SMF.Sessions {
+		k.activeSessions.Add(1, session.SessionType.String())
+	}
+
+	k.ues.Reset()
+	for s := ue.Disconnected; s <= ue.Inactive; s++ {
+		k.ues.Set(0, s.String())
+	}
+	for _, u := range n.UEs {
+		k.ues.Add(1, u.State.String())
+	}
+
+	k.gnodebUp.Reset()
+	k.cellUEs.Reset()
+	k.cellCapacity.Reset()
+	k.cellLoad.Reset()
+	for _, g := range n.Sites {
+		id := strconv.Itoa(g.ID)
+		up := 1.0
+		if g.Down {
+			up = 0
+		}
+		k.gnodebUp.Set(up, id)
+		for _, c := range g.Cells {
+			cell := strconv.Itoa(c.ID)
+			k.cellUEs.Set(float64(len(c.ConnectedUEs)), id, cell)
+			k.cellCapacity.Set(float64(c.MaxCap), id, cell)
+			if c.MaxCap > 0 {
+				k.cellLoad.Set(float64(len(c.ConnectedUEs))/float64(c.MaxCap), id, cell)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+func TestWriteMetricsGolden(t *testing.T) {
+	// Step 1: A registration and a rejected one, a call, a handover to gNodeB 2 and an
+	// outage there that drops the call
+	topology, err := config.Load("../configs/topology.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(topology, "../configs/subscribers.json", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := n.AddUE("123456789012345", 110, 110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.AMF.Attach(u); err != nil {
+		t.Fatal(err)
+	}
+	suspended, err := n.AddUE("111222333444555", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.AMF.Attach(suspended); err == nil {
+		t.Fatal("a suspended subscriber attached")
+	}
+	n.Scheduler.RunFor(time.Second)
+	if _, err := n.SMF.EstablishSession(u, smf.VoIP); err != nil {
+		t.Fatal(err)
+	}
+	n.Scheduler.RunFor(time.Second)
+	if err := n.AMF.MoveUE(u, 190, 190); err != nil {
+		t.Fatal(err)
+	}
+	if u.GNodeBConnected != 2 {
+		t.Fatalf("UE on gNodeB %d after the move, want 2", u.GNodeBConnected)
+	}
+	if _, err := n.AMF.FailGNodeB(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Step 2: The exposition matches the golden file
+	var out bytes.Buffer
+	if err := n.WriteMetrics(&out); err != nil {
+		t.Fatal(err)
+	}
+	golden := "testdata/metrics.golden"
+	if *update {
+		if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(want) {
+		t.Errorf("metrics differ from %s (rerun with -update if the change is intended):\n%s", golden, out.String())
+	}
+
+	// Step 3: Whatever the values, the format holds: every family is introduced by
+	// HELP and TYPE, histogram buckets are cumulative up to +Inf, which is the count
+	checkExposition(t, out.String())
+}
+
+// checkExposition checks the structure of Prometheus text output
+func checkExposition(t *testing.T, text string) {
+	t.Helper()
+	var family, kind string
+	var last uint64 // previous bucket of the current histogram series
+	var inf string  // +Inf bucket of the current histogram series
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) >= 3 && fields[0] == "#" && fields[1] == "HELP":
+			family, kind = fields[2], ""
+			continue
+		case len(fields) == 4 && fields[0] == "#" && fields[1] == "TYPE":
+			if fields[2] != family {
+				t.Errorf("line %d: TYPE of %s after the HELP of %s", line, fields[2], family)
+			}
+			kind = fields[3]
+			continue
+		case len(fields) != 2:
+			t.Errorf("line %d: not a sample: %q", line, scanner.Text())
+			continue
+		}
+		name, value := fields[0], fields[1]
+		if i := strings.IndexByte(name, '{'); i >= 0 {
+			name = name[:i]
+		}
+		if kind == "" || strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count") != family {
+			t.Errorf("line %d: sample of %s without its HELP and TYPE", line, name)
+		}
+		if kind != "histogram" {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, "_bucket"):
+			count, _ := strconv.ParseUint(value, 10, 64)
+			if count < last {
+				t.Errorf("line %d: bucket of %d after one of %d", line, count, last)
+			}
+			last = count
+			if strings.Contains(fields[0], `le="+Inf"`) {
+				inf = value
+			}
+		case strings.HasSuffix(name, "_count"):
+			if inf == "" || value != inf {
+				t.Errorf("line %d: count %s, but the +Inf bucket holds %q", line, value, inf)
+			}
+			last, inf = 0, ""
+		}
+	}
+}
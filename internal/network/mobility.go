@@ -0,0 +1,63 @@
+package network
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/mobility"
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+// DefaultMobilityTick is how often mobility models move their UEs
+const DefaultMobilityTick = time.Second
+
+// Coverage returns the box around every gNodeB's coverage, the area mobility models
+// keep their UEs in
+func (n *Network) Coverage() mobility.Area {
+	a := mobility.Area{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for _, g := range n.Sites {
+		a.MinX = math.Min(a.MinX, g.X-g.Range)
+		a.MinY = math.Min(a.MinY, g.Y-g.Range)
+		a.MaxX = math.Max(a.MaxX, g.X+g.Range)
+		a.MaxY = math.Max(a.MaxY, g.Y+g.Range)
+	}
+	return a
+}
+
+// StartMobility moves every UE that has a mobility model once per tick, for as long
+// as the returned event stays queued; cancel it to stop. Moves go through the AMF,
+// so reselection and handovers happen as the UEs travel.
+func (n *Network) StartMobility(tick time.Duration) *sim.Event {
+	if tick <= 0 {
+		tick = DefaultMobilityTick
+	}
+	return n.Scheduler.Every(tick, sim.PriorityNormal, "mobility", func(now time.Time) {
+		n.AdvanceMobility(now, tick)
+	})
+}
+
+// AdvanceMobility moves every UE with a mobility model by dt of simulated time. UEs go
+// in IMSI order so models sharing a random stream draw the same numbers every run.
+// Hold an Update (scheduler events already do).
+func (n *Network) AdvanceMobility(now time.Time, dt time.Duration) {
+	var imsis []string
+	for imsi, u := range n.UEs {
+		if u.Mobility != nil {
+			imsis = append(imsis, imsi)
+		}
+	}
+	if len(imsis) == 0 {
+		return
+	}
+	sort.Strings(imsis)
+
+	n.AMF.TickRRC(now) // let inactivity timers fire before anyone moves
+	for _, imsi := range imsis {
+		u := n.UEs[imsi]
+		x, y := u.Mobility.Advance(u, dt)
+		if x != u.X || y != u.Y {
+			n.AMF.MoveUE(u, x, y) // walking out of coverage is allowed; the UE keeps trying
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package network
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rizpur/NetSim5G/internal/buildings"
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/geo"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/metrics"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/traffic"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// Network wires the core network functions and the RAN together. The components are
+// plain data and not safe for concurrent use on their own; the Network acts as a single
+// actor instead: every change runs under Update (scheduler events do so automatically)
+// and readers use View, so they always see a consistent state across AMF, SMF, UDM,
+// gNodeBs and UEs.
+type Network struct {
+	UDM       *udm.UDM
+	AMF       *amf.AMF
+	SMF       *smf.SMF
+	GNodeBs   map[int]*ran.GNodeB // key = gNodeB ID
+	Sites     []*ran.GNodeB       // gNodeBs in topology file order
+	UEs       map[string]*ue.UE   // All UEs that exist (connected or not)
+	Clock     *sim.Clock          // simulated time shared by every component
+	Scheduler *sim.Scheduler      // drives the clock
+	RNG       *sim.RNG            // all randomness, from one seed
+	Journal   *journal.Journal    // every procedure, in order
+	Metrics   *metrics.Registry   // KPIs, see WriteMetrics
+	Traffic   *traffic.Engine     // session traffic; nil until StartTraffic
+	Geo       *geo.Projection     // lat/lon <-> x,y; nil when the topology has no geographic origin
+	Buildings *buildings.Map      // what links are traced through; nil = open space
+	kpis      *kpis
+	lastID    int // highest gNodeB ID handed out; IDs are never reused
+	mu        sync.RWMutex
+}
+
+// New builds a network from a topology and a subscriber database. Every random draw
+// comes from seed, so the same inputs and seed give the same run.
+func New(topology *config.Topology, subscribersPath string, seed int64) (*Network, error) {
+	// Step 1: Create UDM (subscriber database)
+	udmInstance, err := udm.NewUDM(subscribersPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Create AMF (needs UDM), reading time from the shared simulation clock
+	clock := sim.NewClock(sim.Epoch)
+	rng := sim.NewRNG(seed)
+	events := journal.New(clock)
+	udmInstance.Journal = events
+	amfInstance := amf.NewAMF(udmInstance)
+	amfInstance.Clock = clock
+	amfInstance.Journal = events
+	amfInstance.RACH.Rand = rng.Stream(sim.StreamRACH)
+
+	// Step 3: Create SMF (needs UDM, and the AMF for radio link estimates)
+	smfInstance := smf.NewSMF(udmInstance, amfInstance)
+	smfInstance.Journal = events
+	amfInstance.Sessions = smfInstance // AMF tells the SMF when UEs lose coverage
+
+	// Step 4: Create the gNodeBs described by the topology
+	sites, err := topology.Build()
+	if err != nil {
+		return nil, err
+	}
+	environment, err := topology.Buildings() // already loaded by Build
+	if err != nil {
+		return nil, err
+	}
+	// Sites are numbered per network, not per process, so two runs of the same inputs
+	// journal the same IDs
+	gNodeBs := make(map[int]*ran.GNodeB)
+	for i, g := range sites {
+		g.ID = i + 1
+		amfInstance.RegisterGNodeB(g)
+		gNodeBs[g.ID] = g
+	}
+
+	n := &Network{
+		UDM:       udmInstance,
+		AMF:       amfInstance,
+		SMF:       smfInstance,
+		GNodeBs:   gNodeBs,
+		Sites:     sites,
+		UEs:       make(map[string]*ue.UE),
+		Clock:     clock,
+		Scheduler: sim.NewScheduler(clock),
+		RNG:       rng,
+		Journal:   events,
+		Metrics:   metrics.NewRegistry(),
+		Geo:       topology.Projection(),
+		Buildings: environment,
+		lastID:    len(sites),
+	}
+	n.kpis = newKPIs(n.Metrics)
+	events.Subscribe(n.kpis.observe)
+	n.Scheduler.Locker = &n.mu
+	return n, nil
+}
+
+// Update runs fn with exclusive access to the network. Do not call it from a
+// scheduler event, which already holds the lock.
+func (n *Network) Update(fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fn()
+}
+
+// View runs fn with shared, read-only access; nothing changes while fn runs
+func (n *Network) View(fn func()) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	fn()
+}
+
+// Site returns a gNodeB by its position in the topology file (1 = first)
+func (n *Network) Site(pos int) (*ran.GNodeB, error) {
+	if pos < 1 || pos > len(n.Sites) {
+		return nil, fmt.Errorf("gNodeB %d does not exist, topology has %d", pos, len(n.Sites))
+	}
+	return n.Sites[pos-1], nil
+}
+
+// SitePosition returns the topology position of a gNodeB ID, or 0
+func (n *Network) SitePosition(id int) int {
+	for i, g := range n.Sites {
+		if g.ID == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// AddUE creates a UE at (x, y) and tracks it
+func (n *Network) AddUE(imsi string, x, y float64) (*ue.UE, error) {
+	if _, exists := n.UEs[imsi]; exists {
+		return nil, fmt.Errorf("UE %s already exists", imsi)
+	}
+	u := ue.NewUE(imsi, x, y)
+	n.UEs[imsi] = u
+	n.Journal.Record(journal.Event{Kind: journal.UECreated, IMSI: imsi, UE: n.AMF.DescribeUE(u)})
+	return u, nil
+}
+
+// RemoveUE deregisters a UE (which releases its sessions), drops its radio connection
+// and forgets it
+func (n *Network) RemoveUE(imsi string) error {
+	u, exists := n.UEs[imsi]
+	if !exists {
+		return fmt.Errorf("UE %s does not exist", imsi)
+	}
+	if _, registered := n.AMF.RegisteredUEs[imsi]; registered {
+		if err := n.AMF.DeregisterUE(u); err != nil {
+			return err
+		}
+	} else if g, exists := n.GNodeBs[u.GNodeBConnected]; exists && u.State == ue.Connected {
+		g.Disconnect(u)
+	}
+	n.SMF.ReleaseUESessions(imsi) // sessions of a UE that was never registered
+	delete(n.UEs, imsi)
+	n.Journal.Record(journal.Event{Kind: journal.UERemoved, IMSI: imsi})
+	return nil
+}
+
+// AddGNodeB gives a new gNodeB the network's next ID and puts it on air after the
+// topology's sites; it traces its links through the same buildings as they do
+func (n *Network) AddGNodeB(g *ran.GNodeB) {
+	n.lastID++
+	g.ID = n.lastID
+	if n.Buildings != nil {
+		g.Propagation = n.Buildings
+	}
+	n.AMF.RegisterGNodeB(g)
+	n.GNodeBs[g.ID] = g
+	n.Sites = append(n.Sites, g)
+	n.Journal.Record(journal.Event{Kind: journal.GNodeBAdded, GNodeB: g.ID})
+}
+
+// MoveGNodeB changes where a gNodeB stands and how far it reaches. UEs it serves are
+// not moved off it here: the next cell (re)selection sees the new coverage.
+func (n *Network) MoveGNodeB(g *ran.GNodeB, x, y, rangeVal float64) {
+	g.X, g.Y, g.Range = x, y, rangeVal
+	n.AMF.Index.Update(g)
+	n.Journal.Record(journal.Event{Kind: journal.GNodeBUpdated, GNodeB: g.ID, Detail: fmt.Sprintf("at %.0f,%.0f, range %.0f m", x, y, rangeVal)})
+}
+
+// RemoveGNodeB takes a gNodeB out of the network. It must not serve any UE, so fail it
+// first. The sites after it move up one topology position.
+func (n *Network) RemoveGNodeB(id int) error {
+	if err := n.AMF.UnregisterGNodeB(id); err != nil {
+		return err
+	}
+	delete(n.GNodeBs, id)
+	for i, g := range n.Sites {
+		if g.ID == id {
+			n.Sites = append(n.Sites[:i], n.Sites[i+1:]...)
+			break
+		}
+	}
+	n.Journal.Record(journal.Event{Kind: journal.GNodeBRemoved, GNodeB: id})
+	return nil
+}
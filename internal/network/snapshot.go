@@ -0,0 +1,225 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/core/udm"
+	"github.com/rizpur/NetSim5G/internal/journal"
+	"github.com/rizpur/NetSim5G/internal/ran"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// SnapshotVersion is bumped whenever the snapshot format changes incompatibly
+const SnapshotVersion = 1
+
+// Snapshot is the complete state of a network at one simulated instant: clock, random
+// streams, subscribers, gNodeBs (connections, RRC contexts and inactivity timers), UEs,
+// registrations and sessions. Scheduled events are not part of it: they are code, and
+// stay with the process that restores the snapshot, keeping their distance from now.
+type Snapshot struct {
+	Version     int               `json:"version"`
+	Time        time.Time         `json:"time"`       // simulated time when taken
+	ClockStart  time.Time         `json:"clockStart"` // simulated time zero
+	Seed        int64             `json:"seed"`
+	RNGDraws    map[string]uint64 `json:"rngDraws"` // values drawn so far, by stream
+	Subscribers []udm.Subscriber  `json:"subscribers"`
+	GNodeBs     []ran.GNodeBState `json:"gnodebs"` // in topology order
+	UEs         []SavedUE         `json:"ues"`
+	AMF         amf.State         `json:"amf"`
+	SMF         smf.State         `json:"smf"`
+}
+
+// SavedUE is the state of one UE
+type SavedUE struct {
+	IMSI            string  `json:"imsi"`
+	X               float64 `json:"x"`
+	Y               float64 `json:"y"`
+	GNodeBConnected int     `json:"gnodebConnected"`
+	CellConnected   int     `json:"cellConnected"`
+	State           string  `json:"state"`
+	Speed           float64 `json:"speed,omitempty"`
+	Heading         float64 `json:"heading,omitempty"`
+}
+
+// Snapshot captures the whole network. Hold at least a View while calling it.
+func (n *Network) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Version:     SnapshotVersion,
+		Time:        n.Clock.Now(),
+		ClockStart:  n.Clock.Start(),
+		Seed:        n.RNG.Seed(),
+		RNGDraws:    n.RNG.Draws(),
+		Subscribers: n.UDM.State(),
+		AMF:         n.AMF.State(),
+		SMF:         n.SMF.State(),
+	}
+	for _, g := range n.Sites {
+		s.GNodeBs = append(s.GNodeBs, g.State())
+	}
+	for _, u := range n.UEs {
+		s.UEs = append(s.UEs, SavedUE{
+			IMSI:            u.IMSI,
+			X:               u.X,
+			Y:               u.Y,
+			GNodeBConnected: u.GNodeBConnected,
+			CellConnected:   u.CellConnected,
+			State:           u.State.String(),
+			Speed:           u.Speed,
+			Heading:         u.Heading,
+		})
+	}
+	sort.Slice(s.UEs, func(i, j int) bool { return s.UEs[i].IMSI < s.UEs[j].IMSI })
+	return s
+}
+
+// Restore replaces the whole network state with a snapshot, in place: the Network and
+// its components keep their identity, so the API and scheduled events carry on using
+// them. Nothing changes if the snapshot is invalid. Call it inside Update.
+func (n *Network) Restore(s *Snapshot) error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("snapshot version %d is not supported (want %d)", s.Version, SnapshotVersion)
+	}
+
+	// Step 1: UEs, everything else refers to them. Mobility models are code, like
+	// scheduled events: a UE that exists now keeps its model.
+	ues := make(map[string]*ue.UE)
+	for _, saved := range s.UEs {
+		state, err := ue.ParseUEState(saved.State)
+		if err != nil {
+			return fmt.Errorf("snapshot: UE %s: %w", saved.IMSI, err)
+		}
+		ues[saved.IMSI] = &ue.UE{
+			IMSI:            saved.IMSI,
+			X:               saved.X,
+			Y:               saved.Y,
+			GNodeBConnected: saved.GNodeBConnected,
+			CellConnected:   saved.CellConnected,
+			State:           state,
+			Speed:           saved.Speed,
+			Heading:         saved.Heading,
+		}
+		if current, exists := n.UEs[saved.IMSI]; exists {
+			ues[saved.IMSI].Mobility = current.Mobility
+		}
+	}
+
+	// Step 2: Subscribers and gNodeBs
+	udmInstance := &udm.UDM{Journal: n.Journal}
+	udmInstance.Restore(s.Subscribers)
+	var sites []*ran.GNodeB
+	for _, saved := range s.GNodeBs {
+		g, err := ran.RestoreGNodeB(saved, ues)
+		if err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		if n.Buildings != nil {
+			g.Propagation = n.Buildings // the buildings are part of the topology, not the snapshot
+		}
+		sites = append(sites, g)
+	}
+
+	// Step 3: Core functions, built fresh and wired to the existing components
+	amfInstance := amf.NewAMF(n.UDM)
+	amfInstance.Clock = n.Clock
+	amfInstance.Journal = n.Journal
+	amfInstance.Sessions = n.SMF
+	amfInstance.LinkAdaptation = n.AMF.LinkAdaptation // configuration stays as the process set it
+	amfInstance.HandoverHysteresisDB = n.AMF.HandoverHysteresisDB
+	amfInstance.Outage = n.AMF.Outage
+	amfInstance.RACH = n.AMF.RACH // with its random stream
+	for _, g := range sites {
+		amfInstance.RegisterGNodeB(g)
+	}
+	if err := amfInstance.Restore(s.AMF, ues); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	smfInstance := smf.NewSMF(n.UDM, n.AMF)
+	smfInstance.Journal = n.Journal
+	smfInstance.Setup = n.SMF.Setup
+	if err := smfInstance.Restore(s.SMF, ues); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	// Step 4: Everything checked out, swap the state in
+	*n.UDM = *udmInstance
+	*n.AMF = *amfInstance
+	*n.SMF = *smfInstance
+	n.Sites = sites
+	for id := range n.GNodeBs {
+		delete(n.GNodeBs, id)
+	}
+	for _, g := range sites {
+		n.GNodeBs[g.ID] = g
+		n.lastID = max(n.lastID, g.ID)
+	}
+	for imsi := range n.UEs {
+		delete(n.UEs, imsi)
+	}
+	for imsi, u := range ues {
+		n.UEs[imsi] = u
+	}
+	n.RNG.Restore(s.Seed, s.RNGDraws)
+	n.Scheduler.Rebase(s.ClockStart, s.Time)
+	n.journalRestore()
+	return nil
+}
+
+// journalRestore records the restored state, so a replay can start over from it
+func (n *Network) journalRestore() {
+	n.Journal.Begin(journal.Event{Kind: journal.SnapshotRestored})
+	defer n.Journal.End()
+
+	imsis := make([]string, 0, len(n.UEs))
+	for imsi := range n.UEs {
+		imsis = append(imsis, imsi)
+	}
+	sort.Strings(imsis)
+	for _, imsi := range imsis {
+		n.Journal.Record(journal.Event{Kind: journal.UECreated, IMSI: imsi, UE: n.AMF.DescribeUE(n.UEs[imsi])})
+	}
+	for _, session := range n.SMF.State().Sessions {
+		n.Journal.Record(journal.Event{
+			Kind:        journal.SessionEstablished,
+			IMSI:        session.IMSI,
+			Session:     session.SessionID,
+			SessionType: session.SessionType,
+			Detail:      "restored",
+		})
+	}
+	for _, g := range n.Sites {
+		if g.Down {
+			n.Journal.Record(journal.Event{Kind: journal.GNodeBFailed, GNodeB: g.ID, Detail: "restored"})
+		}
+	}
+}
+
+// SaveSnapshot writes a snapshot as indented JSON
+func SaveSnapshot(path string, s *Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
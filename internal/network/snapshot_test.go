@@ -0,0 +1,145 @@
+package network
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/mobility"
+	"github.com/rizpur/NetSim5G/internal/sim"
+)
+
+// testIMSIs are subscribers of configs/subscribers.json the gNodeBs of
+// configs/topology.yaml let in
+var testIMSIs = []string{"123456789012345", "208930000000001", "987654321098765"}
+
+// newTestNetwork is the topology of configs/topology.yaml with three UEs walking at
+// random around the two sites, each holding a session
+func newTestNetwork(t *testing.T, seed int64) *Network {
+	t.Helper()
+	topology, err := config.Load("../configs/topology.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(topology, "../configs/subscribers.json", seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walk := config.Mobility{Model: config.MobilityRandomWalk, Interval: 5 * time.Second}
+	start := [][2]float64{{110, 110}, {190, 190}, {210, 200}}
+	for i, imsi := range testIMSIs {
+		u, err := n.AddUE(imsi, start[i][0], start[i][1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Mobility, err = mobility.New(walk, imsi, n.Coverage(), n.RNG.Stream(sim.StreamMobility)); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.AMF.Attach(u); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := n.SMF.EstablishSession(u, smf.IoT); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.StartMobility(DefaultMobilityTick)
+	return n
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	// Step 1: Run a while, then save the network to a file and read it back
+	n := newTestNetwork(t, 7)
+	n.Scheduler.RunFor(30 * time.Second)
+	var probed []time.Time
+	n.Scheduler.After(time.Minute, sim.PriorityLow, "probe", func(now time.Time) { probed = append(probed, now) })
+	saved := n.Snapshot()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := SaveSnapshot(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := n.Scheduler.Pending()
+	rng := n.RNG.Stream(sim.StreamMobility)
+	nextDraw := rng.Int63() // what the stream gives right after the snapshot
+
+	// Step 2: Move on and change things, then go back
+	n.Scheduler.RunFor(20 * time.Second)
+	if err := n.RemoveUE(testIMSIs[0]); err != nil {
+		t.Fatal(err)
+	}
+	var restoreErr error
+	n.Update(func() { restoreErr = n.Restore(loaded) })
+	if restoreErr != nil {
+		t.Fatal(restoreErr)
+	}
+
+	// Step 3: The network is as it was saved
+	restored := n.Snapshot()
+	for _, part := range []struct {
+		name      string
+		want, got interface{}
+	}{
+		{"time", saved.Time, restored.Time},
+		{"clock start", saved.ClockStart, restored.ClockStart},
+		{"seed", saved.Seed, restored.Seed},
+		{"RNG draws", saved.RNGDraws, restored.RNGDraws},
+		{"subscribers", saved.Subscribers, restored.Subscribers},
+		{"gNodeBs", saved.GNodeBs, restored.GNodeBs},
+		{"UEs", saved.UEs, restored.UEs},
+		{"AMF", saved.AMF, restored.AMF},
+		{"sessions", saved.SMF, restored.SMF},
+	} {
+		want, _ := json.Marshal(part.want)
+		got, _ := json.Marshal(part.got)
+		if string(want) != string(got) {
+			t.Errorf("%s after restore:\n got %s\nwant %s", part.name, got, want)
+		}
+	}
+	if len(saved.UEs) != len(testIMSIs) || len(saved.SMF.Sessions) != len(testIMSIs) || saved.RNGDraws[sim.StreamMobility] == 0 {
+		t.Errorf("saved %d UEs, %d sessions and %d mobility draws; want %d UEs and sessions, and draws",
+			len(saved.UEs), len(saved.SMF.Sessions), saved.RNGDraws[sim.StreamMobility], len(testIMSIs))
+	}
+	if !n.Clock.Now().Equal(saved.Time) {
+		t.Errorf("clock at %v, want %v", n.Clock.Now(), saved.Time)
+	}
+
+	// Streams resume where they were, also through generators handed out before
+	if got := rng.Int63(); got != nextDraw {
+		t.Errorf("mobility stream resumed with %d, want %d", got, nextDraw)
+	}
+
+	// Scheduled events stay queued and keep their distance from now: the probe was 40s
+	// away when the snapshot was restored, the mobility tick 1s
+	if got := n.Scheduler.Pending(); got != pending {
+		t.Errorf("%d events pending, want %d", got, pending)
+	}
+	if next, _ := n.Scheduler.Next(); !next.Equal(saved.Time.Add(time.Second)) {
+		t.Errorf("next event at %v, want %v", next, saved.Time.Add(time.Second))
+	}
+	n.Scheduler.RunFor(time.Minute)
+	if want := saved.Time.Add(40 * time.Second); len(probed) != 1 || !probed[0].Equal(want) {
+		t.Errorf("probe ran at %v, want once at %v", probed, want)
+	}
+}
+
+func TestRestoreRejectsVersion(t *testing.T) {
+	n := newTestNetwork(t, 1)
+	s := n.Snapshot()
+	before, _ := json.Marshal(s)
+	s.Version = SnapshotVersion + 1
+	var err error
+	n.Update(func() { err = n.Restore(s) })
+	if err == nil {
+		t.Fatal("restored a snapshot of an unsupported version")
+	}
+	s.Version = SnapshotVersion
+	if after, _ := json.Marshal(n.Snapshot()); string(after) != string(before) {
+		t.Error("a rejected snapshot changed the network")
+	}
+}
@@ -0,0 +1,24 @@
+package network
+
+import (
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/traffic"
+)
+
+// StartTraffic gives every PDU session traffic of its type, generated and sent once
+// per tick for as long as the returned event stays queued; cancel it to stop. Traffic
+// keeps UEs connected and pages idle ones, like real downlink data would.
+func (n *Network) StartTraffic(tick time.Duration) *sim.Event {
+	if tick <= 0 {
+		tick = traffic.DefaultTick
+	}
+	if n.Traffic == nil {
+		n.Traffic = traffic.NewEngine(n.AMF, n.SMF, n.RNG.Stream(sim.StreamTraffic), n.Metrics)
+	}
+	return n.Scheduler.Every(tick, sim.PriorityNormal, "traffic", func(now time.Time) {
+		n.AMF.TickRRC(now) // UEs go idle between bursts, and are paged for the next one
+		n.Traffic.Tick(now, tick)
+	})
+}
@@ -0,0 +1,51 @@
+package radio
+
+import "math"
+
+// 3GPP TR 38.901 Table 7.3-1 antenna element parameters
+const (
+	maxAntennaGainDBi      = 8.0
+	frontToBackRatioDB     = 30.0 // Am
+	sideLobeLevelDB        = 30.0 // SLAv
+	verticalBeamwidthDeg   = 65.0
+	OmniBeamwidth          = 360.0
+	DefaultSectorBeamwidth = 65.0
+)
+
+// AntennaPattern describes how a cell's antenna is pointed. Azimuth is measured
+// clockwise from north (+Y), tilt is the downtilt below the horizon.
+type AntennaPattern struct {
+	AzimuthDeg   float64
+	BeamwidthDeg float64 // horizontal 3dB beamwidth, 0 or 360 = omnidirectional
+	TiltDeg      float64
+}
+
+// Omni reports whether the pattern radiates equally in every direction
+func (p AntennaPattern) Omni() bool {
+	return p.BeamwidthDeg <= 0 || p.BeamwidthDeg >= OmniBeamwidth
+}
+
+// Gain returns the antenna gain in dBi towards a receiver seen at bearingDeg
+// (clockwise from north) and depressionDeg below the horizon.
+// Omnidirectional antennas have 0 dBi everywhere.
+func (p AntennaPattern) Gain(bearingDeg, depressionDeg float64) float64 {
+	if p.Omni() {
+		return 0
+	}
+	phi := math.Mod(bearingDeg-p.AzimuthDeg+540, 360) - 180 // offset from boresight in [-180, 180)
+	horizontal := -math.Min(12*math.Pow(phi/p.BeamwidthDeg, 2), frontToBackRatioDB)
+	vertical := -math.Min(12*math.Pow((depressionDeg-p.TiltDeg)/verticalBeamwidthDeg, 2), sideLobeLevelDB)
+	return maxAntennaGainDBi - math.Min(-(horizontal+vertical), frontToBackRatioDB)
+}
+
+// Bearing returns the direction from (x1,y1) to (x2,y2) in degrees clockwise from north
+func Bearing(x1, y1, x2, y2 float64) float64 {
+	deg := math.Atan2(x2-x1, y2-y1) * 180 / math.Pi
+	return math.Mod(deg+360, 360)
+}
+
+// Depression returns the angle below the horizon at which an antenna heightDiff
+// metres above the receiver sees it at horizontal distance distance
+func Depression(heightDiff, distance float64) float64 {
+	return math.Atan2(heightDiff, distance) * 180 / math.Pi
+}
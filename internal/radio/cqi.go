@@ -0,0 +1,118 @@
+package radio
+
+// Modulation order (bits per symbol)
+const (
+	QPSK  = 2
+	QAM16 = 4
+	QAM64 = 6
+)
+
+// MCSEntry is one row of a 3GPP modulation and coding scheme table
+type MCSEntry struct {
+	Modulation int     // bits per symbol (Qm)
+	CodeRate   float64 // target code rate x 1024
+	Efficiency float64 // spectral efficiency in bits/s/Hz
+}
+
+// CQITable is TS 38.214 Table 5.2.2.1-2 (4-bit CQI table 1). Index 0 = out of range.
+var CQITable = [...]MCSEntry{
+	{0, 0, 0},
+	{QPSK, 78, 0.1523},
+	{QPSK, 120, 0.2344},
+	{QPSK, 193, 0.3770},
+	{QPSK, 308, 0.6016},
+	{QPSK, 449, 0.8770},
+	{QPSK, 602, 1.1758},
+	{QAM16, 378, 1.4766},
+	{QAM16, 490, 1.9141},
+	{QAM16, 616, 2.4063},
+	{QAM64, 466, 2.7305},
+	{QAM64, 567, 3.3223},
+	{QAM64, 666, 3.9023},
+	{QAM64, 772, 4.5234},
+	{QAM64, 873, 5.1152},
+	{QAM64, 948, 5.5547},
+}
+
+// MCSTable is TS 38.214 Table 5.1.3.1-1 (PDSCH MCS index table 1), MCS 0-28
+var MCSTable = [...]MCSEntry{
+	{QPSK, 120, 0.2344},
+	{QPSK, 157, 0.3066},
+	{QPSK, 193, 0.3770},
+	{QPSK, 251, 0.4902},
+	{QPSK, 308, 0.6016},
+	{QPSK, 379, 0.7402},
+	{QPSK, 449, 0.8770},
+	{QPSK, 526, 1.0273},
+	{QPSK, 602, 1.1758},
+	{QPSK, 679, 1.3262},
+	{QAM16, 340, 1.3281},
+	{QAM16, 378, 1.4766},
+	{QAM16, 434, 1.6953},
+	{QAM16, 490, 1.9141},
+	{QAM16, 553, 2.1602},
+	{QAM16, 616, 2.4063},
+	{QAM16, 658, 2.5703},
+	{QAM64, 438, 2.5664},
+	{QAM64, 466, 2.7305},
+	{QAM64, 517, 3.0293},
+	{QAM64, 567, 3.3223},
+	{QAM64, 616, 3.6094},
+	{QAM64, 666, 3.9023},
+	{QAM64, 719, 4.2129},
+	{QAM64, 772, 4.5234},
+	{QAM64, 822, 4.8164},
+	{QAM64, 873, 5.1152},
+	{QAM64, 910, 5.3320},
+	{QAM64, 948, 5.5547},
+}
+
+// Minimum SINR (dB) for each CQI at 10% BLER, AWGN link-level results commonly
+// used in system-level simulators. cqiSINRThresholds[i] is the threshold for CQI i+1.
+var cqiSINRThresholds = [...]float64{
+	-6.7, -4.7, -2.3, 0.2, 2.4, 4.3, 5.9, 8.1, 10.3, 11.7, 14.1, 16.3, 18.7, 21.0, 22.7,
+}
+
+// SINRToCQI returns the highest CQI whose threshold the SINR meets (0 = out of range)
+func SINRToCQI(sinrDB float64) int {
+	cqi := 0
+	for i, threshold := range cqiSINRThresholds {
+		if sinrDB >= threshold {
+			cqi = i + 1
+		}
+	}
+	return cqi
+}
+
+// CQIToMCS returns the highest MCS whose spectral efficiency does not exceed the
+// one reported by the CQI, or -1 when the UE is out of range (CQI 0).
+func CQIToMCS(cqi int) int {
+	if cqi <= 0 {
+		return -1
+	}
+	target := CQITable[cqi].Efficiency
+	mcs := 0
+	for i, entry := range MCSTable {
+		if entry.Efficiency <= target {
+			mcs = i
+		}
+	}
+	return mcs
+}
+
+// requiredSINR estimates the SINR an MCS needs by interpolating the CQI thresholds
+// on spectral efficiency. Used as the 10% BLER point of the BLER curve.
+func requiredSINR(mcs int) float64 {
+	efficiency := MCSTable[mcs].Efficiency
+	for cqi := 1; cqi < len(CQITable); cqi++ {
+		if efficiency <= CQITable[cqi].Efficiency {
+			lowEff, lowSINR := CQITable[cqi-1].Efficiency, cqiSINRThresholds[0]-2
+			if cqi > 1 {
+				lowSINR = cqiSINRThresholds[cqi-2]
+			}
+			highEff, highSINR := CQITable[cqi].Efficiency, cqiSINRThresholds[cqi-1]
+			return lowSINR + (efficiency-lowEff)/(highEff-lowEff)*(highSINR-lowSINR)
+		}
+	}
+	return cqiSINRThresholds[len(cqiSINRThresholds)-1]
+}
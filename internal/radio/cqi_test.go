@@ -0,0 +1,127 @@
+package radio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	if len(CQITable) != 16 || len(MCSTable) != 29 {
+		t.Fatalf("%d CQIs and %d MCSs, want 16 (0-15) and 29 (0-28)", len(CQITable), len(MCSTable))
+	}
+	// The edges of TS 38.214 tables 5.2.2.1-2 and 5.1.3.1-1
+	for _, tt := range []struct {
+		name      string
+		got, want MCSEntry
+	}{
+		{"CQI 0", CQITable[0], MCSEntry{0, 0, 0}},
+		{"CQI 1", CQITable[1], MCSEntry{QPSK, 78, 0.1523}},
+		{"CQI 15", CQITable[15], MCSEntry{QAM64, 948, 5.5547}},
+		{"MCS 0", MCSTable[0], MCSEntry{QPSK, 120, 0.2344}},
+		{"MCS 9, the last QPSK", MCSTable[9], MCSEntry{QPSK, 679, 1.3262}},
+		{"MCS 10, the first 16QAM", MCSTable[10], MCSEntry{QAM16, 340, 1.3281}},
+		{"MCS 17, the first 64QAM", MCSTable[17], MCSEntry{QAM64, 438, 2.5664}},
+		{"MCS 28", MCSTable[28], MCSEntry{QAM64, 948, 5.5547}},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s is %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+	// Spectral efficiency is Qm x code rate, as rounded in the tables
+	for name, table := range map[string][]MCSEntry{"CQI": CQITable[1:], "MCS": MCSTable[:]} {
+		for i, e := range table {
+			if want := float64(e.Modulation) * e.CodeRate / 1024; math.Abs(e.Efficiency-want) > 0.0001 {
+				t.Errorf("%s table row %d: efficiency %g, want %g", name, i, e.Efficiency, want)
+			}
+		}
+	}
+}
+
+func TestSINRToCQI(t *testing.T) {
+	tests := []struct {
+		sinrDB float64
+		cqi    int
+	}{
+		{-100, 0},
+		{-6.71, 0},
+		{-6.7, 1}, // thresholds are inclusive
+		{-4.71, 1},
+		{-4.7, 2},
+		{5.9, 7},
+		{14.09, 10},
+		{14.1, 11},
+		{22.69, 14},
+		{22.7, 15},
+		{100, 15},
+	}
+	for _, tt := range tests {
+		if got := SINRToCQI(tt.sinrDB); got != tt.cqi {
+			t.Errorf("SINRToCQI(%g) = %d, want %d", tt.sinrDB, got, tt.cqi)
+		}
+	}
+	for i, threshold := range cqiSINRThresholds {
+		if got := SINRToCQI(threshold); got != i+1 {
+			t.Errorf("SINRToCQI(%g) = %d at the threshold of CQI %d", threshold, got, i+1)
+		}
+		if got := SINRToCQI(threshold - 0.01); got != i {
+			t.Errorf("SINRToCQI(%g) = %d just below the threshold of CQI %d", threshold-0.01, got, i+1)
+		}
+	}
+}
+
+func TestCQIToMCS(t *testing.T) {
+	tests := []struct {
+		cqi, mcs int
+	}{
+		{-1, -1},
+		{0, -1}, // out of range
+		{1, 0},  // below the lowest MCS: still MCS 0
+		{2, 0},
+		{6, 8},
+		{7, 11}, // the first 16QAM CQI, same efficiency as MCS 11
+		{9, 15},
+		{10, 18}, // the first 64QAM CQI
+		{14, 26},
+		{15, 28},
+	}
+	for _, tt := range tests {
+		if got := CQIToMCS(tt.cqi); got != tt.mcs {
+			t.Errorf("CQIToMCS(%d) = %d, want %d", tt.cqi, got, tt.mcs)
+		}
+	}
+	// The MCS never reports more than the CQI, and grows with it
+	for cqi := 2; cqi < len(CQITable); cqi++ {
+		mcs := CQIToMCS(cqi)
+		if MCSTable[mcs].Efficiency > CQITable[cqi].Efficiency {
+			t.Errorf("CQI %d gives MCS %d, which is more efficient", cqi, mcs)
+		}
+		if mcs < CQIToMCS(cqi-1) {
+			t.Errorf("CQI %d gives MCS %d, below the one of CQI %d", cqi, mcs, cqi-1)
+		}
+	}
+}
+
+func TestRequiredSINR(t *testing.T) {
+	// An MCS as efficient as a CQI needs that CQI's threshold
+	for _, tt := range []struct {
+		mcs  int
+		sinr float64
+	}{
+		{0, -4.7},
+		{11, 5.9},
+		{18, 11.7},
+		{28, 22.7},
+	} {
+		if got := requiredSINR(tt.mcs); math.Abs(got-tt.sinr) > 1e-9 {
+			t.Errorf("requiredSINR(%d) = %g, want %g", tt.mcs, got, tt.sinr)
+		}
+	}
+	// More efficient needs more SINR. Not always a higher MCS: MCS 17, the first 64QAM
+	// one, is a little less efficient than MCS 16.
+	for mcs := 1; mcs < len(MCSTable); mcs++ {
+		higher := MCSTable[mcs].Efficiency > MCSTable[mcs-1].Efficiency
+		if higher != (requiredSINR(mcs) > requiredSINR(mcs-1)) {
+			t.Errorf("MCS %d needs %g dB, MCS %d %g dB", mcs, requiredSINR(mcs), mcs-1, requiredSINR(mcs-1))
+		}
+	}
+}
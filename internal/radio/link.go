@@ -0,0 +1,97 @@
+package radio
+
+import "math"
+
+// Typical UE receiver noise figure (dB)
+const UENoiseFigureDB = 7.0
+
+// Fraction of resource elements lost to control channels and reference signals
+// (TS 38.306 downlink FR1 overhead)
+const overhead = 0.14
+
+// Carrier describes the NR carrier a cell transmits on
+type Carrier struct {
+	FrequencyGHz float64
+	BandwidthMHz float64
+	Numerology   int // mu: subcarrier spacing = 15kHz * 2^mu
+}
+
+// PRBs approximates the number of physical resource blocks on the carrier,
+// assuming ~92% of the channel is usable after guard bands (within a few PRBs of TS 38.101-1)
+func (c Carrier) PRBs() int {
+	scsKHz := 15.0 * float64(int(1)<<c.Numerology)
+	return int(c.BandwidthMHz * 1000 * 0.92 / (12 * scsKHz))
+}
+
+// Throughput returns the physical layer data rate in Mbps for a UE scheduled on
+// prbs resource blocks at the given MCS
+func Throughput(mcs, prbs, numerology int) float64 {
+	if mcs < 0 || prbs <= 0 {
+		return 0
+	}
+	slotsPerSecond := 1000.0 * float64(int(1)<<numerology)
+	resourceElements := 12.0 * 14.0 * float64(prbs) * (1 - overhead) // 12 subcarriers x 14 symbols per slot
+	return MCSTable[mcs].Efficiency * resourceElements * slotsPerSecond / 1e6
+}
+
+// BLER returns the block error rate of a transmission at the given MCS. Modelled as a
+// logistic curve that crosses 10% at the SINR the MCS was chosen for.
+func BLER(sinrDB float64, mcs int) float64 {
+	const slope = 1.5 // per dB, steepness of the waterfall
+	return 1 / (1 + math.Exp(math.Log(9)+slope*(sinrDB-requiredSINR(mcs))))
+}
+
+// LinkAdaptation configures how achievable throughput is estimated
+type LinkAdaptation struct {
+	ModelBLER bool // apply the BLER curve at the selected MCS
+	HARQMaxTx int  // max transmissions per transport block incl. the first (0 or 1 = no HARQ)
+}
+
+// LinkMetrics is the outcome of link adaptation for one UE
+type LinkMetrics struct {
+	RSRPDBm        float64 `json:"rsrp"`
+	SINRDB         float64 `json:"sinr"`
+	CQI            int     `json:"cqi"`
+	MCS            int     `json:"mcs"`
+	PRBs           int     `json:"prbs"`
+	BLER           float64 `json:"bler"` // residual, after HARQ
+	ThroughputMbps float64 `json:"throughputMbps"`
+}
+
+// Evaluate maps SINR to CQI and MCS and returns the achievable throughput on prbs resource blocks
+func (la LinkAdaptation) Evaluate(sinrDB float64, prbs, numerology int) LinkMetrics {
+	cqi := SINRToCQI(sinrDB)
+	mcs := CQIToMCS(cqi)
+	metrics := LinkMetrics{
+		SINRDB: sinrDB,
+		CQI:    cqi,
+		MCS:    mcs,
+		PRBs:   prbs,
+	}
+	if mcs < 0 {
+		metrics.BLER = 1
+		return metrics // out of range, nothing can be scheduled
+	}
+
+	rate := Throughput(mcs, prbs, numerology)
+	if !la.ModelBLER {
+		metrics.ThroughputMbps = rate
+		return metrics
+	}
+
+	// HARQ with chase combining: every retransmission adds the energy of the previous ones,
+	// so attempt k sees SINR + 10log10(k+1). Goodput = rate * P(success) / E[transmissions].
+	maxTx := la.HARQMaxTx
+	if maxTx < 1 {
+		maxTx = 1
+	}
+	allFailed := 1.0
+	expectedTx := 0.0
+	for k := 0; k < maxTx; k++ {
+		expectedTx += allFailed // attempt k happens only if all previous ones failed
+		allFailed *= BLER(sinrDB+10*math.Log10(float64(k+1)), mcs)
+	}
+	metrics.BLER = allFailed
+	metrics.ThroughputMbps = rate * (1 - allFailed) / expectedTx
+	return metrics
+}
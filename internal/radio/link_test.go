@@ -0,0 +1,94 @@
+package radio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPRBs(t *testing.T) {
+	for _, tt := range []struct {
+		carrier Carrier
+		prbs    int
+	}{
+		{Carrier{BandwidthMHz: 20, Numerology: 0}, 102},  // 106 in TS 38.101-1
+		{Carrier{BandwidthMHz: 100, Numerology: 1}, 255}, // 273
+		{Carrier{BandwidthMHz: 10, Numerology: 2}, 12},   // 11
+	} {
+		if got := tt.carrier.PRBs(); got != tt.prbs {
+			t.Errorf("%+v has %d PRBs, want %d", tt.carrier, got, tt.prbs)
+		}
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	for _, tt := range []struct {
+		mcs, prbs, numerology int
+		mbps                  float64
+	}{
+		{-1, 273, 1, 0}, // out of range
+		{28, 0, 1, 0},
+		{0, 106, 0, 3.589807872},
+		{11, 51, 1, 21.760595136},
+		{28, 273, 1, 438.188508576},
+	} {
+		if got := Throughput(tt.mcs, tt.prbs, tt.numerology); math.Abs(got-tt.mbps) > 1e-6 {
+			t.Errorf("Throughput(%d, %d, %d) = %g Mbps, want %g", tt.mcs, tt.prbs, tt.numerology, got, tt.mbps)
+		}
+	}
+}
+
+func TestBLER(t *testing.T) {
+	for _, mcs := range []int{0, 11, 28} {
+		required := requiredSINR(mcs)
+		if got := BLER(required, mcs); math.Abs(got-0.1) > 1e-9 {
+			t.Errorf("MCS %d: BLER %g at its required SINR, want 0.1", mcs, got)
+		}
+		if got := BLER(required-20, mcs); got < 0.999 {
+			t.Errorf("MCS %d: BLER %g 20 dB below, want about 1", mcs, got)
+		}
+		if got := BLER(required+20, mcs); got > 1e-10 {
+			t.Errorf("MCS %d: BLER %g 20 dB above, want about 0", mcs, got)
+		}
+		for sinr := required - 10; sinr < required+10; sinr++ {
+			if BLER(sinr+1, mcs) >= BLER(sinr, mcs) {
+				t.Errorf("MCS %d: BLER does not fall from %g to %g dB", mcs, sinr, sinr+1)
+			}
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	const prbs, numerology = 273, 1
+	peak := Throughput(28, prbs, numerology)
+	tests := []struct {
+		name   string
+		la     LinkAdaptation
+		sinrDB float64
+		cqi    int
+		mcs    int
+		bler   float64
+		mbps   float64
+	}{
+		{"out of range", LinkAdaptation{ModelBLER: true, HARQMaxTx: 4}, -10, 0, -1, 1, 0},
+		{"no BLER model", LinkAdaptation{}, 22.7, 15, 28, 0, peak},
+		// At the threshold of CQI 15, MCS 28 loses one block in ten
+		{"no HARQ", LinkAdaptation{ModelBLER: true}, 22.7, 15, 28, 0.1, 0.9 * peak},
+		{"one transmission", LinkAdaptation{ModelBLER: true, HARQMaxTx: 1}, 22.7, 15, 28, 0.1, 0.9 * peak},
+		// A retransmission combines with the first for 3 dB more: 1 in 819.6 fails again
+		{"two transmissions", LinkAdaptation{ModelBLER: true, HARQMaxTx: 2}, 22.7, 15, 28, 0.000121393, 398.304832178},
+		{"four transmissions", LinkAdaptation{ModelBLER: true, HARQMaxTx: 4}, 22.7, 15, 28, 1.39775e-13, 398.309229353},
+		{"lowest CQI", LinkAdaptation{ModelBLER: true}, -6.7, 1, 0, BLER(-6.7, 0), Throughput(0, prbs, numerology) * (1 - BLER(-6.7, 0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.la.Evaluate(tt.sinrDB, prbs, numerology)
+			if m.CQI != tt.cqi || m.MCS != tt.mcs || m.PRBs != prbs || m.SINRDB != tt.sinrDB {
+				t.Errorf("CQI %d, MCS %d, %d PRBs at %g dB; want CQI %d, MCS %d, %d PRBs at %g dB",
+					m.CQI, m.MCS, m.PRBs, m.SINRDB, tt.cqi, tt.mcs, prbs, tt.sinrDB)
+			}
+			if math.Abs(m.BLER-tt.bler) > 1e-9 || math.Abs(m.ThroughputMbps-tt.mbps) > 1e-6 {
+				t.Errorf("BLER %g, %g Mbps; want %g, %g Mbps", m.BLER, m.ThroughputMbps, tt.bler, tt.mbps)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package radio
+
+import "math"
+
+// Thermal noise density at room temperature (dBm/Hz)
+const thermalNoiseDBmPerHz = -174.0
+
+// PathLoss returns the 3GPP TR 38.901 UMi street canyon LOS path loss in dB
+// for a link of distanceM metres on a carrier of freqGHz.
+func PathLoss(distanceM, freqGHz float64) float64 {
+	if distanceM < 1 {
+		distanceM = 1 // model is not defined below 1m, clamp instead of returning -Inf
+	}
+	return 32.4 + 21*math.Log10(distanceM) + 20*math.Log10(freqGHz)
+}
+
+// PathLossNLOS returns the TR 38.901 UMi street canyon NLOS path loss in dB, for a UE
+// antenna 1.5m up. It is never below the LOS loss at the same distance.
+func PathLossNLOS(distanceM, freqGHz float64) float64 {
+	if distanceM < 1 {
+		distanceM = 1
+	}
+	nlos := 22.4 + 35.3*math.Log10(distanceM) + 21.3*math.Log10(freqGHz)
+	return math.Max(PathLoss(distanceM, freqGHz), nlos)
+}
+
+// KnifeEdgeLoss returns the loss in dB of diffraction over a single knife edge with
+// Fresnel-Kirchhoff parameter v (ITU-R P.526). Edges well below the ray (v <= -0.78)
+// cost nothing.
+func KnifeEdgeLoss(v float64) float64 {
+	if v <= -0.78 {
+		return 0
+	}
+	return 6.9 + 20*math.Log10(math.Sqrt((v-0.1)*(v-0.1)+1)+v-0.1)
+}
+
+// NoisePower returns the receiver noise floor in dBm over bandwidthMHz
+func NoisePower(bandwidthMHz, noiseFigureDB float64) float64 {
+	return thermalNoiseDBmPerHz + 10*math.Log10(bandwidthMHz*1e6) + noiseFigureDB
+}
+
+// DBmToMilliwatt and MilliwattToDBm convert between log and linear power so
+// signals and interference can be summed.
+func DBmToMilliwatt(dbm float64) float64 {
+	return math.Pow(10, dbm/10)
+}
+
+func MilliwattToDBm(mw float64) float64 {
+	return 10 * math.Log10(mw)
+}
+
+// SINR returns the signal to interference plus noise ratio in dB.
+// All powers are in dBm; interferers on other carriers must be filtered out by the caller.
+func SINR(signalDBm float64, interferenceDBm []float64, noiseDBm float64) float64 {
+	denominator := DBmToMilliwatt(noiseDBm)
+	for _, i := range interferenceDBm {
+		denominator += DBmToMilliwatt(i)
+	}
+	return signalDBm - MilliwattToDBm(denominator)
+}
@@ -0,0 +1,127 @@
+package ran
+
+import (
+	"fmt"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ue"
+	"github.com/rizpur/NetSim5G/internal/utils"
+)
+
+// UEHeightM is the height of a UE antenna above ground (m), used for vertical antenna
+// angles and for tracing links through buildings
+const UEHeightM = 1.5
+
+// CellConfig holds the radio parameters of one cell (sector) of a gNodeB
+type CellConfig struct {
+	PCI          int // physical cell identity, 0-1007
+	Antenna      radio.AntennaPattern
+	Carrier      radio.Carrier
+	TxPowerDBm   float64
+	MaxCap       int
+	AllowedIMSIs map[string]bool // nil = use the gNodeB's allow-list
+}
+
+// Cell is one sector of a gNodeB. UEs attach to a cell, not to the whole gNodeB.
+type Cell struct {
+	ID           int // index within the gNodeB
+	PCI          int
+	Antenna      radio.AntennaPattern
+	Carrier      radio.Carrier
+	TxPowerDBm   float64
+	MaxCap       int
+	AllowedIMSIs map[string]bool // nil = use the gNodeB's allow-list
+	ConnectedUEs map[string]*ue.UE
+	site         *GNodeB
+}
+
+// ThreeSectors returns the classic 3-sector layout (0°, 120°, 240°) with consecutive PCIs
+func ThreeSectors(firstPCI, maxCapPerCell int) []CellConfig {
+	var cells []CellConfig
+	for i := 0; i < 3; i++ {
+		cells = append(cells, CellConfig{
+			PCI: (firstPCI + i) % 1008,
+			Antenna: radio.AntennaPattern{
+				AzimuthDeg:   float64(120 * i),
+				BeamwidthDeg: radio.DefaultSectorBeamwidth,
+				TiltDeg:      DefaultTiltDeg,
+			},
+			Carrier:    DefaultCarrier,
+			TxPowerDBm: DefaultTxPowerDBm,
+			MaxCap:     maxCapPerCell,
+		})
+	}
+	return cells
+}
+
+// Name identifies the cell across the network, e.g. "gNB-2/cell-1"
+func (c *Cell) Name() string {
+	return fmt.Sprintf("gNB-%d/cell-%d", c.site.ID, c.ID)
+}
+
+// Allows reports whether the IMSI may attach to this cell
+func (c *Cell) Allows(imsi string) bool {
+	if c.AllowedIMSIs != nil {
+		return c.AllowedIMSIs[imsi]
+	}
+	return c.site.Allows(imsi)
+}
+
+// GNodeBID returns the ID of the gNodeB hosting the cell
+func (c *Cell) GNodeBID() int {
+	return c.site.ID
+}
+
+// gainTowards returns the antenna gain of the cell in the direction of the UE
+func (c *Cell) gainTowards(u *ue.UE) float64 {
+	distance := utils.CalculateDistance(u.X, u.Y, c.site.X, c.site.Y)
+	bearing := radio.Bearing(c.site.X, c.site.Y, u.X, u.Y)
+	return c.Antenna.Gain(bearing, radio.Depression(c.site.HeightM-UEHeightM, distance))
+}
+
+// receivedPower returns the total power (dBm) the UE receives from this cell
+func (c *Cell) receivedPower(u *ue.UE) float64 {
+	return c.TxPowerDBm + c.gainTowards(u) - c.pathLoss(u)
+}
+
+// pathLoss returns the loss (dB) from the site to the UE, through the site's
+// propagation model if it has one
+func (c *Cell) pathLoss(u *ue.UE) float64 {
+	if c.site.Propagation != nil {
+		return c.site.Propagation.PathLoss(c.site.X, c.site.Y, c.site.HeightM, u.X, u.Y, UEHeightM, c.Carrier.FrequencyGHz)
+	}
+	distance := utils.CalculateDistance(u.X, u.Y, c.site.X, c.site.Y)
+	return radio.PathLoss(distance, c.Carrier.FrequencyGHz)
+}
+
+// RSRP returns the reference signal power (dBm per resource element) the UE receives from this cell
+func (c *Cell) RSRP(u *ue.UE) float64 {
+	return c.receivedPower(u) - radio.MilliwattToDBm(float64(12*c.Carrier.PRBs()))
+}
+
+// LinkTo estimates the downlink the UE gets from this cell. Every other cell on the
+// same frequency (including the other sectors of this gNodeB) is assumed fully loaded
+// and interferes; PRBs are shared equally between the connected UEs (round robin).
+func (c *Cell) LinkTo(u *ue.UE, gnbs map[int]*GNodeB, la radio.LinkAdaptation) radio.LinkMetrics {
+	var interference []float64
+	for _, g := range SortedByID(gnbs) { // fixed order keeps the float sum identical between runs
+		if g.Down {
+			continue // off air, no interference
+		}
+		for _, other := range g.Cells {
+			if other != c && other.Carrier.FrequencyGHz == c.Carrier.FrequencyGHz {
+				interference = append(interference, other.receivedPower(u))
+			}
+		}
+	}
+	noise := radio.NoisePower(c.Carrier.BandwidthMHz, radio.UENoiseFigureDB)
+	sinr := radio.SINR(c.receivedPower(u), interference, noise)
+
+	sharing := len(c.ConnectedUEs)
+	if _, connected := c.ConnectedUEs[u.IMSI]; !connected {
+		sharing++ // UE would join the cell
+	}
+	metrics := la.Evaluate(sinr, c.Carrier.PRBs()/sharing, c.Carrier.Numerology)
+	metrics.RSRPDBm = c.RSRP(u)
+	return metrics
+}
@@ -2,58 +2,364 @@ package ran
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/rizpur/NetSim5G/internal/radio"
 	"github.com/rizpur/NetSim5G/internal/ue"
 )
 
-// Package-level variable to track next gNodeB ID
-var nextGNodeBID = 1
+// Package-level variable to track next gNodeB ID, guarded by idMu since networks
+// may be built from several goroutines at once
+var (
+	idMu         sync.Mutex
+	nextGNodeBID = 1
+)
+
+// allocateGNodeBID takes the next gNodeB ID
+func allocateGNodeBID() int {
+	idMu.Lock()
+	defer idMu.Unlock()
+	id := nextGNodeBID
+	nextGNodeBID++
+	return id
+}
+
+// Errors the connection procedures return or wrap, to tell failures apart with errors.Is
+var (
+	ErrDown         = errors.New("down") // as in "gNodeB 3 is down"
+	ErrCapacity     = errors.New("max cap reached")
+	ErrNotAllowed   = errors.New("UE not allowed to connect")
+	ErrNotConnected = errors.New("UE is not currently connected to gNodeB")
+)
+
+// Default radio parameters: a 40 MHz n78 small cell on a 10m mast
+const (
+	DefaultTxPowerDBm = 30.0
+	DefaultHeightM    = 10.0
+	DefaultTiltDeg    = 6.0
+	DefaultTAC        = 1
+)
+
+var DefaultCarrier = radio.Carrier{
+	FrequencyGHz: 3.5,
+	BandwidthMHz: 40,
+	Numerology:   1, // 30 kHz subcarrier spacing
+}
+
+// Slice is an S-NSSAI supported by a gNodeB
+type Slice struct {
+	SST int    `json:"sst"`          // slice/service type: 1 = eMBB, 2 = URLLC, 3 = MIoT
+	SD  string `json:"sd,omitempty"` // slice differentiator, 6 hex digits
+}
+
+// Propagation computes the path loss (dB) from a site antenna at (txX,txY), txH metres
+// up, to a UE antenna at (rxX,rxY), rxH metres up, taking in whatever lies between
+type Propagation interface {
+	PathLoss(txX, txY, txH, rxX, rxY, rxH, freqGHz float64) float64
+}
 
 type GNodeB struct {
 	ID           int
 	X, Y         float64
+	HeightM      float64
 	Range        float64
-	MaxCap       int
-	AllowedIMSIs map[string]bool
-	ConnectedUEs map[string]*ue.UE
+	TAC          int // tracking area code
+	Slices       []Slice
+	Down         bool // site outage: no cell radiates, nothing can attach
+	Cells        []*Cell
+	AllowedIMSIs map[string]bool             // nil = open access
+	ConnectedUEs map[string]*ue.UE           // UE contexts held by the gNodeB, across all its cells
+	Inactive     map[string]*InactiveContext // RRC_INACTIVE UEs anchored here
+	RRC          RRCConfig
+	Signalling   SignallingStats
+	Propagation  Propagation          // nil = open space (radio.PathLoss)
+	lastActivity map[string]time.Time // last traffic per connected UE, for the inactivity timer
 }
 
+// ConnectUE attaches the UE to the strongest cell of this gNodeB that still has capacity
 func (g *GNodeB) ConnectUE(u *ue.UE) error {
-	if len(g.ConnectedUEs) >= g.MaxCap { //guard clauses
-		return fmt.Errorf("connection error: max cap reached")
+	if g.Down {
+		return fmt.Errorf("gNodeB %d is %w", g.ID, ErrDown)
+	}
+
+	var best *Cell
+	allowed := false
+	for _, c := range g.Cells {
+		if !c.Allows(u.IMSI) {
+			continue
+		}
+		allowed = true
+		if len(c.ConnectedUEs) >= c.MaxCap { //guard clauses
+			continue
+		}
+		if best == nil || c.RSRP(u) > best.RSRP(u) {
+			best = c
+		}
+	}
+	if !allowed {
+		return ErrNotAllowed
 	}
+	if best == nil {
+		return fmt.Errorf("connection error: %w", ErrCapacity)
+	}
+
+	return g.ConnectUEToCell(u, best)
+}
 
-	if !g.AllowedIMSIs[u.IMSI] {
-		return fmt.Errorf("UE not allowed to connect")
+// ConnectUEToCell attaches the UE to a specific cell of this gNodeB
+func (g *GNodeB) ConnectUEToCell(u *ue.UE, c *Cell) error {
+	if g.Down {
+		return fmt.Errorf("gNodeB %d is %w", g.ID, ErrDown)
+	}
+	if c.site != g {
+		return fmt.Errorf("%s does not belong to gNodeB %d", c.Name(), g.ID)
+	}
+	if len(c.ConnectedUEs) >= c.MaxCap {
+		return fmt.Errorf("connection error: %w on %s", ErrCapacity, c.Name())
+	}
+	if !c.Allows(u.IMSI) {
+		return ErrNotAllowed
 	}
 
 	// Radio connection successful
-	u.State = ue.Connected
-	g.ConnectedUEs[u.IMSI] = u
+	g.attach(u, c)
+	g.Signalling.RRC += 3  // RRCSetupRequest, RRCSetup, RRCSetupComplete
+	g.Signalling.NGAP += 2 // Initial UE Message, Initial Context Setup
+
+	return nil
+}
+
+// HandoverIn admits a UE handed over from the source gNodeB onto cell c. The source
+// releases the UE only once it is admitted here, so a rejected handover leaves it served.
+func (g *GNodeB) HandoverIn(u *ue.UE, source *GNodeB, c *Cell) error {
+	if g.Down {
+		return fmt.Errorf("gNodeB %d is %w", g.ID, ErrDown)
+	}
+	if c.site != g {
+		return fmt.Errorf("%s does not belong to gNodeB %d", c.Name(), g.ID)
+	}
+	if len(c.ConnectedUEs) >= c.MaxCap {
+		return fmt.Errorf("handover error: %w on %s", ErrCapacity, c.Name())
+	}
+	if !c.Allows(u.IMSI) {
+		return ErrNotAllowed
+	}
 
+	g.attach(u, c)
+	source.detach(u)       // UE Context Release over Xn
+	g.Signalling.XnAP += 2 // Handover Request / Acknowledge
+	g.Signalling.RRC += 2  // RRCReconfiguration / Complete
+	g.Signalling.NGAP += 2 // Path Switch Request / Acknowledge
 	return nil
 }
 
 func (g *GNodeB) Disconnect(u *ue.UE) error {
 	if _, exists := g.ConnectedUEs[u.IMSI]; !exists {
-		return fmt.Errorf("UE is not currently connected to gNodeB")
+		return ErrNotConnected
 	}
-	delete(g.ConnectedUEs, u.IMSI)
+	g.detach(u)
 	u.State = ue.Disconnected
+	u.GNodeBConnected = -1
+	u.CellConnected = -1
+	return nil
+}
+
+// attach puts the UE in RRC_CONNECTED on cell c
+func (g *GNodeB) attach(u *ue.UE, c *Cell) {
+	u.State = ue.Connected
+	u.GNodeBConnected = g.ID
+	u.CellConnected = c.ID
+	c.ConnectedUEs[u.IMSI] = u
+	g.ConnectedUEs[u.IMSI] = u
+}
+
+// detach removes the UE from its cell and the gNodeB, leaving its state to the caller
+func (g *GNodeB) detach(u *ue.UE) {
+	for _, c := range g.Cells {
+		delete(c.ConnectedUEs, u.IMSI)
+	}
+	delete(g.ConnectedUEs, u.IMSI)
+	delete(g.lastActivity, u.IMSI)
+}
+
+// SwitchCell moves a connected UE to another sector of this gNodeB (intra-gNodeB handover).
+// The UE context stays on the gNodeB, only the serving cell changes.
+func (g *GNodeB) SwitchCell(u *ue.UE, target *Cell) error {
+	current := g.ServingCell(u.IMSI)
+	if current == nil {
+		return ErrNotConnected
+	}
+	if target.site != g {
+		return fmt.Errorf("%s does not belong to gNodeB %d", target.Name(), g.ID)
+	}
+	if len(target.ConnectedUEs) >= target.MaxCap {
+		return fmt.Errorf("handover error: %w on %s", ErrCapacity, target.Name())
+	}
+
+	delete(current.ConnectedUEs, u.IMSI)
+	target.ConnectedUEs[u.IMSI] = u
+	u.CellConnected = target.ID
+	g.Signalling.RRC += 2 // RRCReconfiguration / Complete
+	return nil
+}
+
+// Reestablish admits a UE that suffered radio link failure elsewhere onto cell c
+// (RRC re-establishment; the AMF re-points the N2 connection with a path switch)
+func (g *GNodeB) Reestablish(u *ue.UE, c *Cell) error {
+	if g.Down {
+		return fmt.Errorf("gNodeB %d is %w", g.ID, ErrDown)
+	}
+	if c.site != g {
+		return fmt.Errorf("%s does not belong to gNodeB %d", c.Name(), g.ID)
+	}
+	if len(c.ConnectedUEs) >= c.MaxCap {
+		return fmt.Errorf("re-establishment error: %w on %s", ErrCapacity, c.Name())
+	}
+	if !c.Allows(u.IMSI) {
+		return ErrNotAllowed
+	}
+
+	g.attach(u, c)
+	g.Signalling.RRC += 3  // RRCReestablishmentRequest, RRCReestablishment, RRCReestablishmentComplete
+	g.Signalling.NGAP += 2 // Path Switch Request / Acknowledge
+	return nil
+}
+
+// SortedByID returns the gNodeBs of a map ordered by ID. Anything whose outcome depends
+// on visiting order (ties, capacity, float sums) must iterate this way, not over the map.
+func SortedByID(gnbs map[int]*GNodeB) []*GNodeB {
+	sorted := make([]*GNodeB, 0, len(gnbs))
+	for _, g := range gnbs {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// sortedUEs returns UEs ordered by IMSI
+func sortedUEs(ues map[string]*ue.UE) []*ue.UE {
+	sorted := make([]*ue.UE, 0, len(ues))
+	for _, u := range ues {
+		sorted = append(sorted, u)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].IMSI < sorted[j].IMSI })
+	return sorted
+}
+
+// Fail takes the gNodeB off air. Every connected UE suffers radio link failure and every
+// RRC_INACTIVE context anchored here is lost; both are returned so the core can react.
+func (g *GNodeB) Fail() (connected, inactive []*ue.UE) {
+	g.Down = true
+	connected = sortedUEs(g.ConnectedUEs)
+	for _, ctx := range g.Inactive {
+		inactive = append(inactive, ctx.UE)
+	}
+	sort.Slice(inactive, func(i, j int) bool { return inactive[i].IMSI < inactive[j].IMSI })
+
+	for _, u := range connected {
+		g.Disconnect(u)
+	}
+	for _, u := range inactive {
+		delete(g.Inactive, u.IMSI)
+		u.State = ue.Idle
+		u.GNodeBConnected = -1
+	}
+	return connected, inactive
+}
+
+// Restore puts a failed gNodeB back on air with empty cells
+func (g *GNodeB) Restore() {
+	g.Down = false
+}
+
+// ServingCell returns the cell the UE is attached to, or nil
+func (g *GNodeB) ServingCell(imsi string) *Cell {
+	for _, c := range g.Cells {
+		if _, exists := c.ConnectedUEs[imsi]; exists {
+			return c
+		}
+	}
 	return nil
 }
 
+// Allows reports whether the gNodeB-wide allow-list admits the IMSI
+func (g *GNodeB) Allows(imsi string) bool {
+	return g.AllowedIMSIs == nil || g.AllowedIMSIs[imsi]
+}
+
+// MaxCap returns the total UE capacity over all cells
+func (g *GNodeB) MaxCap() int {
+	total := 0
+	for _, c := range g.Cells {
+		total += c.MaxCap
+	}
+	return total
+}
+
+// NewGNodeB creates an open-access gNodeB with a single omnidirectional cell
 func NewGNodeB(x, y, rangeVal float64, MaxCap int) (*GNodeB, error) {
-	// Get the current ID and increment for next gNodeB
-	id := nextGNodeBID
-	nextGNodeBID++
+	id := allocateGNodeBID() // the PCI is derived from it
+	omni := CellConfig{
+		PCI:        (3 * id) % 1008,
+		Antenna:    radio.AntennaPattern{BeamwidthDeg: radio.OmniBeamwidth},
+		Carrier:    DefaultCarrier,
+		TxPowerDBm: DefaultTxPowerDBm,
+		MaxCap:     MaxCap,
+	}
+	return newGNodeB(id, x, y, rangeVal, []CellConfig{omni}), nil
+}
+
+// NewSectorGNodeB creates an open-access gNodeB hosting one cell per config
+func NewSectorGNodeB(x, y, rangeVal float64, cells []CellConfig) (*GNodeB, error) {
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("gNodeB needs at least one cell")
+	}
+	return newGNodeB(allocateGNodeBID(), x, y, rangeVal, cells), nil
+}
+
+// newGNodeB builds a gNodeB with an ID already allocated
+func newGNodeB(id int, x, y, rangeVal float64, cells []CellConfig) *GNodeB {
+	newGnodeB := &GNodeB{
+		ID:           id,
+		X:            x,
+		Y:            y,
+		HeightM:      DefaultHeightM,
+		Range:        rangeVal,
+		TAC:          DefaultTAC,
+		ConnectedUEs: make(map[string]*ue.UE),
+		Inactive:     make(map[string]*InactiveContext),
+		RRC:          RRCConfig{InactivityTimer: DefaultInactivityTimer},
+		lastActivity: make(map[string]time.Time),
+	}
+
+	for i, cfg := range cells {
+		newGnodeB.Cells = append(newGnodeB.Cells, &Cell{
+			ID:           i,
+			PCI:          cfg.PCI,
+			Antenna:      cfg.Antenna,
+			Carrier:      cfg.Carrier,
+			TxPowerDBm:   cfg.TxPowerDBm,
+			MaxCap:       cfg.MaxCap,
+			AllowedIMSIs: cfg.AllowedIMSIs,
+			ConnectedUEs: make(map[string]*ue.UE),
+			site:         newGnodeB,
+		})
+	}
 
+	return newGnodeB
+}
+
+// LoadAllowedIMSIs reads an allow-list file with one IMSI per line
+func LoadAllowedIMSIs(path string) (map[string]bool, error) {
 	allowedIMSIs := make(map[string]bool)
 
-	file, err := os.Open("internal/configs/allowed_imsis.txt") // returns file AND an err
+	file, err := os.Open(path) // returns file AND an err
 	if err != nil {
 		return nil, fmt.Errorf("failed to open allowed IMSIs file: %w", err) //gnode b gets nil
 	}
@@ -71,17 +377,7 @@ func NewGNodeB(x, y, rangeVal float64, MaxCap int) (*GNodeB, error) {
 		return nil, fmt.Errorf("error reading allowed IMSIs: %w", err)
 	}
 
-	newGnodeB := &GNodeB{
-		ID:           id,
-		X:            x,
-		Y:            y,
-		Range:        rangeVal,
-		MaxCap:       MaxCap,
-		AllowedIMSIs: allowedIMSIs,
-		ConnectedUEs: make(map[string]*ue.UE),
-	}
-
-	return newGnodeB, nil
+	return allowedIMSIs, nil
 }
 
 //   1. *UE in a type (like func NewUE() *UE) = "pointer to UE"   - * =
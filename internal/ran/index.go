@@ -0,0 +1,150 @@
+package ran
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rizpur/NetSim5G/internal/utils"
+)
+
+// DefaultBucketSize (m) suits networks of small cells and urban macros: a query looks
+// at a handful of buckets and each holds a few sites
+const DefaultBucketSize = 1000.0
+
+// Index is a uniform grid over gNodeB positions. It answers "which gNodeBs are within
+// r of (x,y)" by looking only at the buckets the circle touches, instead of at every
+// gNodeB, so cell selection costs the same with 10 sites or 10,000.
+type Index struct {
+	BucketSize float64
+	buckets    map[bucket][]*GNodeB
+	where      map[int]entry   // by gNodeB ID
+	ranges     map[float64]int // how many gNodeBs have each coverage range
+	maxRange   float64         // largest coverage range of any gNodeB
+}
+
+type bucket struct{ i, j int }
+
+// entry is where a gNodeB was indexed, so it can be found again after it moved
+type entry struct {
+	bucket bucket
+	rng    float64
+}
+
+// NewIndex creates an empty index with square buckets of size metres (0 = DefaultBucketSize)
+func NewIndex(size float64) *Index {
+	if size <= 0 {
+		size = DefaultBucketSize
+	}
+	return &Index{
+		BucketSize: size,
+		buckets:    make(map[bucket][]*GNodeB),
+		where:      make(map[int]entry),
+		ranges:     make(map[float64]int),
+	}
+}
+
+func (x *Index) bucketOf(px, py float64) bucket {
+	return bucket{int(math.Floor(px / x.BucketSize)), int(math.Floor(py / x.BucketSize))}
+}
+
+// Len returns the number of gNodeBs indexed
+func (x *Index) Len() int {
+	return len(x.where)
+}
+
+// Add indexes a gNodeB at its current position; adding it again is the same as Update
+func (x *Index) Add(g *GNodeB) {
+	if _, exists := x.where[g.ID]; exists {
+		x.Remove(g)
+	}
+	b := x.bucketOf(g.X, g.Y)
+	x.buckets[b] = append(x.buckets[b], g)
+	x.where[g.ID] = entry{b, g.Range}
+	x.ranges[g.Range]++
+	x.maxRange = math.Max(x.maxRange, g.Range)
+}
+
+// Remove takes a gNodeB out of the index
+func (x *Index) Remove(g *GNodeB) {
+	e, exists := x.where[g.ID]
+	if !exists {
+		return
+	}
+	b := e.bucket
+	sites := x.buckets[b]
+	for i, other := range sites {
+		if other.ID == g.ID {
+			sites = append(sites[:i], sites[i+1:]...)
+			break
+		}
+	}
+	if len(sites) == 0 {
+		delete(x.buckets, b)
+	} else {
+		x.buckets[b] = sites
+	}
+	delete(x.where, g.ID)
+
+	// Ranges are counted so the largest can shrink again when its last site goes
+	if x.ranges[e.rng]--; x.ranges[e.rng] <= 0 {
+		delete(x.ranges, e.rng)
+		if e.rng == x.maxRange {
+			x.maxRange = 0
+			for r := range x.ranges {
+				x.maxRange = math.Max(x.maxRange, r)
+			}
+		}
+	}
+}
+
+// Update moves a gNodeB to the bucket of its current position, after X, Y or Range changed
+func (x *Index) Update(g *GNodeB) {
+	x.Add(g)
+}
+
+// Within returns the gNodeBs at most r from (px,py), ordered by ID
+func (x *Index) Within(px, py, r float64) []*GNodeB {
+	var found []*GNodeB
+	x.visit(px, py, r, func(g *GNodeB) {
+		if utils.CalculateDistance(px, py, g.X, g.Y) <= r {
+			found = append(found, g)
+		}
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+// Covering returns the gNodeBs whose coverage range reaches (px,py), ordered by ID
+func (x *Index) Covering(px, py float64) []*GNodeB {
+	var found []*GNodeB
+	x.visit(px, py, x.maxRange, func(g *GNodeB) {
+		if utils.CalculateDistance(px, py, g.X, g.Y) <= g.Range {
+			found = append(found, g)
+		}
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+// visit calls fn for every gNodeB in the buckets the circle of radius r around (px,py) touches
+func (x *Index) visit(px, py, r float64, fn func(*GNodeB)) {
+	lo, hi := x.bucketOf(px-r, py-r), x.bucketOf(px+r, py+r)
+	// A circle wider than the network is cheaper to answer by walking the buckets that exist
+	if (hi.i-lo.i+1)*(hi.j-lo.j+1) > len(x.buckets) {
+		for b, sites := range x.buckets {
+			if b.i >= lo.i && b.i <= hi.i && b.j >= lo.j && b.j <= hi.j {
+				for _, g := range sites {
+					fn(g)
+				}
+			}
+		}
+		return
+	}
+	for i := lo.i; i <= hi.i; i++ {
+		for j := lo.j; j <= hi.j; j++ {
+			for _, g := range x.buckets[bucket{i, j}] {
+				fn(g)
+			}
+		}
+	}
+}
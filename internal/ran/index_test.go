@@ -0,0 +1,142 @@
+package ran
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/utils"
+)
+
+// bruteCovering is Index.Covering done the slow way: every site, in ID order
+func bruteCovering(sites []*GNodeB, px, py float64) []int {
+	var ids []int
+	for _, g := range sites {
+		if utils.CalculateDistance(px, py, g.X, g.Y) <= g.Range {
+			ids = append(ids, g.ID)
+		}
+	}
+	return ids
+}
+
+func ids(sites []*GNodeB) []int {
+	var ids []int
+	for _, g := range sites {
+		ids = append(ids, g.ID)
+	}
+	return ids
+}
+
+func TestIndexCoveringMatchesScan(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketSize float64
+		area       float64 // sites and queries in -area..area on both axes
+		ranges     []float64
+	}{
+		{"small cells in big buckets", 1000, 3000, []float64{50, 200, 400}},
+		{"macros wider than the buckets", 250, 3000, []float64{300, 1500, 2500}},
+		{"mixed, over a few buckets", 0, 5000, []float64{100, 1000, 5000}},
+		{"everything in one bucket", 100000, 2000, []float64{500, 800}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			at := func() float64 { return (2*rng.Float64() - 1) * tt.area }
+			x := NewIndex(tt.bucketSize)
+			var sites []*GNodeB
+			for id := 1; id <= 200; id++ {
+				g := &GNodeB{ID: id, X: at(), Y: at(), Range: tt.ranges[rng.Intn(len(tt.ranges))]}
+				sites = append(sites, g)
+				x.Add(g)
+			}
+			check := func(stage string) {
+				t.Helper()
+				if x.Len() != len(sites) {
+					t.Errorf("%s: index holds %d sites, want %d", stage, x.Len(), len(sites))
+				}
+				for q := 0; q < 500; q++ {
+					px, py := at()*1.2, at()*1.2 // some queries fall outside the sites' area
+					if got, want := ids(x.Covering(px, py)), bruteCovering(sites, px, py); !reflect.DeepEqual(got, want) {
+						t.Fatalf("%s: Covering(%.1f, %.1f) = %v, want %v", stage, px, py, got, want)
+					}
+				}
+			}
+
+			// Step 1: as built
+			check("added")
+
+			// Step 2: a site on the exact edge of its range still covers the point
+			edge := sites[0]
+			if got := ids(x.Covering(edge.X+edge.Range, edge.Y)); !contains(got, edge.ID) {
+				t.Errorf("site %d does not cover the edge of its range: %v", edge.ID, got)
+			}
+
+			// Step 3: sites move and change range
+			for _, g := range sites[:50] {
+				g.X, g.Y = at(), at()
+				g.Range = tt.ranges[rng.Intn(len(tt.ranges))]
+				x.Update(g)
+			}
+			check("moved")
+
+			// Step 4: the widest sites go, so the search radius shrinks with them
+			widest := tt.ranges[len(tt.ranges)-1]
+			kept := sites[:0]
+			for _, g := range sites {
+				if g.Range == widest {
+					x.Remove(g)
+				} else {
+					kept = append(kept, g)
+				}
+			}
+			sites = kept
+			if x.maxRange >= widest {
+				t.Errorf("search radius %g after removing every %g m site", x.maxRange, widest)
+			}
+			check("removed")
+		})
+	}
+}
+
+func contains(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIndexWithin(t *testing.T) {
+	x := NewIndex(100)
+	for id, pos := range [][2]float64{{0, 0}, {150, 0}, {-150, 0}, {0, 300}, {-99.5, -99.5}} {
+		x.Add(&GNodeB{ID: id + 1, X: pos[0], Y: pos[1], Range: 10})
+	}
+	tests := []struct {
+		px, py, r float64
+		want      []int
+	}{
+		{0, 0, 0, []int{1}},
+		{0, 0, 149, []int{1, 5}},
+		{0, 0, 150, []int{1, 2, 3, 5}},
+		{0, 150, 150, []int{1, 4}},
+		{1000, 1000, 10, nil},
+		{0, 0, 10000, []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		if got := ids(x.Within(tt.px, tt.py, tt.r)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Within(%g, %g, %g) = %v, want %v", tt.px, tt.py, tt.r, got, tt.want)
+		}
+	}
+
+	// Removing a site that is not indexed is harmless, and adding one twice indexes it once
+	x.Remove(&GNodeB{ID: 42})
+	x.Add(&GNodeB{ID: 1, X: 500, Y: 500, Range: 10})
+	if x.Len() != 5 {
+		t.Errorf("index holds %d sites, want 5", x.Len())
+	}
+	if got := ids(x.Within(0, 0, 1)); got != nil {
+		t.Errorf("re-added site still found at its old position: %v", got)
+	}
+}
@@ -0,0 +1,226 @@
+package ran
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// RACHConfig holds the contention-based random access parameters of a cell
+type RACHConfig struct {
+	Preambles                   int           // contention-based preambles per PRACH occasion
+	OccasionPeriod              time.Duration // time between PRACH occasions
+	RARDelay                    time.Duration // Msg1 -> Msg2 (random access response)
+	Msg3Delay                   time.Duration // Msg2 -> Msg3 (RRC setup request)
+	Msg4Delay                   time.Duration // Msg3 -> Msg4 (contention resolution)
+	ContentionResolutionTimeout time.Duration // how long a losing UE waits for Msg4 after Msg3
+	BackoffMax                  time.Duration // backoff indicator: wait uniform [0, BackoffMax) after a failure
+	MaxAttempts                 int           // preambleTransMax
+	Seed                        int64
+	Rand                        *rand.Rand `json:"-"` // shared random stream; nil = a fresh generator seeded with Seed on every run
+}
+
+// DefaultRACHConfig is a typical FR1 setup: one occasion per 10ms frame, 54 contention preambles
+var DefaultRACHConfig = RACHConfig{
+	Preambles:                   54,
+	OccasionPeriod:              10 * time.Millisecond,
+	RARDelay:                    4 * time.Millisecond,
+	Msg3Delay:                   3 * time.Millisecond,
+	Msg4Delay:                   4 * time.Millisecond,
+	ContentionResolutionTimeout: 32 * time.Millisecond,
+	BackoffMax:                  20 * time.Millisecond,
+	MaxAttempts:                 10,
+	Seed:                        1,
+}
+
+// Validate checks the config can run: with no preambles or no time between occasions
+// random access never resolves
+func (c RACHConfig) Validate() error {
+	switch {
+	case c.Preambles < 1:
+		return fmt.Errorf("RACH: preambles must be >= 1, got %d", c.Preambles)
+	case c.OccasionPeriod <= 0:
+		return fmt.Errorf("RACH: occasion period must be > 0, got %v", c.OccasionPeriod)
+	case c.MaxAttempts < 1:
+		return fmt.Errorf("RACH: max attempts must be >= 1, got %d", c.MaxAttempts)
+	case c.RARDelay < 0 || c.Msg3Delay < 0 || c.Msg4Delay < 0 || c.ContentionResolutionTimeout < 0 || c.BackoffMax < 0:
+		return fmt.Errorf("RACH: delays, timeout and backoff must be >= 0")
+	}
+	return nil
+}
+
+// RACHRequest is a UE that wants to access the cell, starting at Arrival
+type RACHRequest struct {
+	IMSI    string
+	Arrival time.Duration // offset from the start of the scenario
+}
+
+// ErrRACHFailed is wrapped for a UE that gave up random access after MaxAttempts preambles
+var ErrRACHFailed = errors.New("random access failed")
+
+// RACHResult is the outcome of the random access procedure for one UE
+type RACHResult struct {
+	IMSI     string
+	Success  bool
+	Attempts int           // preambles sent
+	Latency  time.Duration // arrival -> Msg4 received (or -> giving up)
+}
+
+// RACHReport summarises a random access run
+type RACHReport struct {
+	Results    []RACHResult // ordered by completion time
+	Occasions  int          // PRACH occasions used
+	Collisions int          // preambles picked by more than one UE
+}
+
+// SimulateRACH runs contention-based random access for all requests. In every PRACH occasion
+// each waiting UE picks a random preamble; a preamble picked by exactly one UE completes
+// Msg1-Msg4, otherwise all UEs on it fail contention resolution and back off.
+func SimulateRACH(requests []RACHRequest, cfg RACHConfig) (RACHReport, error) {
+	if err := cfg.Validate(); err != nil {
+		return RACHReport{}, err
+	}
+	rng := cfg.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(cfg.Seed))
+	}
+
+	type pending struct {
+		RACHRequest
+		nextTry  time.Duration
+		attempts int
+	}
+	waiting := make([]*pending, 0, len(requests))
+	for _, r := range requests {
+		waiting = append(waiting, &pending{RACHRequest: r, nextTry: r.Arrival})
+	}
+
+	var report RACHReport
+	completedAt := make(map[string]time.Duration)
+
+	for occasion := time.Duration(0); len(waiting) > 0; occasion += cfg.OccasionPeriod {
+		// Step 1: every UE whose backoff has expired sends a preamble (Msg1)
+		picks := make(map[int][]*pending)
+		var stillWaiting []*pending
+		for _, p := range waiting {
+			if p.nextTry > occasion {
+				stillWaiting = append(stillWaiting, p)
+				continue
+			}
+			p.attempts++
+			preamble := rng.Intn(cfg.Preambles)
+			picks[preamble] = append(picks[preamble], p)
+		}
+		if len(picks) == 0 {
+			waiting = stillWaiting
+			continue
+		}
+		report.Occasions++
+
+		// Step 2: resolve contention per preamble. Iterate in preamble order so runs are reproducible.
+		preambles := make([]int, 0, len(picks))
+		for preamble := range picks {
+			preambles = append(preambles, preamble)
+		}
+		sort.Ints(preambles)
+
+		for _, preamble := range preambles {
+			contenders := picks[preamble]
+			if len(contenders) == 1 {
+				p := contenders[0]
+				done := occasion + cfg.RARDelay + cfg.Msg3Delay + cfg.Msg4Delay
+				completedAt[p.IMSI] = done
+				report.Results = append(report.Results, RACHResult{IMSI: p.IMSI, Success: true, Attempts: p.attempts, Latency: done - p.Arrival})
+				continue
+			}
+
+			// Collision: all contenders send Msg3 on the same grant, none get their identity echoed in Msg4
+			report.Collisions++
+			failedAt := occasion + cfg.RARDelay + cfg.Msg3Delay + cfg.ContentionResolutionTimeout
+			for _, p := range contenders {
+				if p.attempts >= cfg.MaxAttempts {
+					completedAt[p.IMSI] = failedAt
+					report.Results = append(report.Results, RACHResult{IMSI: p.IMSI, Attempts: p.attempts, Latency: failedAt - p.Arrival})
+					continue
+				}
+				backoff := time.Duration(0)
+				if cfg.BackoffMax > 0 {
+					backoff = time.Duration(rng.Int63n(int64(cfg.BackoffMax)))
+				}
+				p.nextTry = failedAt + backoff
+				stillWaiting = append(stillWaiting, p)
+			}
+		}
+		waiting = stillWaiting
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return completedAt[report.Results[i].IMSI] < completedAt[report.Results[j].IMSI]
+	})
+	return report, nil
+}
+
+// SuccessRate returns the fraction of UEs that completed random access
+func (r RACHReport) SuccessRate() float64 {
+	if len(r.Results) == 0 {
+		return 0
+	}
+	ok := 0
+	for _, res := range r.Results {
+		if res.Success {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(r.Results))
+}
+
+// LatencyPercentile returns the p-th percentile (0-100) of attach latency over successful UEs
+func (r RACHReport) LatencyPercentile(p float64) time.Duration {
+	var latencies []time.Duration
+	for _, res := range r.Results {
+		if res.Success {
+			latencies = append(latencies, res.Latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	idx := int(p / 100 * float64(len(latencies)-1))
+	return latencies[idx]
+}
+
+// MassAttach runs random access for many UEs at once (stadium, post-outage recovery) and
+// connects the winners in the order they complete Msg4. UEs that win RACH can still be
+// rejected by the allow-list or cell capacity; those are returned in rejected.
+func (g *GNodeB) MassAttach(ues []*ue.UE, arrivals []time.Duration, cfg RACHConfig) (RACHReport, map[string]error, error) {
+	byIMSI := make(map[string]*ue.UE)
+	var requests []RACHRequest
+	for i, u := range ues {
+		byIMSI[u.IMSI] = u
+		req := RACHRequest{IMSI: u.IMSI}
+		if i < len(arrivals) {
+			req.Arrival = arrivals[i]
+		}
+		requests = append(requests, req)
+	}
+
+	report, err := SimulateRACH(requests, cfg)
+	if err != nil {
+		return report, nil, err
+	}
+	rejected := make(map[string]error)
+	for _, res := range report.Results {
+		if !res.Success {
+			continue
+		}
+		if err := g.ConnectUE(byIMSI[res.IMSI]); err != nil {
+			rejected[res.IMSI] = err
+		}
+	}
+	return report, rejected, nil
+}
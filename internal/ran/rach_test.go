@@ -0,0 +1,226 @@
+package ran
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// requests are n UEs arriving at once
+func requests(n int) []RACHRequest {
+	var reqs []RACHRequest
+	for i := 0; i < n; i++ {
+		reqs = append(reqs, RACHRequest{IMSI: fmt.Sprintf("99999%010d", i+1)})
+	}
+	return reqs
+}
+
+func TestRACHConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *RACHConfig)
+		err    string
+	}{
+		{"default", func(*RACHConfig) {}, ""},
+		{"one preamble, no delays", func(c *RACHConfig) {
+			c.Preambles = 1
+			c.RARDelay, c.Msg3Delay, c.Msg4Delay, c.ContentionResolutionTimeout, c.BackoffMax = 0, 0, 0, 0, 0
+		}, ""},
+		{"no preambles", func(c *RACHConfig) { c.Preambles = 0 }, "preambles must be >= 1, got 0"},
+		{"negative preambles", func(c *RACHConfig) { c.Preambles = -3 }, "preambles must be >= 1, got -3"},
+		{"no occasion period", func(c *RACHConfig) { c.OccasionPeriod = 0 }, "occasion period must be > 0, got 0s"},
+		{"negative occasion period", func(c *RACHConfig) { c.OccasionPeriod = -time.Millisecond }, "occasion period must be > 0, got -1ms"},
+		{"no attempts", func(c *RACHConfig) { c.MaxAttempts = 0 }, "max attempts must be >= 1, got 0"},
+		{"negative delay", func(c *RACHConfig) { c.Msg3Delay = -time.Millisecond }, "delays, timeout and backoff must be >= 0"},
+		{"negative backoff", func(c *RACHConfig) { c.BackoffMax = -time.Millisecond }, "delays, timeout and backoff must be >= 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultRACHConfig
+			tt.change(&cfg)
+			err := cfg.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+
+			// Regression: these used to panic (no preambles) or never return (no period)
+			if _, err := SimulateRACH(requests(3), cfg); err == nil {
+				t.Error("SimulateRACH ran with an invalid config")
+			}
+			g, _ := NewGNodeB(0, 0, 500, 10)
+			u := ue.NewUE("999990000000001", 10, 0)
+			if _, _, err := g.MassAttach([]*ue.UE{u}, nil, cfg); err == nil || u.State == ue.Connected {
+				t.Errorf("MassAttach with an invalid config: error %v, UE %s", err, u.State)
+			}
+		})
+	}
+}
+
+func TestSimulateRACHLatency(t *testing.T) {
+	// A UE alone goes through Msg1-Msg4 at the first occasion after it arrives
+	const msgs = 4*time.Millisecond + 3*time.Millisecond + 4*time.Millisecond // RAR + Msg3 + Msg4
+	for _, tt := range []struct {
+		arrival time.Duration
+		latency time.Duration
+	}{
+		{0, msgs},
+		{10 * time.Millisecond, msgs},
+		{5 * time.Millisecond, 5*time.Millisecond + msgs}, // waits for the occasion at 10ms
+		{31 * time.Millisecond, 9*time.Millisecond + msgs},
+	} {
+		report, err := SimulateRACH([]RACHRequest{{IMSI: "1", Arrival: tt.arrival}}, DefaultRACHConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := RACHReport{Results: []RACHResult{{IMSI: "1", Success: true, Attempts: 1, Latency: tt.latency}}, Occasions: 1}
+		if !reflect.DeepEqual(report, want) {
+			t.Errorf("arrival %v: got %+v, want %+v", tt.arrival, report, want)
+		}
+	}
+}
+
+func TestSimulateRACHCollisions(t *testing.T) {
+	// Two UEs, one preamble and no backoff: they collide on every try. Each failure
+	// costs RAR + Msg3 + the contention resolution timeout (39ms), then the next
+	// occasion is at 40ms, 80ms.
+	cfg := DefaultRACHConfig
+	cfg.Preambles = 1
+	cfg.BackoffMax = 0
+	cfg.MaxAttempts = 3
+	report, err := SimulateRACH(requests(2), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := RACHResult{Attempts: 3, Latency: 119 * time.Millisecond}
+	for i, res := range report.Results {
+		failed.IMSI = requests(2)[i].IMSI
+		if res != failed {
+			t.Errorf("got %+v, want %+v", res, failed)
+		}
+	}
+	if len(report.Results) != 2 || report.Occasions != 3 || report.Collisions != 3 {
+		t.Errorf("%d results, %d occasions, %d collisions; want 2, 3 and 3", len(report.Results), report.Occasions, report.Collisions)
+	}
+	if report.SuccessRate() != 0 || report.LatencyPercentile(50) != 0 {
+		t.Errorf("success rate %g, median %v; want 0 for nobody through", report.SuccessRate(), report.LatencyPercentile(50))
+	}
+}
+
+func TestSimulateRACHBackoff(t *testing.T) {
+	// Step 1: Backoff spreads colliding UEs over later occasions until all get through
+	cfg := DefaultRACHConfig
+	cfg.Preambles = 2
+	cfg.BackoffMax = 100 * time.Millisecond
+	cfg.MaxAttempts = 50
+	report, err := SimulateRACH(requests(10), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessRate() != 1 || len(report.Results) != 10 || report.Collisions == 0 {
+		t.Fatalf("success rate %g over %d UEs with %d collisions; want all through after collisions",
+			report.SuccessRate(), len(report.Results), report.Collisions)
+	}
+	const msgs = 11 * time.Millisecond
+	retried := false
+	for i, res := range report.Results {
+		// Msg4 comes right after an occasion; every failed try costs 39ms and up to
+		// the backoff, then waits for an occasion
+		if (res.Latency-msgs)%cfg.OccasionPeriod != 0 {
+			t.Errorf("UE %s done %v after arrival, not Msg4 after an occasion", res.IMSI, res.Latency)
+		}
+		earliest := time.Duration(res.Attempts-1)*40*time.Millisecond + msgs
+		latest := time.Duration(res.Attempts-1)*(40*time.Millisecond+cfg.BackoffMax) + msgs
+		if res.Latency < earliest || res.Latency > latest {
+			t.Errorf("UE %s done after %v in %d attempts, want %v-%v", res.IMSI, res.Latency, res.Attempts, earliest, latest)
+		}
+		if i > 0 && res.Latency < report.Results[i-1].Latency {
+			t.Errorf("results not in completion order: %v after %v", res.Latency, report.Results[i-1].Latency)
+		}
+		retried = retried || res.Attempts > 1
+	}
+	if !retried {
+		t.Error("no UE needed a second preamble")
+	}
+
+	// Step 2: The same seed gives the same run, through a shared stream too
+	again, _ := SimulateRACH(requests(10), cfg)
+	if !reflect.DeepEqual(again, report) {
+		t.Error("same seed, different runs")
+	}
+	cfg.Rand = rand.New(rand.NewSource(cfg.Seed))
+	if shared, _ := SimulateRACH(requests(10), cfg); !reflect.DeepEqual(shared, report) {
+		t.Error("a stream seeded alike gave a different run")
+	}
+}
+
+func TestSimulateRACHCrowd(t *testing.T) {
+	report, err := SimulateRACH(requests(300), DefaultRACHConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessRate() != 1 || len(report.Results) != 300 {
+		t.Fatalf("success rate %g over %d UEs, want all 300 through", report.SuccessRate(), len(report.Results))
+	}
+	// 300 UEs on 54 preambles must collide, and nobody beats a lone UE
+	if report.Collisions == 0 || report.Occasions < 2 {
+		t.Errorf("%d collisions over %d occasions", report.Collisions, report.Occasions)
+	}
+	latencies := make([]time.Duration, 0, len(report.Results))
+	for _, res := range report.Results {
+		latencies = append(latencies, res.Latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if p0 := report.LatencyPercentile(0); p0 != 11*time.Millisecond || p0 != latencies[0] {
+		t.Errorf("fastest UE took %v, want 11ms", p0)
+	}
+	if p50, p100 := report.LatencyPercentile(50), report.LatencyPercentile(100); p50 != latencies[149] || p100 != latencies[299] || p50 > p100 {
+		t.Errorf("median %v, slowest %v; want %v and %v", p50, p100, latencies[149], latencies[299])
+	}
+}
+
+func TestMassAttach(t *testing.T) {
+	// A cell of 2 behind an allow-list: four UEs win random access, two get in
+	g, err := NewSectorGNodeB(0, 0, 500, []CellConfig{{PCI: 1, Carrier: DefaultCarrier, TxPowerDBm: DefaultTxPowerDBm, MaxCap: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ues []*ue.UE
+	g.AllowedIMSIs = make(map[string]bool)
+	for i, req := range requests(4) {
+		ues = append(ues, ue.NewUE(req.IMSI, 10*float64(i+1), 0))
+		if i != 0 {
+			g.AllowedIMSIs[req.IMSI] = true
+		}
+	}
+	arrivals := []time.Duration{0, 0, 20 * time.Millisecond} // the last UE arrives at 0 too
+	report, rejected, err := g.MassAttach(ues, arrivals, DefaultRACHConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessRate() != 1 {
+		t.Fatalf("success rate %g, want 1", report.SuccessRate())
+	}
+	if len(g.ConnectedUEs) != 2 || len(rejected) != 2 {
+		t.Fatalf("%d connected, %d rejected; want 2 and 2", len(g.ConnectedUEs), len(rejected))
+	}
+	if err := rejected[ues[0].IMSI]; !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("UE off the allow-list rejected with %v, want %v", err, ErrNotAllowed)
+	}
+	// The UE arriving at 20ms completes last, when the cell is already full
+	if last := report.Results[len(report.Results)-1]; last.IMSI != ues[2].IMSI || !errors.Is(rejected[last.IMSI], ErrCapacity) {
+		t.Errorf("last to complete: %s, rejected with %v; want %s with %v", last.IMSI, rejected[last.IMSI], ues[2].IMSI, ErrCapacity)
+	}
+}
@@ -0,0 +1,169 @@
+package ran
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// Default RRC settings: release after 10s without traffic, straight to RRC_IDLE
+const DefaultInactivityTimer = 10 * time.Second
+
+// RRCConfig controls when and how a gNodeB releases connected UEs
+type RRCConfig struct {
+	InactivityTimer time.Duration // RRC_CONNECTED with no traffic for this long -> release
+	UseInactive     bool          // release to RRC_INACTIVE instead of RRC_IDLE
+	RNA             []int         // gNodeB IDs in the RAN notification area, empty = this gNodeB only
+}
+
+// InactiveContext is the UE context an anchor gNodeB keeps while the UE is in RRC_INACTIVE
+type InactiveContext struct {
+	UE     *ue.UE
+	CellID int          // last serving cell
+	RNA    map[int]bool // gNodeBs the UE may roam between without telling the network
+	Since  time.Time
+}
+
+// SignallingStats counts control plane messages handled by a gNodeB
+type SignallingStats struct {
+	RRC    int `json:"rrc"`    // Uu, to/from UEs
+	NGAP   int `json:"ngap"`   // N2, to/from the AMF
+	XnAP   int `json:"xnap"`   // Xn, to/from other gNodeBs (context fetch)
+	Paging int `json:"paging"` // paging messages broadcast
+}
+
+// Add returns the sum of two counters
+func (s SignallingStats) Add(o SignallingStats) SignallingStats {
+	return SignallingStats{RRC: s.RRC + o.RRC, NGAP: s.NGAP + o.NGAP, XnAP: s.XnAP + o.XnAP, Paging: s.Paging + o.Paging}
+}
+
+// NoteActivity restarts the inactivity timer of a connected UE
+func (g *GNodeB) NoteActivity(u *ue.UE, now time.Time) {
+	if _, connected := g.ConnectedUEs[u.IMSI]; connected {
+		g.lastActivity[u.IMSI] = now
+	}
+}
+
+// ExpireInactivity releases every connected UE whose inactivity timer ran out and
+// returns them. Depending on RRC.UseInactive they end up in RRC_INACTIVE or RRC_IDLE.
+func (g *GNodeB) ExpireInactivity(now time.Time) []*ue.UE {
+	if g.RRC.InactivityTimer <= 0 {
+		return nil
+	}
+	var released []*ue.UE
+	for _, u := range sortedUEs(g.ConnectedUEs) {
+		imsi := u.IMSI
+		last, seen := g.lastActivity[imsi]
+		if !seen {
+			g.lastActivity[imsi] = now // connected before timers were running, start counting now
+			continue
+		}
+		if now.Sub(last) < g.RRC.InactivityTimer {
+			continue
+		}
+		if g.RRC.UseInactive {
+			g.Suspend(u, now)
+		} else {
+			g.Release(u)
+		}
+		released = append(released, u)
+	}
+	return released
+}
+
+// Suspend moves a connected UE to RRC_INACTIVE (RRCRelease with suspendConfig).
+// The UE leaves its cell but this gNodeB keeps its context as anchor.
+func (g *GNodeB) Suspend(u *ue.UE, now time.Time) error {
+	cell := g.ServingCell(u.IMSI)
+	if cell == nil {
+		return ErrNotConnected
+	}
+
+	rna := map[int]bool{g.ID: true}
+	for _, id := range g.RRC.RNA {
+		rna[id] = true
+	}
+
+	g.detach(u)
+	g.Inactive[u.IMSI] = &InactiveContext{UE: u, CellID: cell.ID, RNA: rna, Since: now}
+	u.State = ue.Inactive
+	u.GNodeBConnected = g.ID // anchor
+	u.CellConnected = -1
+	g.Signalling.RRC++
+	return nil
+}
+
+// Release moves a connected UE to RRC_IDLE and tells the AMF to drop the N2 connection
+func (g *GNodeB) Release(u *ue.UE) error {
+	if err := g.Disconnect(u); err != nil {
+		return err
+	}
+	u.State = ue.Idle
+	g.Signalling.RRC++     // RRCRelease
+	g.Signalling.NGAP += 2 // UE Context Release Command / Complete
+	return nil
+}
+
+// Resume brings an RRC_INACTIVE UE back to RRC_CONNECTED on cell c of this gNodeB.
+// If the anchor is another gNodeB the context is fetched over Xn and the path switched.
+func (g *GNodeB) Resume(u *ue.UE, anchor *GNodeB, c *Cell, now time.Time) error {
+	if err := g.relocate(u, anchor, c, now); err != nil {
+		return err
+	}
+	g.Signalling.RRC += 3 // RRCResumeRequest, RRCResume, RRCResumeComplete
+	return nil
+}
+
+// UpdateRNA handles an RRC_INACTIVE UE that camped on a gNodeB outside its notification
+// area: the context moves to this gNodeB and the UE is suspended again straight away
+func (g *GNodeB) UpdateRNA(u *ue.UE, anchor *GNodeB, c *Cell, now time.Time) error {
+	if err := g.relocate(u, anchor, c, now); err != nil {
+		return err
+	}
+	g.Signalling.RRC++ // RRCResumeRequest (cause rna-Update), answered by the release below
+	return g.Suspend(u, now)
+}
+
+// relocate takes over the inactive context from the anchor and attaches the UE to cell c
+func (g *GNodeB) relocate(u *ue.UE, anchor *GNodeB, c *Cell, now time.Time) error {
+	if g.Down {
+		return fmt.Errorf("gNodeB %d is %w", g.ID, ErrDown)
+	}
+	if _, exists := anchor.Inactive[u.IMSI]; !exists {
+		return fmt.Errorf("no inactive context for UE %s at gNodeB %d", u.IMSI, anchor.ID)
+	}
+	if c.site != g {
+		return fmt.Errorf("%s does not belong to gNodeB %d", c.Name(), g.ID)
+	}
+	if len(c.ConnectedUEs) >= c.MaxCap {
+		return fmt.Errorf("resume failed: %w on %s", ErrCapacity, c.Name())
+	}
+
+	delete(anchor.Inactive, u.IMSI)
+	if anchor != g {
+		g.Signalling.XnAP += 2 // Retrieve UE Context Request / Response
+		g.Signalling.NGAP += 2 // Path Switch Request / Acknowledge
+	}
+	g.attach(u, c)
+	g.lastActivity[u.IMSI] = now
+	return nil
+}
+
+// DropInactive discards the anchor context of an RRC_INACTIVE UE (e.g. RAN paging failed),
+// leaving it in RRC_IDLE
+func (g *GNodeB) DropInactive(u *ue.UE) {
+	if _, exists := g.Inactive[u.IMSI]; !exists {
+		return
+	}
+	delete(g.Inactive, u.IMSI)
+	u.State = ue.Idle
+	u.GNodeBConnected = -1
+	g.Signalling.NGAP += 2 // UE Context Release Request / Command
+}
+
+// InRNA reports whether gNodeB id belongs to the notification area of an inactive UE anchored here
+func (g *GNodeB) InRNA(imsi string, id int) bool {
+	ctx, exists := g.Inactive[imsi]
+	return exists && ctx.RNA[id]
+}
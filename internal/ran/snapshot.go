@@ -0,0 +1,218 @@
+package ran
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/radio"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// GNodeBState is everything needed to rebuild a gNodeB, as saved in network snapshots.
+// UEs are referred to by IMSI.
+type GNodeBState struct {
+	ID           int                  `json:"id"`
+	X            float64              `json:"x"`
+	Y            float64              `json:"y"`
+	HeightM      float64              `json:"heightM"`
+	Range        float64              `json:"range"`
+	TAC          int                  `json:"tac"`
+	Slices       []Slice              `json:"slices,omitempty"`
+	Down         bool                 `json:"down"`
+	AllowedIMSIs []string             `json:"allowedIMSIs"` // null = open access
+	RRC          RRCState             `json:"rrc"`
+	Signalling   SignallingStats      `json:"signalling"`
+	Cells        []CellState          `json:"cells"`
+	Inactive     []InactiveState      `json:"inactive,omitempty"`
+	LastActivity map[string]time.Time `json:"lastActivity,omitempty"` // inactivity timers, by IMSI
+}
+
+// RRCState is an RRCConfig with its timer in seconds
+type RRCState struct {
+	InactivitySeconds float64 `json:"inactivitySeconds"`
+	UseInactive       bool    `json:"useInactive"`
+	RNA               []int   `json:"rna,omitempty"`
+}
+
+// CellState is the saved state of one cell
+type CellState struct {
+	ID           int                  `json:"id"`
+	PCI          int                  `json:"pci"`
+	Antenna      radio.AntennaPattern `json:"antenna"`
+	Carrier      radio.Carrier        `json:"carrier"`
+	TxPowerDBm   float64              `json:"txPowerDBm"`
+	MaxCap       int                  `json:"maxCap"`
+	AllowedIMSIs []string             `json:"allowedIMSIs"` // null = the gNodeB's allow-list
+	Connected    []string             `json:"connected,omitempty"`
+}
+
+// InactiveState is a saved RRC_INACTIVE context
+type InactiveState struct {
+	IMSI   string    `json:"imsi"`
+	CellID int       `json:"cellId"`
+	RNA    []int     `json:"rna"`
+	Since  time.Time `json:"since"`
+}
+
+// State captures the gNodeB for a snapshot
+func (g *GNodeB) State() GNodeBState {
+	s := GNodeBState{
+		ID:           g.ID,
+		X:            g.X,
+		Y:            g.Y,
+		HeightM:      g.HeightM,
+		Range:        g.Range,
+		TAC:          g.TAC,
+		Slices:       g.Slices,
+		Down:         g.Down,
+		AllowedIMSIs: imsiList(g.AllowedIMSIs),
+		RRC: RRCState{
+			InactivitySeconds: g.RRC.InactivityTimer.Seconds(),
+			UseInactive:       g.RRC.UseInactive,
+			RNA:               g.RRC.RNA,
+		},
+		Signalling:   g.Signalling,
+		LastActivity: make(map[string]time.Time),
+	}
+	for _, c := range g.Cells {
+		cs := CellState{
+			ID:           c.ID,
+			PCI:          c.PCI,
+			Antenna:      c.Antenna,
+			Carrier:      c.Carrier,
+			TxPowerDBm:   c.TxPowerDBm,
+			MaxCap:       c.MaxCap,
+			AllowedIMSIs: imsiList(c.AllowedIMSIs),
+		}
+		for _, u := range sortedUEs(c.ConnectedUEs) {
+			cs.Connected = append(cs.Connected, u.IMSI)
+		}
+		s.Cells = append(s.Cells, cs)
+	}
+	for _, ctx := range g.Inactive {
+		is := InactiveState{IMSI: ctx.UE.IMSI, CellID: ctx.CellID, Since: ctx.Since}
+		for id := range ctx.RNA {
+			is.RNA = append(is.RNA, id)
+		}
+		sort.Ints(is.RNA)
+		s.Inactive = append(s.Inactive, is)
+	}
+	sort.Slice(s.Inactive, func(i, j int) bool { return s.Inactive[i].IMSI < s.Inactive[j].IMSI })
+	for imsi, t := range g.lastActivity {
+		s.LastActivity[imsi] = t
+	}
+	return s
+}
+
+// RestoreGNodeB rebuilds a gNodeB from its saved state, keeping its ID. ues holds
+// every UE of the network by IMSI; their own fields are restored separately.
+func RestoreGNodeB(s GNodeBState, ues map[string]*ue.UE) (*GNodeB, error) {
+	lookup := func(imsi string) (*ue.UE, error) {
+		u, exists := ues[imsi]
+		if !exists {
+			return nil, fmt.Errorf("gNodeB %d refers to unknown UE %s", s.ID, imsi)
+		}
+		return u, nil
+	}
+
+	reserveGNodeBID(s.ID)
+	g := &GNodeB{
+		ID:           s.ID,
+		X:            s.X,
+		Y:            s.Y,
+		HeightM:      s.HeightM,
+		Range:        s.Range,
+		TAC:          s.TAC,
+		Slices:       s.Slices,
+		Down:         s.Down,
+		AllowedIMSIs: imsiSet(s.AllowedIMSIs),
+		ConnectedUEs: make(map[string]*ue.UE),
+		Inactive:     make(map[string]*InactiveContext),
+		RRC: RRCConfig{
+			InactivityTimer: time.Duration(s.RRC.InactivitySeconds * float64(time.Second)),
+			UseInactive:     s.RRC.UseInactive,
+			RNA:             s.RRC.RNA,
+		},
+		Signalling:   s.Signalling,
+		lastActivity: make(map[string]time.Time),
+	}
+
+	for i, cs := range s.Cells {
+		if cs.ID != i {
+			return nil, fmt.Errorf("gNodeB %d: cell %d saved at position %d", s.ID, cs.ID, i)
+		}
+		c := &Cell{
+			ID:           cs.ID,
+			PCI:          cs.PCI,
+			Antenna:      cs.Antenna,
+			Carrier:      cs.Carrier,
+			TxPowerDBm:   cs.TxPowerDBm,
+			MaxCap:       cs.MaxCap,
+			AllowedIMSIs: imsiSet(cs.AllowedIMSIs),
+			ConnectedUEs: make(map[string]*ue.UE),
+			site:         g,
+		}
+		for _, imsi := range cs.Connected {
+			u, err := lookup(imsi)
+			if err != nil {
+				return nil, err
+			}
+			c.ConnectedUEs[imsi] = u
+			g.ConnectedUEs[imsi] = u
+		}
+		g.Cells = append(g.Cells, c)
+	}
+	if len(g.Cells) == 0 {
+		return nil, fmt.Errorf("gNodeB %d has no cells", s.ID)
+	}
+
+	for _, is := range s.Inactive {
+		u, err := lookup(is.IMSI)
+		if err != nil {
+			return nil, err
+		}
+		ctx := &InactiveContext{UE: u, CellID: is.CellID, RNA: make(map[int]bool), Since: is.Since}
+		for _, id := range is.RNA {
+			ctx.RNA[id] = true
+		}
+		g.Inactive[is.IMSI] = ctx
+	}
+	for imsi, t := range s.LastActivity {
+		g.lastActivity[imsi] = t
+	}
+	return g, nil
+}
+
+// reserveGNodeBID makes sure new gNodeBs get IDs above id
+func reserveGNodeBID(id int) {
+	idMu.Lock()
+	defer idMu.Unlock()
+	if id >= nextGNodeBID {
+		nextGNodeBID = id + 1
+	}
+}
+
+// imsiList turns an allow-list into a sorted slice, keeping nil (open access) as nil
+func imsiList(set map[string]bool) []string {
+	if set == nil {
+		return nil
+	}
+	list := make([]string, 0, len(set))
+	for imsi := range set {
+		list = append(list, imsi)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func imsiSet(list []string) map[string]bool {
+	if list == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(list))
+	for _, imsi := range list {
+		set[imsi] = true
+	}
+	return set
+}
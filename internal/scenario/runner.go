@@ -0,0 +1,450 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/mobility"
+	"github.com/rizpur/NetSim5G/internal/network"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/traffic"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// A move with a duration is applied in steps of this size
+const moveStep = time.Second
+
+// Result is the outcome of one assertion (an expect event, an expectError, or an
+// action that failed unexpectedly)
+type Result struct {
+	At          time.Duration
+	Line        int
+	Description string
+	Passed      bool
+	Message     string // why it failed
+}
+
+// Report collects the results of a scenario run
+type Report struct {
+	Name    string
+	File    string
+	Seed    int64 // rerun with this seed to reproduce the run exactly
+	Results []Result
+	Traffic []traffic.Stats // per session, when the scenario simulates traffic
+}
+
+// Options control how a scenario is played
+type Options struct {
+	Speed   float64   // scheduler speed factor: 0 = as fast as possible, 1 = real time
+	Seed    *int64    // overrides the scenario's seed; nil = the scenario's, or sim.DefaultSeed
+	Journal io.Writer // receives the event journal as JSON lines; nil = not written
+
+	// Setup, if set, runs once the network and the scenario's UEs exist, before any
+	// event; it may change the network and queue events of its own
+	Setup func(net *network.Network) error
+}
+
+// Failed returns the number of failed assertions
+func (r Report) Failed() int {
+	failed := 0
+	for _, res := range r.Results {
+		if !res.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Passed reports whether every assertion held
+func (r Report) Passed() bool {
+	return r.Failed() == 0
+}
+
+// Print writes a human readable pass/fail listing
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Scenario: %s (%s), seed %d\n", r.Name, r.File, r.Seed)
+	for _, res := range r.Results {
+		mark := "✓"
+		if !res.Passed {
+			mark = "✗"
+		}
+		fmt.Fprintf(w, "  %s t=%v line %d: %s", mark, res.At, res.Line, res.Description)
+		if res.Message != "" {
+			fmt.Fprintf(w, " (%s)", res.Message)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Traffic) > 0 {
+		fmt.Fprintln(w, "Traffic:")
+		for _, t := range r.Traffic {
+			fmt.Fprintf(w, "  #%d %s %s: %.2f Mbps, delay %.1f ms (max %.1f), jitter %.1f ms, loss %.1f%% (%d/%d)",
+				t.Session, t.Type, t.IMSI, t.ThroughputMbps, t.MeanDelayMs, t.MaxDelayMs, t.JitterMs, 100*t.LossRatio, t.Lost, t.Sent)
+			if t.MeanBitrateMbps > 0 {
+				fmt.Fprintf(w, ", %.1f Mbps video, %d stalls (%.1fs)", t.MeanBitrateMbps, t.Stalls, t.StallSeconds)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "%d/%d passed\n", len(r.Results)-r.Failed(), len(r.Results))
+}
+
+type runner struct {
+	net    *network.Network
+	report Report
+}
+
+// Run builds a fresh network and plays the scenario against it on the simulation
+// clock. topologyPath and subscribersPath are used unless the scenario names its own files.
+func Run(sc *config.Scenario, topologyPath, subscribersPath string, opts Options) (Report, error) {
+	if sc.Topology != "" {
+		topologyPath = sc.Resolve(sc.Topology)
+	}
+	if sc.Subscribers != "" {
+		subscribersPath = sc.Resolve(sc.Subscribers)
+	}
+	topology, err := config.Load(topologyPath)
+	if err != nil {
+		return Report{}, err
+	}
+	seed := int64(sim.DefaultSeed)
+	switch {
+	case opts.Seed != nil:
+		seed = *opts.Seed
+	case sc.Seed != nil:
+		seed = *sc.Seed
+	}
+	net, err := network.New(topology, subscribersPath, seed)
+	if err != nil {
+		return Report{}, err
+	}
+	net.Journal.SetOutput(opts.Journal)
+	moving := false
+	for _, u := range sc.UEs {
+		if u.Position != nil {
+			if u.X, u.Y, err = net.ToXY(*u.Position); err != nil {
+				return Report{}, fmt.Errorf("UE %s: %w", u.IMSI, err)
+			}
+		}
+		created, err := net.AddUE(u.IMSI, u.X, u.Y)
+		if err != nil {
+			return Report{}, err
+		}
+		if u.Mobility != nil {
+			spec := *u.Mobility
+			spec.Trace = sc.Resolve(spec.Trace)
+			if created.Mobility, err = mobility.New(spec, u.IMSI, net.Coverage(), net.RNG.Stream(sim.StreamMobility)); err != nil {
+				return Report{}, fmt.Errorf("UE %s: %w", u.IMSI, err)
+			}
+			moving = true
+		}
+	}
+
+	if opts.Setup != nil {
+		if err := opts.Setup(net); err != nil {
+			return Report{}, err
+		}
+	}
+
+	r := &runner{net: net, report: Report{Name: sc.Name, File: sc.File(), Seed: seed}}
+	end := time.Duration(0)
+	for _, e := range sc.Events {
+		if e.Position != nil {
+			if e.X, e.Y, err = net.ToXY(*e.Position); err != nil {
+				return Report{}, fmt.Errorf("%s:%d: %w", sc.File(), e.Line, err)
+			}
+		}
+		r.schedule(e)
+		end = max(end, e.At+e.Duration)
+	}
+	// Mobility and traffic tick forever, so stop them once the last event has run
+	var background []*sim.Event
+	if moving {
+		background = append(background, net.StartMobility(sc.MobilityTick))
+	}
+	if sc.Traffic {
+		background = append(background, net.StartTraffic(traffic.DefaultTick))
+	}
+	if len(background) > 0 {
+		net.Scheduler.At(net.Clock.Start().Add(end), sim.PriorityLow, "stop background", func(time.Time) {
+			for _, e := range background {
+				net.Scheduler.Cancel(e)
+			}
+		})
+	}
+	net.Scheduler.SetSpeed(opts.Speed)
+	net.Scheduler.Run()
+	if net.Traffic != nil {
+		r.report.Traffic = net.Traffic.Report(net.Clock.Now())
+	}
+	if err := net.Journal.Flush(); err != nil {
+		return r.report, fmt.Errorf("failed to write journal: %w", err)
+	}
+	return r.report, nil
+}
+
+// schedule queues a scenario event on the simulation clock. All events share one
+// priority, so those at the same time run in file order.
+func (r *runner) schedule(e config.Event) {
+	r.net.Scheduler.At(r.net.Clock.Start().Add(e.At), sim.PriorityNormal, e.Action, func(now time.Time) {
+		r.net.AMF.TickRRC(now) // let inactivity timers fire before the event
+		r.execute(e, now)
+	})
+}
+
+func (r *runner) record(e config.Event, description string, err error) {
+	res := Result{At: e.At, Line: e.Line, Description: description, Passed: true}
+	if err != nil {
+		res.Passed = false
+		res.Message = err.Error()
+	}
+	r.report.Results = append(r.report.Results, res)
+}
+
+func (r *runner) execute(e config.Event, now time.Time) {
+	if e.Action == config.ActionExpect {
+		description, err := r.check(e)
+		r.record(e, description, err)
+		return
+	}
+
+	description := e.Action
+	if e.UE != "" {
+		description += " " + e.UE
+	}
+	if e.GNodeB > 0 {
+		description += fmt.Sprintf(" gNodeB %d", e.GNodeB)
+	}
+
+	err := r.apply(e, now)
+	switch {
+	case e.ExpectError && err == nil:
+		r.record(e, description+" fails", fmt.Errorf("expected an error, action succeeded"))
+	case e.ExpectError:
+		r.record(e, description+" fails", nil)
+	case err != nil:
+		r.record(e, description, err)
+	}
+}
+
+// apply performs an action against the network
+func (r *runner) apply(e config.Event, now time.Time) error {
+	net := r.net
+	u := net.UEs[e.UE]
+
+	switch e.Action {
+	case config.ActionAttach:
+		if e.GNodeB == 0 {
+			return net.AMF.Attach(u)
+		}
+		g, err := net.Site(e.GNodeB)
+		if err != nil {
+			return err
+		}
+		if err := g.ConnectUE(u); err != nil {
+			return err
+		}
+		if err := net.AMF.RegisterUE(u.IMSI, g.ID); err != nil {
+			g.Disconnect(u)
+			return err
+		}
+		return nil
+
+	case config.ActionDetach:
+		return net.AMF.DeregisterUE(u)
+
+	case config.ActionMove:
+		if e.Duration >= moveStep {
+			// Walk there in a straight line, one step per moveStep
+			steps := int((e.Duration + moveStep - 1) / moveStep)
+			fromX, fromY := u.X, u.Y
+			for k := 1; k <= steps; k++ {
+				frac := float64(k) / float64(steps)
+				next := e
+				next.At = e.At + time.Duration(k)*e.Duration/time.Duration(steps)
+				next.Duration = 0
+				next.X = fromX + (e.X-fromX)*frac
+				next.Y = fromY + (e.Y-fromY)*frac
+				r.schedule(next)
+			}
+			return nil
+		}
+		return net.AMF.MoveUE(u, e.X, e.Y)
+
+	case config.ActionEstablishSession:
+		sessionType, err := smf.ParseSessionType(e.SessionType)
+		if err != nil {
+			return err
+		}
+		if _, registered := net.AMF.RegisteredUEs[u.IMSI]; registered {
+			if err := net.AMF.UplinkData(u, now); err != nil { // service request first if not connected
+				return err
+			}
+		}
+		_, err = net.SMF.EstablishSession(u, sessionType)
+		return err
+
+	case config.ActionTerminateSession:
+		for _, session := range net.SMF.SessionsOf(u.IMSI) {
+			if session.SessionType.String() == e.SessionType {
+				return net.SMF.TerminateSession(session.SessionID)
+			}
+		}
+		return fmt.Errorf("UE %s has no %s session", u.IMSI, e.SessionType)
+
+	case config.ActionUplinkData:
+		return net.AMF.UplinkData(u, now)
+
+	case config.ActionDownlinkData:
+		return net.AMF.DownlinkData(u, now)
+
+	case config.ActionUpdateSubscriber:
+		if err := net.UDM.UpdateSubscriber(u.IMSI, e.Status, e.MaxDataRate); err != nil {
+			return err
+		}
+		// UDM notifies the AMF: a subscription that is no longer active ends the registration
+		if _, registered := net.AMF.RegisteredUEs[u.IMSI]; registered && e.Status != "" && e.Status != "active" {
+			return net.AMF.DeregisterUE(u)
+		}
+		return nil
+
+	case config.ActionFailGNodeB:
+		g, err := net.Site(e.GNodeB)
+		if err != nil {
+			return err
+		}
+		_, err = net.AMF.FailGNodeB(g.ID)
+		return err
+
+	case config.ActionRestoreGNodeB:
+		g, err := net.Site(e.GNodeB)
+		if err != nil {
+			return err
+		}
+		_, err = net.AMF.RestoreGNodeB(g.ID)
+		return err
+	}
+	return fmt.Errorf("unknown action %q", e.Action)
+}
+
+// check evaluates an expect event and returns its description and the mismatches, if any
+func (r *runner) check(e config.Event) (string, error) {
+	net := r.net
+	ex := e.Expect
+	var wanted, problems []string
+	expect := func(name string, want, got interface{}) {
+		wanted = append(wanted, fmt.Sprintf("%s=%v", name, want))
+		if fmt.Sprint(want) != fmt.Sprint(got) {
+			problems = append(problems, fmt.Sprintf("%s is %v", name, got))
+		}
+	}
+
+	subject := ""
+	if e.GNodeB > 0 {
+		subject = fmt.Sprintf("gNodeB %d", e.GNodeB)
+		g, err := net.Site(e.GNodeB)
+		if err != nil {
+			return "expect " + subject, err
+		}
+		if ex.GNodeBDown != nil {
+			expect("down", *ex.GNodeBDown, g.Down)
+		}
+	}
+
+	if u, exists := net.UEs[e.UE]; exists {
+		subject = strings.TrimSpace(subject + " " + u.IMSI)
+		regUE, registered := net.AMF.RegisteredUEs[u.IMSI]
+
+		if ex.ServingGNodeB != nil {
+			serving := 0
+			if u.State == ue.Connected {
+				serving = net.SitePosition(u.GNodeBConnected)
+			}
+			expect("servingGNodeB", *ex.ServingGNodeB, serving)
+		}
+		if ex.ServingCell != nil {
+			expect("servingCell", *ex.ServingCell, u.CellConnected)
+		}
+		if ex.State != nil {
+			expect("state", *ex.State, u.State)
+		}
+		if ex.Registered != nil {
+			expect("registered", *ex.Registered, registered)
+		}
+		if ex.CMState != nil {
+			got := "not registered"
+			if registered {
+				got = regUE.CMState.String()
+			}
+			expect("cmState", *ex.CMState, got)
+		}
+		if ex.Sessions != nil {
+			expect("sessions", *ex.Sessions, len(net.SMF.SessionsOf(u.IMSI)))
+		}
+		if ex.MinThroughputMbps != nil {
+			wanted = append(wanted, fmt.Sprintf("throughput>=%g Mbps", *ex.MinThroughputMbps))
+			link, err := net.AMF.EstimateLink(u)
+			if err != nil {
+				problems = append(problems, err.Error())
+			} else if link.ThroughputMbps < *ex.MinThroughputMbps {
+				problems = append(problems, fmt.Sprintf("throughput is %.1f Mbps", link.ThroughputMbps))
+			}
+		}
+		if ex.LOS != nil {
+			wanted = append(wanted, fmt.Sprintf("los=%v", *ex.LOS))
+			switch path, ok := net.ServingPath(u); {
+			case net.Buildings == nil:
+				problems = append(problems, "the topology has no buildings")
+			case !ok:
+				problems = append(problems, "not served")
+			case path.LOS != *ex.LOS:
+				problems = append(problems, fmt.Sprintf("los is %v (%d buildings in the way)", path.LOS, path.Obstructions))
+			}
+		}
+		if ex.Building != nil {
+			got := ""
+			if b := net.Indoors(u); b != nil {
+				got = b.Name
+			}
+			expect("building", fmt.Sprintf("%q", *ex.Building), fmt.Sprintf("%q", got))
+		}
+		if ex.MaxPacketLoss != nil || ex.MaxDelayMs != nil || ex.MaxStalls != nil {
+			// Over all the UE's active sessions so far
+			lost, done, stalls := 0, 0, 0
+			delaySum := 0.0
+			for _, t := range net.Traffic.SessionsOf(u.IMSI, net.Clock.Now()) {
+				lost += t.Lost
+				done += t.Lost + t.Delivered
+				delaySum += t.MeanDelayMs * float64(t.Delivered)
+				stalls += t.Stalls
+			}
+			if ex.MaxPacketLoss != nil {
+				wanted = append(wanted, fmt.Sprintf("loss<=%g", *ex.MaxPacketLoss))
+				if done > 0 && float64(lost)/float64(done) > *ex.MaxPacketLoss {
+					problems = append(problems, fmt.Sprintf("loss is %.3f (%d/%d)", float64(lost)/float64(done), lost, done))
+				}
+			}
+			if ex.MaxDelayMs != nil {
+				wanted = append(wanted, fmt.Sprintf("delay<=%g ms", *ex.MaxDelayMs))
+				if delivered := done - lost; delivered > 0 && delaySum/float64(delivered) > *ex.MaxDelayMs {
+					problems = append(problems, fmt.Sprintf("delay is %.1f ms", delaySum/float64(delivered)))
+				}
+			}
+			if ex.MaxStalls != nil {
+				wanted = append(wanted, fmt.Sprintf("stalls<=%d", *ex.MaxStalls))
+				if stalls > *ex.MaxStalls {
+					problems = append(problems, fmt.Sprintf("%d stalls", stalls))
+				}
+			}
+		}
+	}
+
+	description := fmt.Sprintf("expect %s: %s", subject, strings.Join(wanted, ", "))
+	if len(problems) > 0 {
+		return description, fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return description, nil
+}
@@ -0,0 +1,50 @@
+package scenario
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/rizpur/NetSim5G/internal/config"
+)
+
+// run plays a scenario of configs/scenarios with the given seed and returns its report
+// and journal
+func run(t *testing.T, file string, seed int64) (Report, string) {
+	t.Helper()
+	sc, err := config.LoadScenario("../configs/scenarios/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var journal bytes.Buffer
+	report, err := Run(sc, "", "", Options{Seed: &seed, Journal: &journal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report, journal.String()
+}
+
+func TestRunReproducible(t *testing.T) {
+	// Random mobility models, and traffic with random arrivals and losses
+	for _, file := range []string{"mobility.yaml", "traffic.yaml"} {
+		t.Run(file, func(t *testing.T) {
+			// Step 1: The same seed twice gives the same run, event for event
+			first, journal := run(t, file, 42)
+			again, journalAgain := run(t, file, 42)
+			if journal == "" {
+				t.Fatal("nothing journaled")
+			}
+			if journalAgain != journal {
+				t.Error("same seed, different journals")
+			}
+			if !reflect.DeepEqual(again, first) {
+				t.Errorf("same seed, different reports:\n%+v\n%+v", first, again)
+			}
+
+			// Step 2: The seed is what drives the randomness
+			if _, other := run(t, file, 43); other == journal {
+				t.Error("a different seed gave the same journal")
+			}
+		})
+	}
+}
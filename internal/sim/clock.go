@@ -0,0 +1,58 @@
+package sim
+
+import (
+	"sync"
+	"time"
+)
+
+// Epoch is simulated time zero; only differences matter
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is the virtual time every component reads instead of time.Now().
+// Only the Scheduler moves it forward.
+type Clock struct {
+	mu    sync.Mutex
+	start time.Time
+	now   time.Time
+}
+
+func NewClock(start time.Time) *Clock {
+	return &Clock{start: start, now: start}
+}
+
+// Now returns the current simulated time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Elapsed returns how much simulated time has passed since the clock was created
+func (c *Clock) Elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now.Sub(c.start)
+}
+
+// Start returns simulated time zero of this clock
+func (c *Clock) Start() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.start
+}
+
+// Reset moves the clock to any point, also backwards, e.g. when restoring a snapshot
+func (c *Clock) Reset(start, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.start, c.now = start, now
+}
+
+// advance moves the clock to t; time never goes backwards
+func (c *Clock) advance(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.now = t
+	}
+}
@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Named random streams. Each component draws from its own stream, so adding draws
+// in one (say, a new mobility model) does not change what another (RACH) sees.
+const (
+	StreamRACH      = "rach"
+	StreamMobility  = "mobility"
+	StreamTraffic   = "traffic"
+	StreamShadowing = "shadowing"
+	StreamPlacement = "placement"
+)
+
+// DefaultSeed is used when no seed is given
+const DefaultSeed = 1
+
+// RNG is the central source of randomness: one seed, independent named streams.
+// Two runs with the same seed and the same inputs draw exactly the same numbers.
+type RNG struct {
+	seed    int64
+	mu      sync.Mutex
+	streams map[string]*countingSource
+}
+
+func NewRNG(seed int64) *RNG {
+	return &RNG{seed: seed, streams: make(map[string]*countingSource)}
+}
+
+// Seed returns the seed every stream is derived from
+func (r *RNG) Seed() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seed
+}
+
+// Restore puts every stream back where a saved run left it: same seed, same number
+// of draws. Generators already handed out by Stream follow along.
+func (r *RNG) Restore(seed int64, draws map[string]uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seed = seed
+	for name := range draws {
+		if _, exists := r.streams[name]; !exists {
+			r.streams[name] = newCountingSource(streamSeed(seed, name))
+		}
+	}
+	for name, src := range r.streams {
+		src.Seed(streamSeed(seed, name))
+		for i := uint64(0); i < draws[name]; i++ {
+			src.Uint64()
+		}
+	}
+}
+
+// Stream returns the named stream, creating it on first use. The same name always
+// returns the same generator.
+func (r *RNG) Stream(name string) *rand.Rand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	src, exists := r.streams[name]
+	if !exists {
+		src = newCountingSource(streamSeed(r.seed, name))
+		r.streams[name] = src
+	}
+	return rand.New(src)
+}
+
+// Draws returns how many values each stream has produced so far, by stream name
+func (r *RNG) Draws() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	draws := make(map[string]uint64)
+	for name, src := range r.streams {
+		draws[name] = src.draws
+	}
+	return draws
+}
+
+// Streams returns the names of the streams in use, sorted
+func (r *RNG) Streams() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.streams))
+	for name := range r.streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// streamSeed derives a stream's seed from the run seed and the stream name
+// (FNV-1a of the name, mixed with the seed by a splitmix64 finaliser)
+func streamSeed(seed int64, name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	z := h.Sum64() ^ uint64(seed)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// countingSource is a math/rand source that remembers how many values it produced,
+// so a stream can be put back in the same position by seeding and skipping
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
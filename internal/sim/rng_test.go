@@ -0,0 +1,73 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRNGStreams(t *testing.T) {
+	a, b := NewRNG(7), NewRNG(7)
+	// Drawing from one stream does not move another
+	a.Stream(StreamTraffic).Int63()
+	a.Stream(StreamTraffic).Int63()
+	if got, want := a.Stream(StreamRACH).Int63(), b.Stream(StreamRACH).Int63(); got != want {
+		t.Errorf("RACH stream gave %d after traffic draws, want %d", got, want)
+	}
+	if a.Stream(StreamMobility).Int63() == a.Stream(StreamShadowing).Int63() {
+		t.Error("two streams of one seed gave the same first draw")
+	}
+	if NewRNG(8).Stream(StreamRACH).Int63() == NewRNG(7).Stream(StreamRACH).Int63() {
+		t.Error("two seeds gave the same first draw")
+	}
+	want := []string{StreamMobility, StreamRACH, StreamShadowing, StreamTraffic}
+	if got := a.Streams(); !reflect.DeepEqual(got, want) {
+		t.Errorf("streams %v, want %v", got, want)
+	}
+}
+
+func TestRNGRestore(t *testing.T) {
+	// Step 1: Draw a while, note the positions and what comes next
+	r := NewRNG(3)
+	rach, mobility := r.Stream(StreamRACH), r.Stream(StreamMobility)
+	for i := 0; i < 5; i++ {
+		rach.Intn(10)
+		mobility.Float64()
+	}
+	mobility.NormFloat64() // draws a varying number of values
+	r.Stream(StreamTraffic).Uint64()
+	draws := r.Draws()
+	if draws[StreamRACH] != 5 || draws[StreamTraffic] != 1 || draws[StreamMobility] < 6 {
+		t.Errorf("draws %v, want 5 RACH, 1 traffic and at least 6 mobility", draws)
+	}
+	next := map[string]int64{}
+	for _, name := range r.Streams() {
+		next[name] = r.Stream(name).Int63()
+	}
+
+	// Step 2: Draw on, then put the streams back: each resumes at the same value,
+	// also through generators handed out before
+	for i := 0; i < 100; i++ {
+		rach.Int63()
+		mobility.Int63()
+	}
+	r.Restore(3, draws)
+	if got := rach.Int63(); got != next[StreamRACH] {
+		t.Errorf("RACH stream resumed with %d, want %d", got, next[StreamRACH])
+	}
+	if got := mobility.Int63(); got != next[StreamMobility] {
+		t.Errorf("mobility stream resumed with %d, want %d", got, next[StreamMobility])
+	}
+
+	// Step 3: A fresh RNG restored to the same draws agrees too, streams it never
+	// used included
+	fresh := NewRNG(99)
+	fresh.Restore(3, draws)
+	if fresh.Seed() != 3 {
+		t.Errorf("seed %d after restore, want 3", fresh.Seed())
+	}
+	for name, want := range next {
+		if got := fresh.Stream(name).Int63(); got != want {
+			t.Errorf("%s stream of a fresh RNG resumed with %d, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package sim
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Event priorities: at the same simulated time, lower values run first
+const (
+	PriorityHigh   = 0  // timers that must fire before anything else at that instant
+	PriorityNormal = 10 // procedures: attach, move, sessions, outages
+	PriorityLow    = 20 // observers: assertions, reports
+)
+
+// In paced mode the clock is never more than this far behind the wall clock while idle
+const maxIdleWait = 100 * time.Millisecond
+
+// Event is an action scheduled at a simulated time
+type Event struct {
+	At       time.Time
+	Priority int
+	Name     string
+	Every    time.Duration // > 0: run again this long after each run, until cancelled
+	Action   func(now time.Time)
+
+	seq   uint64 // scheduling order, breaks ties between equal time and priority
+	index int    // position in the heap, -1 when not queued
+}
+
+// eventQueue is a min-heap ordered by time, priority, then scheduling order
+type eventQueue []*Event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if !a.At.Equal(b.At) {
+		return a.At.Before(b.At)
+	}
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	return a.seq < b.seq
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *eventQueue) Push(x interface{}) {
+	e := x.(*Event)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Scheduler is a discrete-event kernel: it runs events in time order and moves the
+// clock from one event to the next. With speed 0 it jumps straight to the next event
+// (as fast as possible); with speed N it waits so that simulated time runs N times
+// faster than the wall clock (1 = real time). A paused scheduler runs nothing on its
+// own; Step and RunFor still work and run immediately, for single-stepping.
+type Scheduler struct {
+	Clock  *Clock
+	Locker sync.Locker // held while an event runs, nil = none; shared with whoever else touches the model
+
+	mu         sync.Mutex
+	processed  int // events run so far
+	queue      eventQueue
+	seq        uint64
+	speed      float64
+	paused     bool
+	wallAnchor time.Time // wall time at which the clock read simAnchor, for pacing
+	simAnchor  time.Time
+	wake       chan struct{} // interrupts a paced wait when the queue or speed changes
+}
+
+func NewScheduler(clock *Clock) *Scheduler {
+	return &Scheduler{
+		Clock: clock,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Speed returns the speed factor, 0 = as fast as possible
+func (s *Scheduler) Speed() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed
+}
+
+// SetSpeed changes the speed factor: 0 = as fast as possible, 1 = real time, N = N× real time
+func (s *Scheduler) SetSpeed(speed float64) {
+	if speed < 0 {
+		speed = 0
+	}
+	s.mu.Lock()
+	s.speed = speed
+	s.anchor()
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Rebase moves the clock to a restored point in time. Queued events keep their
+// distance from now, so periodic timers carry on as before.
+func (s *Scheduler) Rebase(start, now time.Time) {
+	s.mu.Lock()
+	shift := now.Sub(s.Clock.Now())
+	for _, e := range s.queue {
+		e.At = e.At.Add(shift)
+	}
+	s.Clock.Reset(start, now)
+	s.anchor()
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Pause freezes the clock: Serve stops running events until Resume
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Resume lets Serve run events again, pacing from the current simulated time
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.anchor()
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Paused reports whether the scheduler is paused
+func (s *Scheduler) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// At schedules an action at simulated time t. Events in the past run at the current time.
+func (s *Scheduler) At(t time.Time, priority int, name string, action func(now time.Time)) *Event {
+	e := &Event{At: t, Priority: priority, Name: name, Action: action}
+	s.Schedule(e)
+	return e
+}
+
+// After schedules an action d after the current simulated time
+func (s *Scheduler) After(d time.Duration, priority int, name string, action func(now time.Time)) *Event {
+	return s.At(s.Clock.Now().Add(d), priority, name, action)
+}
+
+// Every schedules an action every interval, the first run one interval from now
+func (s *Scheduler) Every(interval time.Duration, priority int, name string, action func(now time.Time)) *Event {
+	e := &Event{At: s.Clock.Now().Add(interval), Priority: priority, Name: name, Every: interval, Action: action}
+	s.Schedule(e)
+	return e
+}
+
+// Schedule queues a prepared event
+func (s *Scheduler) Schedule(e *Event) {
+	s.mu.Lock()
+	if now := s.Clock.Now(); e.At.Before(now) {
+		e.At = now
+	}
+	s.seq++
+	e.seq = s.seq
+	heap.Push(&s.queue, e)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Cancel removes a queued event (and stops a periodic one); false if it was not queued
+func (s *Scheduler) Cancel(e *Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Every = 0
+	if e.index < 0 || e.index >= len(s.queue) || s.queue[e.index] != e {
+		return false
+	}
+	heap.Remove(&s.queue, e.index)
+	return true
+}
+
+// Processed returns the number of events run so far
+func (s *Scheduler) Processed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.processed
+}
+
+// Pending returns the number of queued events
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Next returns the time of the next queued event
+func (s *Scheduler) Next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].At, true
+}
+
+// Step runs the next event, false if the queue is empty
+func (s *Scheduler) Step() bool {
+	return s.run(time.Time{}, false, false, true, nil) > 0
+}
+
+// Run runs events until the queue is empty and returns how many ran
+func (s *Scheduler) Run() int {
+	return s.run(time.Time{}, false, false, false, nil)
+}
+
+// RunUntil runs every event up to and including t, then sets the clock to t
+func (s *Scheduler) RunUntil(t time.Time) int {
+	return s.run(t, true, false, false, nil)
+}
+
+// RunFor runs d worth of simulated time
+func (s *Scheduler) RunFor(d time.Duration) int {
+	return s.RunUntil(s.Clock.Now().Add(d))
+}
+
+// Serve keeps running events, waiting for new ones when the queue is empty, until
+// stop is closed. Use it when the simulation runs alongside the API server.
+func (s *Scheduler) Serve(stop <-chan struct{}) {
+	s.run(time.Time{}, false, true, false, stop)
+}
+
+// run is the event loop behind Step, Run, RunUntil and Serve. bounded stops at until,
+// keepAlive waits for new events instead of returning, once returns after one event.
+func (s *Scheduler) run(until time.Time, bounded, keepAlive, once bool, stop <-chan struct{}) int {
+	ran := 0
+	s.mu.Lock()
+	s.anchor()
+	s.mu.Unlock()
+
+	for {
+		s.mu.Lock()
+		var next *Event
+		if len(s.queue) > 0 && (!bounded || !s.queue[0].At.After(until)) {
+			next = s.queue[0]
+		}
+		if next == nil && !bounded && !keepAlive {
+			s.mu.Unlock()
+			return ran
+		}
+		if s.paused && keepAlive {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+			case <-stop:
+				return ran
+			}
+			continue
+		}
+
+		// Step 1: In paced mode, let the wall clock catch up with the next point in time
+		if wait := s.pace(next, until, bounded); wait > 0 || (next == nil && !bounded) {
+			if s.speed == 0 || wait > maxIdleWait {
+				wait = maxIdleWait
+			}
+			s.mu.Unlock()
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-s.wake:
+			case <-stop:
+				timer.Stop()
+				return ran
+			}
+			timer.Stop()
+			continue
+		}
+
+		// Step 2: Nothing left before the bound, the clock just moves there
+		if next == nil {
+			s.Clock.advance(until)
+			s.mu.Unlock()
+			return ran
+		}
+
+		// Step 3: Take the model lock first (same order as everyone else: model, then
+		// queue), so readers never see the clock at an event that has not run yet
+		if s.Locker != nil {
+			s.mu.Unlock()
+			s.Locker.Lock()
+			s.mu.Lock()
+			if len(s.queue) == 0 || s.queue[0] != next {
+				s.mu.Unlock()
+				s.Locker.Unlock()
+				continue // the queue changed meanwhile, look again
+			}
+		}
+		heap.Pop(&s.queue)
+		now := next.At
+		s.Clock.advance(now)
+		s.processed++
+		if next.Every > 0 {
+			next.At = next.At.Add(next.Every)
+			s.seq++
+			next.seq = s.seq
+			heap.Push(&s.queue, next)
+		}
+		s.mu.Unlock()
+
+		// Run the event outside the queue lock so it can schedule more
+		next.Action(now)
+		if s.Locker != nil {
+			s.Locker.Unlock()
+		}
+		ran++
+		if once {
+			return ran
+		}
+	}
+}
+
+// pace moves the clock along with the wall clock and returns how long to wait before
+// the next event (or the bound) is due. Always 0 when running as fast as possible,
+// or when paused (manual steps do not wait). Callers hold s.mu.
+func (s *Scheduler) pace(next *Event, until time.Time, bounded bool) time.Duration {
+	if s.speed == 0 || s.paused {
+		return 0
+	}
+	position := s.simAnchor.Add(time.Duration(float64(time.Since(s.wallAnchor)) * s.speed))
+	target := until
+	if next != nil {
+		target = next.At
+	} else if !bounded {
+		s.Clock.advance(position) // idle: simulated time keeps flowing
+		return 0
+	}
+	if !position.Before(target) {
+		return 0
+	}
+	s.Clock.advance(position)
+	return time.Duration(float64(target.Sub(position)) / s.speed)
+}
+
+// anchor restarts pacing from the current clock; callers hold s.mu
+func (s *Scheduler) anchor() {
+	s.wallAnchor = time.Now() // the only wall clock read in the simulator
+	s.simAnchor = s.Clock.Now()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
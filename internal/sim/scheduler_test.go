@@ -0,0 +1,256 @@
+package sim
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// record returns an action that appends name to the log
+func record(log *[]string, name string) func(time.Time) {
+	return func(time.Time) { *log = append(*log, name) }
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	s := NewScheduler(NewClock(Epoch))
+	var log []string
+	at := Epoch.Add(time.Second)
+	s.At(at.Add(time.Second), PriorityHigh, "later", record(&log, "later"))
+	s.At(at, PriorityLow, "low", record(&log, "low"))
+	s.At(at, PriorityNormal, "normal 1", record(&log, "normal 1"))
+	s.At(at, PriorityHigh, "high", record(&log, "high"))
+	s.At(at, PriorityNormal, "normal 2", record(&log, "normal 2"))
+	s.At(at, PriorityNormal, "normal 3", record(&log, "normal 3"))
+
+	if n := s.Run(); n != 6 {
+		t.Errorf("ran %d events, want 6", n)
+	}
+	// Time first, then priority, then the order they were scheduled in
+	want := []string{"high", "normal 1", "normal 2", "normal 3", "low", "later"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("ran %v, want %v", log, want)
+	}
+	if !s.Clock.Now().Equal(at.Add(time.Second)) {
+		t.Errorf("clock at %v, want the last event's time", s.Clock.Now())
+	}
+	if s.Processed() != 6 || s.Pending() != 0 {
+		t.Errorf("processed %d, pending %d; want 6 and 0", s.Processed(), s.Pending())
+	}
+}
+
+func TestSchedulerPastAndNested(t *testing.T) {
+	s := NewScheduler(NewClock(Epoch))
+	s.RunFor(time.Minute)
+	var log []string
+	var times []time.Time
+	s.At(Epoch, PriorityNormal, "past", func(now time.Time) {
+		times = append(times, now)
+		log = append(log, "past")
+		// Scheduled for now from inside an event: runs after what is already queued
+		// at the same priority
+		s.After(0, PriorityNormal, "nested", record(&log, "nested"))
+	})
+	s.After(0, PriorityNormal, "now", record(&log, "now"))
+	s.Run()
+
+	if want := []string{"past", "now", "nested"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("ran %v, want %v", log, want)
+	}
+	if want := Epoch.Add(time.Minute); len(times) != 1 || !times[0].Equal(want) {
+		t.Errorf("past event ran at %v, want now (%v)", times, want)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(NewClock(Epoch))
+	var log []string
+	keep := s.After(time.Second, PriorityNormal, "keep", record(&log, "keep"))
+	drop := s.After(time.Second, PriorityNormal, "drop", record(&log, "drop"))
+	if !s.Cancel(drop) {
+		t.Error("Cancel of a queued event returned false")
+	}
+	if s.Cancel(drop) {
+		t.Error("Cancel of a cancelled event returned true")
+	}
+	s.Run()
+	if s.Cancel(keep) {
+		t.Error("Cancel of an event that ran returned true")
+	}
+	if want := []string{"keep"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("ran %v, want %v", log, want)
+	}
+
+	// A periodic event can stop itself
+	runs := 0
+	var tick *Event
+	tick = s.Every(time.Second, PriorityNormal, "tick", func(time.Time) {
+		if runs++; runs == 3 {
+			s.Cancel(tick)
+		}
+	})
+	s.RunFor(10 * time.Second)
+	if runs != 3 || s.Pending() != 0 {
+		t.Errorf("periodic event ran %d times, %d pending; want 3 and 0", runs, s.Pending())
+	}
+}
+
+func TestSchedulerEvery(t *testing.T) {
+	s := NewScheduler(NewClock(Epoch))
+	var times []time.Duration
+	s.Every(10*time.Second, PriorityNormal, "tick", func(now time.Time) {
+		times = append(times, now.Sub(Epoch))
+	})
+	s.RunFor(35 * time.Second)
+
+	if want := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}; !reflect.DeepEqual(times, want) {
+		t.Errorf("ran at %v, want %v", times, want)
+	}
+	if next, ok := s.Next(); !ok || !next.Equal(Epoch.Add(40*time.Second)) {
+		t.Errorf("next run at %v, want %v", next, Epoch.Add(40*time.Second))
+	}
+}
+
+func TestSchedulerBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		run       func(s *Scheduler) int
+		ran       int
+		clockAt   time.Duration
+		remaining int
+	}{
+		{"RunUntil includes its bound", func(s *Scheduler) int { return s.RunUntil(Epoch.Add(2 * time.Second)) }, 2, 2 * time.Second, 1},
+		{"RunUntil moves the clock without events", func(s *Scheduler) int { return s.RunUntil(Epoch.Add(1500 * time.Millisecond)) }, 1, 1500 * time.Millisecond, 2},
+		{"RunUntil before every event", func(s *Scheduler) int { return s.RunUntil(Epoch.Add(time.Millisecond)) }, 0, time.Millisecond, 3},
+		{"RunFor", func(s *Scheduler) int { return s.RunFor(3 * time.Second) }, 3, 3 * time.Second, 0},
+		{"Step runs one", func(s *Scheduler) int {
+			if s.Step() {
+				return 1
+			}
+			return 0
+		}, 1, time.Second, 2},
+		{"Run empties the queue", func(s *Scheduler) int { return s.Run() }, 3, 3 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler(NewClock(Epoch))
+			for i := 1; i <= 3; i++ {
+				s.At(Epoch.Add(time.Duration(i)*time.Second), PriorityNormal, fmt.Sprint(i), func(time.Time) {})
+			}
+			if ran := tt.run(s); ran != tt.ran {
+				t.Errorf("ran %d events, want %d", ran, tt.ran)
+			}
+			if got := s.Clock.Elapsed(); got != tt.clockAt {
+				t.Errorf("clock at %v, want %v", got, tt.clockAt)
+			}
+			if s.Pending() != tt.remaining {
+				t.Errorf("%d events pending, want %d", s.Pending(), tt.remaining)
+			}
+		})
+	}
+
+	s := NewScheduler(NewClock(Epoch))
+	if s.Step() {
+		t.Error("Step on an empty queue returned true")
+	}
+}
+
+func TestSchedulerPace(t *testing.T) {
+	// As fast as possible: an hour of simulated time takes no wall time to speak of
+	s := NewScheduler(NewClock(Epoch))
+	s.Every(time.Minute, PriorityNormal, "tick", func(time.Time) {})
+	start := time.Now()
+	s.RunFor(time.Hour)
+	if wall := time.Since(start); wall > time.Second {
+		t.Errorf("an unpaced hour took %v", wall)
+	}
+
+	// At 10x, 500ms of simulated time take 50ms
+	s = NewScheduler(NewClock(Epoch))
+	s.SetSpeed(10)
+	s.Every(100*time.Millisecond, PriorityNormal, "tick", func(time.Time) {})
+	start = time.Now()
+	s.RunFor(500 * time.Millisecond)
+	if wall := time.Since(start); wall < 45*time.Millisecond || wall > 2*time.Second {
+		t.Errorf("500ms at 10x took %v, want about 50ms", wall)
+	}
+	if s.Speed() != 10 {
+		t.Errorf("speed %g, want 10", s.Speed())
+	}
+
+	// Paused, manual steps do not wait for the wall clock
+	s = NewScheduler(NewClock(Epoch))
+	s.SetSpeed(1)
+	s.Pause()
+	s.After(time.Hour, PriorityNormal, "far", func(time.Time) {})
+	start = time.Now()
+	if !s.Step() || time.Since(start) > time.Second {
+		t.Errorf("a paused step waited %v", time.Since(start))
+	}
+	if !s.Paused() {
+		t.Error("not paused after Pause")
+	}
+	s.Resume()
+	if s.Paused() {
+		t.Error("paused after Resume")
+	}
+	s.SetSpeed(-1)
+	if s.Speed() != 0 {
+		t.Errorf("negative speed gave %g, want 0", s.Speed())
+	}
+}
+
+func TestSchedulerRebase(t *testing.T) {
+	s := NewScheduler(NewClock(Epoch))
+	s.RunFor(time.Minute)
+	s.After(10*time.Second, PriorityNormal, "timer", func(time.Time) {})
+
+	// Forwards and backwards, the timer stays 10s away
+	for _, now := range []time.Time{Epoch.Add(time.Hour), Epoch.Add(time.Second)} {
+		start := Epoch.Add(-time.Hour)
+		s.Rebase(start, now)
+		if !s.Clock.Now().Equal(now) || !s.Clock.Start().Equal(start) {
+			t.Errorf("clock at %v from %v, want %v from %v", s.Clock.Now(), s.Clock.Start(), now, start)
+		}
+		if next, _ := s.Next(); !next.Equal(now.Add(10 * time.Second)) {
+			t.Errorf("timer at %v after rebasing to %v, want 10s later", next, now)
+		}
+	}
+}
+
+func TestSchedulerServe(t *testing.T) {
+	s := NewScheduler(NewClock(Epoch))
+	var model sync.Mutex
+	s.Locker = &model
+	stop := make(chan struct{})
+	served := make(chan struct{})
+	go func() {
+		s.Serve(stop)
+		close(served)
+	}()
+
+	// Events queued while serving run, holding the model lock
+	ran := make(chan bool)
+	s.After(time.Second, PriorityNormal, "event", func(time.Time) {
+		locked := !model.TryLock()
+		if !locked {
+			model.Unlock()
+		}
+		ran <- locked
+	})
+	select {
+	case locked := <-ran:
+		if !locked {
+			t.Error("event ran without the model lock")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event did not run")
+	}
+	close(stop)
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after stop")
+	}
+}
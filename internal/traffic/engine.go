@@ -0,0 +1,336 @@
+package traffic
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/core/amf"
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/metrics"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// DefaultTick is how often the engine generates and sends traffic: one voice frame
+const DefaultTick = 20 * time.Millisecond
+
+// pagingRetry is how long a UE with queued data waits before it is paged again
+const pagingRetry = time.Second
+
+// MaxFinished is how many ended sessions an engine keeps for Report; older ones only
+// count in the metrics
+const MaxFinished = 10000
+
+// Downlink delay buckets, in seconds
+var delayBuckets = []float64{0.005, 0.01, 0.02, 0.05, 0.1, 0.15, 0.3, 1, 3, 10}
+
+// Stats are what one session got out of the network. Delay is per packet for VoIP and
+// IoT, and the download time of the whole segment or page for video and web.
+type Stats struct {
+	Session         int     `json:"session"`
+	IMSI            string  `json:"imsi"`
+	Type            string  `json:"type"`
+	Model           string  `json:"model"`
+	Active          bool    `json:"active"`
+	Seconds         float64 `json:"seconds"` // how long the session carried traffic
+	OfferedBytes    int64   `json:"offeredBytes"`
+	DeliveredBytes  int64   `json:"deliveredBytes"`
+	ThroughputMbps  float64 `json:"throughputMbps"`
+	Sent            int     `json:"sent"`      // packets or objects generated
+	Delivered       int     `json:"delivered"` // fully received
+	Lost            int     `json:"lost"`      // radio errors and deadline misses
+	LossRatio       float64 `json:"lossRatio"`
+	MeanDelayMs     float64 `json:"meanDelayMs"`
+	MaxDelayMs      float64 `json:"maxDelayMs"`
+	JitterMs        float64 `json:"jitterMs"` // RFC 3550 interarrival jitter
+	Stalls          int     `json:"stalls,omitempty"`
+	StallSeconds    float64 `json:"stallSeconds,omitempty"`
+	MeanBitrateMbps float64 `json:"meanBitrateMbps,omitempty"` // video: average of the fetched segments
+
+	StallTime   time.Duration `json:"-"`
+	PlayTime    time.Duration `json:"-"`
+	start       time.Time
+	delaySum    float64 // ms
+	jitter      float64 // ms
+	lastTransit float64 // ms, of the previous delivered chunk; -1 = none yet
+	bitrateTime float64 // Mbps x seconds spent fetching
+	fetchTime   float64 // seconds spent fetching
+}
+
+// Flow is the traffic of one session: its source and the data queued for it at the UPF
+type Flow struct {
+	Session *smf.PDUSession
+	Source  Source
+	Queue   []*Chunk
+	Stats   Stats
+}
+
+// Engine drives the traffic of every session. Sessions are picked up and let go by
+// watching the SMF, so nothing else needs to know about traffic.
+type Engine struct {
+	AMF      *amf.AMF
+	SMF      *smf.SMF
+	Rand     *rand.Rand
+	Flows    map[int]*Flow // active, by session ID
+	Finished []Stats       // the latest sessions that have ended, in order, at most MaxFinished
+	paged    map[string]time.Time
+
+	delivered *metrics.Counter
+	lost      *metrics.Counter
+	stalls    *metrics.Counter
+	delay     *metrics.Histogram
+}
+
+// NewEngine creates an engine drawing from rng and registers its metrics in registry
+func NewEngine(a *amf.AMF, s *smf.SMF, rng *rand.Rand, registry *metrics.Registry) *Engine {
+	return &Engine{
+		AMF:       a,
+		SMF:       s,
+		Rand:      rng,
+		Flows:     make(map[int]*Flow),
+		paged:     make(map[string]time.Time),
+		delivered: registry.Counter("netsim_traffic_delivered_bytes_total", "Downlink bytes delivered to UEs by session type.", "type"),
+		lost:      registry.Counter("netsim_traffic_lost_packets_total", "Downlink packets lost to radio errors or deadlines by session type.", "type"),
+		stalls:    registry.Counter("netsim_video_stalls_total", "Video playback stalls."),
+		delay:     registry.Histogram("netsim_traffic_delay_seconds", "Downlink packet delay, or object download time, by session type.", delayBuckets, "type"),
+	}
+}
+
+// Tick runs the traffic of the interval (now-dt, now]: sources generate, the UPF
+// queues, paging wakes UEs with pending data and each UE's link carries what it can.
+// Hold an Update (scheduler events already do).
+func (e *Engine) Tick(now time.Time, dt time.Duration) {
+	// Step 1: Follow the SMF's sessions
+	e.sync(now)
+	ids := make([]int, 0, len(e.Flows))
+	for id := range e.Flows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	// Step 2: Sources generate; the data waits at the UPF
+	byUE := make(map[string][]*Flow)
+	var imsis []string
+	for _, id := range ids {
+		f := e.Flows[id]
+		chunks := f.Source.Generate(now, dt)
+		for _, c := range chunks {
+			f.Stats.Sent++
+			f.Stats.OfferedBytes += int64(c.Bytes)
+		}
+		f.Queue = append(f.Queue, chunks...)
+
+		imsi := f.Session.UE.IMSI
+		if _, seen := byUE[imsi]; !seen {
+			imsis = append(imsis, imsi)
+		}
+		byUE[imsi] = append(byUE[imsi], f)
+	}
+	sort.Strings(imsis)
+
+	for _, imsi := range imsis {
+		flows := byUE[imsi]
+		u := flows[0].Session.UE
+
+		// Step 3: Pending data keeps a connected UE awake and pages an idle one
+		if pending(flows) {
+			if u.State == ue.Connected || now.Sub(e.paged[imsi]) >= pagingRetry {
+				e.paged[imsi] = now
+				e.AMF.DownlinkData(u, now) // an unreachable UE keeps its data queued
+			}
+		}
+
+		// Step 4: The serving cell sends, strict priority between the UE's sessions,
+		// each session at most at its QoS bit rate
+		if u.State == ue.Connected {
+			if link, err := e.AMF.EstimateLink(u); err == nil {
+				sort.SliceStable(flows, func(i, j int) bool { return flows[i].Session.QoS.Priority < flows[j].Session.QoS.Priority })
+				used := 0.0
+				for _, f := range flows {
+					rate := math.Min(float64(f.Session.QoS.MaxBitRate), link.ThroughputMbps-used)
+					if rate <= 0 {
+						break
+					}
+					sent := e.serve(f, now.Add(-dt), now, rate, link.BLER)
+					used += float64(sent) * 8 / 1e6 / dt.Seconds()
+				}
+			}
+		}
+
+		// Step 5: Drop what missed its deadline, and let the applications see time pass
+		for _, f := range flows {
+			e.expire(f, now)
+			stalls := f.Stats.Stalls
+			f.Source.Observe(now, dt, &f.Stats)
+			if f.Stats.Stalls > stalls {
+				e.stalls.Add(float64(f.Stats.Stalls - stalls))
+			}
+		}
+	}
+}
+
+// sync creates flows for new sessions and finishes those of ended ones
+func (e *Engine) sync(now time.Time) {
+	for id, f := range e.Flows {
+		if e.SMF.Sessions[id] != f.Session {
+			e.finish(f, now)
+		}
+	}
+	var ids []int
+	for id := range e.SMF.Sessions {
+		if _, exists := e.Flows[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids) // sources draw from the shared stream in a fixed order
+	for _, id := range ids {
+		session := e.SMF.Sessions[id]
+		e.Flows[id] = &Flow{
+			Session: session,
+			Source:  NewSource(session.SessionType, float64(session.QoS.MaxBitRate), now, e.Rand),
+			Stats: Stats{
+				Session:     id,
+				IMSI:        session.UE.IMSI,
+				Type:        session.SessionType.String(),
+				start:       now,
+				lastTransit: -1,
+			},
+		}
+		e.Flows[id].Stats.Model = e.Flows[id].Source.Name()
+	}
+}
+
+// finish keeps the final stats of an ended session; the UPF discards its data
+func (e *Engine) finish(f *Flow, now time.Time) {
+	delete(e.Flows, f.Stats.Session)
+	e.Finished = append(e.Finished, f.Stats.summary(now, false))
+	if len(e.Finished) > MaxFinished {
+		e.Finished = e.Finished[len(e.Finished)-MaxFinished:]
+	}
+}
+
+// serve sends f's queue at rate Mbps during [from, to) and returns the bytes sent
+func (e *Engine) serve(f *Flow, from, to time.Time, rate, bler float64) int {
+	sent := 0
+	cursor := from
+	for len(f.Queue) > 0 {
+		c := f.Queue[0]
+		if c.Arrival.After(cursor) {
+			cursor = c.Arrival
+		}
+		if !cursor.Before(to) {
+			break
+		}
+		need := time.Duration(float64(c.Remaining) * 8 / (rate * 1e6) * float64(time.Second))
+		if available := to.Sub(cursor); need > available {
+			part := int(available.Seconds() * rate * 1e6 / 8)
+			c.Remaining -= part
+			sent += part
+			break
+		}
+		cursor = cursor.Add(need)
+		sent += c.Remaining
+		c.Remaining = 0
+		f.Queue = f.Queue[1:]
+		e.complete(f, c, cursor, bler)
+	}
+	return sent
+}
+
+// complete accounts for a chunk that has been sent. Packets have no retransmission
+// above HARQ, so the residual BLER loses some; objects ride on TCP and always arrive.
+func (e *Engine) complete(f *Flow, c *Chunk, at time.Time, bler float64) {
+	delay := at.Sub(c.Arrival)
+	isPacket := c.Deadline > 0
+	if isPacket && (delay > c.Deadline || e.Rand.Float64() < bler) {
+		e.drop(f)
+		return
+	}
+
+	s := &f.Stats
+	ms := float64(delay) / float64(time.Millisecond)
+	s.Delivered++
+	s.DeliveredBytes += int64(c.Bytes)
+	s.delaySum += ms
+	s.MaxDelayMs = math.Max(s.MaxDelayMs, ms)
+	if s.lastTransit >= 0 {
+		s.jitter += (math.Abs(ms-s.lastTransit) - s.jitter) / 16
+	}
+	s.lastTransit = ms
+	e.delivered.Add(float64(c.Bytes), s.Type)
+	e.delay.Observe(delay.Seconds(), s.Type)
+	f.Source.Delivered(c, at)
+}
+
+// expire drops queued packets whose deadline has passed
+func (e *Engine) expire(f *Flow, now time.Time) {
+	kept := f.Queue[:0]
+	for _, c := range f.Queue {
+		if c.Deadline > 0 && now.Sub(c.Arrival) > c.Deadline {
+			e.drop(f)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	f.Queue = kept
+}
+
+func (e *Engine) drop(f *Flow) {
+	f.Stats.Lost++
+	e.lost.Inc(f.Stats.Type)
+}
+
+// pending reports whether any of the flows has data waiting
+func pending(flows []*Flow) bool {
+	for _, f := range flows {
+		if len(f.Queue) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Report returns the stats of every active session and of the ended ones kept in
+// Finished, by session ID
+func (e *Engine) Report(now time.Time) []Stats {
+	report := append([]Stats(nil), e.Finished...)
+	for _, f := range e.Flows {
+		report = append(report, f.Stats.summary(now, true))
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Session < report[j].Session })
+	return report
+}
+
+// SessionsOf returns the stats of a UE's active sessions, by session ID
+func (e *Engine) SessionsOf(imsi string, now time.Time) []Stats {
+	var stats []Stats
+	for _, f := range e.Flows {
+		if f.Stats.IMSI == imsi {
+			stats = append(stats, f.Stats.summary(now, true))
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Session < stats[j].Session })
+	return stats
+}
+
+// summary fills in the derived figures as of end
+func (s Stats) summary(end time.Time, active bool) Stats {
+	s.Active = active
+	s.Seconds = end.Sub(s.start).Seconds()
+	if s.Seconds > 0 {
+		s.ThroughputMbps = float64(s.DeliveredBytes) * 8 / 1e6 / s.Seconds
+	}
+	if done := s.Delivered + s.Lost; done > 0 {
+		s.LossRatio = float64(s.Lost) / float64(done)
+	}
+	if s.Delivered > 0 {
+		s.MeanDelayMs = s.delaySum / float64(s.Delivered)
+	}
+	s.JitterMs = s.jitter
+	s.StallSeconds = s.StallTime.Seconds()
+	if s.fetchTime > 0 {
+		s.MeanBitrateMbps = s.bitrateTime / s.fetchTime
+	}
+	return s
+}
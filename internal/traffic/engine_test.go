@@ -0,0 +1,204 @@
+package traffic
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+	"github.com/rizpur/NetSim5G/internal/metrics"
+	"github.com/rizpur/NetSim5G/internal/sim"
+	"github.com/rizpur/NetSim5G/internal/ue"
+)
+
+// recorder is a source that generates nothing and notes what was delivered
+type recorder struct {
+	delivered []time.Time
+}
+
+func (r *recorder) Name() string                               { return "recorder" }
+func (r *recorder) Generate(time.Time, time.Duration) []*Chunk { return nil }
+func (r *recorder) Delivered(c *Chunk, t time.Time)            { r.delivered = append(r.delivered, t) }
+func (r *recorder) Observe(time.Time, time.Duration, *Stats)   {}
+
+// newTestEngine is an engine with no network around it, for serve, expire and complete
+func newTestEngine() (*Engine, *metrics.Registry) {
+	registry := metrics.NewRegistry()
+	return NewEngine(nil, smf.NewSMF(nil, nil), rand.New(rand.NewSource(1)), registry), registry
+}
+
+// newFlow is a flow of the given session type with chunks already queued
+func newFlow(sessionType string, chunks ...*Chunk) (*Flow, *recorder) {
+	source := &recorder{}
+	return &Flow{Source: source, Queue: chunks, Stats: Stats{Type: sessionType, lastTransit: -1}}, source
+}
+
+func at(ms int) time.Time { return sim.Epoch.Add(time.Duration(ms) * time.Millisecond) }
+
+func TestServe(t *testing.T) {
+	// At 8 Mbps a byte takes 1µs
+	const rate = 8
+	e, registry := newTestEngine()
+	object := &Chunk{Arrival: at(6), Bytes: 30000, Remaining: 30000}
+	later := &Chunk{Arrival: at(25), Bytes: 100, Remaining: 100, Deadline: 100 * time.Millisecond}
+	f, source := newFlow("VoIP",
+		&Chunk{Arrival: at(0), Bytes: 1000, Remaining: 1000, Deadline: 100 * time.Millisecond},
+		&Chunk{Arrival: at(5), Bytes: 1000, Remaining: 1000, Deadline: 100 * time.Millisecond},
+		object,
+		later,
+	)
+
+	// Step 1: [0, 20ms): both packets in 1ms each, then the object until the end
+	if sent := e.serve(f, at(0), at(20), rate, 0); sent != 16000 {
+		t.Errorf("sent %d bytes, want 16000", sent)
+	}
+	if len(f.Queue) != 2 || f.Queue[0] != object || object.Remaining != 16000 {
+		t.Fatalf("queue %d long, object with %d bytes left; want the object with 16000 and the later packet", len(f.Queue), object.Remaining)
+	}
+	if want := []time.Time{at(1), at(6)}; len(source.delivered) != 2 || !source.delivered[0].Equal(want[0]) || !source.delivered[1].Equal(want[1]) {
+		t.Errorf("delivered at %v, want %v", source.delivered, want)
+	}
+
+	// Step 2: [20ms, 40ms): the object completes at 36ms, the packet that arrived at 25ms waits for it
+	if sent := e.serve(f, at(20), at(40), rate, 0); sent != 16100 {
+		t.Errorf("sent %d bytes, want 16100", sent)
+	}
+	if len(f.Queue) != 0 || !source.delivered[2].Equal(at(36)) || !source.delivered[3].Equal(at(36).Add(100*time.Microsecond)) {
+		t.Errorf("queue %d long, delivered at %v; want empty, the object at 36ms", len(f.Queue), source.delivered)
+	}
+
+	// Step 3: Data that has not arrived yet is not sent
+	f.Queue = []*Chunk{{Arrival: at(100), Bytes: 10, Remaining: 10}}
+	if sent := e.serve(f, at(40), at(60), rate, 0); sent != 0 || len(f.Queue) != 1 {
+		t.Errorf("sent %d bytes before they arrived", sent)
+	}
+
+	s := f.Stats.summary(at(40), true)
+	if s.Delivered != 4 || s.DeliveredBytes != 32100 || s.Lost != 0 || s.MaxDelayMs != 30 {
+		t.Errorf("delivered %d (%d bytes), lost %d, max delay %g ms; want 4 (32100), 0, 30", s.Delivered, s.DeliveredBytes, s.Lost, s.MaxDelayMs)
+	}
+	if got := registry.Sum("netsim_traffic_delivered_bytes_total"); got != 32100 {
+		t.Errorf("delivered bytes metric %g, want 32100", got)
+	}
+	if got := registry.Count("netsim_traffic_delay_seconds"); got != 4 {
+		t.Errorf("%d delays observed, want 4", got)
+	}
+}
+
+func TestDeadlineDrops(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunk     Chunk
+		bler      float64
+		delivered int
+		lost      int
+	}{
+		{"on time", Chunk{Bytes: 5000, Deadline: 10 * time.Millisecond}, 0, 1, 0},
+		{"just on time", Chunk{Bytes: 10000, Deadline: 10 * time.Millisecond}, 0, 1, 0},
+		{"sent after its deadline", Chunk{Bytes: 20000, Deadline: 10 * time.Millisecond}, 0, 0, 1},
+		{"radio error", Chunk{Bytes: 100, Deadline: 10 * time.Millisecond}, 1, 0, 1},
+		{"object on TCP: never lost", Chunk{Bytes: 20000}, 1, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, registry := newTestEngine()
+			c := tt.chunk
+			c.Arrival, c.Remaining = at(0), c.Bytes
+			f, _ := newFlow("VoIP", &c)
+			e.serve(f, at(0), at(50), 8, tt.bler)
+			if f.Stats.Delivered != tt.delivered || f.Stats.Lost != tt.lost || len(f.Queue) != 0 {
+				t.Errorf("delivered %d, lost %d, %d queued; want %d, %d, 0", f.Stats.Delivered, f.Stats.Lost, len(f.Queue), tt.delivered, tt.lost)
+			}
+			if got := registry.Sum("netsim_traffic_lost_packets_total"); got != float64(tt.lost) {
+				t.Errorf("lost packets metric %g, want %d", got, tt.lost)
+			}
+		})
+	}
+}
+
+func TestExpire(t *testing.T) {
+	e, registry := newTestEngine()
+	stale := &Chunk{Arrival: at(0), Bytes: 100, Remaining: 100, Deadline: 50 * time.Millisecond}
+	due := &Chunk{Arrival: at(10), Bytes: 100, Remaining: 100, Deadline: 50 * time.Millisecond}
+	fresh := &Chunk{Arrival: at(20), Bytes: 100, Remaining: 100, Deadline: 50 * time.Millisecond}
+	object := &Chunk{Arrival: at(0), Bytes: 100, Remaining: 100}
+	f, _ := newFlow("VoIP", stale, due, object, fresh)
+
+	// At 60ms the first packet is 60ms old and goes; one exactly at its deadline stays
+	e.expire(f, at(60))
+	if len(f.Queue) != 3 || f.Queue[0] != due || f.Queue[1] != object || f.Queue[2] != fresh {
+		t.Errorf("kept %d chunks, want the due packet, the object and the fresh packet in order", len(f.Queue))
+	}
+	e.expire(f, at(1000))
+	if len(f.Queue) != 1 || f.Queue[0] != object {
+		t.Errorf("kept %d chunks, want only the object, which has no deadline", len(f.Queue))
+	}
+	if f.Stats.Lost != 3 || registry.Sum("netsim_traffic_lost_packets_total") != 3 {
+		t.Errorf("lost %d, metric %g; want 3", f.Stats.Lost, registry.Sum("netsim_traffic_lost_packets_total"))
+	}
+}
+
+func TestJitter(t *testing.T) {
+	// RFC 3550: J += (|D| - J) / 16 over the transit times of consecutive packets
+	e, _ := newTestEngine()
+	f, _ := newFlow("VoIP")
+	want := 0.0
+	last := -1.0
+	for i, transit := range []int{10, 30, 20, 20, 45} {
+		arrival := at(100 * i)
+		e.complete(f, &Chunk{Arrival: arrival, Bytes: 10, Deadline: time.Second}, arrival.Add(time.Duration(transit)*time.Millisecond), 0)
+		if last >= 0 {
+			want += (math.Abs(float64(transit)-last) - want) / 16
+		}
+		last = float64(transit)
+	}
+	s := f.Stats.summary(at(500), true)
+	if math.Abs(s.JitterMs-want) > 1e-9 || math.Abs(want-3.14178466796875) > 1e-9 {
+		t.Errorf("jitter %g ms, want %g", s.JitterMs, want)
+	}
+	if s.MeanDelayMs != 25 || s.MaxDelayMs != 45 {
+		t.Errorf("mean delay %g ms, max %g; want 25 and 45", s.MeanDelayMs, s.MaxDelayMs)
+	}
+	// One packet has nothing to compare with
+	f, _ = newFlow("VoIP")
+	e.complete(f, &Chunk{Arrival: at(0), Bytes: 10, Deadline: time.Second}, at(40), 0)
+	if f.Stats.jitter != 0 {
+		t.Errorf("jitter %g after one packet, want 0", f.Stats.jitter)
+	}
+}
+
+func TestFinishedCap(t *testing.T) {
+	e, _ := newTestEngine()
+	u := ue.NewUE("999990000000001", 0, 0)
+	session := func(id int) *smf.PDUSession {
+		return &smf.PDUSession{SessionID: id, UE: u, SessionType: smf.IoT, QoS: smf.QoSProfiles[smf.IoT]}
+	}
+
+	// Step 1: Sessions come and go, one at a time
+	const ended = MaxFinished + 3
+	for id := 1; id <= ended; id++ {
+		e.SMF.Sessions[id] = session(id)
+		e.sync(at(id))
+		delete(e.SMF.Sessions, id)
+		e.sync(at(id + 1))
+	}
+	e.SMF.Sessions[ended+1] = session(ended + 1)
+	e.sync(at(ended + 1))
+
+	// Step 2: Only the latest MaxFinished are kept, with their final stats
+	if len(e.Finished) != MaxFinished || e.Finished[0].Session != 4 || e.Finished[MaxFinished-1].Session != ended {
+		t.Fatalf("kept %d ended sessions, from %d to %d; want %d from 4 to %d",
+			len(e.Finished), e.Finished[0].Session, e.Finished[len(e.Finished)-1].Session, MaxFinished, ended)
+	}
+	if s := e.Finished[0]; s.Active || s.Seconds != 0.001 || s.IMSI != u.IMSI || s.Model != "periodic reports" {
+		t.Errorf("ended session %+v, want inactive after 1ms", s)
+	}
+	report := e.Report(at(ended + 2))
+	if len(report) != MaxFinished+1 || !report[MaxFinished].Active || report[MaxFinished].Session != ended+1 {
+		t.Errorf("report of %d sessions, last %+v; want %d, the active one last", len(report), report[len(report)-1], MaxFinished+1)
+	}
+	if active := e.SessionsOf(u.IMSI, at(ended+2)); len(active) != 1 || active[0].Session != ended+1 {
+		t.Errorf("active sessions %v, want only %d", active, ended+1)
+	}
+}
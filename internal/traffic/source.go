@@ -0,0 +1,218 @@
+// Package traffic gives PDU sessions something to carry. Every session gets a source
+// that models its application (a voice codec, an adaptive-bitrate video player, a web
+// browser, a sensor); the Engine queues what the sources produce at the UPF and the
+// serving cell's scheduler sends it over the UE's radio link, measuring what each
+// session actually got: throughput, delay, jitter, loss and video stalls.
+package traffic
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/rizpur/NetSim5G/internal/core/smf"
+)
+
+// Chunk is one unit of downlink data: a packet for VoIP and IoT, a whole object
+// (video segment, web page) for the others, sent as one TCP transfer
+type Chunk struct {
+	Arrival   time.Time // when it reached the UPF
+	Bytes     int
+	Remaining int           // bytes not sent yet
+	Deadline  time.Duration // dropped if still queued this long after arrival; 0 = never
+}
+
+// Source generates a session's downlink traffic
+type Source interface {
+	Name() string
+	// Generate returns the chunks arriving in (now-dt, now]
+	Generate(now time.Time, dt time.Duration) []*Chunk
+	// Delivered tells the source a chunk has fully reached the UE at t
+	Delivered(c *Chunk, t time.Time)
+	// Observe lets the source account for time passing (playback, stalls)
+	Observe(now time.Time, dt time.Duration, stats *Stats)
+}
+
+// NewSource returns the traffic model of a session type. maxMbps is the session's
+// QoS bit rate, which caps the video bitrate.
+func NewSource(sessionType smf.SessionType, maxMbps float64, start time.Time, rng *rand.Rand) Source {
+	switch sessionType {
+	case smf.VoIP:
+		return &VoIP{Rand: rng, next: start}
+	case smf.VideoStreaming:
+		return &Video{Ladder: VideoLadder, SegmentDuration: 2 * time.Second, BufferTarget: 10 * time.Second, MaxMbps: maxMbps}
+	case smf.WebBrowsing:
+		return &Web{Rand: rng, next: start}
+	}
+	return &IoT{Period: 30 * time.Second, Bytes: 200, next: start.Add(time.Duration(rng.Int63n(int64(30 * time.Second))))}
+}
+
+// VoIP is a voice codec with talk spurts: 20ms frames while talking, a small silence
+// descriptor every 160ms while listening. Talk and silence periods are exponential
+// with means of 1s and 1.5s (ITU-T P.59). Frames later than the jitter buffer
+// allows are useless and count as lost.
+type VoIP struct {
+	Rand     *rand.Rand
+	talking  bool
+	switchAt time.Time // end of the current talk spurt or silence
+	next     time.Time // next frame
+}
+
+const (
+	voiceFrameBytes = 72 // AMR-WB 12.65 payload + RTP/UDP/IPv4 headers
+	silenceBytes    = 46 // SID frame + headers
+	voiceInterval   = 20 * time.Millisecond
+	silenceInterval = 160 * time.Millisecond
+	jitterBuffer    = 150 * time.Millisecond
+)
+
+func (v *VoIP) Name() string { return "voice" }
+
+func (v *VoIP) Generate(now time.Time, dt time.Duration) []*Chunk {
+	var chunks []*Chunk
+	for !v.next.After(now) {
+		if !v.next.Before(v.switchAt) {
+			v.talking = !v.talking
+			mean := 1500 * time.Millisecond
+			if v.talking {
+				mean = time.Second
+			}
+			v.switchAt = v.next.Add(time.Duration(v.Rand.ExpFloat64() * float64(mean)))
+		}
+		size, interval := silenceBytes, silenceInterval
+		if v.talking {
+			size, interval = voiceFrameBytes, voiceInterval
+		}
+		chunks = append(chunks, &Chunk{Arrival: v.next, Bytes: size, Remaining: size, Deadline: jitterBuffer})
+		v.next = v.next.Add(interval)
+	}
+	return chunks
+}
+
+func (v *VoIP) Delivered(c *Chunk, t time.Time) {}
+
+func (v *VoIP) Observe(now time.Time, dt time.Duration, stats *Stats) {}
+
+// VideoLadder lists the bitrates (Mbps) a video is encoded at, lowest first
+var VideoLadder = []float64{1, 2.5, 5, 8, 16, 35}
+
+// Video is an adaptive-bitrate player. It fetches one segment at a time while its
+// buffer is below BufferTarget, at the highest bitrate below 80% of the throughput
+// it measured on the last segment. Playback starts once a segment is buffered; an
+// empty buffer during playback is a stall, which lasts until a segment arrives.
+type Video struct {
+	Ladder          []float64
+	SegmentDuration time.Duration
+	BufferTarget    time.Duration
+	MaxMbps         float64 // never fetch above the session's QoS bit rate
+	buffer          time.Duration
+	playing         bool
+	started         bool
+	fetching        bool
+	estimateMbps    float64
+	bitrate         float64 // of the segment in flight
+}
+
+func (v *Video) Name() string { return "adaptive bitrate video" }
+
+func (v *Video) Generate(now time.Time, dt time.Duration) []*Chunk {
+	if v.fetching || v.buffer >= v.BufferTarget {
+		return nil
+	}
+	v.bitrate = v.Ladder[0]
+	for _, rate := range v.Ladder {
+		if rate <= 0.8*v.estimateMbps && rate <= v.MaxMbps {
+			v.bitrate = rate
+		}
+	}
+	v.fetching = true
+	size := int(v.bitrate * 1e6 / 8 * v.SegmentDuration.Seconds())
+	return []*Chunk{{Arrival: now, Bytes: size, Remaining: size}}
+}
+
+func (v *Video) Delivered(c *Chunk, t time.Time) {
+	if took := t.Sub(c.Arrival).Seconds(); took > 0 {
+		v.estimateMbps = float64(c.Bytes) * 8 / 1e6 / took
+	}
+	v.fetching = false
+	v.buffer += v.SegmentDuration
+	v.playing = true
+	v.started = true
+}
+
+func (v *Video) Observe(now time.Time, dt time.Duration, stats *Stats) {
+	if v.fetching {
+		stats.bitrateTime += v.bitrate * dt.Seconds()
+		stats.fetchTime += dt.Seconds()
+	}
+	if !v.playing {
+		if v.started {
+			stats.StallTime += dt
+		}
+		return
+	}
+	stats.PlayTime += dt
+	v.buffer -= dt
+	if v.buffer <= 0 {
+		v.buffer = 0
+		v.playing = false
+		stats.Stalls++
+	}
+}
+
+// Web loads pages one after the other. Page sizes are Pareto distributed (heavy
+// tailed: mostly small pages, now and then a huge one); between pages the user
+// reads for an exponential time with a mean of 10s.
+type Web struct {
+	Rand    *rand.Rand
+	loading bool
+	next    time.Time // next page request
+}
+
+const (
+	pageMinBytes = 50_000
+	pageMaxBytes = 10_000_000
+	pageShape    = 1.2 // Pareto alpha, < 2 = infinite variance
+	readingMean  = 10 * time.Second
+)
+
+func (w *Web) Name() string { return "web browsing" }
+
+func (w *Web) Generate(now time.Time, dt time.Duration) []*Chunk {
+	if w.loading || w.next.After(now) {
+		return nil
+	}
+	w.loading = true
+	size := int(math.Min(pageMaxBytes, pageMinBytes/math.Pow(1-w.Rand.Float64(), 1/pageShape)))
+	return []*Chunk{{Arrival: now, Bytes: size, Remaining: size}}
+}
+
+func (w *Web) Delivered(c *Chunk, t time.Time) {
+	w.loading = false
+	w.next = t.Add(time.Duration(w.Rand.ExpFloat64() * float64(readingMean)))
+}
+
+func (w *Web) Observe(now time.Time, dt time.Duration, stats *Stats) {}
+
+// IoT sends a small report every Period; a report still queued when the next one is
+// due is stale and counts as lost
+type IoT struct {
+	Period time.Duration
+	Bytes  int
+	next   time.Time
+}
+
+func (m *IoT) Name() string { return "periodic reports" }
+
+func (m *IoT) Generate(now time.Time, dt time.Duration) []*Chunk {
+	var chunks []*Chunk
+	for !m.next.After(now) {
+		chunks = append(chunks, &Chunk{Arrival: m.next, Bytes: m.Bytes, Remaining: m.Bytes, Deadline: m.Period})
+		m.next = m.next.Add(m.Period)
+	}
+	return chunks
+}
+
+func (m *IoT) Delivered(c *Chunk, t time.Time) {}
+
+func (m *IoT) Observe(now time.Time, dt time.Duration, stats *Stats) {}
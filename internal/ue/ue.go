@@ -1,24 +1,52 @@
 package ue
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type UE struct {
 	IMSI            string
 	X, Y            float64
 	GNodeBConnected int
+	CellConnected   int // cell index within GNodeBConnected
 	State           UEState
+	Speed           float64  // m/s, as last set by the mobility model
+	Heading         float64  // radians, 0 = towards +x, counter-clockwise
+	Mobility        Mobility // nil = the UE only moves when told to
+}
+
+// Mobility moves a UE as simulated time passes (models live in package mobility).
+// Advance returns where the UE is dt later and updates its Speed and Heading;
+// the caller applies the position, so cell selection and handover follow.
+type Mobility interface {
+	Name() string
+	Advance(u *UE, dt time.Duration) (x, y float64)
 }
 
 type UEState int
 
+// The RRC state of the UE: Connected = RRC_CONNECTED, Idle = RRC_IDLE,
+// Inactive = RRC_INACTIVE (context suspended at an anchor gNodeB)
 const (
 	Disconnected UEState = iota
 	Connected
 	Idle
+	Inactive
 )
 
 func (u UEState) String() string {
-	return [...]string{"disconnected", "connected", "idle"}[u]
+	return [...]string{"disconnected", "connected", "idle", "inactive"}[u]
+}
+
+// ParseUEState converts a name such as "connected" back to its UEState
+func ParseUEState(name string) (UEState, error) {
+	for s := Disconnected; s <= Inactive; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown UE state %q", name)
 }
 
 func NewUE(imsi string, x, y float64) *UE {
@@ -27,6 +55,7 @@ func NewUE(imsi string, x, y float64) *UE {
 		X:               x,
 		Y:               y,
 		GNodeBConnected: -1,
+		CellConnected:   -1,
 		State:           Disconnected,
 	}
 }